@@ -0,0 +1,330 @@
+package sip
+
+import (
+	"sip/header"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The directives of the Request-Disposition header, RFC 3841 §9.1.
+const (
+	DISPOSITION_PROXY      = "proxy"
+	DISPOSITION_REDIRECT   = "redirect"
+	DISPOSITION_CANCEL     = "cancel"
+	DISPOSITION_NO_CANCEL  = "no-cancel"
+	DISPOSITION_FORK       = "fork"
+	DISPOSITION_NO_FORK    = "no-fork"
+	DISPOSITION_RECURSE    = "recurse"
+	DISPOSITION_NO_RECURSE = "no-recurse"
+	DISPOSITION_PARALLEL   = "parallel"
+	DISPOSITION_SEQUENTIAL = "sequential"
+	DISPOSITION_QUEUE      = "queue"
+	DISPOSITION_NO_QUEUE   = "no-queue"
+)
+
+// featureTags are the base feature tags of RFC 3840 §10, which a Contact
+// carries without the "+" the other feature tags start with.
+var featureTags = []string{
+	"audio", "automata", "class", "duplex", "data", "control", "mobility",
+	"description", "events", "priority", "methods", "schemes", "application",
+	"video", "language", "type", "isfocus", "actor", "text", "extensions",
+}
+
+// Disposition is how the caller asks the proxies to handle a request with
+// its Request-Disposition, RFC 3841 §9.1. The zero Disposition is the
+// default of every directive: proxy, cancel, fork, recurse, parallel and
+// no-queue.
+type Disposition struct {
+	Redirect   bool
+	NoCancel   bool
+	NoFork     bool
+	NoRecurse  bool
+	Sequential bool
+	Queue      bool
+}
+
+// GetDisposition returns the Disposition of the Request-Disposition of
+// req. A later directive overrides an earlier one it contradicts.
+func GetDisposition(req Request) (Disposition, error) {
+	var d Disposition
+	headers, err := parseHeaders(req, "Request-Disposition")
+	if err != nil {
+		return d, err
+	}
+	for _, h := range headers {
+		for e := h.(*header.RequestDispositionList).Front(); e != nil; e = e.Next() {
+			switch directive := e.Value.(*header.RequestDisposition).GetDirective(); directive {
+			case DISPOSITION_PROXY, DISPOSITION_REDIRECT:
+				d.Redirect = directive == DISPOSITION_REDIRECT
+			case DISPOSITION_CANCEL, DISPOSITION_NO_CANCEL:
+				d.NoCancel = directive == DISPOSITION_NO_CANCEL
+			case DISPOSITION_FORK, DISPOSITION_NO_FORK:
+				d.NoFork = directive == DISPOSITION_NO_FORK
+			case DISPOSITION_RECURSE, DISPOSITION_NO_RECURSE:
+				d.NoRecurse = directive == DISPOSITION_NO_RECURSE
+			case DISPOSITION_PARALLEL, DISPOSITION_SEQUENTIAL:
+				d.Sequential = directive == DISPOSITION_SEQUENTIAL
+			case DISPOSITION_QUEUE, DISPOSITION_NO_QUEUE:
+				d.Queue = directive == DISPOSITION_QUEUE
+			}
+		}
+	}
+	return d, nil
+}
+
+// SelectTargets applies the caller preferences of req, a request a proxy or
+// redirect server routes to the registered contacts of its Request-URI, to
+// the target set contacts, RFC 3841 §7.2. It discards the contacts whose
+// feature tags, their contact predicate, don't support the method of req or
+// match a Reject-Contact, and those not matching an Accept-Contact with the
+// require parameter. The contacts left are ordered by their q value, then
+// by how well they match the Accept-Contact preferences. The contacts
+// without feature tags are immune to the caller preferences.
+func SelectTargets(req Request, contacts []*header.Contact) ([]*header.Contact, error) {
+	preferences, err := callerPreferencesOf(req)
+	if err != nil {
+		return nil, err
+	}
+	type target struct {
+		contact *header.Contact
+		q       float32
+		qa      float64
+	}
+	var targets []target
+	for _, c := range contacts {
+		qa, ok := preferences.score(featurePredicate(c))
+		if !ok {
+			continue
+		}
+		q := c.GetQValue()
+		if q < 0 {
+			q = 1
+		}
+		targets = append(targets, target{c, q, qa})
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].q != targets[j].q {
+			return targets[i].q > targets[j].q
+		}
+		return targets[i].qa > targets[j].qa
+	})
+	selected := make([]*header.Contact, len(targets))
+	for i, t := range targets {
+		selected[i] = t.contact
+	}
+	return selected, nil
+}
+
+// SelectBindings applies the caller preferences of req to the bindings
+// a registrar keeps for its Request-URI, as SelectTargets does.
+func SelectBindings(req Request, bindings []Binding) ([]Binding, error) {
+	var contacts []*header.Contact
+	byContact := make(map[*header.Contact]Binding)
+	for _, b := range bindings {
+		h, err := parseHeaderValue("Contact", b.Contact)
+		if err != nil {
+			return nil, err
+		}
+		c := h.(*header.ContactList).GetContacts()[0].(*header.Contact)
+		contacts = append(contacts, c)
+		byContact[c] = b
+	}
+	contacts, err := SelectTargets(req, contacts)
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]Binding, len(contacts))
+	for i, c := range contacts {
+		selected[i] = byContact[c]
+	}
+	return selected, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// predicate is the value of each feature tag of a contact or preference,
+// the values of a tag-value-list one by one.
+type predicate map[string][]string
+
+// callerPreferences are the Accept-Contact and Reject-Contact of a request,
+// with the implicit preferences of its method, RFC 3841 §7.2.1.
+type callerPreferences struct {
+	method string
+	event  string
+	accept []*header.AcceptContact
+	reject []predicate
+}
+
+func callerPreferencesOf(req Request) (*callerPreferences, error) {
+	this := &callerPreferences{method: req.GetMethod()}
+	if req.GetMethod() == SUBSCRIBE {
+		this.event = strings.TrimSpace(strings.SplitN(req.GetHeader().Get("Event"), ";", 2)[0])
+	}
+	headers, err := parseHeaders(req, "Accept-Contact")
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		for e := h.(*header.AcceptContactList).Front(); e != nil; e = e.Next() {
+			this.accept = append(this.accept, e.Value.(*header.AcceptContact))
+		}
+	}
+	if headers, err = parseHeaders(req, "Reject-Contact"); err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		for e := h.(*header.RejectContactList).Front(); e != nil; e = e.Next() {
+			this.reject = append(this.reject, featurePredicate(e.Value.(*header.RejectContact)))
+		}
+	}
+	return this, nil
+}
+
+// score returns the caller preference of a contact predicate, Qa of RFC 3841
+// §7.2.4, and false when the contact is discarded.
+func (this *callerPreferences) score(contact predicate) (float64, bool) {
+	if len(contact) == 0 {
+		//RFC 3841 §7.2.3, an immune contact
+		return 1, true
+	}
+	//the implicit preferences, a contact only gets the methods and event
+	//packages it supports
+	if methods, ok := contact["methods"]; ok && !matchValues(methods, []string{this.method}) {
+		return 0, false
+	}
+	if events, ok := contact["events"]; ok && this.event != "" && !matchValues(events, []string{this.event}) {
+		return 0, false
+	}
+	for _, reject := range this.reject {
+		matches := len(reject) > 0
+		for tag, values := range reject {
+			v, ok := contact[tag]
+			matches = matches && ok && matchValues(v, values)
+		}
+		if matches {
+			return 0, false
+		}
+	}
+
+	total, n := 0.0, 0
+	for _, accept := range this.accept {
+		preference := featurePredicate(accept)
+		if len(preference) == 0 {
+			continue
+		}
+		matches, common := true, 0
+		for tag, values := range preference {
+			if v, ok := contact[tag]; ok {
+				common++
+				matches = matches && matchValues(v, values)
+			}
+		}
+		if accept.IsExplicit() && common < len(preference) {
+			matches = false
+		}
+		if !matches && accept.IsRequired() {
+			return 0, false
+		}
+		if matches {
+			total += float64(common) / float64(len(preference))
+		}
+		n++
+	}
+	if n == 0 {
+		//without preferences, only the q values order the contacts
+		return 1, true
+	}
+	return total / float64(n), true
+}
+
+// featurePredicate returns the feature tags of the parameters of h, RFC
+// 3840 §9, with their values.
+func featurePredicate(h header.ParametersHeader) predicate {
+	p := make(predicate)
+	for e := h.GetParameterNames().Front(); e != nil; e = e.Next() {
+		name := strings.ToLower(e.Value.(string))
+		if !strings.HasPrefix(name, "+") && !containsFold(featureTags, name) {
+			continue
+		}
+		p[name] = featureValues(h.GetParameter(name))
+	}
+	return p
+}
+
+// featureValues returns the tag-values of a feature parameter value, TRUE
+// for a parameter without value.
+func featureValues(value string) []string {
+	if value == "" {
+		return []string{"TRUE"}
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+	var values []string
+	inString := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '<':
+			inString = true
+		case '>':
+			inString = false
+		case ',':
+			if !inString {
+				values = append(values, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(values, strings.TrimSpace(value[start:]))
+}
+
+// matchValues reports whether a value of the contact satisfies a value of
+// the preference, RFC 2533 §5.
+func matchValues(contact, preference []string) bool {
+	for _, p := range preference {
+		negated := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		for _, c := range contact {
+			if matchValue(c, p) != negated {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchValue(c, p string) bool {
+	switch {
+	case strings.HasPrefix(p, "<"):
+		return c == p
+	case strings.HasPrefix(p, "#"):
+		n, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimPrefix(c, "#"), "="), 64)
+		if err != nil {
+			return false
+		}
+		return matchNumeric(n, p[1:])
+	}
+	return strings.EqualFold(c, p)
+}
+
+// matchNumeric reports whether n is in a numeric range of RFC 3840 §9:
+// "=x", "x", ">=x", "<=x" or "x:y".
+func matchNumeric(n float64, r string) bool {
+	bounds := []float64{}
+	for _, b := range strings.SplitN(strings.TrimLeft(r, "=<>"), ":", 2) {
+		x, err := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if err != nil {
+			return false
+		}
+		bounds = append(bounds, x)
+	}
+	switch {
+	case len(bounds) == 2:
+		return n >= bounds[0] && n <= bounds[1]
+	case strings.HasPrefix(r, ">="):
+		return n >= bounds[0]
+	case strings.HasPrefix(r, "<="):
+		return n <= bounds[0]
+	}
+	return n == bounds[0]
+}
@@ -0,0 +1,117 @@
+package sip
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func testContacts(t *testing.T, values ...string) []*header.Contact {
+	var contacts []*header.Contact
+	for _, v := range values {
+		h, err := parseHeaderValue("Contact", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contacts = append(contacts, h.(*header.ContactList).GetContacts()[0].(*header.Contact))
+	}
+	return contacts
+}
+
+func contactURIs(contacts []*header.Contact) []string {
+	var uris []string
+	for _, c := range contacts {
+		uris = append(uris, c.GetAddress().GetURI().String())
+	}
+	return uris
+}
+
+func TestSelectTargets(t *testing.T) {
+	contacts := testContacts(t,
+		"<sip:immune@192.0.2.1>;q=0.2",
+		"<sip:audio@192.0.2.2>;audio;methods=\"INVITE,BYE\";q=0.5",
+		"<sip:video@192.0.2.3>;audio;video;methods=\"INVITE,BYE\";q=0.5",
+		"<sip:mute@192.0.2.4>;audio=\"FALSE\";q=0.5",
+		"<sip:vm@192.0.2.5>;actor=\"msg-taker\";video",
+		"<sip:im@192.0.2.6>;methods=\"MESSAGE\"",
+	)
+	req := testInvite(t, false)
+	req.GetHeader().Set("Accept-Contact", "*;audio;require, *;video")
+	req.GetHeader().Set("Reject-Contact", "*;actor=\"msg-taker\";video")
+	selected, err := SelectTargets(req, contacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uris := contactURIs(selected)
+	if len(uris) != 3 || uris[0] != "sip:video@192.0.2.3" || uris[1] != "sip:audio@192.0.2.2" || uris[2] != "sip:immune@192.0.2.1" {
+		t.Error(uris)
+	}
+
+	//without preferences only the method and the q values count
+	req = testInvite(t, false)
+	selected, _ = SelectTargets(req, contacts)
+	if uris := contactURIs(selected); len(uris) != 5 || uris[0] != "sip:vm@192.0.2.5" || uris[4] != "sip:immune@192.0.2.1" {
+		t.Error(uris)
+	}
+
+	//explicit only counts the contacts that say they have the feature
+	req.GetHeader().Set("Accept-Contact", "*;video;explicit;require")
+	selected, _ = SelectTargets(req, contacts)
+	if uris := contactURIs(selected); len(uris) != 3 || uris[0] != "sip:vm@192.0.2.5" || uris[1] != "sip:video@192.0.2.3" {
+		t.Error(uris)
+	}
+}
+
+func TestSelectTargetsValues(t *testing.T) {
+	contacts := testContacts(t,
+		"<sip:a@192.0.2.1>;+sip.instance=\"<urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6>\";+priority=\"#5\"",
+		"<sip:b@192.0.2.2>;+sip.instance=\"<urn:uuid:00000000-0000-1000-8000-000000000000>\";+priority=\"#1\"",
+		"<sip:c@192.0.2.3>;mobility=\"fixed\";language=\"en,fr\"",
+	)
+	for preference, expected := range map[string]string{
+		"*;+sip.instance=\"<urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6>\";require;explicit": "sip:a@192.0.2.1",
+		"*;+priority=\"#<=2\";require;explicit":                                                "sip:b@192.0.2.2",
+		"*;+priority=\"#3:9\";require;explicit":                                                "sip:a@192.0.2.1",
+		"*;mobility=\"!mobile\";language=\"fr\";require;explicit":                              "sip:c@192.0.2.3",
+	} {
+		req := testInvite(t, false)
+		req.GetHeader().Set("Accept-Contact", preference)
+		selected, err := SelectTargets(req, contacts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uris := contactURIs(selected); len(uris) != 1 || uris[0] != expected {
+			t.Error(preference, uris)
+		}
+	}
+}
+
+func TestSelectBindings(t *testing.T) {
+	bindings := []Binding{
+		{Contact: "<sip:bob@192.0.2.4>;audio", URI: "sip:bob@192.0.2.4"},
+		{Contact: "<sip:bob@192.0.2.5>;audio;video", URI: "sip:bob@192.0.2.5"},
+	}
+	req := testInvite(t, false)
+	req.GetHeader().Set("a", "*;video")
+	selected, err := SelectBindings(req, bindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].URI != "sip:bob@192.0.2.5" {
+		t.Error(selected)
+	}
+}
+
+func TestGetDisposition(t *testing.T) {
+	req := testInvite(t, false)
+	if d, err := GetDisposition(req); err != nil || d != (Disposition{}) {
+		t.Error(d, err)
+	}
+	req.GetHeader().Set("Request-Disposition", "redirect, sequential, no-cancel")
+	d, err := GetDisposition(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Redirect || !d.Sequential || !d.NoCancel || d.NoFork || d.Queue {
+		t.Error(d)
+	}
+}
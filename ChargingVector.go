@@ -0,0 +1,92 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+)
+
+// GenerateICID returns a new IMS Charging Identifier, which RFC 7315 §5.6
+// asks to be globally unique over time.
+func GenerateICID() string {
+	return randomHex(16)
+}
+
+// GetChargingVector returns the P-Charging-Vector of msg, or nil if msg
+// does not carry one.
+func GetChargingVector(msg Message) (*header.PChargingVector, error) {
+	h, err := parseHeader(msg, "P-Charging-Vector")
+	if err != nil || h == nil {
+		return nil, err
+	}
+	return h.(*header.PChargingVector), nil
+}
+
+// AddChargingVector adds a P-Charging-Vector with a new ICID generated at
+// genAddr, the host of the node, or "", to req and returns the ICID. The
+// ICID of a vector req already carries is kept.
+func AddChargingVector(req Request, genAddr string) (string, error) {
+	pcv, err := GetChargingVector(req)
+	if err != nil || pcv != nil {
+		if pcv != nil {
+			return pcv.GetICID(), nil
+		}
+		return "", err
+	}
+	pcv = header.NewPChargingVector()
+	if err := pcv.SetICID(GenerateICID()); err != nil {
+		return "", err
+	}
+	pcv.SetICIDGeneratedAt(genAddr)
+	req.GetHeader().Set("P-Charging-Vector", pcv.EncodeBody())
+	return pcv.GetICID(), nil
+}
+
+// PropagateChargingVector copies the ICID of incoming, a message a B2BUA
+// received, to outgoing, the message it sends on the other leg, and returns
+// it. The inter operator identifiers are dropped, as they identify the
+// operators of the incoming leg, RFC 7315 §5.6. A new ICID generated at
+// genAddr is used when incoming carries no vector. The
+// P-Charging-Function-Addresses of incoming are copied too, unless outgoing
+// carries its own.
+func PropagateChargingVector(incoming, outgoing Message, genAddr string) (string, error) {
+	pcv, err := GetChargingVector(incoming)
+	if err != nil {
+		return "", err
+	}
+	propagated := header.NewPChargingVector()
+	if pcv != nil {
+		if err := propagated.SetICID(pcv.GetICID()); err != nil {
+			return "", err
+		}
+		propagated.SetICIDGeneratedAt(pcv.GetICIDGeneratedAt())
+	} else {
+		if err := propagated.SetICID(GenerateICID()); err != nil {
+			return "", err
+		}
+		propagated.SetICIDGeneratedAt(genAddr)
+	}
+	outgoing.GetHeader().Set("P-Charging-Vector", propagated.EncodeBody())
+
+	if addresses := headerValues(incoming, "P-Charging-Function-Addresses"); len(addresses) > 0 &&
+		len(headerValues(outgoing, "P-Charging-Function-Addresses")) == 0 {
+		outgoing.GetHeader().Set("P-Charging-Function-Addresses", addresses[0])
+	}
+	return propagated.GetICID(), nil
+}
+
+// SetInterOperatorIdentifiers sets the orig-ioi and term-ioi of the
+// P-Charging-Vector of msg, which identify the operators of the originating
+// and the terminating network. An empty identifier is removed.
+func SetInterOperatorIdentifiers(msg Message, origIOI, termIOI string) error {
+	pcv, err := GetChargingVector(msg)
+	if err != nil {
+		return err
+	}
+	if pcv == nil {
+		return errors.New("the message carries no P-Charging-Vector")
+	}
+	pcv.SetOrigIOI(origIOI)
+	pcv.SetTermIOI(termIOI)
+	msg.GetHeader().Set("P-Charging-Vector", pcv.EncodeBody())
+	return nil
+}
@@ -0,0 +1,50 @@
+package sip
+
+import (
+	"testing"
+)
+
+func TestChargingVector(t *testing.T) {
+	req := testInvite(t, false)
+	icid, err := AddChargingVector(req, "192.0.6.8")
+	if err != nil || len(icid) != 32 {
+		t.Fatal(icid, err)
+	}
+	if again, _ := AddChargingVector(req, "192.0.6.9"); again != icid {
+		t.Error(again)
+	}
+	if err := SetInterOperatorIdentifiers(req, "home1.net", ""); err != nil {
+		t.Fatal(err)
+	}
+	if v := req.GetHeader().Get("P-Charging-Vector"); v != "icid-value="+icid+";icid-generated-at=192.0.6.8;orig-ioi=home1.net" {
+		t.Error(v)
+	}
+	req.GetHeader().Set("P-Charging-Function-Addresses", "ccf=192.1.1.1;ccf=192.1.1.2")
+
+	//the outgoing leg of a B2BUA keeps the ICID but not the IOIs
+	out := testInvite(t, false)
+	if propagated, err := PropagateChargingVector(req, out, "192.0.6.9"); err != nil || propagated != icid {
+		t.Fatal(propagated, err)
+	}
+	pcv, err := GetChargingVector(out)
+	if err != nil || pcv.GetICID() != icid || pcv.GetICIDGeneratedAt() != "192.0.6.8" || pcv.GetOrigIOI() != "" {
+		t.Fatal(pcv, err)
+	}
+	if v := out.GetHeader().Get("P-Charging-Function-Addresses"); v != "ccf=192.1.1.1;ccf=192.1.1.2" {
+		t.Error(v)
+	}
+
+	//a vector is generated when the incoming leg has none
+	out = testInvite(t, false)
+	generated, err := PropagateChargingVector(testInvite(t, false), out, "192.0.6.9")
+	if err != nil || generated == "" || generated == icid {
+		t.Fatal(generated, err)
+	}
+	if pcv, _ := GetChargingVector(out); pcv.GetICIDGeneratedAt() != "192.0.6.9" {
+		t.Error(pcv)
+	}
+
+	if err := SetInterOperatorIdentifiers(testInvite(t, false), "home1.net", "home2.net"); err == nil {
+		t.Error("set the identifiers of a missing vector")
+	}
+}
@@ -1,5 +1,11 @@
 package sip
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
 type ClientTransaction interface {
 	Transaction
 
@@ -22,11 +28,39 @@ func newClientTransaction(request Request) *clientTransaction {
 }
 
 func (this *clientTransaction) SendRequest() error {
+	if this.provider != nil {
+		return this.provider.SendRequest(this.request)
+	}
 	return nil
 }
 
+// CreateCancel builds the CANCEL of the request of the transaction, RFC 3261
+// §9.1. It shares the Request-URI, Call-ID, To, From, CSeq number, Route and
+// topmost Via of the request it cancels.
 func (this *clientTransaction) CreateCancel() (Request, error) {
-	return nil, nil
+	req := this.GetRequest()
+	if req.GetMethod() != INVITE {
+		return nil, errors.New("only an INVITE can be cancelled")
+	}
+	vias := headerValues(req, "Via")
+	if len(vias) == 0 {
+		return nil, errors.New("the request has no Via header")
+	}
+	cancel := NewRequest(CANCEL, req.GetRequestURI(), nil)
+	h := cancel.GetHeader()
+	h.Set("Via", strings.TrimSpace(strings.SplitN(vias[0], ",", 2)[0]))
+	for _, name := range []string{"To", "From", "Call-ID"} {
+		if v := headerValues(req, name); len(v) > 0 {
+			h.Set(name, v[0])
+		}
+	}
+	h.Set("CSeq", strconv.Itoa(cSeqOf(req))+" "+CANCEL)
+	for _, route := range headerValues(req, "Route") {
+		h.Add("Route", route)
+	}
+	h.Set("Max-Forwards", "70")
+	cancel.SetContentLength(0)
+	return cancel, nil
 }
 
 func (this *clientTransaction) CreateAck() (Request, error) {
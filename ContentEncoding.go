@@ -0,0 +1,246 @@
+package sip
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// The content codings the stack decodes and produces, RFC 3261 §20.12.
+const (
+	ENCODING_GZIP     = "gzip"
+	ENCODING_DEFLATE  = "deflate"
+	ENCODING_IDENTITY = "identity"
+)
+
+// CompressionThreshold is the body size, in bytes, above which bodies are
+// compressed for a peer that accepts it. Smaller bodies gain too little to
+// be worth the peer's effort.
+var CompressionThreshold = 1024
+
+// MaxDecodedBodySize is the size, in bytes, a compressed body may inflate
+// to. A larger body is left encoded: the request is answered with 413 and
+// the response dropped, so that a small message can't exhaust the memory.
+var MaxDecodedBodySize int64 = 1 << 20
+
+var ErrUnsupportedEncoding = errors.New("unsupported content coding")
+
+var ErrBodyTooLarge = errors.New("decoded body too large")
+
+// acceptedEncodings is advertised in the Accept-Encoding header of the
+// target refresh requests and responses sent within a dialog.
+var acceptedEncodings = []string{ENCODING_GZIP, ENCODING_DEFLATE, ENCODING_IDENTITY}
+
+// contentCodings returns the codings listed by the Content-Encoding of msg,
+// in the order they were applied, identity left out.
+func contentCodings(msg Message) []string {
+	var codings []string
+	for _, v := range headerValues(msg, "Content-Encoding") {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if c != "" && c != ENCODING_IDENTITY {
+				codings = append(codings, c)
+			}
+		}
+	}
+	return codings
+}
+
+// decodeContent undoes the codings applied to body, the last one first. It
+// fails with ErrBodyTooLarge once the content exceeds MaxDecodedBodySize.
+func decodeContent(codings []string, body []byte) ([]byte, error) {
+	for i := len(codings) - 1; i >= 0; i-- {
+		var r io.ReadCloser
+		var err error
+		switch codings[i] {
+		case ENCODING_GZIP, "x-gzip":
+			r, err = gzip.NewReader(bytes.NewReader(body))
+		case ENCODING_DEFLATE:
+			r, err = newDeflateReader(body)
+		default:
+			return nil, ErrUnsupportedEncoding
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err = ioutil.ReadAll(io.LimitReader(r, MaxDecodedBodySize+1))
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > MaxDecodedBodySize {
+			return nil, ErrBodyTooLarge
+		}
+	}
+	return body, nil
+}
+
+// decodedTooLarge reports whether the encoded body of msg inflated beyond
+// MaxDecodedBodySize when ReadMessage decoded it.
+func decodedTooLarge(msg Message) bool {
+	m, ok := msg.(interface{ isBodyTooLarge() bool })
+	return ok && m.isBodyTooLarge()
+}
+
+func markBodyTooLarge(msg Message) {
+	if m, ok := msg.(interface{ setBodyTooLarge() }); ok {
+		m.setBodyTooLarge()
+	}
+}
+
+// newDeflateReader reads a deflate body. The coding is the zlib format,
+// RFC 1950, but some peers send raw deflate data, RFC 1951, instead.
+func newDeflateReader(body []byte) (io.ReadCloser, error) {
+	if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+		return r, nil
+	}
+	return flate.NewReader(bytes.NewReader(body)), nil
+}
+
+func encodeContent(coding string, body []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case ENCODING_GZIP:
+		w = gzip.NewWriter(&b)
+	case ENCODING_DEFLATE:
+		w = zlib.NewWriter(&b)
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeBody replaces the encoded body of msg by its decoded content and
+// drops its Content-Encoding. Received messages are decoded by ReadMessage,
+// so that only a body with an unsupported coding is left encoded.
+func DecodeBody(msg Message) error {
+	codings := contentCodings(msg)
+	if len(codings) == 0 {
+		return nil
+	}
+	body, err := rawBodyBytes(msg)
+	if err != nil {
+		return err
+	}
+	if body, err = decodeContent(codings, body); err != nil {
+		return err
+	}
+	setDecodedBody(msg, body)
+	return nil
+}
+
+func setDecodedBody(msg Message, body []byte) {
+	msg.SetBody(bytes.NewReader(body))
+	msg.SetContentLength(int64(len(body)))
+	msg.GetHeader().Del("Content-Encoding")
+	msg.GetHeader().Del("E")
+}
+
+// CompressBody encodes the body of msg with coding, gzip or deflate, and
+// sets its Content-Encoding and Content-Length.
+func CompressBody(msg Message, coding string) error {
+	coding = strings.ToLower(coding)
+	if len(contentCodings(msg)) > 0 {
+		return errors.New("the body is already encoded")
+	}
+	body, err := rawBodyBytes(msg)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	encoded, err := encodeContent(coding, body)
+	if err != nil {
+		return err
+	}
+	msg.SetBody(bytes.NewReader(encoded))
+	msg.SetContentLength(int64(len(encoded)))
+	msg.GetHeader().Del("E")
+	msg.GetHeader().Set("Content-Encoding", coding)
+	return nil
+}
+
+// compressFor compresses the body of msg when it is larger than
+// CompressionThreshold and the peer listed a coding we produce in the
+// given Accept-Encoding values. The body is sent as is otherwise.
+func compressFor(msg Message, acceptEncoding []string) error {
+	if msg.GetContentLength() <= int64(CompressionThreshold) || len(contentCodings(msg)) > 0 {
+		return nil
+	}
+	coding := preferredEncoding(acceptEncoding)
+	if coding == "" {
+		return nil
+	}
+	return CompressBody(msg, coding)
+}
+
+// preferredEncoding returns the coding we produce with the highest q-value
+// in the given Accept-Encoding values, gzip on a tie, or "" when neither
+// gzip nor deflate is acceptable, RFC 3261 §20.2.
+func preferredEncoding(acceptEncoding []string) string {
+	var best string
+	var bestQ float64
+	wildcard := -1.0
+	qs := make(map[string]float64)
+	for _, v := range acceptEncoding {
+		for _, e := range strings.Split(v, ",") {
+			params := strings.Split(e, ";")
+			coding := strings.ToLower(strings.TrimSpace(params[0]))
+			q := 1.0
+			for _, p := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+					if f, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+						q = f
+					}
+				}
+			}
+			if coding == "*" {
+				wildcard = q
+			} else if coding == "x-gzip" {
+				qs[ENCODING_GZIP] = q
+			} else {
+				qs[coding] = q
+			}
+		}
+	}
+	for _, coding := range []string{ENCODING_GZIP, ENCODING_DEFLATE} {
+		q, ok := qs[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// rawBodyBytes reads the whole body of msg, as it is encoded, and leaves a
+// rewindable copy in its place.
+func rawBodyBytes(msg Message) ([]byte, error) {
+	body := msg.GetBody()
+	if body == nil {
+		return nil, nil
+	}
+	if s, ok := body.(io.Seeker); ok {
+		s.Seek(0, io.SeekStart)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	msg.SetBody(bytes.NewReader(b))
+	return b, nil
+}
@@ -0,0 +1,139 @@
+package sip
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadCompressedMessage(t *testing.T) {
+	for _, coding := range []string{ENCODING_GZIP, ENCODING_DEFLATE} {
+		body, err := encodeContent(coding, []byte(testSDP))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := "INVITE sip:bob@biloxi.com SIP/2.0\r\n" +
+			"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\r\n" +
+			"To: Bob <sip:bob@biloxi.com>\r\n" +
+			"From: Alice <sip:alice@atlanta.com>;tag=1928301774\r\n" +
+			"Call-ID: a84b4c76e66710@pc33.atlanta.com\r\n" +
+			"CSeq: 314159 INVITE\r\n" +
+			"Content-Type: application/sdp\r\n" +
+			"e: " + coding + "\r\n" +
+			"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body)
+		msg := readTestMessage(t, s)
+		if msg.GetContentLength() != int64(len(testSDP)) || len(contentCodings(msg)) != 0 {
+			t.Fatal(coding, msg.GetContentLength(), msg.GetHeader())
+		}
+		if b, _ := bodyBytes(msg); string(b) != testSDP {
+			t.Log(coding, string(b))
+			t.Fail()
+		}
+	}
+
+	s := "MESSAGE sip:bob@biloxi.com SIP/2.0\r\n" +
+		"Content-Encoding: br\r\n" +
+		"Content-Length: 4\r\n\r\nabcd"
+	msg := readTestMessage(t, s)
+	if msg.GetHeader().Get("Content-Encoding") != "br" || msg.GetContentLength() != 4 {
+		t.Log(msg.GetHeader())
+		t.Fail()
+	}
+	if err := DecodeBody(msg); err != ErrUnsupportedEncoding {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	body, _ := encodeContent(ENCODING_GZIP, make([]byte, MaxDecodedBodySize+1))
+	s := "MESSAGE sip:bob@biloxi.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: Bob <sip:bob@biloxi.com>\r\n" +
+		"From: Alice <sip:alice@atlanta.com>;tag=1928301774\r\n" +
+		"Call-ID: a84b4c76e66710@pc33.atlanta.com\r\n" +
+		"CSeq: 1 MESSAGE\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Encoding: gzip\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body)
+	req := readTestMessage(t, s).(Request)
+	if req.GetContentLength() != int64(len(body)) {
+		t.Error("the body was inflated", req.GetContentLength())
+	}
+	if resp, _ := NewValidator().ValidateRequest(req); resp == nil || resp.GetStatusCode() != REQUEST_ENTITY_TOO_LARGE {
+		t.Error(resp)
+	}
+	if err := DecodeBody(req); err != ErrBodyTooLarge {
+		t.Error(err)
+	}
+}
+
+func TestPreferredEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                            "",
+		"identity":                    "",
+		"gzip":                        ENCODING_GZIP,
+		"deflate, gzip":               ENCODING_GZIP,
+		"gzip;q=0.5, deflate":         ENCODING_DEFLATE,
+		"gzip;q=0, *":                 ENCODING_DEFLATE,
+		"*;q=0":                       "",
+		"x-gzip":                      ENCODING_GZIP,
+		"identity, deflate;q=0.1":     ENCODING_DEFLATE,
+		"gzip;q=0.2, deflate;q=0.200": ENCODING_GZIP,
+	}
+	for accept, expected := range tests {
+		if coding := preferredEncoding([]string{accept}); coding != expected {
+			t.Log(accept, coding)
+			t.Fail()
+		}
+	}
+}
+
+func TestCompressResponse(t *testing.T) {
+	invite := testInvite(t, true)
+	invite.GetHeader().Set("Accept-Encoding", "gzip")
+	d, st := newTestServerDialog(t, invite)
+	large := strings.Repeat("a=sendrecv\r\n", CompressionThreshold/12+1)
+
+	resp := newResponseFor(invite, OK)
+	resp.SetBody(strings.NewReader(testSDP + large))
+	resp.SetContentLength(int64(len(testSDP + large)))
+	resp.GetHeader().Set("Content-Type", "application/sdp")
+	if err := st.SendResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetHeader().Get("Content-Encoding") != ENCODING_GZIP || resp.GetContentLength() >= int64(len(testSDP+large)) {
+		t.Fatal(resp.GetHeader(), resp.GetContentLength())
+	}
+	if b, _ := bodyBytes(resp); string(b) != testSDP+large {
+		t.Fail()
+	}
+	if d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Log(d.GetOfferAnswerState())
+		t.Fail()
+	}
+
+	//the message on the wire reads back decoded
+	var b bytes.Buffer
+	if err := resp.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	msg := readTestMessage(t, b.String())
+	if msg.GetContentLength() != int64(len(testSDP+large)) {
+		t.Log(msg.GetContentLength())
+		t.Fail()
+	}
+
+	//small bodies and peers without Accept-Encoding get the body as is
+	small := sdpResponse(testInvite(t, true), OK)
+	newServerTransaction(invite).SendResponse(small)
+	other := sdpResponse(testInvite(t, true), OK)
+	other.SetBody(strings.NewReader(large))
+	other.SetContentLength(int64(len(large)))
+	newServerTransaction(testInvite(t, true)).SendResponse(other)
+	if small.GetHeader().Get("Content-Encoding") != "" || other.GetHeader().Get("Content-Encoding") != "" {
+		t.Fail()
+	}
+}
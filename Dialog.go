@@ -1,5 +1,19 @@
 package sip
 
+import (
+	"bytes"
+	"errors"
+	"sip/address"
+	"sip/header"
+	"sip/sdp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+////////////////////Interface//////////////////////////////
+
 type Dialog interface {
 	GetLocalParty() string
 	GetRemoteParty() string
@@ -13,15 +27,32 @@ type Dialog interface {
 	IsServer() bool
 	IncrementLocalSequenceNumber()
 	CreateRequest(method string) (Request, error)
+	CreateAck(cSeq int) (Request, error)
 	SendRequest(ct ClientTransaction) error
 	SendAck(ack Request) error
 	GetState() DialogState
+	GetOfferAnswerState() OfferAnswerState
+	SetNegotiator(n *sdp.Negotiator)
+	GetNegotiator() *sdp.Negotiator
+	CreateOffer() (*sdp.SessionDescription, error)
+	CreateAnswer() (*sdp.SessionDescription, error)
+	// GetAnswerError returns why the negotiator rejected the last answer
+	// received to an offer of the dialog, nil if it accepted it. The
+	// session keeps its description, and the application may end the call
+	// or offer again, RFC 3261 §13.2.1.
+	GetAnswerError() error
 	Close()
 	GetFirstTransaction() Transaction
 	GetLocalTag() string
 	GetRemoteTag() string
 	SetApplicationData(applicationData interface{})
 	GetApplicationData() interface{}
+	// GetLocalRecvInfo returns the Info Packages of the last Recv-Info sent
+	// in the dialog, nil if none was sent, RFC 6086 §5.2.
+	GetLocalRecvInfo() []string
+	// GetRemoteRecvInfo returns the Info Packages of the last Recv-Info
+	// received in the dialog, the ones INFO requests may be sent for.
+	GetRemoteRecvInfo() []string
 }
 
 type DialogState int
@@ -32,3 +63,890 @@ const (
 	DIALOGSTATE_COMPLETED                     //2
 	DIALOGSTATE_TERMINATED                    //3
 )
+
+var ErrDialogTerminated = errors.New("the dialog is terminated")
+var ErrInvitePending = errors.New("an INVITE transaction is already in progress on the dialog")
+var ErrNoNegotiator = errors.New("the dialog has no session negotiator")
+var ErrNoRemoteOffer = errors.New("no offer received is waiting for an answer")
+var ErrBadInfoPackage = errors.New("the remote party did not indicate it receives the Info Package")
+
+// allowedMethods is advertised in the Allow header of the target refresh
+// requests and responses sent within a dialog.
+var allowedMethods = []string{INVITE, ACK, CANCEL, BYE, OPTIONS, UPDATE, SUBSCRIBE, NOTIFY, REFER, INFO}
+
+// targetRefreshMethods are the requests whose Contact replaces the remote
+// target of the dialog, RFC 3261 §12.2, RFC 3311 §5 and RFC 6665 §4.
+var targetRefreshMethods = map[string]bool{INVITE: true, UPDATE: true, SUBSCRIBE: true, NOTIFY: true}
+
+// dialogHeaders are set by the dialog itself and never copied from an
+// application supplied request.
+var dialogHeaders = map[string]bool{
+	"Via": true, "V": true,
+	"Call-Id": true, "I": true,
+	"From": true, "F": true,
+	"To": true, "T": true,
+	"Cseq":           true,
+	"Max-Forwards":   true,
+	"Route":          true,
+	"Content-Length": true, "L": true,
+	//the body is compressed again when the request is sent
+	"Content-Encoding": true, "E": true,
+}
+
+////////////////////Implementation////////////////////////
+
+type dialog struct {
+	mutex    sync.Mutex
+	provider *provider
+
+	callId       string
+	localTag     string
+	remoteTag    string
+	localParty   address.Address
+	remoteParty  address.Address
+	remoteTarget address.URI
+	localContact string
+	localSeq     int
+	remoteSeq    int
+	routeSet     []string
+	secure       bool
+	server       bool
+	state        DialogState
+
+	firstTransaction Transaction
+	applicationData  interface{}
+
+	offerAnswer offerAnswer
+	negotiator  *sdp.Negotiator
+	remoteOffer *sdp.SessionDescription //the offer received, until answered
+	answerError error                   //why the last answer received was rejected
+
+	//the INVITE transactions in progress, RFC 3261 §14
+	clientInvite *inviteSession
+	serverInvite *inviteSession
+
+	//the dialog to shut down once this one is accepted, RFC 3891
+	replaced *dialog
+
+	//the Accept-Encoding last received from the remote party, which
+	//decides whether the bodies sent to it are compressed
+	remoteAcceptEncoding []string
+
+	//the Info Packages of the last Recv-Info sent and received, nil until
+	//one is, RFC 6086 §5.2
+	localRecvInfo  []string
+	remoteRecvInfo []string
+}
+
+// inviteSession remembers the session as it was before an INVITE
+// transaction started, so that it can be restored when the INVITE fails
+// with a non-2xx final response, RFC 3261 §14.1 and §14.2.
+type inviteSession struct {
+	cSeq         int
+	remoteTarget address.URI
+	offerAnswer  offerAnswer
+}
+
+func (this *dialog) newInviteSession(cSeq int) *inviteSession {
+	return &inviteSession{
+		cSeq:         cSeq,
+		remoteTarget: this.remoteTarget,
+		offerAnswer:  this.offerAnswer,
+	}
+}
+
+// rollback restores the session saved in s. The offer/answer state is only
+// restored when no other exchange took place since the INVITE was sent.
+func (this *dialog) rollback(s *inviteSession) {
+	this.remoteTarget = s.remoteTarget
+	if this.offerAnswer.isFor(INVITE, s.cSeq) {
+		this.offerAnswer = s.offerAnswer
+	}
+}
+
+// newClientDialog creates the UAC side of a dialog from the request that
+// is about to be sent. The remote tag and route set are learned from the
+// first response, RFC 3261 §12.1.2.
+func newClientDialog(ct ClientTransaction) (*dialog, error) {
+	req := ct.GetRequest()
+	from, to, callId, cSeq, err := dialogHeadersOf(req)
+	if err != nil {
+		return nil, err
+	}
+	if !from.HasTag() {
+		return nil, errors.New("the request has no From tag")
+	}
+	this := &dialog{
+		callId:           callId,
+		localTag:         from.GetTag(),
+		remoteTag:        to.GetTag(),
+		localParty:       from.GetAddress(),
+		remoteParty:      to.GetAddress(),
+		localSeq:         cSeq,
+		server:           false,
+		state:            DIALOGSTATE_EARLY,
+		firstTransaction: ct,
+	}
+	if this.remoteTarget, err = parseURI(req.GetRequestURI()); err != nil {
+		return nil, err
+	}
+	this.secure = this.remoteTarget.GetScheme() == "sips"
+	if contacts := headerValues(req, "Contact"); len(contacts) > 0 {
+		this.localContact = contacts[0]
+	}
+	if req.GetMethod() == INVITE {
+		this.clientInvite = this.newInviteSession(cSeq)
+	}
+	this.updateLocalRecvInfo(req)
+	this.offerAnswer.sendingRequest(req, cSeq)
+	return this, nil
+}
+
+// newServerDialog creates the UAS side of a dialog from a received
+// request, RFC 3261 §12.1.1. A local tag is chosen when the request does
+// not carry one.
+func newServerDialog(st ServerTransaction) (*dialog, error) {
+	req := st.GetRequest()
+	from, to, callId, cSeq, err := dialogHeadersOf(req)
+	if err != nil {
+		return nil, err
+	}
+	this := &dialog{
+		callId:           callId,
+		localTag:         to.GetTag(),
+		remoteTag:        from.GetTag(),
+		localParty:       to.GetAddress(),
+		remoteParty:      from.GetAddress(),
+		remoteSeq:        cSeq,
+		routeSet:         recordRoutes(req),
+		server:           true,
+		state:            DIALOGSTATE_EARLY,
+		firstTransaction: st,
+	}
+	if this.localTag == "" {
+		this.localTag = generateTag()
+	}
+	if uri, err := parseURI(req.GetRequestURI()); err == nil {
+		this.secure = uri.GetScheme() == "sips"
+	}
+	if this.remoteTarget, err = contactURI(req); err != nil {
+		return nil, err
+	}
+	if req.GetMethod() == INVITE {
+		this.serverInvite = this.newInviteSession(cSeq)
+	}
+	this.updateAcceptEncoding(req)
+	this.updateRemoteRecvInfo(req)
+	this.offerAnswer.receivedRequest(req, cSeq)
+	this.negotiate(OFFERANSWER_NONE, req)
+	return this, nil
+}
+
+func (this *dialog) GetLocalParty() string {
+	return this.localParty.String()
+}
+
+func (this *dialog) GetRemoteParty() string {
+	return this.remoteParty.String()
+}
+
+func (this *dialog) GetRemoteTarget() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.remoteTarget == nil {
+		return ""
+	}
+	return this.remoteTarget.String()
+}
+
+func (this *dialog) GetDialogId() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return dialogId(this.callId, this.localTag, this.remoteTag)
+}
+
+func (this *dialog) GetCallId() string {
+	return this.callId
+}
+
+func (this *dialog) GetLocalSequenceNumber() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.localSeq
+}
+
+func (this *dialog) GetRemoteSequenceNumber() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.remoteSeq
+}
+
+func (this *dialog) GetRouteSet() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	routeSet := make([]string, len(this.routeSet))
+	copy(routeSet, this.routeSet)
+	return routeSet
+}
+
+func (this *dialog) IsSecure() bool {
+	return this.secure
+}
+
+func (this *dialog) IsServer() bool {
+	return this.server
+}
+
+func (this *dialog) IncrementLocalSequenceNumber() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.localSeq++
+}
+
+// CreateRequest builds a request within the dialog, RFC 3261 §12.2.1.1.
+// ACKs are built with CreateAck and CANCELs by the ClientTransaction.
+func (this *dialog) CreateRequest(method string) (Request, error) {
+	if method == ACK || method == CANCEL {
+		return nil, errors.New("Dialog.CreateRequest can't create " + method)
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.state == DIALOGSTATE_TERMINATED {
+		return nil, ErrDialogTerminated
+	}
+	if this.remoteTarget == nil {
+		return nil, errors.New("the dialog has no remote target yet")
+	}
+	this.localSeq++
+	req := NewRequest(method, this.remoteTarget.String(), nil)
+	this.setDialogHeaders(req, this.localSeq, method)
+	if targetRefreshMethods[method] {
+		if this.localContact != "" {
+			req.GetHeader().Set("Contact", this.localContact)
+		}
+		req.GetHeader().Set("Allow", strings.Join(allowedMethods, ", "))
+		req.GetHeader().Set("Accept-Encoding", strings.Join(acceptedEncodings, ", "))
+	}
+	if (method == INVITE || method == UPDATE) && this.localRecvInfo != nil {
+		req.GetHeader().Set("Recv-Info", strings.Join(this.localRecvInfo, ", "))
+	}
+	return req, nil
+}
+
+// CreateAck builds the ACK for the 2xx response to the INVITE sent with
+// sequence number cSeq.
+func (this *dialog) CreateAck(cSeq int) (Request, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.remoteTarget == nil {
+		return nil, errors.New("the dialog has no remote target yet")
+	}
+	req := NewRequest(ACK, this.remoteTarget.String(), nil)
+	this.setDialogHeaders(req, cSeq, ACK)
+	return req, nil
+}
+
+func (this *dialog) setDialogHeaders(req Request, cSeq int, method string) {
+	from := header.NewFrom()
+	from.SetAddress(this.localParty)
+	from.SetTag(this.localTag)
+	to := header.NewTo()
+	to.SetAddress(this.remoteParty)
+	if this.remoteTag != "" {
+		to.SetTag(this.remoteTag)
+	}
+	h := req.GetHeader()
+	h.Set("From", from.EncodeBody())
+	h.Set("To", to.EncodeBody())
+	h.Set("Call-ID", this.callId)
+	h.Set("CSeq", header.NewCSeq(cSeq, method).EncodeBody())
+	h.Set("Max-Forwards", "70")
+	for _, route := range this.routeSet {
+		h.Add("Route", route)
+	}
+}
+
+// SendRequest sends the request of ct within the dialog. Requests that
+// would start a second offer/answer exchange while one is outstanding are
+// refused with ErrOfferPending, and a re-INVITE while an INVITE transaction
+// is in progress in either direction with ErrInvitePending.
+func (this *dialog) SendRequest(ct ClientTransaction) error {
+	req := ct.GetRequest()
+	method := req.GetMethod()
+	cSeq := cSeqOf(req)
+	if method == INVITE || method == UPDATE {
+		//keep the body replayable for a retry after 491
+		if _, err := bodyBytes(req); err != nil {
+			return err
+		}
+	}
+
+	this.mutex.Lock()
+	if this.state == DIALOGSTATE_TERMINATED {
+		this.mutex.Unlock()
+		return ErrDialogTerminated
+	}
+	if method == INVITE && (this.clientInvite != nil || this.serverInvite != nil) {
+		this.mutex.Unlock()
+		return ErrInvitePending
+	}
+	//an INFO is only sent for a package the remote party receives, RFC
+	//6086 §4.2.1
+	if pkg := infoPackageOf(req); method == INFO && pkg != "" && !containsFold(this.remoteRecvInfo, pkg) {
+		this.mutex.Unlock()
+		return ErrBadInfoPackage
+	}
+	if err := compressFor(req, this.remoteAcceptEncoding); err != nil {
+		this.mutex.Unlock()
+		return err
+	}
+	saved := this.newInviteSession(cSeq)
+	if err := this.offerAnswer.sendingRequest(req, cSeq); err != nil {
+		this.mutex.Unlock()
+		return err
+	}
+	this.negotiate(saved.offerAnswer.state, req)
+	if method == INVITE {
+		this.clientInvite = saved
+	}
+	this.updateLocalRecvInfo(req)
+	this.mutex.Unlock()
+
+	if t, ok := ct.(*clientTransaction); ok {
+		t.SetDialog(this)
+	}
+	if err := ct.SendRequest(); err != nil {
+		this.mutex.Lock()
+		if this.negotiator != nil && this.offerAnswer.state != saved.offerAnswer.state {
+			this.negotiator.CancelOffer()
+		}
+		this.offerAnswer = saved.offerAnswer
+		if method == INVITE {
+			this.clientInvite = nil
+		}
+		this.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (this *dialog) SendAck(ack Request) error {
+	this.mutex.Lock()
+	before := this.offerAnswer.state
+	this.offerAnswer.sendingRequest(ack, cSeqOf(ack))
+	this.negotiate(before, ack)
+	err := compressFor(ack, this.remoteAcceptEncoding)
+	this.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if this.provider != nil {
+		return this.provider.SendRequest(ack)
+	}
+	return nil
+}
+
+func (this *dialog) GetState() DialogState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.state
+}
+
+func (this *dialog) GetOfferAnswerState() OfferAnswerState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.offerAnswer.state
+}
+
+func (this *dialog) Close() {
+	this.mutex.Lock()
+	this.state = DIALOGSTATE_TERMINATED
+	this.mutex.Unlock()
+	if this.provider != nil {
+		this.provider.removeDialog(this)
+	}
+}
+
+// SetNegotiator hands the session of the dialog to n: the answers
+// received complete the offers n created, and the offers received are
+// kept for CreateAnswer.
+func (this *dialog) SetNegotiator(n *sdp.Negotiator) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.negotiator = n
+}
+
+func (this *dialog) GetNegotiator() *sdp.Negotiator {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.negotiator
+}
+
+// CreateOffer builds a new offer for the session of the dialog. It fails
+// with ErrOfferPending while an offer/answer exchange is in progress.
+func (this *dialog) CreateOffer() (*sdp.SessionDescription, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.negotiator == nil {
+		return nil, ErrNoNegotiator
+	}
+	if this.offerAnswer.state != OFFERANSWER_NONE {
+		return nil, ErrOfferPending
+	}
+	return this.negotiator.CreateOffer()
+}
+
+// CreateAnswer answers the offer received within the dialog.
+func (this *dialog) CreateAnswer() (*sdp.SessionDescription, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.negotiator == nil {
+		return nil, ErrNoNegotiator
+	}
+	if this.offerAnswer.state != OFFERANSWER_REMOTE_OFFER || this.remoteOffer == nil {
+		return nil, ErrNoRemoteOffer
+	}
+	return this.negotiator.CreateAnswer(this.remoteOffer)
+}
+
+// negotiate keeps the negotiator in step with the offer/answer transition
+// msg caused from the state before: an offer received is kept for
+// CreateAnswer, an answer received completes the offer of the negotiator
+// and a rejection cancels it.
+func (this *dialog) negotiate(before OfferAnswerState, msg Message) {
+	after := this.offerAnswer.state
+	switch {
+	case after == OFFERANSWER_REMOTE_OFFER && before != OFFERANSWER_REMOTE_OFFER:
+		this.remoteOffer = sessionDescriptionOf(msg)
+	case before == OFFERANSWER_REMOTE_OFFER && after != OFFERANSWER_REMOTE_OFFER:
+		this.remoteOffer = nil
+	case before == OFFERANSWER_LOCAL_OFFER && after == OFFERANSWER_NONE && this.negotiator != nil:
+		if resp, ok := msg.(Response); ok && resp.GetStatusCode() >= 300 || !hasSessionBody(msg) {
+			this.negotiator.CancelOffer()
+		} else if answer := sessionDescriptionOf(msg); answer != nil {
+			this.answerError = this.negotiator.ProcessAnswer(answer)
+			if this.answerError != nil {
+				tracerOf(this.provider).Println("Processing answer failed:", this.answerError)
+			}
+		}
+	}
+}
+
+func (this *dialog) GetAnswerError() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.answerError
+}
+
+func (this *dialog) GetFirstTransaction() Transaction {
+	return this.firstTransaction
+}
+
+func (this *dialog) GetLocalTag() string {
+	return this.localTag
+}
+
+func (this *dialog) GetRemoteTag() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.remoteTag
+}
+
+func (this *dialog) SetApplicationData(applicationData interface{}) {
+	this.applicationData = applicationData
+}
+
+func (this *dialog) GetApplicationData() interface{} {
+	return this.applicationData
+}
+
+func (this *dialog) GetLocalRecvInfo() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.localRecvInfo
+}
+
+func (this *dialog) GetRemoteRecvInfo() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.remoteRecvInfo
+}
+
+// processRequest updates the dialog with a request received within it. A
+// non nil response is returned when the request must be rejected without
+// involving the application.
+func (this *dialog) processRequest(req Request) Response {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	method := req.GetMethod()
+	cSeq := cSeqOf(req)
+	if method != ACK && method != CANCEL {
+		if cSeq < this.remoteSeq {
+			return newResponseFor(req, SERVER_INTERNAL_ERROR)
+		}
+		this.remoteSeq = cSeq
+	}
+	//an INFO for a package we did not tell we receive, RFC 6086 §4.2.2
+	if pkg := infoPackageOf(req); method == INFO && pkg != "" && !containsFold(this.localRecvInfo, pkg) {
+		resp := newResponseFor(req, BAD_INFO_PACKAGE)
+		resp.GetHeader().Set("Recv-Info", strings.Join(this.localRecvInfo, ", "))
+		return resp
+	}
+
+	saved := this.newInviteSession(cSeq)
+	var code int
+	switch {
+	//overlapping INVITE transactions, RFC 3261 §14.2
+	case method == INVITE && this.clientInvite != nil:
+		code = REQUEST_PENDING
+	case method == INVITE && this.serverInvite != nil:
+		code = SERVER_INTERNAL_ERROR
+	default:
+		code = this.offerAnswer.receivedRequest(req, cSeq)
+		this.negotiate(saved.offerAnswer.state, req)
+	}
+	switch code {
+	case REQUEST_PENDING:
+		return newResponseFor(req, REQUEST_PENDING)
+	case SERVER_INTERNAL_ERROR:
+		resp := newResponseFor(req, SERVER_INTERNAL_ERROR)
+		resp.GetHeader().Set("Retry-After", strconv.Itoa(pendingRetryAfter()))
+		return resp
+	}
+
+	if method == INVITE {
+		this.serverInvite = saved
+	}
+	this.updateAcceptEncoding(req)
+	this.updateRemoteRecvInfo(req)
+	switch {
+	case targetRefreshMethods[method]:
+		if uri, err := contactURI(req); err == nil {
+			this.remoteTarget = uri
+		}
+	case method == BYE:
+		this.state = DIALOGSTATE_TERMINATED
+	}
+	return nil
+}
+
+// updateAcceptEncoding remembers the Accept-Encoding of a message received
+// from the remote party, when it carries one.
+func (this *dialog) updateAcceptEncoding(msg Message) {
+	if values := headerValues(msg, "Accept-Encoding"); len(values) > 0 {
+		this.remoteAcceptEncoding = values
+	}
+}
+
+// updateLocalRecvInfo remembers the Info Packages of the Recv-Info of a
+// message sent to the remote party, when it carries one.
+func (this *dialog) updateLocalRecvInfo(msg Message) {
+	if packages, ok := recvInfoOf(msg); ok {
+		this.localRecvInfo = packages
+	}
+}
+
+// updateRemoteRecvInfo remembers the Info Packages of the Recv-Info of a
+// message received from the remote party, when it carries one.
+func (this *dialog) updateRemoteRecvInfo(msg Message) {
+	if packages, ok := recvInfoOf(msg); ok {
+		this.remoteRecvInfo = packages
+	}
+}
+
+// sendingResponse updates the dialog with a response the application sends
+// to a request received within it.
+func (this *dialog) sendingResponse(st ServerTransaction, resp Response) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	req := st.GetRequest()
+	method := req.GetMethod()
+	code := resp.GetStatusCode()
+
+	if code > 100 {
+		if h, err := parseHeader(resp, "To"); err == nil && h != nil {
+			//the response may have been built with a tag of its own
+			if to := h.(*header.To); to.GetTag() != this.localTag {
+				to.SetTag(this.localTag)
+				resp.GetHeader().Set("To", to.EncodeBody())
+			}
+		}
+	}
+	if st == this.firstTransaction {
+		this.updateState(code)
+	}
+	if targetRefreshMethods[method] && code > 100 && code < 300 {
+		if contacts := headerValues(resp, "Contact"); len(contacts) > 0 {
+			this.localContact = contacts[0]
+		}
+		if code >= 200 && resp.GetHeader().Get("Allow") == "" {
+			resp.GetHeader().Set("Allow", strings.Join(allowedMethods, ", "))
+		}
+		if code >= 200 && resp.GetHeader().Get("Accept-Encoding") == "" {
+			resp.GetHeader().Set("Accept-Encoding", strings.Join(acceptedEncodings, ", "))
+		}
+	}
+	if (method == INVITE || method == UPDATE) && code > 100 && code < 300 {
+		if this.localRecvInfo != nil && len(headerValues(resp, "Recv-Info")) == 0 {
+			resp.GetHeader().Set("Recv-Info", strings.Join(this.localRecvInfo, ", "))
+		}
+		this.updateLocalRecvInfo(resp)
+	}
+	before := this.offerAnswer.state
+	this.offerAnswer.sendingResponse(req, cSeqOf(req), resp)
+	this.negotiate(before, resp)
+
+	if s := this.serverInvite; method == INVITE && code >= 200 && s != nil && s.cSeq == cSeqOf(req) {
+		if code >= 300 {
+			this.rollback(s)
+		}
+		this.serverInvite = nil
+	}
+}
+
+// processResponse updates the dialog with a response received for a
+// request it sent.
+func (this *dialog) processResponse(ct ClientTransaction, resp Response) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	req := ct.GetRequest()
+	method := req.GetMethod()
+	code := resp.GetStatusCode()
+
+	if this.remoteTag == "" && code > 100 {
+		if h, err := parseHeader(resp, "To"); err == nil && h != nil {
+			if to := h.(*header.To); to.HasTag() {
+				this.remoteTag = to.GetTag()
+				//the route set is the Record-Route of the response in
+				//reverse order, RFC 3261 §12.1.2
+				rr := recordRoutes(resp)
+				this.routeSet = make([]string, 0, len(rr))
+				for i := len(rr) - 1; i >= 0; i-- {
+					this.routeSet = append(this.routeSet, rr[i])
+				}
+				if this.provider != nil {
+					this.provider.putDialog(this)
+				}
+			}
+		}
+	}
+	if ct == this.firstTransaction {
+		this.updateState(code)
+	}
+	if targetRefreshMethods[method] && code > 100 && code < 300 {
+		if uri, err := contactURI(resp); err == nil {
+			this.remoteTarget = uri
+		}
+	}
+	if code >= 200 && code < 300 {
+		this.updateAcceptEncoding(resp)
+	}
+	if code > 100 && code < 300 {
+		this.updateRemoteRecvInfo(resp)
+	}
+	before := this.offerAnswer.state
+	this.offerAnswer.receivedResponse(req, cSeqOf(req), resp)
+	this.negotiate(before, resp)
+
+	if s := this.clientInvite; method == INVITE && code >= 200 && s != nil && s.cSeq == cSeqOf(req) {
+		if code >= 300 {
+			this.rollback(s)
+		}
+		this.clientInvite = nil
+	}
+
+	switch {
+	case code == REQUEST_PENDING && ct != this.firstTransaction && (method == INVITE || method == UPDATE):
+		this.scheduleRetry(req)
+	case code >= 200 && code < 300 && method == BYE:
+		this.state = DIALOGSTATE_TERMINATED
+	}
+}
+
+// updateState moves the dialog along with the responses to the request that
+// created it.
+func (this *dialog) updateState(code int) {
+	switch {
+	case code > 100 && code < 200:
+		if this.state != DIALOGSTATE_CONFIRMED {
+			this.state = DIALOGSTATE_EARLY
+		}
+	case code >= 200 && code < 300:
+		this.state = DIALOGSTATE_CONFIRMED
+	case code >= 300 && this.state == DIALOGSTATE_EARLY:
+		this.state = DIALOGSTATE_TERMINATED
+	}
+}
+
+// establish confirms an early UAC dialog with the request that created it
+// on the remote side, a NOTIFY overtaking the 2xx to its SUBSCRIBE, RFC 6665
+// §4.1.2.4. The route set is taken from the request, RFC 3261 §12.1.1.
+func (this *dialog) establish(req Request) error {
+	from, _, _, _, err := dialogHeadersOf(req)
+	if err != nil {
+		return err
+	}
+	remoteTarget, err := contactURI(req)
+	if err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	this.remoteTag = from.GetTag()
+	this.remoteParty = from.GetAddress()
+	this.remoteTarget = remoteTarget
+	this.routeSet = recordRoutes(req)
+	this.state = DIALOGSTATE_CONFIRMED
+	this.mutex.Unlock()
+	if this.provider != nil {
+		this.provider.putDialog(this)
+	}
+	return nil
+}
+
+// fork creates the dialog of a request sent by another branch of the
+// forked request that created this dialog, RFC 6665 §4.1.2.4.
+func (this *dialog) fork(req Request) (*dialog, error) {
+	this.mutex.Lock()
+	d := &dialog{
+		provider:         this.provider,
+		callId:           this.callId,
+		localTag:         this.localTag,
+		localParty:       this.localParty,
+		localContact:     this.localContact,
+		localSeq:         this.localSeq,
+		secure:           this.secure,
+		server:           this.server,
+		state:            DIALOGSTATE_EARLY,
+		firstTransaction: this.firstTransaction,
+	}
+	this.mutex.Unlock()
+	if err := d.establish(req); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// scheduleRetry sends req again, with a new CSeq, once the RFC 3261 §14.1
+// glare interval has elapsed.
+func (this *dialog) scheduleRetry(req Request) {
+	if this.provider == nil {
+		return
+	}
+	body, _ := bodyBytes(req)
+	time.AfterFunc(glareRetryInterval(!this.server), func() {
+		retry, err := this.CreateRequest(req.GetMethod())
+		if err != nil {
+			this.provider.tracer.Println("Retrying", req.GetMethod(), "failed:", err)
+			return
+		}
+		for key, values := range req.GetHeader() {
+			if !dialogHeaders[key] && retry.GetHeader().Get(key) == "" {
+				retry.GetHeader()[key] = append([]string(nil), values...)
+			}
+		}
+		if body != nil {
+			retry.SetBody(bytes.NewReader(body))
+			retry.SetContentLength(int64(len(body)))
+		}
+		ct := this.provider.GetNewClientTransaction(retry)
+		if err := this.SendRequest(ct); err != nil {
+			this.provider.tracer.Println("Retrying", req.GetMethod(), "failed:", err)
+		}
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func dialogId(callId, localTag, remoteTag string) string {
+	return callId + ":" + localTag + ":" + remoteTag
+}
+
+// dialogHeadersOf returns the headers that identify the dialog msg belongs
+// to.
+func dialogHeadersOf(msg Message) (from *header.From, to *header.To, callId string, cSeq int, err error) {
+	var h header.Header
+	if h, err = parseHeader(msg, "From"); err != nil || h == nil {
+		return nil, nil, "", 0, errors.New("missing or malformed From header")
+	}
+	from = h.(*header.From)
+	if h, err = parseHeader(msg, "To"); err != nil || h == nil {
+		return nil, nil, "", 0, errors.New("missing or malformed To header")
+	}
+	to = h.(*header.To)
+	if h, err = parseHeader(msg, "Call-ID"); err != nil || h == nil {
+		return nil, nil, "", 0, errors.New("missing or malformed Call-ID header")
+	}
+	callId = h.(*header.CallID).GetCallId()
+	if h, err = parseHeader(msg, "CSeq"); err != nil || h == nil {
+		return nil, nil, "", 0, errors.New("missing or malformed CSeq header")
+	}
+	cSeq = h.(*header.CSeq).GetSequenceNumber()
+	return from, to, callId, cSeq, nil
+}
+
+// cSeqOf returns the CSeq sequence number of msg, or 0 if it can't be
+// parsed.
+func cSeqOf(msg Message) int {
+	if h, err := parseHeader(msg, "CSeq"); err == nil && h != nil {
+		return h.(*header.CSeq).GetSequenceNumber()
+	}
+	return 0
+}
+
+// contactURI returns the URI of the first Contact of msg.
+func contactURI(msg Message) (address.URI, error) {
+	h, err := parseHeader(msg, "Contact")
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.New("missing Contact header")
+	}
+	contacts := h.(*header.ContactList).GetContacts()
+	if len(contacts) == 0 || contacts[0].GetAddress() == nil {
+		return nil, errors.New("missing Contact header")
+	}
+	return contacts[0].GetAddress().GetURI(), nil
+}
+
+// recordRoutes returns the Record-Route entries of msg one by one, in the
+// order they appear.
+func recordRoutes(msg Message) []string {
+	var routes []string
+	headers, err := parseHeaders(msg, "Record-Route")
+	if err != nil {
+		return nil
+	}
+	for _, h := range headers {
+		for e := h.(*header.RecordRouteList).Front(); e != nil; e = e.Next() {
+			routes = append(routes, e.Value.(header.Header).EncodeBody())
+		}
+	}
+	return routes
+}
+
+// parseURI parses a Request-URI.
+func parseURI(uri string) (address.URI, error) {
+	h, err := parseHeaderValue("Contact", "<"+uri+">")
+	if err != nil {
+		return nil, err
+	}
+	return h.(*header.ContactList).GetContacts()[0].GetAddress().GetURI(), nil
+}
+
+// bodyBytes reads the whole body of msg, decoded from its Content-Encoding,
+// and leaves a rewindable copy of the body as sent in its place so that the
+// message can be sent again.
+func bodyBytes(msg Message) ([]byte, error) {
+	b, err := rawBodyBytes(msg)
+	if err != nil || len(b) == 0 {
+		return b, err
+	}
+	if codings := contentCodings(msg); len(codings) > 0 {
+		return decodeContent(codings, b)
+	}
+	return b, nil
+}
@@ -0,0 +1,407 @@
+package sip
+
+import (
+	"bufio"
+	"sip/sdp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=alice 2890844526 2890844526 IN IP4 pc33.atlanta.com\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 pc33.atlanta.com\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 49172 RTP/AVP 0\r\n" +
+	"a=rtpmap:0 PCMU/8000\r\n"
+
+func readTestMessage(t *testing.T, s string) Message {
+	msg, err := ReadMessage(bufio.NewReader(strings.NewReader(s)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// testRequest returns a request as read from the wire: the request line of
+// method and requestURI, the header lines, "Name: value" each, and body
+// with its Content-Length. The empty header lines are left out.
+func testRequest(t *testing.T, method, requestURI string, headers []string, body string) Request {
+	s := method + " " + requestURI + " SIP/2.0\r\n"
+	for _, h := range headers {
+		if h != "" {
+			s += h + "\r\n"
+		}
+	}
+	s += "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	return readTestMessage(t, s).(Request)
+}
+
+func testInvite(t *testing.T, withSDP bool) Request {
+	s := "INVITE sip:bob@biloxi.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: Bob <sip:bob@biloxi.com>\r\n" +
+		"From: Alice <sip:alice@atlanta.com>;tag=1928301774\r\n" +
+		"Call-ID: a84b4c76e66710@pc33.atlanta.com\r\n" +
+		"CSeq: 314159 INVITE\r\n" +
+		"Contact: <sip:alice@pc33.atlanta.com>\r\n" +
+		"Record-Route: <sip:p1.example.com;lr>, <sip:p2.example.com;lr>\r\n"
+	if withSDP {
+		s += "Content-Type: application/sdp\r\n" +
+			"Content-Length: " + strconv.Itoa(len(testSDP)) + "\r\n\r\n" + testSDP
+	} else {
+		s += "Content-Length: 0\r\n\r\n"
+	}
+	return readTestMessage(t, s).(Request)
+}
+
+// testUpdate returns an UPDATE received by the UAS side of the dialog of
+// testInvite.
+func testUpdate(t *testing.T, d Dialog, cSeq int) Request {
+	return testInDialogRequest(t, d, UPDATE, cSeq)
+}
+
+// testInDialogRequest returns a request with an offer received by the UAS
+// side of the dialog of testInvite.
+func testInDialogRequest(t *testing.T, d Dialog, method string, cSeq int) Request {
+	s := method + " sip:bob@client.biloxi.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK" + strconv.Itoa(cSeq) + "\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: Bob <sip:bob@biloxi.com>;tag=" + d.GetLocalTag() + "\r\n" +
+		"From: Alice <sip:alice@atlanta.com>;tag=1928301774\r\n" +
+		"Call-ID: a84b4c76e66710@pc33.atlanta.com\r\n" +
+		"CSeq: " + strconv.Itoa(cSeq) + " " + method + "\r\n" +
+		"Contact: <sip:alice@pc34.atlanta.com>\r\n" +
+		"Content-Type: application/sdp\r\n" +
+		"Content-Length: " + strconv.Itoa(len(testSDP)) + "\r\n\r\n" + testSDP
+	return readTestMessage(t, s).(Request)
+}
+
+func newTestServerDialog(t *testing.T, invite Request) (*dialog, *serverTransaction) {
+	st := newServerTransaction(invite)
+	d, err := newServerDialog(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetDialog(d)
+	return d, st
+}
+
+func sdpResponse(req Request, statusCode int) *response {
+	resp := newResponseFor(req, statusCode)
+	resp.SetBody(strings.NewReader(testSDP))
+	resp.SetContentLength(int64(len(testSDP)))
+	resp.GetHeader().Set("Content-Type", "application/sdp")
+	return resp
+}
+
+func TestServerDialog(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+
+	if d.GetCallId() != "a84b4c76e66710@pc33.atlanta.com" || d.GetRemoteTag() != "1928301774" ||
+		d.GetRemoteSequenceNumber() != 314159 || d.GetRemoteTarget() != "sip:alice@pc33.atlanta.com" {
+		t.Log(d.GetDialogId(), d.GetRemoteSequenceNumber(), d.GetRemoteTarget())
+		t.Fail()
+	}
+	if rs := d.GetRouteSet(); len(rs) != 2 || rs[0] != "<sip:p1.example.com;lr>" {
+		t.Log(rs)
+		t.Fail()
+	}
+	if d.GetOfferAnswerState() != OFFERANSWER_REMOTE_OFFER {
+		t.Log("the INVITE offer was not recorded")
+		t.Fail()
+	}
+
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+	if d.GetState() != DIALOGSTATE_CONFIRMED || d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Log(d.GetState(), d.GetOfferAnswerState())
+		t.Fail()
+	}
+}
+
+func TestDialogCreateUpdate(t *testing.T) {
+	d, _ := newTestServerDialog(t, testInvite(t, false))
+
+	req, err := d.CreateRequest(UPDATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := req.GetHeader()
+	if req.GetRequestURI() != "sip:alice@pc33.atlanta.com" ||
+		h.Get("CSeq") != "1 UPDATE" ||
+		h.Get("To") != "\"Alice\" <sip:alice@atlanta.com>;tag=1928301774" ||
+		!hasToken(h.Get("Allow"), "update") ||
+		len(h["Route"]) != 2 {
+		t.Log(h)
+		t.Fail()
+	}
+
+	//no offer/answer exchange has completed yet
+	req.SetBody(strings.NewReader(testSDP))
+	req.SetContentLength(int64(len(testSDP)))
+	req.GetHeader().Set("Content-Type", "application/sdp")
+	if err := d.SendRequest(newClientTransaction(req)); err != ErrNoInitialAnswer {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestDialogUpdateGlare(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+
+	//our UPDATE is outstanding when the peer's arrives
+	req, _ := d.CreateRequest(UPDATE)
+	req.SetBody(strings.NewReader(testSDP))
+	req.SetContentLength(int64(len(testSDP)))
+	req.GetHeader().Set("Content-Type", "application/sdp")
+	if err := d.SendRequest(newClientTransaction(req)); err != nil {
+		t.Fatal(err)
+	}
+	if resp := d.processRequest(testUpdate(t, d, 314160)); resp == nil || resp.GetStatusCode() != REQUEST_PENDING {
+		t.Log(resp)
+		t.Fail()
+	}
+
+	//a second offer while ours is pending is refused locally as well
+	again, _ := d.CreateRequest(UPDATE)
+	again.SetBody(strings.NewReader(testSDP))
+	again.SetContentLength(int64(len(testSDP)))
+	again.GetHeader().Set("Content-Type", "application/sdp")
+	if err := d.SendRequest(newClientTransaction(again)); err != ErrOfferPending {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestDialogUpdateAnswerPending(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+
+	update := testUpdate(t, d, 314160)
+	if resp := d.processRequest(update); resp != nil {
+		t.Fatal(resp.GetStatusCode())
+	}
+	if d.GetRemoteTarget() != "sip:alice@pc34.atlanta.com" {
+		t.Log("UPDATE did not refresh the remote target:", d.GetRemoteTarget())
+		t.Fail()
+	}
+
+	//the answer to the first UPDATE has not been sent yet
+	resp := d.processRequest(testUpdate(t, d, 314161))
+	if resp == nil || resp.GetStatusCode() != SERVER_INTERNAL_ERROR {
+		t.Fatal(resp)
+	}
+	if retryAfter, err := strconv.Atoi(resp.GetHeader().Get("Retry-After")); err != nil || retryAfter < 0 || retryAfter > 10 {
+		t.Log(resp.GetHeader().Get("Retry-After"))
+		t.Fail()
+	}
+
+	//a request with a lower CSeq is out of order
+	if resp := d.processRequest(testUpdate(t, d, 314150)); resp == nil || resp.GetStatusCode() != SERVER_INTERNAL_ERROR {
+		t.Log(resp)
+		t.Fail()
+	}
+
+	updateSt := newServerTransaction(update)
+	updateSt.SetDialog(d)
+	updateSt.SendResponse(sdpResponse(update, OK))
+	if d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Log(d.GetOfferAnswerState())
+		t.Fail()
+	}
+}
+
+func TestDialogReInviteGlare(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+
+	//our re-INVITE is in progress when the peer's arrives
+	req, _ := d.CreateRequest(INVITE)
+	if err := d.SendRequest(newClientTransaction(req)); err != nil {
+		t.Fatal(err)
+	}
+	if resp := d.processRequest(testInDialogRequest(t, d, INVITE, 314160)); resp == nil || resp.GetStatusCode() != REQUEST_PENDING {
+		t.Log(resp)
+		t.Fail()
+	}
+	again, _ := d.CreateRequest(INVITE)
+	if err := d.SendRequest(newClientTransaction(again)); err != ErrInvitePending {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestDialogReInvitePending(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+
+	if resp := d.processRequest(testInDialogRequest(t, d, INVITE, 314160)); resp != nil {
+		t.Fatal(resp.GetStatusCode())
+	}
+	//the final response to the first re-INVITE has not been sent yet
+	resp := d.processRequest(testInDialogRequest(t, d, INVITE, 314161))
+	if resp == nil || resp.GetStatusCode() != SERVER_INTERNAL_ERROR || resp.GetHeader().Get("Retry-After") == "" {
+		t.Fatal(resp)
+	}
+	req, _ := d.CreateRequest(INVITE)
+	if err := d.SendRequest(newClientTransaction(req)); err != ErrInvitePending {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestDialogReInviteRollback(t *testing.T) {
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+
+	reInvite := testInDialogRequest(t, d, INVITE, 314160)
+	if resp := d.processRequest(reInvite); resp != nil {
+		t.Fatal(resp.GetStatusCode())
+	}
+	if d.GetRemoteTarget() != "sip:alice@pc34.atlanta.com" || d.GetOfferAnswerState() != OFFERANSWER_REMOTE_OFFER {
+		t.Fatal(d.GetRemoteTarget(), d.GetOfferAnswerState())
+	}
+
+	reInviteSt := newServerTransaction(reInvite)
+	reInviteSt.SetDialog(d)
+	reInviteSt.SendResponse(newResponseFor(reInvite, NOT_ACCEPTABLE_HERE))
+	if d.GetRemoteTarget() != "sip:alice@pc33.atlanta.com" || d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Log("the session was not rolled back:", d.GetRemoteTarget(), d.GetOfferAnswerState())
+		t.Fail()
+	}
+
+	//a new re-INVITE may follow the failed one
+	req, _ := d.CreateRequest(INVITE)
+	if err := d.SendRequest(newClientTransaction(req)); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestGlareRetryInterval(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		if d := glareRetryInterval(true); d < 2100*time.Millisecond || d > 4*time.Second || d%(10*time.Millisecond) != 0 {
+			t.Fatal("owner", d)
+		}
+		if d := glareRetryInterval(false); d < 0 || d > 2*time.Second || d%(10*time.Millisecond) != 0 {
+			t.Fatal("non-owner", d)
+		}
+	}
+}
+
+func TestDialogMultipartOffer(t *testing.T) {
+	invite := testInvite(t, false)
+	m := NewMultipartBody(MULTIPART_MIXED)
+	m.AddPart(NewBodyPart("application/pidf+xml", []byte("<presence/>")))
+	offer := NewBodyPart(sdp.CONTENT_TYPE, []byte(testSDP))
+	offer.SetContentDisposition("session")
+	m.AddPart(offer)
+	SetMultipartBody(invite, m)
+
+	d, st := newTestServerDialog(t, invite)
+	if d.GetOfferAnswerState() != OFFERANSWER_REMOTE_OFFER {
+		t.Fatal("the multipart INVITE made no offer", d.GetOfferAnswerState())
+	}
+	capabilities := sdp.NewSessionDescription("bob", 2808844564, "192.0.2.201")
+	audio := &sdp.Media{Type: "audio", Port: 49174, Protocol: "RTP/AVP"}
+	audio.AddCodec(&sdp.Codec{PayloadType: 0, Name: "PCMU", ClockRate: 8000})
+	capabilities.Media = append(capabilities.Media, audio)
+	d.SetNegotiator(sdp.NewNegotiator(capabilities))
+	answer, err := d.CreateAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := newResponseFor(st.GetRequest(), OK)
+	SetSessionDescription(resp, answer)
+	st.SendResponse(resp)
+	if d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Error(d.GetOfferAnswerState())
+	}
+
+	//an application/sdp part rendered rather than negotiated is no offer
+	offer.SetContentDisposition("render")
+	SetMultipartBody(invite, m)
+	if _, err := GetSessionDescription(invite); err != ErrNoSessionDescription {
+		t.Error(err)
+	}
+}
+
+func TestDialogNegotiator(t *testing.T) {
+	capabilities := sdp.NewSessionDescription("bob", 2808844564, "192.0.2.201")
+	audio := &sdp.Media{Type: "audio", Port: 49174, Protocol: "RTP/AVP"}
+	audio.AddCodec(&sdp.Codec{PayloadType: 0, Name: "PCMU", ClockRate: 8000})
+	capabilities.Media = append(capabilities.Media, audio)
+	n := sdp.NewNegotiator(capabilities)
+
+	d, st := newTestServerDialog(t, testInvite(t, true))
+	d.SetNegotiator(n)
+	if _, err := d.CreateOffer(); err != ErrOfferPending {
+		t.Log(err)
+		t.Fail()
+	}
+	answer, err := d.CreateAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := newResponseFor(st.GetRequest(), OK)
+	SetSessionDescription(resp, answer)
+	st.SendResponse(resp)
+	if d.GetOfferAnswerState() != OFFERANSWER_NONE || n.GetRemote().Origin.Username != "alice" {
+		t.Fail()
+	}
+	if _, err := d.CreateAnswer(); err != ErrNoRemoteOffer {
+		t.Log(err)
+		t.Fail()
+	}
+
+	//a re-offer in an UPDATE, completed by the 200
+	offer, err := d.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, _ := d.CreateRequest(UPDATE)
+	SetSessionDescription(update, offer)
+	ct := newClientTransaction(update)
+	if err := d.SendRequest(ct); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateOffer(); err != ErrOfferPending {
+		t.Log(err)
+		t.Fail()
+	}
+	d.processResponse(ct, sdpResponse(update, OK))
+	if n.GetLocal() != offer || n.IsOfferPending() || d.GetAnswerError() != nil {
+		t.Log("the answer did not complete the offer", d.GetAnswerError())
+		t.Fail()
+	}
+
+	//a rejected offer leaves the session in place
+	offer, _ = d.CreateOffer()
+	update, _ = d.CreateRequest(UPDATE)
+	SetSessionDescription(update, offer)
+	ct = newClientTransaction(update)
+	d.SendRequest(ct)
+	d.processResponse(ct, newResponseFor(update, NOT_ACCEPTABLE_HERE))
+	if n.IsOfferPending() || n.GetLocal() == offer || d.GetOfferAnswerState() != OFFERANSWER_NONE {
+		t.Fail()
+	}
+
+	//an answer the negotiator rejects is reported by the dialog
+	offer, _ = d.CreateOffer()
+	update, _ = d.CreateRequest(UPDATE)
+	SetSessionDescription(update, offer)
+	ct = newClientTransaction(update)
+	d.SendRequest(ct)
+	resp = sdpResponse(update, OK)
+	body := testSDP + "m=video 0 RTP/AVP 31\r\n"
+	resp.SetBody(strings.NewReader(body))
+	resp.SetContentLength(int64(len(body)))
+	d.processResponse(ct, resp)
+	if d.GetAnswerError() == nil || n.GetLocal() == offer {
+		t.Error("the answer with an extra m-line was accepted")
+	}
+}
@@ -0,0 +1,398 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+////////////////////Interface//////////////////////////////
+
+// EventState is one event state a publisher published for a resource, RFC
+// 3903 §2.
+type EventState struct {
+	ETag        string
+	ContentType string
+	Body        []byte
+	// Expires is the number of seconds left before the state expires.
+	Expires int
+}
+
+// CompositionFunc composes the event states published for resource, oldest
+// first, into the body of the NOTIFYs sent to its subscribers. A nil body
+// sends a NOTIFY without body.
+type CompositionFunc func(resource string, states []EventState) ([]byte, error)
+
+// EventStateCompositor accepts the PUBLISH requests of the event packages
+// added to it, RFC 3903 §6, and feeds the composed state of each resource
+// to its subscribers through a Notifier. The application hands it the
+// PUBLISH requests it receives, and the SUBSCRIBE requests to the
+// Notifier.
+type EventStateCompositor interface {
+	// AddEventPackage accepts the publications of eventType, whose bodies
+	// are of contentType, and serves its subscriptions with the states
+	// composed by compose.
+	AddEventPackage(eventType, contentType string, compose CompositionFunc)
+	RemoveEventPackage(eventType string)
+
+	// SetMinExpires sets the shortest publication accepted, shorter ones
+	// are refused with 423 Interval Too Brief.
+	SetMinExpires(seconds int)
+	// SetMaxExpires sets the longest publication accepted, longer ones are
+	// shortened to it.
+	SetMaxExpires(seconds int)
+
+	// ProcessPublish answers a PUBLISH: an initial publication carrying a
+	// body without SIP-If-Match, the refresh of the state its SIP-If-Match
+	// names without body, its modification with a body or its removal with
+	// "Expires: 0". A SIP-If-Match naming no state is answered with 412
+	// Conditional Request Failed. The 2xx carries the new entity-tag of the
+	// state in SIP-ETag.
+	ProcessPublish(requestEvent RequestEvent) error
+	// GetEventStates returns the states published for resource, oldest
+	// first.
+	GetEventStates(resource, eventType string) []EventState
+	// GetComposedState returns the state of resource composed by its event
+	// package.
+	GetComposedState(resource, eventType string) ([]byte, error)
+}
+
+// DEFAULT_PUBLICATION_EXPIRES is the duration of the publications that
+// don't ask for one, RFC 3903 §4.
+const DEFAULT_PUBLICATION_EXPIRES = 3600
+
+// ComposeLatest is the CompositionFunc of the event packages whose state is
+// the one published last.
+func ComposeLatest(resource string, states []EventState) ([]byte, error) {
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return states[len(states)-1].Body, nil
+}
+
+////////////////////Implementation////////////////////////
+
+type eventStateCompositor struct {
+	mutex    sync.Mutex
+	notifier Notifier
+
+	packages   map[string]*publicationPackage
+	states     map[publicationKey][]*eventState
+	minExpires int
+	maxExpires int
+}
+
+// publicationKey identifies the states of a resource for an event package.
+type publicationKey struct {
+	resource  string
+	eventType string
+}
+
+type eventState struct {
+	etag        string
+	contentType string
+	body        []byte
+	expiry      time.Time
+	timer       *time.Timer
+}
+
+// NewEventStateCompositor returns a compositor notifying the subscribers of
+// the resources through n, to which it adds its event packages.
+func NewEventStateCompositor(n Notifier) EventStateCompositor {
+	return &eventStateCompositor{
+		notifier:   n,
+		packages:   make(map[string]*publicationPackage),
+		states:     make(map[publicationKey][]*eventState),
+		minExpires: DEFAULT_MIN_EXPIRES,
+	}
+}
+
+func (this *eventStateCompositor) AddEventPackage(eventType, contentType string, compose CompositionFunc) {
+	pkg := &publicationPackage{
+		compositor:  this,
+		eventType:   eventType,
+		contentType: contentType,
+		compose:     compose,
+	}
+	this.mutex.Lock()
+	this.packages[eventType] = pkg
+	this.mutex.Unlock()
+	this.notifier.AddEventPackage(pkg)
+}
+
+func (this *eventStateCompositor) RemoveEventPackage(eventType string) {
+	this.mutex.Lock()
+	delete(this.packages, eventType)
+	for key, states := range this.states {
+		if key.eventType == eventType {
+			for _, s := range states {
+				s.timer.Stop()
+			}
+			delete(this.states, key)
+		}
+	}
+	this.mutex.Unlock()
+	this.notifier.RemoveEventPackage(eventType)
+}
+
+func (this *eventStateCompositor) SetMinExpires(seconds int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.minExpires = seconds
+}
+
+func (this *eventStateCompositor) SetMaxExpires(seconds int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.maxExpires = seconds
+}
+
+func (this *eventStateCompositor) ProcessPublish(requestEvent RequestEvent) error {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != PUBLISH {
+		return errors.New("EventStateCompositor.ProcessPublish can't process " + req.GetMethod())
+	}
+
+	//RFC 3903 §6 step 2, the event package
+	event := eventOf(req)
+	this.mutex.Lock()
+	var pkg *publicationPackage
+	if event != nil {
+		pkg = this.packages[event.GetEventType()]
+	}
+	minExpires, maxExpires := this.minExpires, this.maxExpires
+	this.mutex.Unlock()
+	if pkg == nil {
+		resp := newResponseFor(req, BAD_EVENT)
+		resp.GetHeader().Set("Allow-Events", strings.Join(this.notifier.GetAllowEvents(), ", "))
+		st.SendResponse(resp)
+		return ErrBadEvent
+	}
+
+	//step 3, the entity-tag of the state the request applies to
+	var etag string
+	if h, err := parseHeader(req, "SIP-If-Match"); err != nil {
+		return st.SendResponse(newBadRequest(req, "Malformed SIP-If-Match Header"))
+	} else if h != nil {
+		etag = h.(*header.SIPIfMatch).GetETag()
+	}
+
+	//step 4, the duration
+	expires := expiresOf(req)
+	if expires < 0 {
+		expires = DEFAULT_PUBLICATION_EXPIRES
+	}
+	if expires > 0 && expires < minExpires {
+		resp := newResponseFor(req, INTERVAL_TOO_BRIEF)
+		resp.GetHeader().Set("Min-Expires", strconv.Itoa(minExpires))
+		return st.SendResponse(resp)
+	}
+	if maxExpires > 0 && expires > maxExpires {
+		expires = maxExpires
+	}
+
+	//step 5, the body
+	body, err := rawBodyBytes(req)
+	if err != nil {
+		return st.SendResponse(newBadRequest(req, "Unreadable Body"))
+	}
+	contentType := req.GetHeader().Get("Content-Type")
+	if len(body) > 0 && !strings.EqualFold(mediaTypeOf(contentType), pkg.contentType) {
+		resp := newResponseFor(req, UNSUPPORTED_MEDIA_TYPE)
+		resp.GetHeader().Set("Accept", pkg.contentType)
+		return st.SendResponse(resp)
+	}
+	if etag == "" && len(body) == 0 {
+		return st.SendResponse(newBadRequest(req, "Missing Body"))
+	}
+
+	uri, err := parseURI(req.GetRequestURI())
+	if err != nil {
+		return st.SendResponse(newBadRequest(req, "Malformed Request-URI"))
+	}
+	key := publicationKey{addressOfRecord(uri), pkg.eventType}
+
+	//steps 6 and 7, the state is stored under a new entity-tag
+	this.mutex.Lock()
+	var state *eventState
+	if etag != "" {
+		if state = this.find(key, etag); state == nil {
+			this.mutex.Unlock()
+			return st.SendResponse(newResponseFor(req, CONDITIONAL_REQUEST_FAILED))
+		}
+	}
+	changed := len(body) > 0 && expires > 0 || expires == 0 && state != nil
+	switch {
+	case expires == 0:
+		if state != nil {
+			this.remove(key, state)
+		}
+	case state == nil:
+		state = &eventState{contentType: mediaTypeOf(contentType), body: body}
+		this.states[key] = append(this.states[key], state)
+	case len(body) > 0:
+		//a modification makes the state the latest one
+		this.remove(key, state)
+		state.contentType, state.body = mediaTypeOf(contentType), body
+		this.states[key] = append(this.states[key], state)
+	}
+	resp := newResponseFor(req, OK)
+	if expires > 0 {
+		state.etag = randomHex(8)
+		this.schedule(key, state, expires)
+		resp.GetHeader().Set("SIP-ETag", state.etag)
+	}
+	this.mutex.Unlock()
+
+	resp.GetHeader().Set("Expires", strconv.Itoa(expires))
+	if err := st.SendResponse(resp); err != nil {
+		return err
+	}
+	if changed {
+		return this.notify(key)
+	}
+	return nil
+}
+
+func (this *eventStateCompositor) GetEventStates(resource, eventType string) []EventState {
+	if uri, err := parseURI(resource); err == nil {
+		resource = addressOfRecord(uri)
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.eventStates(publicationKey{resource, eventType})
+}
+
+func (this *eventStateCompositor) GetComposedState(resource, eventType string) ([]byte, error) {
+	this.mutex.Lock()
+	pkg := this.packages[eventType]
+	this.mutex.Unlock()
+	if pkg == nil {
+		return nil, ErrBadEvent
+	}
+	return pkg.compose(resource, this.GetEventStates(resource, eventType))
+}
+
+// eventStates returns the states of key. The compositor must be locked.
+func (this *eventStateCompositor) eventStates(key publicationKey) []EventState {
+	var states []EventState
+	for _, s := range this.states[key] {
+		left := int(time.Until(s.expiry) / time.Second)
+		if left < 0 {
+			left = 0
+		}
+		states = append(states, EventState{s.etag, s.contentType, s.body, left})
+	}
+	return states
+}
+
+// find returns the state of key whose entity-tag is etag. The compositor
+// must be locked.
+func (this *eventStateCompositor) find(key publicationKey, etag string) *eventState {
+	for _, s := range this.states[key] {
+		if s.etag == etag {
+			return s
+		}
+	}
+	return nil
+}
+
+// remove forgets state and stops its timer. The compositor must be locked.
+func (this *eventStateCompositor) remove(key publicationKey, state *eventState) {
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	states := this.states[key]
+	for i, s := range states {
+		if s == state {
+			states = append(states[:i:i], states[i+1:]...)
+			break
+		}
+	}
+	if len(states) == 0 {
+		delete(this.states, key)
+	} else {
+		this.states[key] = states
+	}
+}
+
+// schedule (re)arms the expiry timer of state. The compositor must be
+// locked.
+func (this *eventStateCompositor) schedule(key publicationKey, state *eventState, expires int) {
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	state.expiry = time.Now().Add(time.Duration(expires) * time.Second)
+	etag := state.etag
+	state.timer = time.AfterFunc(time.Duration(expires)*time.Second, func() {
+		this.mutex.Lock()
+		expired := this.find(key, etag) == state
+		if expired {
+			this.remove(key, state)
+		}
+		this.mutex.Unlock()
+		if expired {
+			this.notify(key)
+		}
+	})
+}
+
+// notify sends the composed state of key to the subscribers of its
+// resource.
+func (this *eventStateCompositor) notify(key publicationKey) error {
+	var err error
+	for _, sub := range this.notifier.GetSubscriptions(key.eventType) {
+		if resourceOf(sub) != key.resource || sub.GetState() != SUBSCRIPTIONSTATE_ACTIVE {
+			continue
+		}
+		if e := this.notifier.Notify(sub); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// publicationPackage is the EventPackage of the subscriptions to the state
+// the compositor composes.
+type publicationPackage struct {
+	compositor  *eventStateCompositor
+	eventType   string
+	contentType string
+	compose     CompositionFunc
+}
+
+func (this *publicationPackage) GetEventType() string   { return this.eventType }
+func (this *publicationPackage) GetDefaultExpires() int { return DEFAULT_PUBLICATION_EXPIRES }
+func (this *publicationPackage) GetContentType() string { return this.contentType }
+
+func (this *publicationPackage) Authorize(Subscription, Request) SubscriptionState {
+	return SUBSCRIPTIONSTATE_ACTIVE
+}
+
+func (this *publicationPackage) GetNotifyBody(sub Subscription) ([]byte, error) {
+	resource := resourceOf(sub)
+	this.compositor.mutex.Lock()
+	states := this.compositor.eventStates(publicationKey{resource, this.eventType})
+	this.compositor.mutex.Unlock()
+	return this.compose(resource, states)
+}
+
+// resourceOf returns the address of record of the resource sub is to, the
+// local party of its dialog.
+func resourceOf(sub Subscription) string {
+	s, ok := sub.(*subscription)
+	if !ok {
+		return ""
+	}
+	s.mutex.Lock()
+	d := s.dialog
+	s.mutex.Unlock()
+	if d == nil || d.localParty == nil {
+		return ""
+	}
+	return addressOfRecord(d.localParty.GetURI())
+}
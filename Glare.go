@@ -0,0 +1,24 @@
+package sip
+
+import (
+	"math/rand"
+	"time"
+)
+
+// glareRetryInterval returns how long a UAC waits before retrying a request
+// that failed with 491 Request Pending, RFC 3261 §14.1. The owner of the
+// Call-ID waits between 2.1 and 4 seconds, the other side between 0 and 2
+// seconds, both in units of 10 ms.
+func glareRetryInterval(callIdOwner bool) time.Duration {
+	if callIdOwner {
+		return time.Duration(210+rand.Intn(191)) * 10 * time.Millisecond
+	}
+	return time.Duration(rand.Intn(201)) * 10 * time.Millisecond
+}
+
+// pendingRetryAfter returns the Retry-After value, in seconds, of a 500
+// rejecting a request that arrived while an answer was still owed,
+// RFC 3311 §5.2 asks for a random value between 0 and 10.
+func pendingRetryAfter() int {
+	return rand.Intn(11)
+}
@@ -0,0 +1,279 @@
+package sip
+
+import (
+	"errors"
+	"sip/address"
+	"sip/core"
+	"sip/header"
+	"strconv"
+	"strings"
+)
+
+// How a retargeted request's new target was derived from the previous one,
+// the tag of its History-Info entry, RFC 7044 §4.
+const (
+	// RETARGET_RC is a change of contact, e.g. by a registrar.
+	RETARGET_RC = "rc"
+	// RETARGET_MP is a mapping to another user, e.g. a call forwarding.
+	RETARGET_MP = "mp"
+	// RETARGET_NP is no change of target.
+	RETARGET_NP = "np"
+)
+
+// diversionReasons maps the cause of the Reason of a History-Info entry to
+// the reason of a Diversion, RFC 6044 §6.
+var diversionReasons = map[int]string{
+	NOT_FOUND:               "unknown",
+	MOVED_TEMPORARILY:       "unconditional",
+	BUSY_HERE:               "user-busy",
+	REQUEST_TIMEOUT:         "no-answer",
+	TEMPORARILY_UNAVAILABLE: "deflection",
+	SERVICE_UNAVAILABLE:     "unavailable",
+}
+
+// RetargetRequest sends req, a request a proxy or B2BUA received, to target
+// instead of its Request-URI, and records it in History-Info, RFC 7044
+// §10.3. An entry is first added for the Request-URI when req has no
+// History-Info. cause, the status code that triggered the retargeting or 0,
+// is recorded in a Reason embedded in the entry of the Request-URI.
+// mechanism is RETARGET_RC, RETARGET_MP, RETARGET_NP or "".
+func RetargetRequest(req Request, target string, mechanism string, cause int) error {
+	entries, err := historyInfoEntries(req)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		entry, err := newHistoryInfo(req.GetRequestURI(), "1")
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	//the entry of the current target, the most recent one for its URI
+	parent := entries[len(entries)-1]
+	for _, entry := range entries {
+		if strings.EqualFold(withoutHeaders(entry.GetAddress().GetURI()), req.GetRequestURI()) {
+			parent = entry
+		}
+	}
+	if cause > 0 && parent.GetReason() == "" {
+		if uri, ok := parent.GetAddress().GetURI().(*address.SipURIImpl); ok {
+			reason := "SIP;cause=" + strconv.Itoa(cause) + ";text=\"" + ReasonPhrase(cause) + "\""
+			uri.SetHeader(core.SIPHeaderNames_REASON, escapeURIHeader(reason))
+		}
+	}
+
+	//the next child of the parent entry
+	children := 0
+	for _, entry := range entries {
+		index := entry.GetIndex()
+		if strings.HasPrefix(index, parent.GetIndex()+".") && !strings.Contains(index[len(parent.GetIndex())+1:], ".") {
+			if n, _ := strconv.Atoi(index[len(parent.GetIndex())+1:]); n > children {
+				children = n
+			}
+		}
+	}
+	entry, err := newHistoryInfo(target, parent.GetIndex()+"."+strconv.Itoa(children+1))
+	if err != nil {
+		return err
+	}
+	switch mechanism {
+	case RETARGET_RC:
+		entry.SetRc(parent.GetIndex())
+	case RETARGET_MP:
+		entry.SetMp(parent.GetIndex())
+	case RETARGET_NP:
+		entry.SetNp(parent.GetIndex())
+	case "":
+	default:
+		return errors.New("unknown retargeting mechanism " + mechanism)
+	}
+	if err := req.SetRequestURI(target); err != nil {
+		return err
+	}
+	setHistoryInfo(req, append(entries, entry))
+	return nil
+}
+
+// AddDiversion records in a Diversion header that req, a request sent to
+// diverter, is diverted for reason, such as "user-busy", RFC 5806 §3.
+func AddDiversion(req Request, diverter string, reason string) error {
+	h, err := parseHeaderValue("Diversion", "<"+diverter+">")
+	if err != nil {
+		return err
+	}
+	diversion := h.(*header.DiversionList).Front().Value.(*header.Diversion)
+	if err := diversion.SetReason(reason); err != nil {
+		return err
+	}
+	diversion.SetCounter(1)
+	diversions := append([]string{diversion.EncodeBody()}, headerValues(req, "Diversion")...)
+	removeHeader(req, "Diversion")
+	req.GetHeader().Set("Diversion", strings.Join(diversions, ", "))
+	return nil
+}
+
+// HistoryInfoToDiversion replaces the History-Info of req with the
+// Diversion headers a trunk that only speaks Diversion expects, RFC 6044
+// §6.1. Each entry that a later entry was mapped from, through the mp
+// parameter, is a diversion, whose reason is taken from the cause of its
+// embedded Reason.
+func HistoryInfoToDiversion(req Request) error {
+	entries, err := historyInfoEntries(req)
+	if err != nil {
+		return err
+	}
+	var diversions []string
+	for _, entry := range entries {
+		mapped := false
+		for _, e := range entries {
+			mapped = mapped || e.GetMp() == entry.GetIndex()
+		}
+		if !mapped {
+			continue
+		}
+		reason := "unknown"
+		if r, ok := diversionReasons[reasonCause(entry.GetReason())]; ok {
+			reason = r
+		}
+		diversion := "<" + withoutHeaders(entry.GetAddress().GetURI()) + ">;reason=" + reason + ";counter=1"
+		//the most recent diversion comes first
+		diversions = append([]string{diversion}, diversions...)
+	}
+	removeHeader(req, "History-Info")
+	if len(diversions) > 0 {
+		req.GetHeader().Set("Diversion", strings.Join(diversions, ", "))
+	}
+	return nil
+}
+
+// DiversionToHistoryInfo replaces the Diversion headers of req, received
+// from a trunk that only speaks Diversion, with the equivalent History-Info,
+// RFC 6044 §6.2: an entry per diversion, in the order they happened, with
+// its reason as the cause of an embedded Reason, and an entry for the
+// Request-URI, each mapped from the previous one.
+func DiversionToHistoryInfo(req Request) error {
+	headers, err := parseHeaders(req, "Diversion")
+	if err != nil {
+		return err
+	}
+	var diversions []*header.Diversion
+	for _, h := range headers {
+		for e := h.(*header.DiversionList).Front(); e != nil; e = e.Next() {
+			diversions = append([]*header.Diversion{e.Value.(*header.Diversion)}, diversions...)
+		}
+	}
+	if len(diversions) == 0 {
+		return nil
+	}
+	var entries []*header.HistoryInfo
+	index := ""
+	for _, d := range append(diversions, nil) {
+		uri := req.GetRequestURI()
+		if d != nil {
+			uri = d.GetAddress().GetURI().String()
+		}
+		parent := index
+		if index == "" {
+			index = "1"
+		} else {
+			index += ".1"
+		}
+		entry, err := newHistoryInfo(uri, index)
+		if err != nil {
+			return err
+		}
+		if parent != "" {
+			entry.SetMp(parent)
+		}
+		if d != nil {
+			cause := NOT_FOUND
+			for c, reason := range diversionReasons {
+				if strings.EqualFold(reason, d.GetReason()) {
+					cause = c
+				}
+			}
+			if sipURI, ok := entry.GetAddress().GetURI().(*address.SipURIImpl); ok {
+				sipURI.SetHeader(core.SIPHeaderNames_REASON, escapeURIHeader("SIP;cause="+strconv.Itoa(cause)))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	removeHeader(req, "Diversion")
+	setHistoryInfo(req, entries)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// historyInfoEntries returns the History-Info entries of msg, in the order
+// they appear.
+func historyInfoEntries(msg Message) ([]*header.HistoryInfo, error) {
+	headers, err := parseHeaders(msg, "History-Info")
+	if err != nil {
+		return nil, err
+	}
+	var entries []*header.HistoryInfo
+	for _, h := range headers {
+		for e := h.(*header.HistoryInfoList).Front(); e != nil; e = e.Next() {
+			entries = append(entries, e.Value.(*header.HistoryInfo))
+		}
+	}
+	return entries, nil
+}
+
+func setHistoryInfo(msg Message, entries []*header.HistoryInfo) {
+	values := make([]string, len(entries))
+	for i, entry := range entries {
+		values[i] = entry.EncodeBody()
+	}
+	removeHeader(msg, "History-Info")
+	msg.GetHeader().Set("History-Info", strings.Join(values, ", "))
+}
+
+// newHistoryInfo returns the History-Info entry of uri with index.
+func newHistoryInfo(uri string, index string) (*header.HistoryInfo, error) {
+	h, err := parseHeaderValue("History-Info", "<"+uri+">;index="+index)
+	if err != nil {
+		return nil, err
+	}
+	return h.(*header.HistoryInfoList).Front().Value.(*header.HistoryInfo), nil
+}
+
+// withoutHeaders returns uri without its embedded headers.
+func withoutHeaders(uri address.URI) string {
+	return strings.SplitN(uri.String(), "?", 2)[0]
+}
+
+// reasonCause returns the cause of a SIP Reason, 0 if it has none.
+func reasonCause(reason string) int {
+	if reason == "" {
+		return 0
+	}
+	h, err := parseHeaderValue("Reason", reason)
+	if err != nil {
+		return 0
+	}
+	for e := h.(*header.ReasonList).Front(); e != nil; e = e.Next() {
+		if r := e.Value.(*header.Reason); strings.EqualFold(r.GetProtocol(), "SIP") {
+			return r.GetCause()
+		}
+	}
+	return 0
+}
+
+// escapeURIHeader escapes the value of a header embedded in a URI, RFC 3261
+// §25.1 hvalue.
+func escapeURIHeader(s string) string {
+	const hex = "0123456789ABCDEF"
+	var escaped []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-_.!~*'()[]/?:+$", c) >= 0 {
+			escaped = append(escaped, c)
+		} else {
+			escaped = append(escaped, '%', hex[c>>4], hex[c&15])
+		}
+	}
+	return string(escaped)
+}
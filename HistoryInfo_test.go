@@ -0,0 +1,103 @@
+package sip
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func TestRetargetRequest(t *testing.T) {
+	req := testInvite(t, false)
+	//the registrar retargets to the contact, which doesn't answer, and the
+	//call is forwarded to the voicemail
+	if err := RetargetRequest(req, "sip:bob@192.0.2.4", RETARGET_RC, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := RetargetRequest(req, "sip:vm@biloxi.com;user=bob", RETARGET_MP, REQUEST_TIMEOUT); err != nil {
+		t.Fatal(err)
+	}
+	if req.GetRequestURI() != "sip:vm@biloxi.com;user=bob" {
+		t.Error(req.GetRequestURI())
+	}
+	expected := "<sip:bob@biloxi.com>;index=1, " +
+		"<sip:bob@192.0.2.4?Reason=SIP%3Bcause%3D408%3Btext%3D%22Request%20Timeout%22>;index=1.1;rc=1, " +
+		"<sip:vm@biloxi.com;user=bob>;index=1.1.1;mp=1.1"
+	if hi := req.GetHeader().Get("History-Info"); hi != expected {
+		t.Errorf("%s\n%s", hi, expected)
+	}
+
+	entries, err := historyInfoEntries(req)
+	if err != nil || len(entries) != 3 {
+		t.Fatal(entries, err)
+	}
+	if reason := entries[1].GetReason(); reason != "SIP;cause=408;text=\"Request Timeout\"" {
+		t.Error(reason)
+	}
+	if entries[2].GetMp() != "1.1" || entries[1].GetRc() != "1" {
+		t.Error(entries[2].GetMp(), entries[1].GetRc())
+	}
+
+	//a sequential fork gives the next target a sibling index
+	req.SetRequestURI("sip:bob@192.0.2.4")
+	if err := RetargetRequest(req, "sip:bob@198.51.100.7", RETARGET_NP, 0); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = historyInfoEntries(req)
+	if last := entries[len(entries)-1]; last.GetIndex() != "1.1.2" || last.GetNp() != "1.1" {
+		t.Error(last.EncodeBody())
+	}
+	if err := RetargetRequest(req, "sip:x@biloxi.com", "xx", 0); err == nil {
+		t.Error("accepted an unknown mechanism")
+	}
+}
+
+func TestHistoryInfoToDiversion(t *testing.T) {
+	req := testInvite(t, false)
+	RetargetRequest(req, "sip:carol@biloxi.com", RETARGET_MP, BUSY_HERE)
+	RetargetRequest(req, "sip:carol@192.0.2.9", RETARGET_RC, 0)
+	RetargetRequest(req, "sip:vm@biloxi.com", RETARGET_MP, REQUEST_TIMEOUT)
+	if err := HistoryInfoToDiversion(req); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<sip:carol@192.0.2.9>;reason=no-answer;counter=1, <sip:bob@biloxi.com>;reason=user-busy;counter=1"
+	if diversion := req.GetHeader().Get("Diversion"); diversion != expected {
+		t.Errorf("%s\n%s", diversion, expected)
+	}
+	if len(headerValues(req, "History-Info")) != 0 {
+		t.Error("History-Info was kept")
+	}
+	h, err := parseHeader(req, "Diversion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := h.(*header.DiversionList).Front().Value.(*header.Diversion); d.GetReason() != "no-answer" || d.GetCounter() != 1 {
+		t.Error(d.EncodeBody())
+	}
+
+	//and back, for a request received from such a trunk
+	if err := DiversionToHistoryInfo(req); err != nil {
+		t.Fatal(err)
+	}
+	expected = "<sip:bob@biloxi.com?Reason=SIP%3Bcause%3D486>;index=1, " +
+		"<sip:carol@192.0.2.9?Reason=SIP%3Bcause%3D408>;index=1.1;mp=1, " +
+		"<sip:vm@biloxi.com>;index=1.1.1;mp=1.1"
+	if hi := req.GetHeader().Get("History-Info"); hi != expected {
+		t.Errorf("%s\n%s", hi, expected)
+	}
+	if len(headerValues(req, "Diversion")) != 0 {
+		t.Error("Diversion was kept")
+	}
+}
+
+func TestAddDiversion(t *testing.T) {
+	req := testInvite(t, false)
+	if err := AddDiversion(req, "sip:bob@biloxi.com", "user-busy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddDiversion(req, "sip:carol@biloxi.com", "no-answer"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<sip:carol@biloxi.com>;reason=no-answer;counter=1, <sip:bob@biloxi.com>;reason=user-busy;counter=1"
+	if diversion := req.GetHeader().Get("Diversion"); diversion != expected {
+		t.Error(diversion)
+	}
+}
@@ -0,0 +1,309 @@
+package sip
+
+import (
+	"errors"
+	"net"
+	"sip/address"
+	"sip/header"
+	"strings"
+	"sync"
+)
+
+// The priv-values of the Privacy header, RFC 3323 §4.2 and RFC 3325 §9.3.
+const (
+	PRIVACY_HEADER   = "header"
+	PRIVACY_SESSION  = "session"
+	PRIVACY_USER     = "user"
+	PRIVACY_ID       = "id"
+	PRIVACY_NONE     = "none"
+	PRIVACY_CRITICAL = "critical"
+)
+
+// ANONYMOUS_FROM is the From of a message anonymized for user privacy, RFC
+// 3323 §4.1.1.3.
+const ANONYMOUS_FROM = "\"Anonymous\" <sip:anonymous@anonymous.invalid>"
+
+// userHeaders are the headers revealing the user that user privacy
+// removes, RFC 3323 §4.1.
+var userHeaders = []string{"Subject", "Call-Info", "Organization", "User-Agent", "Reply-To", "In-Reply-To"}
+
+var ErrPrivacyUnavailable = errors.New("the privacy the message requires can't be provided")
+
+////////////////////Interface//////////////////////////////
+
+// TrustDomain is the policy of a proxy at the edge of a trust domain for
+// network asserted identities, RFC 3325. The proxy hands it each message it
+// receives and each message it forwards, with the host it came from or goes
+// to.
+type TrustDomain interface {
+	// AddTrusted adds host names, IP addresses or CIDR networks to the
+	// trust domain.
+	AddTrusted(hosts ...string) error
+	// IsTrusted reports whether host, which may carry a port, is in the
+	// trust domain.
+	IsTrusted(host string) bool
+
+	// SetAnonymousContact sets the URI, routing back to the proxy, that
+	// replaces the Contact of the messages asking for header privacy.
+	SetAnonymousContact(uri string)
+	GetAnonymousContact() string
+
+	// ReceiveMessage removes the P-Asserted-Identity of a message received
+	// from a host outside the trust domain, RFC 3325 §5.
+	ReceiveMessage(msg Message, host string)
+	// ForwardMessage applies the Privacy of a message sent to a host outside
+	// the trust domain, RFC 3323 §5 and RFC 3325 §5: "id" removes the
+	// P-Asserted-Identity, "user" anonymizes the From of a request, which
+	// its responses have to echo, and removes its user headers, "header"
+	// anonymizes Contact and hides the Vias below the topmost one, the
+	// proxy's own. It returns the hidden Via values, to be restored in the
+	// responses by RestoreVias, and ErrPrivacyUnavailable when the message
+	// asks for a "critical" privacy it can't provide.
+	ForwardMessage(msg Message, host string) ([]string, error)
+	// AssertIdentity replaces the P-Preferred-Identity of an authenticated
+	// request with a P-Asserted-Identity, RFC 3325 §6. identities are the
+	// name-addrs or URIs the user may use: the preferred identities among
+	// them are asserted, the first one when none is.
+	AssertIdentity(req Request, identities ...string) error
+}
+
+////////////////////Implementation////////////////////////
+
+type trustDomain struct {
+	mutex            sync.RWMutex
+	hosts            []string
+	networks         []*net.IPNet
+	anonymousContact string
+}
+
+// NewTrustDomain returns the trust domain made of hosts, see AddTrusted.
+func NewTrustDomain(hosts ...string) (TrustDomain, error) {
+	this := &trustDomain{}
+	if err := this.AddTrusted(hosts...); err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *trustDomain) AddTrusted(hosts ...string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, host := range hosts {
+		if strings.Contains(host, "/") {
+			_, network, err := net.ParseCIDR(host)
+			if err != nil {
+				return err
+			}
+			this.networks = append(this.networks, network)
+		} else if host = strings.Trim(host, "[]"); host != "" {
+			this.hosts = append(this.hosts, host)
+		}
+	}
+	return nil
+}
+
+func (this *trustDomain) IsTrusted(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	ip := net.ParseIP(host)
+	for _, trusted := range this.hosts {
+		if strings.EqualFold(trusted, host) || ip != nil && ip.Equal(net.ParseIP(trusted)) {
+			return true
+		}
+	}
+	for _, network := range this.networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *trustDomain) SetAnonymousContact(uri string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.anonymousContact = uri
+}
+
+func (this *trustDomain) GetAnonymousContact() string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.anonymousContact
+}
+
+func (this *trustDomain) ReceiveMessage(msg Message, host string) {
+	if !this.IsTrusted(host) {
+		removeHeader(msg, "P-Asserted-Identity")
+	}
+}
+
+func (this *trustDomain) ForwardMessage(msg Message, host string) ([]string, error) {
+	if this.IsTrusted(host) {
+		return nil, nil
+	}
+	privacy := GetPrivacy(msg)
+	if containsFold(privacy, PRIVACY_NONE) {
+		return nil, nil
+	}
+	critical := containsFold(privacy, PRIVACY_CRITICAL)
+	if critical && containsFold(privacy, PRIVACY_SESSION) {
+		//media anonymization is left to a B2BUA
+		return nil, ErrPrivacyUnavailable
+	}
+	if containsFold(privacy, PRIVACY_ID) {
+		removeHeader(msg, "P-Asserted-Identity")
+	}
+	if _, ok := msg.(Request); ok && containsFold(privacy, PRIVACY_USER) {
+		if err := anonymizeFrom(msg); err != nil {
+			return nil, err
+		}
+		for _, name := range userHeaders {
+			removeHeader(msg, name)
+		}
+	}
+	var hidden []string
+	if containsFold(privacy, PRIVACY_HEADER) {
+		if contact := this.GetAnonymousContact(); contact != "" {
+			if len(headerValues(msg, "Contact")) > 0 {
+				removeHeader(msg, "Contact")
+				msg.GetHeader().Set("Contact", "<"+contact+">")
+			}
+		} else if critical {
+			return nil, ErrPrivacyUnavailable
+		}
+		if _, ok := msg.(Request); ok {
+			hidden = hideVias(msg)
+		}
+	}
+	return hidden, nil
+}
+
+func (this *trustDomain) AssertIdentity(req Request, identities ...string) error {
+	var allowed []address.Address
+	for _, identity := range identities {
+		if !strings.Contains(identity, "<") {
+			identity = "<" + identity + ">"
+		}
+		addrs, err := identityAddresses(identity)
+		if err != nil {
+			return err
+		}
+		allowed = append(allowed, addrs...)
+	}
+	if len(allowed) == 0 {
+		return errors.New("no identity to assert")
+	}
+
+	var asserted []string
+	for _, v := range headerValues(req, "P-Preferred-Identity") {
+		preferred, err := identityAddresses(v)
+		if err != nil {
+			return err
+		}
+		for _, p := range preferred {
+			for _, a := range allowed {
+				if strings.EqualFold(p.GetURI().String(), a.GetURI().String()) {
+					asserted = append(asserted, header.NewPAssertedIdentityFromAddress(a).EncodeBody())
+					break
+				}
+			}
+		}
+	}
+	if len(asserted) == 0 {
+		asserted = append(asserted, header.NewPAssertedIdentityFromAddress(allowed[0]).EncodeBody())
+	}
+	removeHeader(req, "P-Preferred-Identity")
+	removeHeader(req, "P-Asserted-Identity")
+	req.GetHeader().Set("P-Asserted-Identity", strings.Join(asserted, ", "))
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// GetPrivacy returns the priv-values of the Privacy headers of msg.
+func GetPrivacy(msg Message) []string {
+	var privacy []string
+	for _, v := range headerValues(msg, "Privacy") {
+		for _, value := range strings.Split(v, ";") {
+			if value = strings.TrimSpace(value); value != "" {
+				privacy = append(privacy, value)
+			}
+		}
+	}
+	return privacy
+}
+
+// RestoreVias puts back, below the topmost Via of resp, the Vias that
+// ForwardMessage hid from the request resp answers.
+func RestoreVias(resp Response, vias []string) {
+	if len(vias) == 0 {
+		return
+	}
+	var restored []string
+	for i, v := range splitVias(resp) {
+		restored = append(restored, v)
+		if i == 0 {
+			restored = append(restored, vias...)
+		}
+	}
+	removeHeader(resp, "Via")
+	resp.GetHeader()["Via"] = restored
+}
+
+// anonymizeFrom replaces the From of msg with ANONYMOUS_FROM, keeping its
+// tag.
+func anonymizeFrom(msg Message) error {
+	h, err := parseHeader(msg, "From")
+	if err != nil {
+		return err
+	}
+	from := ANONYMOUS_FROM
+	if h != nil && h.(*header.From).GetTag() != "" {
+		from += ";tag=" + h.(*header.From).GetTag()
+	}
+	removeHeader(msg, "From")
+	msg.GetHeader().Set("From", from)
+	return nil
+}
+
+// hideVias removes the Vias of msg but the topmost one, and returns them.
+func hideVias(msg Message) []string {
+	vias := splitVias(msg)
+	if len(vias) < 2 {
+		return nil
+	}
+	removeHeader(msg, "Via")
+	msg.GetHeader().Set("Via", vias[0])
+	return vias[1:]
+}
+
+// splitVias returns the Via values of msg, one per hop.
+func splitVias(msg Message) []string {
+	var vias []string
+	for _, v := range headerValues(msg, "Via") {
+		for _, via := range strings.Split(v, ",") {
+			if via = strings.TrimSpace(via); via != "" {
+				vias = append(vias, via)
+			}
+		}
+	}
+	return vias
+}
+
+// identityAddresses returns the addresses of a P-Asserted-Identity or
+// P-Preferred-Identity value.
+func identityAddresses(v string) ([]address.Address, error) {
+	h, err := parseHeaderValue("P-Asserted-Identity", v)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []address.Address
+	for e := h.(*header.PAssertedIdentityList).Front(); e != nil; e = e.Next() {
+		addrs = append(addrs, e.Value.(header.AddressHeader).GetAddress())
+	}
+	return addrs, nil
+}
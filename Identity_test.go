@@ -0,0 +1,145 @@
+package sip
+
+import (
+	"testing"
+)
+
+const identityInvite = "INVITE sip:+14155551212@example.com SIP/2.0\r\n" +
+	"Via: SIP/2.0/TCP proxy.cisco.com;branch=z9hG4bK-proxy\r\n" +
+	"Via: SIP/2.0/TCP useragent.cisco.com;branch=z9hG4bK-ua\r\n" +
+	"To: <sip:+14155551212@example.com>\r\n" +
+	"From: \"Anonymous\" <sip:anonymous@anonymous.invalid>;tag=9802748\r\n" +
+	"Call-ID: 245780247857024504\r\n" +
+	"CSeq: 2 INVITE\r\n" +
+	"Max-Forwards: 69\r\n" +
+	"P-Asserted-Identity: \"Cullen Jennings\" <sip:fluffy@cisco.com>\r\n" +
+	"P-Asserted-Identity: tel:+14085264000\r\n" +
+	"Privacy: id\r\n" +
+	"Contact: <sip:fluffy@useragent.cisco.com>\r\n" +
+	"Subject: private\r\n" +
+	"Content-Length: 0\r\n\r\n"
+
+func TestTrustDomain(t *testing.T) {
+	td, err := NewTrustDomain("proxy.cisco.com", "10.1.0.0/16", "2001:db8::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, trusted := range map[string]bool{
+		"proxy.cisco.com":      true,
+		"PROXY.cisco.com:5060": true,
+		"10.1.2.3:5061":        true,
+		"[2001:db8::1]:5060":   true,
+		"10.2.0.1":             false,
+		"gw.example.com":       false,
+	} {
+		if td.IsTrusted(host) != trusted {
+			t.Error(host, !trusted)
+		}
+	}
+
+	msg := readTestMessage(t, identityInvite)
+	td.ReceiveMessage(msg, "10.1.0.7")
+	if len(headerValues(msg, "P-Asserted-Identity")) != 2 {
+		t.Error("the identity asserted by a trusted host was removed")
+	}
+	td.ReceiveMessage(msg, "gw.example.com")
+	if len(headerValues(msg, "P-Asserted-Identity")) != 0 {
+		t.Error("the identity asserted by an untrusted host was kept")
+	}
+}
+
+func TestTrustDomainPrivacy(t *testing.T) {
+	td, _ := NewTrustDomain("proxy.cisco.com")
+
+	msg := readTestMessage(t, identityInvite)
+	if hidden, err := td.ForwardMessage(msg, "proxy.cisco.com"); err != nil || hidden != nil {
+		t.Error(hidden, err)
+	}
+	if len(headerValues(msg, "P-Asserted-Identity")) != 2 {
+		t.Error("the asserted identity was removed inside the trust domain")
+	}
+	if _, err := td.ForwardMessage(msg, "gw.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(headerValues(msg, "P-Asserted-Identity")) != 0 {
+		t.Error("Privacy: id didn't remove the asserted identity")
+	}
+
+	msg = readTestMessage(t, identityInvite)
+	msg.GetHeader().Set("Privacy", "user; header")
+	msg.GetHeader().Set("From", "\"Cullen Jennings\" <sip:fluffy@cisco.com>;tag=9802748")
+	td.SetAnonymousContact("sip:anon-1234@proxy.cisco.com")
+	hidden, err := td.ForwardMessage(msg, "gw.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := msg.GetHeader()
+	if from := h.Get("From"); from != ANONYMOUS_FROM+";tag=9802748" {
+		t.Error(from)
+	}
+	if contact := h.Get("Contact"); contact != "<sip:anon-1234@proxy.cisco.com>" {
+		t.Error(contact)
+	}
+	if h.Get("Subject") != "" {
+		t.Error("user privacy kept the Subject")
+	}
+	if len(h["Via"]) != 1 || len(hidden) != 1 || hidden[0] != "SIP/2.0/TCP useragent.cisco.com;branch=z9hG4bK-ua" {
+		t.Error(h["Via"], hidden)
+	}
+	if len(headerValues(msg, "P-Asserted-Identity")) != 2 {
+		t.Error("the asserted identity was removed without Privacy: id")
+	}
+
+	resp := newResponseFor(msg.(Request), OK)
+	RestoreVias(resp, hidden)
+	if vias := resp.GetHeader()["Via"]; len(vias) != 2 || vias[1] != hidden[0] {
+		t.Error(vias)
+	}
+
+	//the From of a response is the one of its request
+	resp = newResponseFor(readTestMessage(t, identityInvite).(Request), OK)
+	resp.GetHeader().Set("Privacy", "user")
+	resp.GetHeader().Set("Subject", "lunch")
+	from := resp.GetHeader().Get("From")
+	if _, err := td.ForwardMessage(resp, "gw.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetHeader().Get("From") != from || resp.GetHeader().Get("Subject") != "lunch" {
+		t.Error(resp.GetHeader())
+	}
+
+	msg = readTestMessage(t, identityInvite)
+	msg.GetHeader().Set("Privacy", "session;critical")
+	if _, err := td.ForwardMessage(msg, "gw.example.com"); err != ErrPrivacyUnavailable {
+		t.Error(err)
+	}
+}
+
+func TestAssertIdentity(t *testing.T) {
+	td, _ := NewTrustDomain()
+	identities := []string{"\"Cullen Jennings\" <sip:fluffy@cisco.com>", "tel:+14085264000"}
+
+	req := readTestMessage(t, identityInvite).(Request)
+	req.GetHeader().Set("P-Preferred-Identity", "<tel:+14085264000>")
+	if err := td.AssertIdentity(req, identities...); err != nil {
+		t.Fatal(err)
+	}
+	if pai := headerValues(req, "P-Asserted-Identity"); len(pai) != 1 || pai[0] != "<tel:+14085264000>" {
+		t.Error(pai)
+	}
+	if len(headerValues(req, "P-Preferred-Identity")) != 0 {
+		t.Error("P-Preferred-Identity was kept")
+	}
+
+	//an identity the user may not use falls back to the default one
+	req.GetHeader().Set("P-Preferred-Identity", "<sip:ceo@cisco.com>")
+	if err := td.AssertIdentity(req, identities...); err != nil {
+		t.Fatal(err)
+	}
+	if pai := req.GetHeader().Get("P-Asserted-Identity"); pai != "\"Cullen Jennings\" <sip:fluffy@cisco.com>" {
+		t.Error(pai)
+	}
+	if err := td.AssertIdentity(req); err == nil {
+		t.Error("asserted no identity")
+	}
+}
@@ -0,0 +1,321 @@
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"sip/header"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+////////////////////Interface//////////////////////////////
+
+// InfoPackage is implemented by the application for each Info Package it
+// receives INFO requests for, RFC 6086 §10.
+type InfoPackage interface {
+	// GetPackageName returns the name of the package, e.g. "dtmf".
+	GetPackageName() string
+	// GetContentTypes returns the media types of the bodies of the package.
+	GetContentTypes() []string
+	// ProcessInfo handles the body of an INFO received within dialog and
+	// returns the status code it is answered with.
+	ProcessInfo(dialog Dialog, info Request, body []byte) int
+}
+
+// InfoPackageRegistry dispatches the INFO requests received to the Info
+// Packages added to it, RFC 6086. The packages are negotiated per dialog:
+// the application adds the Recv-Info of the registry to its INVITE, or to
+// the responses to the INVITE it receives, and the dialog then refuses with
+// 469 Bad Info Package the INFO requests of the other packages.
+type InfoPackageRegistry interface {
+	AddInfoPackage(pkg InfoPackage)
+	RemoveInfoPackage(packageName string)
+	// GetInfoPackages returns the names of the packages, sorted.
+	GetInfoPackages() []string
+	// SetRecvInfo sets the Recv-Info of msg, an INVITE, a target refresh
+	// request or a reliable provisional or 2xx response to one, to the
+	// packages of the registry.
+	SetRecvInfo(msg Message)
+	// ProcessInfo answers an INFO received within a dialog with the status
+	// code of the package it is sent for. An INFO for a package that is not
+	// registered is answered with 469, one whose body the package doesn't
+	// understand with 415. An INFO without Info-Package, the legacy usage of
+	// RFC 2976, is handed to the package that understands its body, which
+	// serves the gateways that predate RFC 6086, and answered with 200 when
+	// it has no body.
+	ProcessInfo(requestEvent RequestEvent) error
+}
+
+// DTMF_INFO_PACKAGE is the name of the Info Package that relays DTMF in
+// application/dtmf-relay bodies.
+const DTMF_INFO_PACKAGE = "dtmf"
+
+// DTMF_RELAY_CONTENT_TYPE is the media type of the DTMF relay bodies.
+const DTMF_RELAY_CONTENT_TYPE = "application/dtmf-relay"
+
+// DTMFRelay is the body of a DTMF relay INFO, e.g.
+//
+//	Signal=5
+//	Duration=160
+type DTMFRelay struct {
+	// Signal is the key pressed: 0 to 9, *, #, A to D, or 16 for a hook
+	// flash.
+	Signal string
+	// Duration is how long the key was pressed, in milliseconds, or 0 if
+	// unknown.
+	Duration int
+}
+
+// DTMFListener is told of the DTMF relayed by the INFO requests received.
+type DTMFListener interface {
+	ProcessDTMF(dialog Dialog, dtmf DTMFRelay)
+}
+
+var ErrNoInfoPackage = errors.New("unknown or missing Info Package")
+
+////////////////////Implementation////////////////////////
+
+type infoPackageRegistry struct {
+	mutex    sync.Mutex
+	packages map[string]InfoPackage
+}
+
+func NewInfoPackageRegistry() InfoPackageRegistry {
+	return &infoPackageRegistry{
+		packages: make(map[string]InfoPackage),
+	}
+}
+
+func (this *infoPackageRegistry) AddInfoPackage(pkg InfoPackage) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.packages[strings.ToLower(pkg.GetPackageName())] = pkg
+}
+
+func (this *infoPackageRegistry) RemoveInfoPackage(packageName string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.packages, strings.ToLower(packageName))
+}
+
+func (this *infoPackageRegistry) GetInfoPackages() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	names := make([]string, 0, len(this.packages))
+	for _, pkg := range this.packages {
+		names = append(names, pkg.GetPackageName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (this *infoPackageRegistry) SetRecvInfo(msg Message) {
+	msg.GetHeader().Set("Recv-Info", strings.Join(this.GetInfoPackages(), ", "))
+}
+
+func (this *infoPackageRegistry) ProcessInfo(requestEvent RequestEvent) error {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != INFO {
+		return errors.New("InfoPackageRegistry.ProcessInfo can't process " + req.GetMethod())
+	}
+	body, err := bodyBytes(req)
+	if err != nil {
+		return st.SendResponse(newBadRequest(req, "Unreadable Body"))
+	}
+	mediaType := mediaTypeOf(req.GetHeader().Get("Content-Type"))
+
+	name := infoPackageOf(req)
+	this.mutex.Lock()
+	var pkg InfoPackage
+	if name != "" {
+		pkg = this.packages[strings.ToLower(name)]
+	} else {
+		//a legacy INFO, whose body tells what it is for
+		for _, p := range this.packages {
+			if containsFold(p.GetContentTypes(), mediaType) {
+				pkg = p
+				break
+			}
+		}
+	}
+	this.mutex.Unlock()
+
+	switch {
+	case pkg == nil && name == "" && len(body) == 0:
+		//a legacy INFO without body, which peers send to probe the dialog
+		return st.SendResponse(newResponseFor(req, OK))
+	case pkg == nil && name == "":
+		resp := newResponseFor(req, UNSUPPORTED_MEDIA_TYPE)
+		resp.GetHeader().Set("Accept", strings.Join(this.contentTypes(), ", "))
+		st.SendResponse(resp)
+		return ErrNoInfoPackage
+	case pkg == nil:
+		resp := newResponseFor(req, BAD_INFO_PACKAGE)
+		this.SetRecvInfo(resp)
+		st.SendResponse(resp)
+		return ErrNoInfoPackage
+	case len(body) > 0 && !containsFold(pkg.GetContentTypes(), mediaType):
+		resp := newResponseFor(req, UNSUPPORTED_MEDIA_TYPE)
+		resp.GetHeader().Set("Accept", strings.Join(pkg.GetContentTypes(), ", "))
+		return st.SendResponse(resp)
+	}
+	return st.SendResponse(newResponseFor(req, pkg.ProcessInfo(st.GetDialog(), req, body)))
+}
+
+// contentTypes returns the media types of the bodies of every package.
+func (this *infoPackageRegistry) contentTypes() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var types []string
+	for _, pkg := range this.packages {
+		for _, t := range pkg.GetContentTypes() {
+			if !containsFold(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewInfoRequest builds an INFO of the Info Package packageName within d,
+// which the remote party must have listed in its Recv-Info, RFC 6086
+// §4.2.1. The INFO is sent with d.SendRequest.
+func NewInfoRequest(d Dialog, packageName, contentType string, body []byte) (Request, error) {
+	if !containsFold(d.GetRemoteRecvInfo(), packageName) {
+		return nil, ErrBadInfoPackage
+	}
+	req, err := d.CreateRequest(INFO)
+	if err != nil {
+		return nil, err
+	}
+	h := req.GetHeader()
+	h.Set("Info-Package", packageName)
+	if len(body) > 0 {
+		req.SetBody(bytes.NewReader(body))
+		req.SetContentLength(int64(len(body)))
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Disposition", "Info-Package")
+	}
+	return req, nil
+}
+
+// infoPackageOf returns the Info Package msg is sent for, or "" for a
+// legacy INFO.
+func infoPackageOf(msg Message) string {
+	values := headerValues(msg, "Info-Package")
+	if len(values) == 0 {
+		return ""
+	}
+	h, err := parseHeaderValue("Info-Package", values[0])
+	if err != nil {
+		//a malformed package is not one the dialog receives
+		return strings.TrimSpace(values[0])
+	}
+	return h.(*header.InfoPackage).GetPackageName()
+}
+
+// recvInfoOf returns the Info Packages of the Recv-Info of msg, and whether
+// msg carries one. An empty Recv-Info tells no package is received.
+func recvInfoOf(msg Message) ([]string, bool) {
+	values := headerValues(msg, "Recv-Info")
+	if len(values) == 0 {
+		return nil, false
+	}
+	packages := []string{}
+	for _, v := range values {
+		h, err := parseHeaderValue("Recv-Info", v)
+		if err != nil {
+			return nil, false
+		}
+		packages = append(packages, h.(*header.RecvInfoList).GetPackageNames()...)
+	}
+	return packages, true
+}
+
+// dtmfSignals are the keys a DTMF relay body may carry.
+const dtmfSignals = "0123456789*#ABCD"
+
+// ParseDTMFRelay parses an application/dtmf-relay body.
+func ParseDTMFRelay(body []byte) (DTMFRelay, error) {
+	var dtmf DTMFRelay
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return dtmf, errors.New("malformed DTMF relay line " + line)
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "signal":
+			dtmf.Signal = strings.ToUpper(value)
+		case "duration":
+			duration, err := strconv.Atoi(value)
+			if err != nil || duration < 0 {
+				return dtmf, errors.New("malformed DTMF duration " + value)
+			}
+			dtmf.Duration = duration
+		}
+	}
+	if !validDTMFSignal(dtmf.Signal) {
+		return dtmf, errors.New("malformed DTMF signal " + dtmf.Signal)
+	}
+	return dtmf, nil
+}
+
+func validDTMFSignal(signal string) bool {
+	return signal == "16" || len(signal) == 1 && strings.Contains(dtmfSignals, signal)
+}
+
+// Bytes encodes dtmf as an application/dtmf-relay body.
+func (this DTMFRelay) Bytes() []byte {
+	var body bytes.Buffer
+	body.WriteString("Signal=" + this.Signal + "\r\n")
+	if this.Duration > 0 {
+		body.WriteString("Duration=" + strconv.Itoa(this.Duration) + "\r\n")
+	}
+	return body.Bytes()
+}
+
+// NewDTMFInfoRequest builds an INFO relaying dtmf within d.
+func NewDTMFInfoRequest(d Dialog, dtmf DTMFRelay) (Request, error) {
+	if !validDTMFSignal(dtmf.Signal) {
+		return nil, errors.New("malformed DTMF signal " + dtmf.Signal)
+	}
+	return NewInfoRequest(d, DTMF_INFO_PACKAGE, DTMF_RELAY_CONTENT_TYPE, dtmf.Bytes())
+}
+
+type dtmfInfoPackage struct {
+	listener DTMFListener
+}
+
+// NewDTMFInfoPackage returns the Info Package that tells l of the DTMF
+// relayed in application/dtmf-relay bodies.
+func NewDTMFInfoPackage(l DTMFListener) InfoPackage {
+	return &dtmfInfoPackage{l}
+}
+
+func (this *dtmfInfoPackage) GetPackageName() string {
+	return DTMF_INFO_PACKAGE
+}
+
+func (this *dtmfInfoPackage) GetContentTypes() []string {
+	return []string{DTMF_RELAY_CONTENT_TYPE}
+}
+
+func (this *dtmfInfoPackage) ProcessInfo(dialog Dialog, info Request, body []byte) int {
+	dtmf, err := ParseDTMFRelay(body)
+	if err != nil {
+		return BAD_REQUEST
+	}
+	this.listener.ProcessDTMF(dialog, dtmf)
+	return OK
+}
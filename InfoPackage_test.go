@@ -0,0 +1,155 @@
+package sip
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testDTMFListener struct {
+	dtmf []DTMFRelay
+}
+
+func (this *testDTMFListener) ProcessDTMF(dialog Dialog, dtmf DTMFRelay) {
+	this.dtmf = append(this.dtmf, dtmf)
+}
+
+// testInfo returns an INFO received by the UAS side of the dialog of
+// testInvite. infoPackage is omitted when empty, a legacy INFO.
+func testInfo(t *testing.T, d Dialog, cSeq int, infoPackage, contentType, body string) Request {
+	headers := []string{
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK" + strconv.Itoa(cSeq),
+		"Max-Forwards: 70",
+		"To: Bob <sip:bob@biloxi.com>;tag=" + d.GetLocalTag(),
+		"From: Alice <sip:alice@atlanta.com>;tag=1928301774",
+		"Call-ID: a84b4c76e66710@pc33.atlanta.com",
+		"CSeq: " + strconv.Itoa(cSeq) + " INFO",
+	}
+	if infoPackage != "" {
+		headers = append(headers, "Info-Package: "+infoPackage)
+	}
+	headers = append(headers, "Content-Type: "+contentType)
+	return testRequest(t, INFO, "sip:bob@client.biloxi.com", headers, body)
+}
+
+// processInfo hands info to the dialog and then to r, and returns the
+// response sent.
+func processInfo(t *testing.T, d *dialog, r InfoPackageRegistry, info Request) Response {
+	if resp := d.processRequest(info); resp != nil {
+		return resp
+	}
+	st := newTestServerTransaction(info)
+	st.SetDialog(d)
+	r.ProcessInfo(*NewRequestEvent(st, info))
+	return st.lastResponse(t)
+}
+
+func TestInfoPackages(t *testing.T) {
+	l := &testDTMFListener{}
+	r := NewInfoPackageRegistry()
+	r.AddInfoPackage(NewDTMFInfoPackage(l))
+
+	invite := testInvite(t, true)
+	invite.GetHeader().Set("Recv-Info", "dtmf, foo")
+	d, st := newTestServerDialog(t, invite)
+	ok := sdpResponse(st.GetRequest(), OK)
+	r.SetRecvInfo(ok)
+	st.SendResponse(ok)
+	if local, remote := d.GetLocalRecvInfo(), d.GetRemoteRecvInfo(); len(local) != 1 || len(remote) != 2 {
+		t.Fatal(local, remote)
+	}
+
+	resp := processInfo(t, d, r, testInfo(t, d, 314161, "dtmf", DTMF_RELAY_CONTENT_TYPE, "Signal=5\r\nDuration=160\r\n"))
+	if resp.GetStatusCode() != OK || len(l.dtmf) != 1 || l.dtmf[0] != (DTMFRelay{"5", 160}) {
+		t.Error(resp.GetStatusCode(), l.dtmf)
+	}
+
+	//a package that was not negotiated
+	resp = processInfo(t, d, r, testInfo(t, d, 314162, "foo", "text/plain", "bar"))
+	if resp.GetStatusCode() != BAD_INFO_PACKAGE || resp.GetHeader().Get("Recv-Info") != "dtmf" {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	resp = processInfo(t, d, r, testInfo(t, d, 314163, "dtmf", "text/plain", "5"))
+	if resp.GetStatusCode() != UNSUPPORTED_MEDIA_TYPE || resp.GetHeader().Get("Accept") != DTMF_RELAY_CONTENT_TYPE {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if resp = processInfo(t, d, r, testInfo(t, d, 314164, "dtmf", DTMF_RELAY_CONTENT_TYPE, "Signal=X\r\n")); resp.GetStatusCode() != BAD_REQUEST {
+		t.Error(resp.GetStatusCode())
+	}
+
+	//a legacy INFO of a gateway that predates RFC 6086
+	resp = processInfo(t, d, r, testInfo(t, d, 314165, "", DTMF_RELAY_CONTENT_TYPE, "signal = #\nduration = 250\n"))
+	if resp.GetStatusCode() != OK || len(l.dtmf) != 2 || l.dtmf[1] != (DTMFRelay{"#", 250}) {
+		t.Error(resp.GetStatusCode(), l.dtmf)
+	}
+	if resp = processInfo(t, d, r, testInfo(t, d, 314166, "", "text/plain", "5")); resp.GetStatusCode() != UNSUPPORTED_MEDIA_TYPE {
+		t.Error(resp.GetStatusCode())
+	}
+	if resp = processInfo(t, d, r, testInfo(t, d, 314167, "", "text/plain", "")); resp.GetStatusCode() != OK {
+		t.Error(resp.GetStatusCode())
+	}
+
+	//a target refresh keeps the packages negotiated
+	update, err := d.CreateRequest(UPDATE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.GetHeader().Get("Recv-Info") != "dtmf" {
+		t.Error(update.GetHeader())
+	}
+	reinvite := testInDialogRequest(t, d, INVITE, 314168)
+	reinvite.GetHeader().Set("Recv-Info", "")
+	if resp := d.processRequest(reinvite); resp != nil {
+		t.Fatal(resp.GetStatusCode())
+	}
+	if remote := d.GetRemoteRecvInfo(); remote == nil || len(remote) != 0 {
+		t.Error(remote)
+	}
+}
+
+func TestSendInfo(t *testing.T) {
+	invite := testInvite(t, true)
+	invite.GetHeader().Set("Recv-Info", "dtmf")
+	d := newTestConfirmedDialog(t, invite)
+
+	info, err := NewDTMFInfoRequest(d, DTMFRelay{"*", 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := info.GetHeader()
+	body, _ := rawBodyBytes(info)
+	if info.GetMethod() != INFO || h.Get("Info-Package") != "dtmf" || h.Get("Content-Type") != DTMF_RELAY_CONTENT_TYPE ||
+		h.Get("Content-Disposition") != "Info-Package" || string(body) != "Signal=*\r\nDuration=100\r\n" {
+		t.Error(h, string(body))
+	}
+	if err := d.SendRequest(newClientTransaction(info)); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewInfoRequest(d, "foo", "text/plain", []byte("bar")); err != ErrBadInfoPackage {
+		t.Error(err)
+	}
+	info, _ = d.CreateRequest(INFO)
+	info.GetHeader().Set("Info-Package", "foo")
+	if err := d.SendRequest(newClientTransaction(info)); err != ErrBadInfoPackage {
+		t.Error(err)
+	}
+	if _, err := NewDTMFInfoRequest(d, DTMFRelay{"55", 100}); err == nil {
+		t.Error("built an INFO for a malformed signal")
+	}
+}
+
+func TestParseDTMFRelay(t *testing.T) {
+	dtmf, err := ParseDTMFRelay([]byte("Signal=16\r\n"))
+	if err != nil || dtmf.Signal != "16" || dtmf.Duration != 0 {
+		t.Error(dtmf, err)
+	}
+	for _, body := range []string{"Duration=100\r\n", "Signal=5\r\nDuration=-1\r\n", "Signal\r\n"} {
+		if _, err := ParseDTMFRelay([]byte(body)); err == nil {
+			t.Error("parsed", strings.TrimSpace(body))
+		}
+	}
+	if body := string((DTMFRelay{"d", 0}).Bytes()); body != "Signal=d\r\n" {
+		t.Error(body)
+	}
+}
@@ -5,12 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/textproto"
+	"sip/core"
 	"sip/header"
 	"sip/parser"
 	"strconv"
 	"strings"
-	"sync"
 )
 
 type StartLineWriter interface {
@@ -29,6 +28,10 @@ type Message interface {
 	GetBody() io.Reader
 	SetBody(io.Reader)
 	Write(io.Writer) error
+
+	// GetParseWarnings returns the defects of a message received in
+	// PARSEMODE_TOLERANT that were repaired while parsing it.
+	GetParseWarnings() []string
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -49,6 +52,12 @@ type message struct {
 
 	//contentLength int64
 	body io.Reader
+
+	//the defects repaired by a tolerant parser
+	parseWarnings []string
+
+	//the encoded body inflated beyond MaxDecodedBodySize when it was read
+	bodyTooLarge bool
 }
 
 func (this *message) GetSIPVersion() string {
@@ -95,6 +104,22 @@ func (this *message) SetBody(body io.Reader) {
 	this.body = body
 }
 
+func (this *message) GetParseWarnings() []string {
+	return this.parseWarnings
+}
+
+func (this *message) addParseWarning(warning string) {
+	this.parseWarnings = append(this.parseWarnings, warning)
+}
+
+func (this *message) isBodyTooLarge() bool {
+	return this.bodyTooLarge
+}
+
+func (this *message) setBodyTooLarge() {
+	this.bodyTooLarge = true
+}
+
 // Headers that Request.Write handles itself and should be skipped.
 var reqWriteExcludeHeader = map[string]bool{
 	"Content-Length": true,
@@ -139,52 +164,20 @@ func (this *message) Write(w io.Writer) (err error) {
 
 // ReadMessage reads and parses an incoming message from b.
 func ReadMessage(b *bufio.Reader) (msg Message, err error) {
-	tp := newTextprotoReader(b)
+	return ReadMessageWithMode(b, PARSEMODE_STRICT)
+}
 
-	// First line: INVITE sip:bob@biloxi.com SIP/2.0 or SIP/2.0 180 Ringing
-	var s string
-	if s, err = tp.ReadLine(); err != nil {
-		return nil, err
-	}
-	defer func() {
-		putTextprotoReader(tp)
+// ReadMessageWithMode reads and parses an incoming message from b. In
+// PARSEMODE_TOLERANT, the defects of the message that could be repaired
+// are listed by its GetParseWarnings.
+func ReadMessageWithMode(b *bufio.Reader, mode ParseMode) (msg Message, err error) {
+	msg, bodyStart, err := readHead(b, mode)
+	if err != nil {
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-	}()
-
-	s1 := strings.Index(s, " ")
-	s2 := strings.Index(s[s1+1:], " ")
-	if s1 < 0 || s2 < 0 {
-		return nil, fmt.Errorf("malformed SIP request %s", s)
-	}
-	s2 += s1 + 1
-
-	if strings.TrimSpace(s[:s1]) == "SIP/2.0" {
-		var statusCode int
-		if statusCode, err = strconv.Atoi(s[s1+1 : s2]); err != nil {
-			return nil, fmt.Errorf("malformed SIP status code %s", s[s1+1:s2])
-		}
-		sipVersion, reasonPhrase := s[:s1], s[s2+1:]
-		if _, _, ok := ParseSIPVersion(sipVersion); !ok {
-			return nil, fmt.Errorf("malformed SIP version", sipVersion)
-		}
-		msg = NewResponse(statusCode, reasonPhrase, nil)
-	} else {
-		method, requestURI, sipVersion := s[:s1], s[s1+1:s2], s[s2+1:]
-		if _, _, ok := ParseSIPVersion(sipVersion); !ok {
-			return nil, fmt.Errorf("malformed SIP version", sipVersion)
-		}
-		msg = NewRequest(method, requestURI, nil)
-	}
-
-	////////////////////////////////////////////////////////////////////////////
-	// Subsequent lines: Key: value.
-	mimeHeader, err := tp.ReadMIMEHeader()
-	if err != nil {
 		return nil, err
 	}
-	msg.SetHeader(Header(mimeHeader))
 
 	////////////////////////////////////////////////////////////////////////////
 
@@ -194,7 +187,7 @@ func ReadMessage(b *bufio.Reader) (msg Message, err error) {
 	} else if len(contentLens) == 0 {
 		msg.SetContentLength(0)
 	} else {
-		if cl, err := parser.NewContentLengthParser("Content-Length: " + contentLens[0]).Parse(); err != nil {
+		if cl, err := parseHeaderValue("Content-Length", contentLens[0]); err != nil {
 			return nil, err
 		} else {
 			msg.SetContentLength(int64(cl.(header.ContentLengthHeader).GetContentLength()))
@@ -203,29 +196,303 @@ func ReadMessage(b *bufio.Reader) (msg Message, err error) {
 
 	////////////////////////////////////////////////////////////////////////////
 
-	if msg.GetContentLength() > 0 {
-		msg.SetBody(io.LimitReader(b, int64(msg.GetContentLength())))
+	if bodyStart != "" && len(contentLens) == 0 {
+		//without Content-Length, the body is what was received with the
+		//headers
+		msg.SetContentLength(int64(len(bodyStart) + b.Buffered()))
+		addParseWarning(msg, "missing Content-Length header")
+	}
+	if cl := msg.GetContentLength(); cl > 0 && int64(len(bodyStart)) >= cl {
+		msg.SetBody(strings.NewReader(bodyStart[:cl]))
+	} else if cl > 0 {
+		msg.SetBody(io.MultiReader(strings.NewReader(bodyStart), &bodyReader{b, cl - int64(len(bodyStart))}))
 	} else {
 		msg.SetBody(nil)
 	}
 
+	//decode compressed bodies so that the application reads their content;
+	//a body we can't decode is left as received, and one that inflates too
+	//much is marked for the validator
+	if codings := contentCodings(msg); len(codings) > 0 && msg.GetContentLength() > 0 {
+		body, err := rawBodyBytes(msg)
+		if err != nil {
+			return nil, err
+		}
+		if body, err = decodeContent(codings, body); err == nil {
+			setDecodedBody(msg, body)
+		} else if err == ErrBodyTooLarge {
+			markBodyTooLarge(msg)
+		}
+	}
+
 	return msg, nil
 }
 
-var textprotoReaderPool sync.Pool
+// bodyReader reads the n octets of a body, and fails with
+// io.ErrUnexpectedEOF when the message ends before them, RFC 3261 §18.3.
+type bodyReader struct {
+	r io.Reader
+	n int64
+}
+
+func (this *bodyReader) Read(p []byte) (int, error) {
+	if this.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > this.n {
+		p = p[:this.n]
+	}
+	n, err := this.r.Read(p)
+	this.n -= int64(n)
+	if err == io.EOF && this.n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// readHead reads the start line and the headers of a message, RFC 3261
+// §7.3.1: the header names are case-insensitive and may be followed by
+// whitespace before the colon, and a line starting with whitespace
+// continues the value of the previous one. In PARSEMODE_TOLERANT, it also
+// accepts a body that follows the headers without the empty line, and then
+// returns the line read that starts the body.
+func readHead(b *bufio.Reader, mode ParseMode) (msg Message, bodyStart string, err error) {
+	tolerant := mode == PARSEMODE_TOLERANT
+	var warnings []string
+	lfOnly := false
+	readLine := func() (string, error) {
+		line, err := b.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		if tolerant && !lfOnly && strings.HasSuffix(line, "\n") && !strings.HasSuffix(line, "\r\n") {
+			lfOnly = true
+			warnings = append(warnings, "LF line endings instead of CRLF")
+		}
+		return line, nil
+	}
+
+	// First line: INVITE sip:bob@biloxi.com SIP/2.0 or SIP/2.0 180 Ringing
+	//RFC 3261 §7.5, empty lines before the start line are ignored
+	var s string
+	for s == "" {
+		line, err := readLine()
+		if err != nil {
+			return nil, "", err
+		}
+		s = strings.TrimRight(line, "\r\n")
+	}
+	if tolerant {
+		var repaired bool
+		if s, repaired = repairStartLine(s); repaired {
+			warnings = append(warnings, "spaces in Request-URI")
+		}
+	}
+	if msg, err = parseStartLine(s); err != nil {
+		return nil, "", err
+	}
+
+	// Subsequent lines: Key: value.
+	h := make(Header)
+	var name, value string
+	flush := func() {
+		if name != "" {
+			//a compact form joins the values of its long form in the
+			//order received, the topmost Via may be either
+			key := CanonicalHeaderKey(name)
+			if long, ok := compactHeaderNames[key]; ok {
+				key = long
+			}
+			h[key] = append(h[key], strings.TrimSpace(value))
+		}
+		name, value = "", ""
+	}
+	for {
+		line, err := readLine()
+		if err == io.EOF && line == "" {
+			//the message ends with its headers
+			break
+		} else if err != nil && line == "" {
+			return nil, "", err
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == "" {
+			break
+		}
+		if name != "" && (trimmed[0] == ' ' || trimmed[0] == '\t') {
+			value += " " + strings.TrimSpace(trimmed)
+			continue
+		}
+		colon := strings.IndexByte(trimmed, ':')
+		if colon <= 0 || !isToken(strings.TrimRight(trimmed[:colon], " \t")) {
+			if !tolerant {
+				return nil, "", core.NewParseError(trimmed, 0, "header", "malformed header line")
+			}
+			//not a header, the body started without the empty line
+			warnings = append(warnings, "missing empty line before the body")
+			bodyStart = line
+			break
+		}
+		flush()
+		name, value = strings.TrimRight(trimmed[:colon], " \t"), trimmed[colon+1:]
+	}
+	flush()
+	msg.SetHeader(h)
+
+	if tolerant {
+		warnings = append(warnings, repairAddressHeaders(h)...)
+		for _, warning := range warnings {
+			addParseWarning(msg, warning)
+		}
+	}
+	return msg, bodyStart, nil
+}
+
+// parseStartLine returns the request or response that the start line s
+// begins.
+func parseStartLine(s string) (msg Message, err error) {
+	s1 := strings.Index(s, " ")
+	s2 := strings.Index(s[s1+1:], " ")
+	if s1 < 0 || s2 < 0 {
+		return nil, core.NewParseError(s, len(s), "SP", "malformed SIP start line")
+	}
+	s2 += s1 + 1
+
+	if strings.TrimSpace(s[:s1]) == "SIP/2.0" {
+		//Status-Code is 3DIGIT, RFC 3261 §25.1
+		var statusCode int
+		if statusCode, err = strconv.Atoi(s[s1+1 : s2]); err != nil || s2-s1 != 4 || statusCode < 100 {
+			return nil, core.NewParseError(s, s1+1, "Status-Code", "malformed SIP status code")
+		}
+		sipVersion, reasonPhrase := s[:s1], s[s2+1:]
+		if _, _, ok := ParseSIPVersion(sipVersion); !ok {
+			return nil, core.NewParseError(s, 0, "SIP/2.0", "malformed SIP version")
+		}
+		return NewResponse(statusCode, reasonPhrase, nil), nil
+	}
+	method, requestURI, sipVersion := s[:s1], s[s1+1:s2], s[s2+1:]
+	if !isToken(method) {
+		return nil, core.NewParseError(s, 0, "Method", "malformed SIP method")
+	}
+	if !isAbsoluteURI(requestURI) {
+		return nil, core.NewParseError(s, s1+1, "Request-URI", "malformed Request-URI")
+	}
+	if _, _, ok := ParseSIPVersion(sipVersion); !ok {
+		return nil, core.NewParseError(s, s2+1, "SIP/2.0", "malformed SIP version")
+	}
+	req := NewRequest(method, requestURI, nil)
+	//a version we don't speak is answered with 505 by the validator
+	req.sipVersion = sipVersion
+	return req, nil
+}
+
+// isAbsoluteURI reports whether uri is a scheme followed by a colon and
+// characters a URI may hold, RFC 3261 §25.1. The validator parses the SIP
+// URIs further.
+func isAbsoluteURI(uri string) bool {
+	colon := strings.IndexByte(uri, ':')
+	if colon <= 0 || colon == len(uri)-1 {
+		return false
+	}
+	for i := 0; i < colon; i++ {
+		c := uri[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.')) {
+			return false
+		}
+	}
+	return !strings.ContainsAny(uri[colon+1:], " \t<>\"{}|\\^`")
+}
+
+//compactHeaderNames maps the RFC 3261 §7.3.3 compact forms onto the
+//canonical key of the long form.
+var compactHeaderNames = map[string]string{
+	"I": "Call-Id",
+	"M": "Contact",
+	"E": "Content-Encoding",
+	"L": "Content-Length",
+	"C": "Content-Type",
+	"F": "From",
+	"S": "Subject",
+	"K": "Supported",
+	"T": "To",
+	"V": "Via",
+	"O": "Event",
+	"U": "Allow-Events",
+	"R": "Refer-To",
+	"B": "Referred-By",
+	"A": "Accept-Contact",
+	"J": "Reject-Contact",
+	"D": "Request-Disposition",
+}
+
+//headerValues returns every raw value of the named header, including the
+//ones set in compact form or received under a registered alias. The compact
+//forms received are read under their long form already, in order.
+func headerValues(msg Message, name string) []string {
+	h := msg.GetHeader()
+	key := CanonicalHeaderKey(name)
+	values := h[key]
+	for compact, long := range compactHeaderNames {
+		if long == key {
+			values = append(values, h[compact]...)
+		}
+	}
+	for _, alias := range parser.GetHeaderAliases(name) {
+		if k := CanonicalHeaderKey(alias); k != key && compactHeaderNames[k] != key {
+			values = append(values, h[k]...)
+		}
+	}
+	return values
+}
+
+//removeHeader deletes every value of the named header from msg, including
+//the ones in compact form or under a registered alias.
+func removeHeader(msg Message, name string) {
+	h := msg.GetHeader()
+	key := CanonicalHeaderKey(name)
+	delete(h, key)
+	for compact, long := range compactHeaderNames {
+		if long == key {
+			delete(h, compact)
+		}
+	}
+	for _, alias := range parser.GetHeaderAliases(name) {
+		delete(h, CanonicalHeaderKey(alias))
+	}
+}
 
-func newTextprotoReader(br *bufio.Reader) *textproto.Reader {
-	if v := textprotoReaderPool.Get(); v != nil {
-		tr := v.(*textproto.Reader)
-		tr.R = br
-		return tr
+//parseHeaders runs the typed parser of the named header over each of its
+//values in msg.
+func parseHeaders(msg Message, name string) ([]header.Header, error) {
+	values := headerValues(msg, name)
+	headers := make([]header.Header, 0, len(values))
+	for _, v := range values {
+		h, err := parseHeaderValue(name, v)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
 	}
-	return textproto.NewReader(br)
+	return headers, nil
 }
 
-func putTextprotoReader(r *textproto.Reader) {
-	r.R = nil
-	textprotoReaderPool.Put(r)
+//parseHeaderValue runs the typed parser of the named header over value.
+func parseHeaderValue(name, value string) (header.Header, error) {
+	p, err := parser.CreateParser(name + ": " + strings.TrimSpace(value) + "\n")
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse()
+}
+
+//parseHeader returns the first typed value of the named header, or nil if
+//msg does not carry it.
+func parseHeader(msg Message, name string) (header.Header, error) {
+	headers, err := parseHeaders(msg, name)
+	if err != nil || len(headers) == 0 {
+		return nil, err
+	}
+	return headers[0], nil
 }
 
 // ParseSIPVersion parses a SIP version string.
@@ -0,0 +1,127 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"sip/address"
+	"sip/header"
+)
+
+// BRANCH_MAGIC_COOKIE starts the branch of every Via inserted by an
+// RFC 3261 compliant element, RFC 3261 §8.1.1.7.
+const BRANCH_MAGIC_COOKIE = "z9hG4bK"
+
+// CreateRequest builds a request outside of any dialog, RFC 3261 §8.1.1.
+// The request gets a new Call-ID, a From tag and a Via branch when via has
+// none, CSeq 1 and Max-Forwards 70. via may be nil when the transport adds
+// the Via itself. Requests within a dialog are built by
+// Dialog.CreateRequest, CANCELs by ClientTransaction.CreateCancel.
+func CreateRequest(method string, requestURI address.URI, from, to address.Address, via *header.Via) (Request, error) {
+	if method == ACK || method == CANCEL {
+		return nil, errors.New("CreateRequest can't create " + method)
+	}
+	if requestURI == nil || from == nil || to == nil {
+		return nil, errors.New("a request needs a Request-URI, a From and a To")
+	}
+	req := NewRequest(method, requestURI.String(), nil)
+	h := req.GetHeader()
+
+	host := ""
+	if via != nil {
+		if via.GetBranch() == "" {
+			via.SetBranch(generateBranch())
+		}
+		host = via.GetHost()
+		h.Set("Via", via.EncodeBody())
+	}
+	fromHeader := header.NewFrom()
+	fromHeader.SetAddress(from)
+	fromHeader.SetTag(generateTag())
+	toHeader := header.NewTo()
+	toHeader.SetAddress(to)
+	h.Set("From", fromHeader.EncodeBody())
+	h.Set("To", toHeader.EncodeBody())
+	h.Set("Call-ID", generateCallId(host))
+	h.Set("CSeq", header.NewCSeq(1, method).EncodeBody())
+	h.Set("Max-Forwards", "70")
+	req.SetContentLength(0)
+	return req, nil
+}
+
+// CreateResponse builds a response to req, RFC 3261 §8.2.6. The Via, From,
+// Call-ID and CSeq are copied from the request, and the To as well with a
+// tag added to anything but 100 Trying. The Timestamp of the request is
+// mirrored, RFC 3261 §8.2.6.1.
+func CreateResponse(statusCode int, req Request) (Response, error) {
+	if statusCode < 100 || statusCode > 699 {
+		return nil, errors.New("invalid status code")
+	}
+	if req.GetMethod() == ACK {
+		return nil, errors.New("an ACK is never answered")
+	}
+	for _, name := range []string{"Via", "From", "To", "Call-ID", "CSeq"} {
+		if len(headerValues(req, name)) == 0 {
+			return nil, errors.New("the request has no " + name + " header")
+		}
+	}
+	if _, err := parseHeader(req, "To"); err != nil {
+		return nil, err
+	}
+	return newResponseFor(req, statusCode), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// nextRequest returns a request sent again outside of any dialog after
+// previous was refused, e.g. for a Min-Expires, RFC 3261 §8.1.3.5: the same
+// headers and body with the next CSeq and a new branch in the topmost Via.
+func nextRequest(previous Request) (Request, error) {
+	method := previous.GetMethod()
+	req := NewRequest(method, previous.GetRequestURI(), nil)
+	h := req.GetHeader()
+	for key, values := range previous.GetHeader() {
+		h[key] = append([]string(nil), values...)
+	}
+	h.Set("CSeq", header.NewCSeq(cSeqOf(previous)+1, method).EncodeBody())
+	body, err := rawBodyBytes(previous)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		req.SetBody(bytes.NewReader(body))
+	}
+	req.SetContentLength(int64(len(body)))
+
+	vias := headerListEntries(previous, "Via")
+	if len(vias) == 0 {
+		return req, nil
+	}
+	v, err := parseHeaderValue("Via", vias[0])
+	if err != nil {
+		return nil, err
+	}
+	via := v.(header.Lister).Front().Value.(*header.Via)
+	via.SetBranch(generateBranch())
+	vias[0] = via.EncodeBody()
+	removeHeader(req, "Via")
+	for _, value := range vias {
+		h.Add("Via", value)
+	}
+	return req, nil
+}
+
+// generateBranch returns a new Via branch, unique across space and time
+// as RFC 3261 §8.1.1.7 asks.
+func generateBranch() string {
+	return BRANCH_MAGIC_COOKIE + randomHex(8)
+}
+
+// generateCallId returns a new Call-ID. The 128 random bits make it
+// globally unique on their own, the host only helps reading traces,
+// RFC 3261 §8.1.1.4.
+func generateCallId(host string) string {
+	if host == "" {
+		return randomHex(16)
+	}
+	return randomHex(16) + "@" + host
+}
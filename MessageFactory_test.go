@@ -0,0 +1,90 @@
+package sip
+
+import (
+	"bytes"
+	"sip/header"
+	"strings"
+	"testing"
+)
+
+func TestCreateRequest(t *testing.T) {
+	h, _ := parseHeaderValue("Via", "SIP/2.0/UDP pc33.atlanta.com")
+	via := h.(*header.ViaList).Front().Value.(*header.Via)
+	h, _ = parseHeaderValue("From", "Alice <sip:alice@atlanta.com>")
+	from := h.(*header.From).GetAddress()
+	h, _ = parseHeaderValue("To", "Bob <sip:bob@biloxi.com>")
+	to := h.(*header.To).GetAddress()
+	uri, _ := parseURI("sip:bob@biloxi.com")
+
+	req, err := CreateRequest(OPTIONS, uri, from, to, via)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.GetMethod() != OPTIONS || req.GetRequestURI() != "sip:bob@biloxi.com" {
+		t.Fatal(req.GetMethod(), req.GetRequestURI())
+	}
+	if !strings.HasPrefix(branchOf(req), BRANCH_MAGIC_COOKIE) {
+		t.Log(req.GetHeader().Get("Via"))
+		t.Fail()
+	}
+	f, to2, callId, cSeq, err := dialogHeadersOf(req)
+	if err != nil || !f.HasTag() || to2.HasTag() || cSeq != 1 || !strings.HasSuffix(callId, "@pc33.atlanta.com") {
+		t.Log(req.GetHeader(), err)
+		t.Fail()
+	}
+
+	//the request goes on the wire and reads back
+	var b bytes.Buffer
+	if err := req.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	readTestMessage(t, b.String())
+
+	other, _ := CreateRequest(OPTIONS, uri, from, to, nil)
+	if other.GetHeader().Get("Call-ID") == callId || other.GetHeader().Get("Via") != "" {
+		t.Log(other.GetHeader())
+		t.Fail()
+	}
+	if _, err := CreateRequest(ACK, uri, from, to, nil); err == nil {
+		t.Fail()
+	}
+}
+
+func TestCreateResponse(t *testing.T) {
+	invite := testInvite(t, false)
+	invite.GetHeader().Set("Timestamp", "54")
+
+	trying, err := CreateResponse(TRYING, invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(trying.GetHeader().Get("To"), "tag=") || trying.GetHeader().Get("Timestamp") != "54" {
+		t.Log(trying.GetHeader())
+		t.Fail()
+	}
+	ringing, _ := CreateResponse(RINGING, invite)
+	_, to, callId, cSeq, err := dialogHeadersOf(ringing)
+	if err != nil || !to.HasTag() || callId != "a84b4c76e66710@pc33.atlanta.com" || cSeq != 314159 ||
+		ringing.GetReasonPhrase() != "Ringing" || branchOf(ringing) != "z9hG4bK776asdhds" {
+		t.Log(ringing.GetHeader(), err)
+		t.Fail()
+	}
+
+	if _, err := CreateResponse(700, invite); err == nil {
+		t.Fail()
+	}
+	invite.GetHeader().Del("Call-ID")
+	if _, err := CreateResponse(OK, invite); err == nil {
+		t.Fail()
+	}
+}
+
+func TestGetNewCallId(t *testing.T) {
+	p := newProvider(nil)
+	p.AddTransport(newTransport(TCP, "192.0.2.1", 5060, nil))
+	a, b := p.GetNewCallId(), p.GetNewCallId()
+	if a == b || !strings.HasSuffix(a, "@192.0.2.1") || len(a) != 32+len("@192.0.2.1") {
+		t.Log(a, b)
+		t.Fail()
+	}
+}
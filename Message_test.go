@@ -3,6 +3,8 @@ package sip
 import (
 	"bufio"
 	"bytes"
+	"sip/core"
+	"sip/parser"
 	"strings"
 	"testing"
 )
@@ -117,3 +119,58 @@ func TestReadMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestReadMessageParseError(t *testing.T) {
+	for _, tv := range []struct {
+		msg    string
+		name   string
+		offset int
+	}{
+		{"INVITE sip:bob@biloxi.com SIP/two\r\n\r\n", "", 26},
+		{"SIP/2.0 2OO OK\r\n\r\n", "", 8},
+		{"INVITE\r\n\r\n", "", 6},
+		{"SIP/2.0 200 OK\r\nContent-Length: x1\r\n\r\n", "Content-Length", 16},
+	} {
+		_, err := ReadMessage(bufio.NewReader(strings.NewReader(tv.msg)))
+		pe, ok := err.(*core.ParseError)
+		if !ok || pe.HeaderName != tv.name || pe.Offset != tv.offset {
+			t.Errorf("%q: %#v", tv.msg, err)
+		}
+	}
+}
+
+func TestHeaderAliases(t *testing.T) {
+	parser.RegisterHeaderParser("P-Served-User", "x", func(line string) parser.Parser {
+		return parser.NewHeaderParser(line)
+	})
+	defer parser.UnregisterHeaderParser("P-Served-User")
+	parser.RegisterHeaderAlias("X-Served-User", "P-Served-User")
+
+	msg := readTestMessage(t, "OPTIONS sip:bob@biloxi.com SIP/2.0\r\n"+
+		"P-Served-User: <sip:user1@example.com>;sescase=orig\r\n"+
+		"x: <sip:user2@example.com>;sescase=orig\r\n"+
+		"X-Served-User: <sip:user3@example.com>;sescase=orig\r\n"+
+		"Content-Length: 0\r\n\r\n")
+	values := headerValues(msg, "P-Served-User")
+	if len(values) != 3 || values[0] != "<sip:user1@example.com>;sescase=orig" {
+		t.Fatal(values)
+	}
+	headers, err := parseHeaders(msg, "P-Served-User")
+	if err != nil || len(headers) != 3 || headers[2].GetValue() != "<sip:user3@example.com>;sescase=orig" {
+		t.Log(headers, err)
+		t.Fail()
+	}
+}
+
+func TestCompactHeaderOrder(t *testing.T) {
+	msg := readTestMessage(t, "OPTIONS sip:bob@biloxi.com SIP/2.0\r\n"+
+		"v: SIP/2.0/UDP proxy.biloxi.com;branch=z9hG4bKproxy\r\n"+
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bKua\r\n"+
+		"Content-Length: 0\r\n\r\n")
+	if vias := headerValues(msg, "Via"); len(vias) != 2 || !strings.HasSuffix(vias[0], "z9hG4bKproxy") {
+		t.Error(vias)
+	}
+	if branch := branchOf(msg); branch != "z9hG4bKproxy" {
+		t.Error("the topmost Via is not the compact one:", branch)
+	}
+}
@@ -0,0 +1,264 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"sip/header"
+	"sort"
+	"strings"
+)
+
+// The multipart subtypes used by SIP, RFC 5621.
+const (
+	MULTIPART_MIXED       = "mixed"
+	MULTIPART_ALTERNATIVE = "alternative"
+	MULTIPART_RELATED     = "related"
+)
+
+var ErrNotMultipart = errors.New("the body is not a multipart body")
+
+// BodyPart is a part of a multipart body, with its own Content-Type and,
+// optionally, Content-Disposition and Content-ID, RFC 5621 §3.
+type BodyPart struct {
+	Header Header
+	Body   []byte
+}
+
+// NewBodyPart returns a part of the given content type.
+func NewBodyPart(contentType string, body []byte) *BodyPart {
+	h := make(Header)
+	h.Set("Content-Type", contentType)
+	return &BodyPart{Header: h, Body: body}
+}
+
+func (this *BodyPart) GetContentType() string {
+	return this.Header.Get("Content-Type")
+}
+
+func (this *BodyPart) GetContentDisposition() string {
+	return this.Header.Get("Content-Disposition")
+}
+
+// GetContentId returns the Content-ID of the part without its angle
+// brackets, the value a cid: URL refers to, RFC 2392.
+func (this *BodyPart) GetContentId() string {
+	return strings.Trim(this.Header.Get("Content-Id"), "<>")
+}
+
+func (this *BodyPart) SetContentDisposition(disposition string) {
+	this.Header.Set("Content-Disposition", disposition)
+}
+
+func (this *BodyPart) SetContentId(id string) {
+	this.Header.Set("Content-Id", "<"+strings.Trim(id, "<>")+">")
+}
+
+// IsMultipart reports whether the part is itself a multipart body, which
+// ParseMultipartBody decodes.
+func (this *BodyPart) IsMultipart() bool {
+	return isMultipart(this.GetContentType())
+}
+
+// MultipartBody is a multipart/mixed, multipart/alternative or
+// multipart/related body, RFC 2046 §5.1 and RFC 5621.
+type MultipartBody struct {
+	Subtype  string
+	Boundary string
+	//the parameters of the Content-Type other than boundary, such as the
+	//type of the root part of multipart/related, RFC 2387
+	Parameters map[string]string
+	Parts      []*BodyPart
+}
+
+// NewMultipartBody returns an empty multipart body of the given subtype
+// with a generated boundary.
+func NewMultipartBody(subtype string) *MultipartBody {
+	return &MultipartBody{Subtype: subtype, Boundary: generateBoundary()}
+}
+
+func (this *MultipartBody) AddPart(part *BodyPart) {
+	this.Parts = append(this.Parts, part)
+}
+
+// GetPart returns the first part of the given content type, compared
+// without its parameters, or nil.
+func (this *MultipartBody) GetPart(contentType string) *BodyPart {
+	for _, p := range this.Parts {
+		if mediaTypeOf(p.GetContentType()) == strings.ToLower(contentType) {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetPartById returns the part with the given Content-ID, or nil.
+func (this *MultipartBody) GetPartById(id string) *BodyPart {
+	id = strings.Trim(id, "<>")
+	for _, p := range this.Parts {
+		if p.GetContentId() == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetContentType returns the Content-Type of the body, boundary included.
+func (this *MultipartBody) GetContentType() string {
+	contentType := header.NewContentTypeFromString("multipart", this.Subtype)
+	contentType.SetParameter("boundary", this.Boundary)
+	for _, name := range sortedKeys(this.Parameters) {
+		value := this.Parameters[name]
+		if strings.ContainsAny(value, "/;,\" ") {
+			contentType.SetQuotedParameter(name, value)
+		} else {
+			contentType.SetParameter(name, value)
+		}
+	}
+	return contentType.EncodeBody()
+}
+
+// Marshal encodes the body. A new boundary is generated when the current
+// one appears in a part.
+func (this *MultipartBody) Marshal() ([]byte, error) {
+	if len(this.Parts) == 0 {
+		return nil, errors.New("a multipart body has at least one part")
+	}
+	for this.Boundary == "" || this.collides() {
+		this.Boundary = generateBoundary()
+	}
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	if err := w.SetBoundary(this.Boundary); err != nil {
+		return nil, err
+	}
+	for _, p := range this.Parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader(p.Header))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(p.Body); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (this *MultipartBody) collides() bool {
+	delimiter := []byte("--" + this.Boundary)
+	for _, p := range this.Parts {
+		if bytes.Contains(p.Body, delimiter) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMultipartBody decodes a body of the given multipart Content-Type.
+func ParseMultipartBody(contentType string, body []byte) (*MultipartBody, error) {
+	h, err := parseHeaderValue("Content-Type", contentType)
+	if err != nil {
+		return nil, err
+	}
+	ct, ok := h.(*header.ContentType)
+	if !ok || !strings.EqualFold(ct.GetContentType(), "multipart") {
+		return nil, ErrNotMultipart
+	}
+	m := &MultipartBody{Subtype: strings.ToLower(ct.GetContentSubType())}
+	for e := ct.GetParameterNames().Front(); e != nil; e = e.Next() {
+		name := e.Value.(string)
+		value := strings.Trim(ct.GetParameter(name), "\"")
+		if strings.EqualFold(name, "boundary") {
+			m.Boundary = value
+			continue
+		}
+		if m.Parameters == nil {
+			m.Parameters = make(map[string]string)
+		}
+		m.Parameters[strings.ToLower(name)] = value
+	}
+	if m.Boundary == "" {
+		return nil, errors.New("the multipart Content-Type has no boundary")
+	}
+
+	r := multipart.NewReader(bytes.NewReader(body), m.Boundary)
+	for {
+		p, err := r.NextRawPart()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.New("malformed multipart body: " + err.Error())
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, errors.New("malformed multipart body: " + err.Error())
+		}
+		m.Parts = append(m.Parts, &BodyPart{Header: Header(p.Header), Body: b})
+	}
+	if len(m.Parts) == 0 {
+		return nil, errors.New("the multipart body has no part")
+	}
+	return m, nil
+}
+
+// GetMultipartBody decodes the multipart body of msg.
+func GetMultipartBody(msg Message) (*MultipartBody, error) {
+	contentType := msg.GetHeader().Get("Content-Type")
+	if contentType == "" {
+		contentType = msg.GetHeader().Get("C")
+	}
+	if !isMultipart(contentType) {
+		return nil, ErrNotMultipart
+	}
+	body, err := bodyBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMultipartBody(contentType, body)
+}
+
+// SetMultipartBody makes m the body of msg, with its Content-Type and
+// Content-Length.
+func SetMultipartBody(msg Message, m *MultipartBody) error {
+	body, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+	msg.SetBody(bytes.NewReader(body))
+	msg.SetContentLength(int64(len(body)))
+	msg.GetHeader().Del("C")
+	msg.GetHeader().Set("Content-Type", m.GetContentType())
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// generateBoundary returns a boundary unlikely to appear in any part.
+func generateBoundary() string {
+	return "boundary" + randomHex(12)
+}
+
+func isMultipart(contentType string) bool {
+	return strings.HasPrefix(mediaTypeOf(contentType), "multipart/")
+}
+
+// mediaTypeOf returns the type/subtype of a Content-Type, in lower case.
+func mediaTypeOf(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
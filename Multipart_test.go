@@ -0,0 +1,85 @@
+package sip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultipartBody(t *testing.T) {
+	invite := testInvite(t, false)
+	m := NewMultipartBody(MULTIPART_MIXED)
+	m.AddPart(NewBodyPart("application/sdp", []byte(testSDP)))
+	pidf := NewBodyPart("application/pidf+xml", []byte("<presence/>\r\n"))
+	pidf.SetContentId("target123@atlanta.example.com")
+	pidf.SetContentDisposition("by-reference;handling=optional")
+	m.AddPart(pidf)
+	if err := SetMultipartBody(invite, m); err != nil {
+		t.Fatal(err)
+	}
+
+	contentType := invite.GetHeader().Get("Content-Type")
+	if !strings.HasPrefix(contentType, "multipart/mixed;boundary=") {
+		t.Fatal(contentType)
+	}
+	body, _ := bodyBytes(invite)
+	if invite.GetContentLength() != int64(len(body)) {
+		t.Log(invite.GetContentLength(), len(body))
+		t.Fail()
+	}
+
+	parsed, err := GetMultipartBody(invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Subtype != MULTIPART_MIXED || parsed.Boundary != m.Boundary || len(parsed.Parts) != 2 {
+		t.Fatal(parsed)
+	}
+	if p := parsed.GetPart("application/sdp"); p == nil || string(p.Body) != testSDP {
+		t.Log(p)
+		t.Fail()
+	}
+	p := parsed.GetPartById("<target123@atlanta.example.com>")
+	if p == nil || p.GetContentDisposition() != "by-reference;handling=optional" || string(p.Body) != "<presence/>\r\n" {
+		t.Log(p)
+		t.Fail()
+	}
+
+	if _, err := GetMultipartBody(testInvite(t, true)); err != ErrNotMultipart {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestParseMultipartBody(t *testing.T) {
+	body := "--boundary1\r\n" +
+		"Content-Type: application/sdp\r\n\r\n" +
+		"v=0\r\n" +
+		"--boundary1\r\n" +
+		"Content-Type: application/isup;version=itu-t92+\r\n" +
+		"Content-Disposition: signal;handling=optional\r\n\r\n" +
+		"\x01\x00\x49\r\n" +
+		"--boundary1--\r\n"
+	m, err := ParseMultipartBody(`multipart/related;type="application/sdp";boundary=boundary1`, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subtype != MULTIPART_RELATED || m.Parameters["type"] != "application/sdp" || len(m.Parts) != 2 {
+		t.Fatal(m)
+	}
+	if p := m.GetPart("Application/ISUP"); p == nil || string(p.Body) != "\x01\x00\x49" {
+		t.Log(p)
+		t.Fail()
+	}
+	if ct := m.GetContentType(); !strings.Contains(ct, `type="application/sdp"`) {
+		t.Log(ct)
+		t.Fail()
+	}
+
+	if _, err := ParseMultipartBody("multipart/mixed", []byte(body)); err == nil {
+		t.Fail()
+	}
+	if _, err := ParseMultipartBody("application/sdp", []byte(body)); err != ErrNotMultipart {
+		t.Log(err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,406 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"sip/header"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+////////////////////Interface//////////////////////////////
+
+// EventPackage is implemented by the application for each event package it
+// serves as a notifier, RFC 6665 §7.
+type EventPackage interface {
+	// GetEventType returns the name of the package, e.g. "presence".
+	GetEventType() string
+	// GetDefaultExpires returns the duration, in seconds, of the
+	// subscriptions that don't ask for one.
+	GetDefaultExpires() int
+	// Authorize decides the state of a new subscription: active, pending
+	// while the decision is deferred, or terminated to refuse it with 403.
+	Authorize(sub Subscription, subscribe Request) SubscriptionState
+	// GetContentType returns the Content-Type of the NOTIFY bodies.
+	GetContentType() string
+	// GetNotifyBody returns the body of the next NOTIFY sent for sub, the
+	// initial one as well as the subsequent ones. A nil body sends a NOTIFY
+	// without body.
+	GetNotifyBody(sub Subscription) ([]byte, error)
+}
+
+// Notifier accepts the SUBSCRIBE requests of the event packages added to it
+// and sends their NOTIFY requests, RFC 6665 §4.2. The application hands it
+// the SUBSCRIBE requests it receives and tells it when the state of a
+// subscription changes.
+type Notifier interface {
+	AddEventPackage(pkg EventPackage)
+	RemoveEventPackage(eventType string)
+	GetAllowEvents() []string
+
+	// SetMinExpires sets the shortest subscription accepted, shorter ones
+	// are refused with 423 Interval Too Brief.
+	SetMinExpires(seconds int)
+	// SetContact sets the Contact of the responses that create a dialog. It
+	// defaults to the Request-URI of the SUBSCRIBE.
+	SetContact(contact string)
+
+	// ProcessSubscribe answers a SUBSCRIBE, creating or refreshing the
+	// subscription it asks for, and sends the NOTIFY that follows it.
+	ProcessSubscribe(requestEvent RequestEvent) (Subscription, error)
+	// ProcessRefer accepts a REFER with 202 Accepted and creates its implicit
+	// subscription, RFC 3515, whose first NOTIFY reports 100 Trying. No
+	// subscription is created, and nil is returned, when the REFER carries
+	// "Refer-Sub: false", RFC 4488. The application then sends the referred
+	// request, see NewReferredRequest, and reports its progress with
+	// NotifyReferStatus.
+	ProcessRefer(requestEvent RequestEvent) (Subscription, error)
+	// NotifyReferStatus reports the status code of the referred request in a
+	// message/sipfrag NOTIFY. A final status terminates the subscription.
+	NotifyReferStatus(sub Subscription, statusCode int) error
+	// Notify sends the current state of sub.
+	Notify(sub Subscription) error
+	// SetState moves sub to a new state and notifies the subscriber. reason
+	// only applies to the terminated state.
+	SetState(sub Subscription, state SubscriptionState, reason string) error
+	GetSubscriptions(eventType string) []Subscription
+}
+
+// DEFAULT_MIN_EXPIRES is the shortest subscription a notifier accepts unless
+// told otherwise.
+const DEFAULT_MIN_EXPIRES = 60
+
+var ErrBadEvent = errors.New("unknown or missing event package")
+
+////////////////////Implementation////////////////////////
+
+type notifier struct {
+	mutex    sync.Mutex
+	provider Provider
+
+	packages      map[string]EventPackage
+	subscriptions []*subscription
+	minExpires    int
+	contact       string
+}
+
+func NewNotifier(p Provider) Notifier {
+	return &notifier{
+		provider:   p,
+		packages:   make(map[string]EventPackage),
+		minExpires: DEFAULT_MIN_EXPIRES,
+	}
+}
+
+func (this *notifier) AddEventPackage(pkg EventPackage) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.packages[pkg.GetEventType()] = pkg
+}
+
+func (this *notifier) RemoveEventPackage(eventType string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.packages, eventType)
+}
+
+// GetAllowEvents returns the event packages served, as advertised in the
+// Allow-Events header.
+func (this *notifier) GetAllowEvents() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	events := make([]string, 0, len(this.packages))
+	for eventType := range this.packages {
+		events = append(events, eventType)
+	}
+	sort.Strings(events)
+	return events
+}
+
+func (this *notifier) SetMinExpires(seconds int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.minExpires = seconds
+}
+
+func (this *notifier) SetContact(contact string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.contact = contact
+}
+
+func (this *notifier) ProcessSubscribe(requestEvent RequestEvent) (Subscription, error) {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != SUBSCRIBE {
+		return nil, errors.New("Notifier.ProcessSubscribe can't process " + req.GetMethod())
+	}
+
+	this.mutex.Lock()
+	event := eventOf(req)
+	var pkg EventPackage
+	if event != nil {
+		pkg = this.packages[event.GetEventType()]
+	}
+	minExpires := this.minExpires
+	contact := this.contact
+	this.mutex.Unlock()
+
+	if pkg == nil {
+		resp := newResponseFor(req, BAD_EVENT)
+		resp.GetHeader().Set("Allow-Events", strings.Join(this.GetAllowEvents(), ", "))
+		st.SendResponse(resp)
+		return nil, ErrBadEvent
+	}
+	expires := expiresOf(req)
+	if expires < 0 {
+		expires = pkg.GetDefaultExpires()
+	}
+	if expires > 0 && expires < minExpires {
+		resp := newResponseFor(req, INTERVAL_TOO_BRIEF)
+		resp.GetHeader().Set("Min-Expires", strconv.Itoa(minExpires))
+		return nil, st.SendResponse(resp)
+	}
+
+	d, _ := st.GetDialog().(*dialog)
+	created := d == nil
+	var sub *subscription
+	if d != nil {
+		sub = this.find(d, event)
+	}
+	if sub == nil {
+		//a new subscription, in a new dialog or in an existing one
+		if created {
+			nd, err := this.provider.GetNewDialog(st)
+			if err != nil {
+				st.SendResponse(newResponseFor(req, BAD_REQUEST))
+				return nil, err
+			}
+			d = nd.(*dialog)
+		}
+		sub = newSubscription(d, event, true)
+		state := pkg.Authorize(sub, req)
+		if state == SUBSCRIPTIONSTATE_TERMINATED {
+			sub.terminate(SUBSCRIPTION_REASON_REJECTED)
+			err := st.SendResponse(newResponseFor(req, FORBIDDEN))
+			if created {
+				d.Close()
+			}
+			return sub, err
+		}
+		sub.state = state
+		this.mutex.Lock()
+		this.subscriptions = append(this.subscriptions, sub)
+		this.mutex.Unlock()
+	}
+
+	resp := newResponseFor(req, OK)
+	resp.GetHeader().Set("Expires", strconv.Itoa(expires))
+	if created {
+		if contact == "" {
+			contact = "<" + req.GetRequestURI() + ">"
+		}
+		resp.GetHeader().Set("Contact", contact)
+	}
+	if err := st.SendResponse(resp); err != nil {
+		return sub, err
+	}
+
+	if expires == 0 {
+		//an unsubscription, or a fetch of the current state
+		return sub, this.SetState(sub, SUBSCRIPTIONSTATE_TERMINATED, SUBSCRIPTION_REASON_TIMEOUT)
+	}
+	sub.mutex.Lock()
+	sub.setExpiry(expires, func() {
+		this.SetState(sub, SUBSCRIPTIONSTATE_TERMINATED, SUBSCRIPTION_REASON_TIMEOUT)
+	})
+	sub.mutex.Unlock()
+	return sub, this.Notify(sub)
+}
+
+func (this *notifier) ProcessRefer(requestEvent RequestEvent) (Subscription, error) {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != REFER {
+		return nil, errors.New("Notifier.ProcessRefer can't process " + req.GetMethod())
+	}
+	if _, err := parseHeader(req, "Refer-To"); err != nil || len(headerValues(req, "Refer-To")) != 1 {
+		st.SendResponse(newResponseFor(req, BAD_REQUEST))
+		return nil, errors.New("a REFER carries exactly one valid Refer-To header")
+	}
+
+	this.mutex.Lock()
+	contact := this.contact
+	this.mutex.Unlock()
+
+	d, _ := st.GetDialog().(*dialog)
+	created := d == nil
+	if created {
+		nd, err := this.provider.GetNewDialog(st)
+		if err != nil {
+			st.SendResponse(newResponseFor(req, BAD_REQUEST))
+			return nil, err
+		}
+		d = nd.(*dialog)
+	}
+
+	resp := newResponseFor(req, ACCEPTED)
+	if created {
+		if contact == "" {
+			contact = "<" + req.GetRequestURI() + ">"
+		}
+		resp.GetHeader().Set("Contact", contact)
+	}
+	if noReferSub(req) {
+		resp.GetHeader().Set("Refer-Sub", "false")
+		err := st.SendResponse(resp)
+		if created {
+			d.Close()
+		}
+		return nil, err
+	}
+
+	sub := newSubscription(d, referEvent(req), true)
+	sub.state = SUBSCRIPTIONSTATE_ACTIVE
+	this.mutex.Lock()
+	this.subscriptions = append(this.subscriptions, sub)
+	this.mutex.Unlock()
+	if err := st.SendResponse(resp); err != nil {
+		return sub, err
+	}
+
+	sub.mutex.Lock()
+	sub.setExpiry(REFER_EXPIRES, func() {
+		this.SetState(sub, SUBSCRIPTIONSTATE_TERMINATED, SUBSCRIPTION_REASON_TIMEOUT)
+	})
+	sub.mutex.Unlock()
+	return sub, this.notify(sub, NewSipfrag(TRYING), "message/sipfrag")
+}
+
+func (this *notifier) NotifyReferStatus(sub Subscription, statusCode int) error {
+	s, ok := sub.(*subscription)
+	if !ok || !s.notifier || s.eventType != REFER_EVENT {
+		return errors.New("not a REFER subscription of the notifier")
+	}
+	s.mutex.Lock()
+	if s.state == SUBSCRIPTIONSTATE_TERMINATED {
+		s.mutex.Unlock()
+		return ErrSubscriptionTerminated
+	}
+	final := statusCode >= 200
+	if final {
+		s.terminate(SUBSCRIPTION_REASON_NORESOURCE)
+	}
+	s.mutex.Unlock()
+
+	err := this.notify(s, NewSipfrag(statusCode), "message/sipfrag")
+	if final {
+		this.remove(s)
+	}
+	return err
+}
+
+func (this *notifier) Notify(sub Subscription) error {
+	s, ok := sub.(*subscription)
+	if !ok || !s.notifier {
+		return errors.New("not a subscription of the notifier")
+	}
+	this.mutex.Lock()
+	pkg := this.packages[s.eventType]
+	this.mutex.Unlock()
+
+	var body []byte
+	var contentType string
+	if pkg != nil {
+		var err error
+		if body, err = pkg.GetNotifyBody(s); err != nil {
+			return err
+		}
+		contentType = pkg.GetContentType()
+	}
+	return this.notify(s, body, contentType)
+}
+
+// notify sends a NOTIFY carrying the state of s and body.
+func (this *notifier) notify(s *subscription, body []byte, contentType string) error {
+	s.mutex.Lock()
+	d := s.dialog
+	event := s.eventHeader()
+	state := s.subscriptionStateHeader()
+	s.mutex.Unlock()
+
+	req, err := d.CreateRequest(NOTIFY)
+	if err != nil {
+		return err
+	}
+	h := req.GetHeader()
+	h.Set("Event", event)
+	h.Set("Subscription-State", state)
+	if body != nil {
+		req.SetBody(bytes.NewReader(body))
+		req.SetContentLength(int64(len(body)))
+		h.Set("Content-Type", contentType)
+	}
+	return d.SendRequest(this.provider.GetNewClientTransaction(req))
+}
+
+func (this *notifier) SetState(sub Subscription, state SubscriptionState, reason string) error {
+	s, ok := sub.(*subscription)
+	if !ok || !s.notifier {
+		return errors.New("not a subscription of the notifier")
+	}
+	s.mutex.Lock()
+	switch {
+	case s.state == SUBSCRIPTIONSTATE_TERMINATED:
+		s.mutex.Unlock()
+		return ErrSubscriptionTerminated
+	case state == SUBSCRIPTIONSTATE_TERMINATED:
+		s.terminate(reason)
+	case state == SUBSCRIPTIONSTATE_PENDING || state == SUBSCRIPTIONSTATE_ACTIVE:
+		s.state = state
+	default:
+		s.mutex.Unlock()
+		return errors.New("invalid subscription state " + state.String())
+	}
+	s.mutex.Unlock()
+
+	err := this.Notify(s)
+	if state == SUBSCRIPTIONSTATE_TERMINATED {
+		this.remove(s)
+	}
+	return err
+}
+
+func (this *notifier) GetSubscriptions(eventType string) []Subscription {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var subs []Subscription
+	for _, s := range this.subscriptions {
+		if s.eventType == eventType {
+			subs = append(subs, s)
+		}
+	}
+	return subs
+}
+
+func (this *notifier) find(d *dialog, event *header.Event) *subscription {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, s := range this.subscriptions {
+		if s.dialog == d && s.matches(event) {
+			return s
+		}
+	}
+	return nil
+}
+
+// remove forgets a terminated subscription.
+func (this *notifier) remove(sub *subscription) {
+	this.mutex.Lock()
+	var inUse bool
+	this.subscriptions, inUse = removeSubscription(this.subscriptions, sub)
+	this.mutex.Unlock()
+	releaseDialog(sub.dialog, inUse)
+}
@@ -0,0 +1,185 @@
+package sip
+
+import (
+	"errors"
+	"sip/sdp"
+	"strings"
+)
+
+// OfferAnswerState tracks where a dialog is in the RFC 3264 offer/answer
+// model, as carried by INVITE, UPDATE (RFC 3311) and their responses.
+type OfferAnswerState int
+
+const (
+	OFFERANSWER_NONE         OfferAnswerState = iota //0 no offer outstanding
+	OFFERANSWER_LOCAL_OFFER                          //1 offer sent, waiting for the answer
+	OFFERANSWER_REMOTE_OFFER                         //2 offer received, answer not sent yet
+)
+
+var ErrOfferPending = errors.New("an offer/answer exchange is already in progress")
+var ErrNoInitialAnswer = errors.New("the initial offer/answer exchange has not completed")
+
+// offerAnswer records the outstanding offer of a dialog. method and cSeq
+// identify the transaction that carried it so that the matching answer, or
+// the failure of that transaction, can be recognised.
+type offerAnswer struct {
+	state     OfferAnswerState
+	completed bool //at least one offer/answer exchange has finished
+	method    string
+	cSeq      int
+}
+
+func (this *offerAnswer) offer(state OfferAnswerState, method string, cSeq int) {
+	this.state = state
+	this.method = method
+	this.cSeq = cSeq
+}
+
+func (this *offerAnswer) answer() {
+	this.state = OFFERANSWER_NONE
+	this.completed = true
+}
+
+func (this *offerAnswer) isFor(method string, cSeq int) bool {
+	return this.method == method && this.cSeq == cSeq
+}
+
+// sendingRequest checks that req may leave the dialog and records the offer
+// or answer it carries.
+func (this *offerAnswer) sendingRequest(req Request, cSeq int) error {
+	method := req.GetMethod()
+	if !hasSessionBody(req) {
+		return nil
+	}
+	switch method {
+	case INVITE, UPDATE:
+		if this.state != OFFERANSWER_NONE {
+			return ErrOfferPending
+		}
+		if method == UPDATE && !this.completed {
+			return ErrNoInitialAnswer
+		}
+		this.offer(OFFERANSWER_LOCAL_OFFER, method, cSeq)
+	case ACK, PRACK:
+		if this.state == OFFERANSWER_REMOTE_OFFER {
+			this.answer()
+		}
+	}
+	return nil
+}
+
+// receivedRequest records the offer or answer carried by req. A non zero
+// status code is returned when req collides with an exchange in progress,
+// RFC 3311 §5.2: 491 while our own offer is outstanding, 500 while we still
+// owe an answer.
+func (this *offerAnswer) receivedRequest(req Request, cSeq int) int {
+	method := req.GetMethod()
+	if !hasSessionBody(req) {
+		return 0
+	}
+	switch method {
+	case INVITE, UPDATE:
+		switch this.state {
+		case OFFERANSWER_LOCAL_OFFER:
+			return REQUEST_PENDING
+		case OFFERANSWER_REMOTE_OFFER:
+			return SERVER_INTERNAL_ERROR
+		}
+		this.offer(OFFERANSWER_REMOTE_OFFER, method, cSeq)
+	case ACK, PRACK:
+		if this.state == OFFERANSWER_LOCAL_OFFER {
+			this.answer()
+		}
+	}
+	return 0
+}
+
+// sendingResponse records the answer, or the new offer, carried by resp to
+// req.
+func (this *offerAnswer) sendingResponse(req Request, cSeq int, resp Response) {
+	this.response(req, cSeq, resp, OFFERANSWER_REMOTE_OFFER, OFFERANSWER_LOCAL_OFFER)
+}
+
+// receivedResponse records the answer, or the new offer, carried by resp to
+// req.
+func (this *offerAnswer) receivedResponse(req Request, cSeq int, resp Response) {
+	this.response(req, cSeq, resp, OFFERANSWER_LOCAL_OFFER, OFFERANSWER_REMOTE_OFFER)
+}
+
+// response is shared by both directions: pending is the state in which the
+// offer went out with req, and offered is the state entered when resp
+// carries an offer of its own (an INVITE sent without SDP).
+func (this *offerAnswer) response(req Request, cSeq int, resp Response, pending, offered OfferAnswerState) {
+	method := req.GetMethod()
+	if method != INVITE && method != UPDATE {
+		return
+	}
+	code := resp.GetStatusCode()
+	answering := code >= 200 && code < 300 || isReliableProvisional(resp)
+
+	if this.state == pending && this.isFor(method, cSeq) {
+		if code >= 300 {
+			//the offer was rejected, the previous session stays in place
+			this.state = OFFERANSWER_NONE
+		} else if answering && hasSessionBody(resp) {
+			this.answer()
+		}
+		return
+	}
+	if this.state == OFFERANSWER_NONE && method == INVITE && !hasSessionBody(req) &&
+		answering && hasSessionBody(resp) {
+		this.offer(offered, method, cSeq)
+	}
+}
+
+// hasSessionBody reports whether msg carries a session description, as its
+// body or as the session part of its multipart body, RFC 5621 §3.
+func hasSessionBody(msg Message) bool {
+	if msg.GetContentLength() <= 0 {
+		return false
+	}
+	contentType := strings.ToLower(msg.GetHeader().Get("Content-Type"))
+	if contentType == "" {
+		contentType = strings.ToLower(msg.GetHeader().Get("C"))
+	}
+	if strings.HasPrefix(strings.TrimSpace(contentType), sdp.CONTENT_TYPE) {
+		return true
+	}
+	m, err := GetMultipartBody(msg)
+	return err == nil && sessionPartOf(m) != nil
+}
+
+// sessionPartOf returns the application/sdp part of m, or of a multipart
+// part of m, whose disposition is session, the default of its type, or nil.
+func sessionPartOf(m *MultipartBody) *BodyPart {
+	for _, p := range m.Parts {
+		if p.IsMultipart() {
+			if nested, err := ParseMultipartBody(p.GetContentType(), p.Body); err == nil {
+				if part := sessionPartOf(nested); part != nil {
+					return part
+				}
+			}
+			continue
+		}
+		disposition := mediaTypeOf(p.GetContentDisposition())
+		if mediaTypeOf(p.GetContentType()) == sdp.CONTENT_TYPE && (disposition == "" || disposition == "session") {
+			return p
+		}
+	}
+	return nil
+}
+
+// isReliableProvisional reports whether resp is a 1xx sent reliably,
+// RFC 3262.
+func isReliableProvisional(resp Response) bool {
+	code := resp.GetStatusCode()
+	if code <= 100 || code >= 200 || resp.GetHeader().Get("RSeq") == "" {
+		return false
+	}
+	for _, v := range headerValues(resp, "Require") {
+		if hasToken(v, "100rel") {
+			return true
+		}
+	}
+	return false
+}
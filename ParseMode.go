@@ -0,0 +1,136 @@
+package sip
+
+import (
+	"net/textproto"
+	"strings"
+)
+
+// ParseMode says how strictly received messages are parsed.
+type ParseMode int
+
+const (
+	// PARSEMODE_STRICT rejects a message that doesn't follow RFC 3261.
+	PARSEMODE_STRICT ParseMode = iota
+	// PARSEMODE_TOLERANT repairs the defects common in the field, and lists
+	// them in the parse warnings of the message.
+	PARSEMODE_TOLERANT
+)
+
+func (this ParseMode) String() string {
+	if this == PARSEMODE_TOLERANT {
+		return "tolerant"
+	}
+	return "strict"
+}
+
+// addressHeaders are the headers whose name-addr values are repaired by the
+// tolerant parser.
+var addressHeaders = []string{"From", "To", "Contact", "Reply-To", "Refer-To", "Referred-By", "Route", "Record-Route", "P-Asserted-Identity", "P-Preferred-Identity", "Path", "Service-Route", "History-Info", "Diversion"}
+
+func addParseWarning(msg Message, warning string) {
+	if m, ok := msg.(interface{ addParseWarning(string) }); ok {
+		m.addParseWarning(warning)
+	}
+}
+
+// repairStartLine percent-encodes the spaces within the Request-URI of a
+// request line, RFC 3261 §25.1 doesn't allow them.
+func repairStartLine(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "SIP/") {
+		return s, false
+	}
+	first, last := strings.IndexByte(s, ' '), strings.LastIndexByte(s, ' ')
+	if first < 0 || last <= first {
+		return s, false
+	}
+	requestURI := strings.TrimSpace(s[first+1 : last])
+	if !strings.ContainsAny(requestURI, " \t") {
+		return s, false
+	}
+	requestURI = strings.Replace(strings.Join(strings.Fields(requestURI), " "), " ", "%20", -1)
+	return s[:first] + " " + requestURI + " " + s[last+1:], true
+}
+
+// repairAddressHeaders repairs the address headers of h that don't parse,
+// and returns what was repaired.
+func repairAddressHeaders(h Header) []string {
+	var warnings []string
+	for _, name := range addressHeaders {
+		for _, key := range headerKeys(h, name) {
+			for i, v := range h[key] {
+				if _, err := parseHeaderValue(key, v); err == nil {
+					continue
+				}
+				if repaired, what := repairAddress(v); what != "" {
+					if _, err := parseHeaderValue(key, repaired); err == nil {
+						h[key][i] = repaired
+						warnings = append(warnings, what+" in "+key+" header")
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// headerKeys returns the keys of h holding the named header, its compact
+// form included.
+func headerKeys(h Header, name string) []string {
+	var keys []string
+	key := textproto.CanonicalMIMEHeaderKey(name)
+	if len(h[key]) > 0 {
+		keys = append(keys, key)
+	}
+	for compact, long := range compactHeaderNames {
+		if long == key && len(h[compact]) > 0 {
+			keys = append(keys, compact)
+		}
+	}
+	return keys
+}
+
+// repairAddress quotes an unquoted display name, which may hold commas, and
+// percent-encodes the spaces within the <> of a name-addr. It returns the
+// repaired value and a description of the repair, "" when there is none.
+func repairAddress(v string) (string, string) {
+	lt := strings.IndexByte(v, '<')
+	gt := strings.LastIndexByte(v, '>')
+	if lt < 0 || gt < lt {
+		return v, ""
+	}
+	var what []string
+	displayName := strings.TrimSpace(v[:lt])
+	if displayName != "" && !strings.HasPrefix(displayName, "\"") {
+		displayName = "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(displayName) + "\""
+		what = append(what, "unquoted display name")
+	}
+	uri := v[lt+1 : gt]
+	if strings.ContainsAny(uri, " \t") {
+		uri = strings.Replace(strings.Join(strings.Fields(uri), " "), " ", "%20", -1)
+		what = append(what, "spaces in URI")
+	}
+	if len(what) == 0 {
+		return v, ""
+	}
+	repaired := "<" + uri + ">" + v[gt+1:]
+	if displayName != "" {
+		repaired = displayName + " " + repaired
+	}
+	return repaired, strings.Join(what, " and ")
+}
+
+// isToken reports whether s is a header name, RFC 3261 §25.1.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-.!%*_+`'~", c) >= 0 {
+			continue
+		}
+		return false
+	}
+	return true
+}
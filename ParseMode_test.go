@@ -0,0 +1,103 @@
+package sip
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const brokenInvite = "INVITE sip:bob smith@biloxi.com SIP/2.0\n" +
+	"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\n" +
+	"Max-Forwards: 70\n" +
+	"To: Smith, Bob <sip:bob smith@biloxi.com>\n" +
+	"From: Alice <sip:alice@atlanta.com>;tag=1928301774\n" +
+	"Call-ID: a84b4c76e66710@pc33.atlanta.com\n" +
+	"CSeq: 314159 INVITE\n" +
+	"Content-Type: application/sdp\n" +
+	"v=0\n" +
+	"o=alice 53655765 2353687637 IN IP4 pc33.atlanta.com\n"
+
+func TestReadMessageTolerant(t *testing.T) {
+	if _, err := ReadMessage(bufio.NewReader(strings.NewReader(brokenInvite))); err == nil {
+		t.Fatal("the strict parser accepted a broken message")
+	}
+
+	msg, err := ReadMessageWithMode(bufio.NewReader(strings.NewReader(brokenInvite)), PARSEMODE_TOLERANT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := msg.(Request)
+	if req.GetRequestURI() != "sip:bob%20smith@biloxi.com" {
+		t.Error(req.GetRequestURI())
+	}
+	if to := req.GetHeader().Get("To"); to != "\"Smith, Bob\" <sip:bob%20smith@biloxi.com>" {
+		t.Error(to)
+	}
+	if _, err := parseHeader(req, "To"); err != nil {
+		t.Error(err)
+	}
+	body, _ := ioutil.ReadAll(req.GetBody())
+	if string(body) != "v=0\no=alice 53655765 2353687637 IN IP4 pc33.atlanta.com\n" {
+		t.Errorf("%q", body)
+	}
+
+	warnings := strings.Join(msg.GetParseWarnings(), "\n")
+	for _, expected := range []string{
+		"LF line endings",
+		"spaces in Request-URI",
+		"missing empty line before the body",
+		"missing Content-Length",
+		"unquoted display name and spaces in URI in To header",
+	} {
+		if !strings.Contains(warnings, expected) {
+			t.Errorf("no warning about %s in %q", expected, warnings)
+		}
+	}
+}
+
+func TestReadMessageTolerantCompliant(t *testing.T) {
+	msg, err := ReadMessageWithMode(bufio.NewReader(strings.NewReader(
+		"OPTIONS sip:bob@biloxi.com SIP/2.0\r\n"+
+			"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\r\n"+
+			"To: <sip:bob@biloxi.com>\r\n"+
+			"From: \"Alice\" <sip:alice@atlanta.com>;tag=1928301774\r\n"+
+			"Subject: folded\r\n line\r\n"+
+			"Content-Length: 4\r\n\r\n"+
+			"body")), PARSEMODE_TOLERANT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.GetParseWarnings()) != 0 {
+		t.Error(msg.GetParseWarnings())
+	}
+	if s := msg.GetHeader().Get("Subject"); s != "folded line" {
+		t.Error(s)
+	}
+	if body, _ := ioutil.ReadAll(msg.GetBody()); string(body) != "body" {
+		t.Errorf("%q", body)
+	}
+}
+
+func TestRepairAddressHeaders(t *testing.T) {
+	//the vectors of the parser tests that the strict parser rejects
+	for key, value := range map[string][2]string{
+		"Contact": {"Bo Bob Biggs\n< sip:user@example.com?Route=%3Csip:sip.example.com%3E >",
+			"Contact: \"Bo Bob Biggs\" <sip:user@example.com?Route=%3Csip:sip.example.com%3E>"},
+		"From": {"foobar at com<sip:4855@166.34.120.100 >;tag=1024181795",
+			"From: \"foobar at com\" <sip:4855@166.34.120.100>;tag=1024181795"},
+	} {
+		if _, err := parseHeaderValue(key, value[0]); err == nil {
+			t.Errorf("the strict parser accepted %q", value[0])
+		}
+		h := Header{key: {value[0]}}
+		if warnings := repairAddressHeaders(h); len(warnings) != 1 {
+			t.Error(key, warnings)
+		}
+		if hdr, err := parseHeaderValue(key, h[key][0]); err != nil {
+			t.Error(err)
+		} else if s := strings.TrimSpace(hdr.String()); s != value[1] {
+			t.Error(s)
+		}
+	}
+}
@@ -3,8 +3,13 @@ package sip
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"log"
 	"net"
+	"sip/address"
+	"sip/header"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,28 +25,63 @@ type Provider interface {
 
 	GetNewCallId() string
 
+	//CreateRequest builds a request outside of any dialog like the
+	//CreateRequest function, with the Service-Route learned by the last
+	//registration of from as its preloaded route set, RFC 3608 §6.1.
+	CreateRequest(method string, requestURI address.URI, from, to address.Address, via *header.Via) (Request, error)
+
 	GetNewClientTransaction(Request) ClientTransaction
 	GetNewServerTransaction(Request) ServerTransaction
 
+	GetNewDialog(Transaction) (Dialog, error)
+
+	//GetValidator returns the validator the received messages go through
+	//before they are dispatched.
+	GetValidator() Validator
+
 	SendRequest(Request) error
 	SendResponse(Response) error
 }
 
 ////////////////////Implementation////////////////////////
 
+//transactionTimeout is 64*T1, how long a client transaction waits for its
+//final response and how long a completed transaction absorbs the
+//retransmissions before it is removed, RFC 3261 §17.
+const transactionTimeout = 64 * 500 * time.Millisecond
+
 type provider struct {
-	listeners    map[Listener]Listener
-	transports   map[Transport]Transport
-	transactions map[Transaction]Transaction
+	listeners  map[Listener]Listener
+	transports map[Transport]Transport
+
+	//the transactions by the keys of RFC 3261 §17.1.3 and §17.2.3
+	clientTransactions map[string]*clientTransaction
+	serverTransactions map[string]*serverTransaction
+	transactionMutex   sync.Mutex
+
+	dialogs     map[string]*dialog
+	dialogMutex sync.Mutex
+
+	//the Service-Route of the registrations, by address-of-record
+	serviceRoutes     map[string][]string
+	serviceRouteMutex sync.Mutex
 
 	forward chan Message
-	join    chan Transaction
-	leave   chan Transaction
+	//the events wait in a queue for the listeners, so that the dispatching
+	//of the received messages never waits for a listener
+	events     []interface{}
+	eventMutex sync.Mutex
+	eventReady chan bool
 
 	quit      chan bool
 	waitGroup *sync.WaitGroup
 
-	tracer Tracer
+	tracer    Tracer
+	validator Validator
+
+	//the stack sets the parse mode while the connections are read
+	parseMode      ParseMode
+	parseModeMutex sync.Mutex
 }
 
 func newProvider(tracer Tracer) *provider {
@@ -49,16 +89,19 @@ func newProvider(tracer Tracer) *provider {
 
 	this.listeners = make(map[Listener]Listener)
 	this.transports = make(map[Transport]Transport)
-	this.transactions = make(map[Transaction]Transaction)
+	this.clientTransactions = make(map[string]*clientTransaction)
+	this.serverTransactions = make(map[string]*serverTransaction)
+	this.dialogs = make(map[string]*dialog)
+	this.serviceRoutes = make(map[string][]string)
 
 	this.forward = make(chan Message)
-	this.join = make(chan Transaction)
-	this.leave = make(chan Transaction)
+	this.eventReady = make(chan bool, 1)
 
 	this.quit = make(chan bool)
 	this.waitGroup = &sync.WaitGroup{}
 
 	this.tracer = tracer
+	this.validator = NewValidator()
 
 	return this
 }
@@ -79,21 +122,244 @@ func (this *provider) RemoveListener(l Listener) {
 	delete(this.listeners, l)
 }
 
+//GetNewCallId returns a globally unique Call-ID for a new dialog or
+//registration, RFC 3261 §8.1.1.4.
 func (this *provider) GetNewCallId() string {
-	return ""
+	for t := range this.transports {
+		return generateCallId(t.GetAddress())
+	}
+	return generateCallId("")
+}
+
+func (this *provider) CreateRequest(method string, requestURI address.URI, from, to address.Address, via *header.Via) (Request, error) {
+	req, err := CreateRequest(method, requestURI, from, to, via)
+	if err != nil || method == REGISTER {
+		return req, err
+	}
+	this.serviceRouteMutex.Lock()
+	routes := this.serviceRoutes[addressOfRecord(from.GetURI())]
+	this.serviceRouteMutex.Unlock()
+	return req, PreloadRoute(req, routes...)
+}
+
+//learnServiceRoute keeps the Service-Route of the 2xx response to a
+//REGISTER for the address-of-record registered, until its next
+//registration, RFC 3608 §6.1.
+func (this *provider) learnServiceRoute(register Request, resp Response) {
+	h, err := parseHeader(register, "To")
+	to, ok := h.(*header.To)
+	if err != nil || !ok {
+		return
+	}
+	aor := addressOfRecord(to.GetAddress().GetURI())
+	this.serviceRouteMutex.Lock()
+	defer this.serviceRouteMutex.Unlock()
+	if routes := GetServiceRoute(resp); len(routes) > 0 {
+		this.serviceRoutes[aor] = routes
+	} else {
+		delete(this.serviceRoutes, aor)
+	}
 }
 
 func (this *provider) GetNewClientTransaction(req Request) ClientTransaction {
 	ct := newClientTransaction(req)
-	this.join <- ct
+	ct.provider = this
+	ct.branchId = branchOf(req)
+	if req.GetMethod() != INVITE {
+		ct.SetState(TRANSACTIONSTATE_TRYING)
+	}
+	this.addClientTransaction(ct)
 	return ct
 }
 func (this *provider) GetNewServerTransaction(req Request) ServerTransaction {
+	st := this.newServerTransaction(req)
+	if key := serverTransactionKey(req); key != "" && req.GetMethod() != ACK {
+		this.transactionMutex.Lock()
+		this.serverTransactions[key] = st
+		this.transactionMutex.Unlock()
+	}
+	return st
+}
+
+//newServerTransaction returns the server transaction of a request received,
+//in the state it starts in, RFC 3261 §17.2.
+func (this *provider) newServerTransaction(req Request) *serverTransaction {
 	st := newServerTransaction(req)
-	this.join <- st
+	st.provider = this
+	st.branchId = branchOf(req)
+	if req.GetMethod() == INVITE {
+		st.SetState(TRANSACTIONSTATE_PROCEEDING)
+	} else {
+		st.SetState(TRANSACTIONSTATE_TRYING)
+	}
 	return st
 }
 
+//addClientTransaction indexes ct until it terminates. A transaction that
+//gets no final response times out after 64*T1, RFC 3261 §17.1.1.2 and
+//§17.1.2.2, but an INVITE that got a provisional one waits on.
+func (this *provider) addClientTransaction(ct *clientTransaction) {
+	this.transactionMutex.Lock()
+	this.clientTransactions[clientTransactionKey(ct.GetRequest())] = ct
+	this.transactionMutex.Unlock()
+	time.AfterFunc(transactionTimeout, func() {
+		this.transactionMutex.Lock()
+		state := ct.GetState()
+		timedOut := state == TRANSACTIONSTATE_CALLING || state == TRANSACTIONSTATE_TRYING ||
+			state == TRANSACTIONSTATE_PROCEEDING && ct.GetRequest().GetMethod() != INVITE
+		if timedOut {
+			ct.SetState(TRANSACTIONSTATE_TERMINATED)
+		}
+		this.transactionMutex.Unlock()
+		if timedOut {
+			this.removeTransaction(ct)
+			this.queueEvent(NewTimeoutEvent(ct, *NewTimeout(TIMEOUT_TRANSACTION)))
+		}
+	})
+}
+
+//removeTransaction forgets t, which no longer matches the messages
+//received.
+func (this *provider) removeTransaction(t Transaction) {
+	this.transactionMutex.Lock()
+	defer this.transactionMutex.Unlock()
+	switch tx := t.(type) {
+	case *clientTransaction:
+		if key := clientTransactionKey(tx.GetRequest()); this.clientTransactions[key] == tx {
+			delete(this.clientTransactions, key)
+		}
+	case *serverTransaction:
+		if key := serverTransactionKey(tx.GetRequest()); this.serverTransactions[key] == tx {
+			delete(this.serverTransactions, key)
+		}
+	}
+}
+
+//removeTransactionLater removes t once it no longer needs to absorb the
+//retransmissions of the request or of the final response.
+func (this *provider) removeTransactionLater(t Transaction) {
+	time.AfterFunc(transactionTimeout, func() {
+		this.transactionMutex.Lock()
+		if tx, ok := t.(interface{ SetState(TransactionState) }); ok {
+			tx.SetState(TRANSACTIONSTATE_TERMINATED)
+		}
+		this.transactionMutex.Unlock()
+		this.removeTransaction(t)
+	})
+}
+
+//responseSent moves st to the state of the response it sent, RFC 3261
+//§17.2.1 and §17.2.2.
+func (this *provider) responseSent(st *serverTransaction, resp Response) {
+	this.transactionMutex.Lock()
+	st.response = resp
+	code := resp.GetStatusCode()
+	switch {
+	case code < 200:
+		st.SetState(TRANSACTIONSTATE_PROCEEDING)
+	case code < 300 && st.GetRequest().GetMethod() == INVITE:
+		//the dialog retransmits the 2xx until it is acknowledged
+		st.SetState(TRANSACTIONSTATE_TERMINATED)
+	default:
+		st.SetState(TRANSACTIONSTATE_COMPLETED)
+	}
+	this.transactionMutex.Unlock()
+	if code >= 200 {
+		if st.GetState() == TRANSACTIONSTATE_TERMINATED {
+			this.removeTransaction(st)
+		} else {
+			this.removeTransactionLater(st)
+		}
+	}
+}
+
+//GetNewDialog creates the dialog of the INVITE, or other dialog creating
+//request, of the given transaction. The dialog is told about the
+//responses to the transaction and about the requests received within it.
+func (this *provider) GetNewDialog(t Transaction) (Dialog, error) {
+	var d *dialog
+	var err error
+	switch tx := t.(type) {
+	case *clientTransaction:
+		d, err = newClientDialog(tx)
+		if err == nil {
+			tx.SetDialog(d)
+		}
+	case *serverTransaction:
+		d, err = newServerDialog(tx)
+		if err == nil {
+			d.replaced = tx.replaces
+			tx.SetDialog(d)
+		}
+	default:
+		err = errors.New("unknown transaction type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.provider = this
+	this.putDialog(d)
+	return d, nil
+}
+
+func (this *provider) GetValidator() Validator {
+	return this.validator
+}
+
+func (this *provider) getParseMode() ParseMode {
+	this.parseModeMutex.Lock()
+	defer this.parseModeMutex.Unlock()
+	return this.parseMode
+}
+
+func (this *provider) setParseMode(mode ParseMode) {
+	this.parseModeMutex.Lock()
+	defer this.parseModeMutex.Unlock()
+	this.parseMode = mode
+}
+
+//putDialog (re)indexes d under its current dialog id.
+func (this *provider) putDialog(d *dialog) {
+	this.dialogMutex.Lock()
+	defer this.dialogMutex.Unlock()
+	for id, v := range this.dialogs {
+		if v == d {
+			delete(this.dialogs, id)
+		}
+	}
+	this.dialogs[dialogId(d.callId, d.localTag, d.remoteTag)] = d
+}
+
+func (this *provider) removeDialog(d *dialog) {
+	this.dialogMutex.Lock()
+	defer this.dialogMutex.Unlock()
+	for id, v := range this.dialogs {
+		if v == d {
+			delete(this.dialogs, id)
+		}
+	}
+}
+
+func (this *provider) getDialog(id string) *dialog {
+	this.dialogMutex.Lock()
+	defer this.dialogMutex.Unlock()
+	return this.dialogs[id]
+}
+
+//hasLocalTag reports whether a dialog with the given Call-ID and local tag
+//exists. The NOTIFYs of a forked SUBSCRIBE match no dialog yet, they create
+//one next to it, RFC 6665 §4.1.2.4.
+func (this *provider) hasLocalTag(callId, localTag string) bool {
+	this.dialogMutex.Lock()
+	defer this.dialogMutex.Unlock()
+	for _, d := range this.dialogs {
+		if d.callId == callId && d.localTag == localTag {
+			return true
+		}
+	}
+	return false
+}
+
 func (this *provider) SendRequest(Request) error {
 	return nil
 }
@@ -112,6 +378,9 @@ func (this *provider) Run() {
 		}
 	}
 
+	this.waitGroup.Add(1)
+	go this.deliverEvents()
+
 	//infinite loop run until ctrl+c
 	for {
 		select {
@@ -119,12 +388,6 @@ func (this *provider) Run() {
 			this.tracer.Println("Provider Stopped!!!")
 			return
 
-		case s := <-this.join:
-			this.transactions[s] = s
-
-		case s := <-this.leave:
-			delete(this.transactions, s)
-
 		case msg := <-this.forward:
 			var buffer bytes.Buffer
 			if err := msg.StartLineWrite(&buffer); err != nil {
@@ -132,15 +395,289 @@ func (this *provider) Run() {
 			} else {
 				log.Println("Received: ", buffer.String())
 			}
+			this.dispatch(msg)
 		}
 	}
 }
 
+//dispatch runs a received message through its transaction and dialog
+//before it is handed to the listeners.
+func (this *provider) dispatch(msg Message) {
+	switch m := msg.(type) {
+	case Request:
+		this.dispatchRequest(m)
+	case Response:
+		this.dispatchResponse(m)
+	}
+}
+
+func (this *provider) dispatchRequest(req Request) {
+	key := serverTransactionKey(req)
+	this.transactionMutex.Lock()
+	st, retransmitted := this.serverTransactions[key]
+	this.transactionMutex.Unlock()
+	if retransmitted {
+		this.absorbRequest(st, req)
+		return
+	}
+
+	resp, err := this.validator.ValidateRequest(req)
+	if err != nil {
+		this.tracer.Println("Dropping request:", err)
+		return
+	}
+	st = this.newServerTransaction(req)
+	if key != "" && req.GetMethod() != ACK {
+		this.transactionMutex.Lock()
+		this.serverTransactions[key] = st
+		this.transactionMutex.Unlock()
+	}
+	if resp != nil {
+		if err := st.SendResponse(resp); err != nil {
+			this.tracer.Println(err)
+		}
+		return
+	}
+
+	from, to, callId, _, err := dialogHeadersOf(req)
+	if err != nil {
+		this.tracer.Println("Dropping request:", err)
+		return
+	}
+	if d := this.getDialog(dialogId(callId, to.GetTag(), from.GetTag())); d != nil {
+		st.SetDialog(d)
+		if resp := d.processRequest(req); resp != nil {
+			if err := st.SendResponse(resp); err != nil {
+				this.tracer.Println(err)
+			}
+			return
+		}
+	} else if to.HasTag() && req.GetMethod() != ACK &&
+		!(req.GetMethod() == NOTIFY && this.hasLocalTag(callId, to.GetTag())) {
+		//a request within a dialog we don't know, RFC 3261 §12.2.2
+		if err := st.SendResponse(newResponseFor(req, CALL_OR_TRANSACTION_DOES_NOT_EXIST)); err != nil {
+			this.tracer.Println(err)
+		}
+		return
+	} else if req.GetMethod() == INVITE && !to.HasTag() {
+		var code int
+		if st.replaces, st.joins, code = this.findReplaced(req); code != 0 {
+			if err := st.SendResponse(newResponseFor(req, code)); err != nil {
+				this.tracer.Println(err)
+			}
+			return
+		}
+	}
+	this.queueEvent(NewRequestEvent(st, req))
+}
+
+//absorbRequest handles a request that matches the server transaction st:
+//the ACK of a non-2xx final response confirms an INVITE transaction, and a
+//retransmitted request is answered with the last response sent again,
+//RFC 3261 §17.2.1 and §17.2.2. Neither reaches the listeners.
+func (this *provider) absorbRequest(st *serverTransaction, req Request) {
+	this.transactionMutex.Lock()
+	resp := st.response
+	if req.GetMethod() == ACK && st.GetState() == TRANSACTIONSTATE_COMPLETED {
+		st.SetState(TRANSACTIONSTATE_CONFIRMED)
+	}
+	this.transactionMutex.Unlock()
+	if req.GetMethod() == ACK || resp == nil {
+		return
+	}
+	if err := this.SendResponse(resp); err != nil {
+		this.tracer.Println(err)
+	}
+}
+
+func (this *provider) dispatchResponse(resp Response) {
+	if err := this.validator.ValidateResponse(resp); err != nil {
+		this.tracer.Println("Dropping response:", err)
+		return
+	}
+	this.transactionMutex.Lock()
+	ct := this.clientTransactions[clientTransactionKey(resp)]
+	this.transactionMutex.Unlock()
+	if ct == nil {
+		this.queueEvent(NewResponseEvent(nil, resp))
+		return
+	}
+	if !this.responseReceived(ct, resp) {
+		//a retransmission of the final response, RFC 3261 §17.1.1.2
+		return
+	}
+	if d, ok := ct.GetDialog().(*dialog); ok {
+		d.processResponse(ct, resp)
+	}
+	if code := resp.GetStatusCode(); code >= 200 && code < 300 && ct.GetRequest().GetMethod() == REGISTER {
+		this.learnServiceRoute(ct.GetRequest(), resp)
+	}
+	this.queueEvent(NewResponseEvent(ct, resp))
+}
+
+//responseReceived moves ct to the state of resp, RFC 3261 §17.1.1 and
+//§17.1.2, and reports whether resp is new to ct.
+func (this *provider) responseReceived(ct *clientTransaction, resp Response) bool {
+	this.transactionMutex.Lock()
+	state := ct.GetState()
+	if state == TRANSACTIONSTATE_COMPLETED || state == TRANSACTIONSTATE_TERMINATED {
+		this.transactionMutex.Unlock()
+		return false
+	}
+	code := resp.GetStatusCode()
+	switch {
+	case code < 200:
+		ct.SetState(TRANSACTIONSTATE_PROCEEDING)
+	case code < 300 && ct.GetRequest().GetMethod() == INVITE:
+		//the retransmissions of the 2xx go to the dialog
+		ct.SetState(TRANSACTIONSTATE_TERMINATED)
+	default:
+		ct.SetState(TRANSACTIONSTATE_COMPLETED)
+	}
+	this.transactionMutex.Unlock()
+	if code >= 200 {
+		if ct.GetState() == TRANSACTIONSTATE_TERMINATED {
+			this.removeTransaction(ct)
+		} else {
+			this.removeTransactionLater(ct)
+		}
+	}
+	return true
+}
+
+//queueEvent queues event for the listeners without waiting for them.
+func (this *provider) queueEvent(event interface{}) {
+	this.eventMutex.Lock()
+	this.events = append(this.events, event)
+	this.eventMutex.Unlock()
+	select {
+	case this.eventReady <- true:
+	default:
+		//the listeners are told already
+	}
+}
+
+//nextEvent dequeues the oldest event, nil when there is none.
+func (this *provider) nextEvent() interface{} {
+	this.eventMutex.Lock()
+	defer this.eventMutex.Unlock()
+	if len(this.events) == 0 {
+		return nil
+	}
+	event := this.events[0]
+	this.events[0] = nil
+	this.events = this.events[1:]
+	return event
+}
+
+//deliverEvents hands the events to the listeners one at a time, in the
+//order the messages were received, so that a listener may use the
+//provider from within its callbacks.
+func (this *provider) deliverEvents() {
+	defer this.waitGroup.Done()
+	for {
+		select {
+		case <-this.quit:
+			return
+		case <-this.eventReady:
+			for event := this.nextEvent(); event != nil; event = this.nextEvent() {
+				for _, l := range this.listeners {
+					switch e := event.(type) {
+					case *RequestEvent:
+						l.ProcessRequest(*e)
+					case *ResponseEvent:
+						l.ProcessResponse(*e)
+					case *TimeoutEvent:
+						l.ProcessTimeout(*e)
+					}
+				}
+			}
+		}
+	}
+}
+
+//topViaOf returns the topmost Via of msg, or nil.
+func topViaOf(msg Message) *header.Via {
+	h, err := parseHeader(msg, "Via")
+	if err != nil || h == nil {
+		return nil
+	}
+	vias, ok := h.(*header.ViaList)
+	if !ok || vias.Front() == nil {
+		return nil
+	}
+	via, _ := vias.Front().Value.(*header.Via)
+	return via
+}
+
+//branchOf returns the branch parameter of the topmost Via of msg.
+func branchOf(msg Message) string {
+	if via := topViaOf(msg); via != nil {
+		return via.GetBranch()
+	}
+	return ""
+}
+
+//cSeqMethodOf returns the method of the CSeq of msg, or "".
+func cSeqMethodOf(msg Message) string {
+	if h, err := parseHeader(msg, "CSeq"); err == nil && h != nil {
+		if cSeq, ok := h.(*header.CSeq); ok {
+			return cSeq.GetMethod()
+		}
+	}
+	return ""
+}
+
+//clientTransactionKey returns the key matching a response to the client
+//transaction of its request, RFC 3261 §17.1.3: the branch of the topmost
+//Via and the CSeq method. Without a branch, the Call-ID and the CSeq
+//number stand in for it.
+func clientTransactionKey(msg Message) string {
+	method := cSeqMethodOf(msg)
+	if branch := branchOf(msg); branch != "" {
+		return branch + " " + method
+	}
+	return msg.GetHeader().Get("Call-ID") + " " + strconv.Itoa(cSeqOf(msg)) + " " + method
+}
+
+//serverTransactionKey returns the key matching a request to its server
+//transaction, RFC 3261 §17.2.3: the branch and the sent-by of the topmost
+//Via and the method, the ACK matching the INVITE it acknowledges. The
+//branch of an RFC 2543 peer lacks the magic cookie, the Call-ID, the From
+//tag and the CSeq number then stand in for it. It returns "" for a request
+//without Via.
+func serverTransactionKey(req Request) string {
+	via := topViaOf(req)
+	if via == nil || via.GetSentBy() == nil {
+		return ""
+	}
+	method := req.GetMethod()
+	if method == ACK {
+		method = INVITE
+	}
+	key := via.GetSentBy().String() + " " + method
+	if branch := via.GetBranch(); strings.HasPrefix(branch, BRANCH_MAGIC_COOKIE) {
+		return branch + " " + key
+	}
+	fromTag := ""
+	if h, err := parseHeader(req, "From"); err == nil && h != nil {
+		if from, ok := h.(*header.From); ok {
+			fromTag = from.GetTag()
+		}
+	}
+	return req.GetHeader().Get("Call-ID") + " " + fromTag + " " + strconv.Itoa(cSeqOf(req)) + " " + key
+}
+
 func (this *provider) Stop() {
 	close(this.quit)
-	for _, s := range this.transactions {
-		s.Close()
+	this.transactionMutex.Lock()
+	for _, ct := range this.clientTransactions {
+		ct.Close()
 	}
+	for _, st := range this.serverTransactions {
+		st.Close()
+	}
+	this.transactionMutex.Unlock()
 	this.waitGroup.Wait()
 }
 
@@ -183,7 +720,7 @@ func (this *provider) ServeConn(conn net.Conn) {
 		}
 
 		conn.SetDeadline(time.Now().Add(1e9)) //wait for 1 second
-		if msg, err := ReadMessage(bufio.NewReader(conn)); err != nil {
+		if msg, err := ReadMessageWithMode(bufio.NewReader(conn), this.getParseMode()); err != nil {
 			if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
 				continue
 			} else {
@@ -191,6 +728,11 @@ func (this *provider) ServeConn(conn net.Conn) {
 				return
 			}
 		} else {
+			//the peers sending broken messages are reported, their
+			//messages are still handled
+			for _, warning := range msg.GetParseWarnings() {
+				this.tracer.Println("Repaired message from", conn.RemoteAddr(), ":", warning)
+			}
 			this.forward <- msg
 		}
 	}
@@ -0,0 +1,123 @@
+package sip
+
+import (
+	"net"
+	"sip/parser"
+	"testing"
+)
+
+func TestServerTransactionRetransmission(t *testing.T) {
+	p := newTestDispatchProvider()
+	event := dispatchTestRequest(p, testInvite(t, false))
+	if event == nil {
+		t.Fatal("the INVITE was not dispatched")
+	}
+	st := event.GetServerTransaction()
+	if st.GetState() != TRANSACTIONSTATE_PROCEEDING {
+		t.Error(st.GetState())
+	}
+	if err := st.SendResponse(newResponseFor(st.GetRequest(), BUSY_HERE)); err != nil {
+		t.Fatal(err)
+	}
+	if st.GetState() != TRANSACTIONSTATE_COMPLETED {
+		t.Error(st.GetState())
+	}
+
+	//the retransmitted INVITE and the ACK of the 486 are absorbed
+	if event := dispatchTestRequest(p, testInvite(t, false)); event != nil {
+		t.Error("the retransmitted INVITE was dispatched")
+	}
+	ack := testInvite(t, false)
+	ack.SetMethod(ACK)
+	ack.GetHeader().Set("CSeq", "314159 ACK")
+	if event := dispatchTestRequest(p, ack); event != nil {
+		t.Error("the ACK of the 486 was dispatched")
+	}
+	if st.GetState() != TRANSACTIONSTATE_CONFIRMED {
+		t.Error(st.GetState())
+	}
+
+	//the transaction of a 2xx ends at once
+	invite := testInvite(t, false)
+	invite.GetHeader().Set("Via", "SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhdt")
+	st = dispatchTestRequest(p, invite).GetServerTransaction()
+	st.SendResponse(newResponseFor(invite, OK))
+	if st.GetState() != TRANSACTIONSTATE_TERMINATED || len(p.serverTransactions) != 1 {
+		t.Error(st.GetState(), len(p.serverTransactions))
+	}
+}
+
+func TestClientTransactionResponses(t *testing.T) {
+	p := newTestDispatchProvider()
+	options := testInvite(t, false)
+	options.SetMethod(OPTIONS)
+	options.GetHeader().Set("CSeq", "1 OPTIONS")
+	ct := p.GetNewClientTransaction(options)
+	if ct.GetState() != TRANSACTIONSTATE_TRYING {
+		t.Error(ct.GetState())
+	}
+
+	//a response to another method of the same branch is not the transaction's
+	p.dispatchResponse(newResponseFor(testInvite(t, false), OK))
+	if event, ok := p.nextEvent().(*ResponseEvent); !ok || event.GetClientTransaction() != nil {
+		t.Error("the 200 of the INVITE matched the OPTIONS")
+	}
+
+	p.dispatchResponse(newResponseFor(options, OK))
+	if event, ok := p.nextEvent().(*ResponseEvent); !ok || event.GetClientTransaction() != ct {
+		t.Error("the 200 of the OPTIONS matched no transaction")
+	}
+	if ct.GetState() != TRANSACTIONSTATE_COMPLETED {
+		t.Error(ct.GetState())
+	}
+	p.dispatchResponse(newResponseFor(options, OK))
+	if event := p.nextEvent(); event != nil {
+		t.Error("the retransmitted 200 was dispatched")
+	}
+}
+
+func TestBuiltinHeaderParsers(t *testing.T) {
+	//the stack relies on the types of the headers it parses itself
+	for _, name := range []string{"From", "f", "To", "Call-ID", "CSeq", "Contact", "Identity"} {
+		if err := parser.RegisterHeaderParser(name, "", func(line string) parser.Parser {
+			return parser.NewHeaderParser(line)
+		}); err == nil {
+			parser.UnregisterHeaderParser(name)
+			t.Error("registered a parser for", name)
+		}
+	}
+	p := newTestDispatchProvider()
+	event := dispatchTestRequest(p, testInvite(t, false))
+	if event == nil {
+		t.Fatal("the INVITE was not dispatched")
+	}
+	if _, err := p.GetNewDialog(event.GetServerTransaction()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetParseModeWhileServing(t *testing.T) {
+	s := newStack(TraceOff())
+	p := s.CreateProvider().(*provider)
+	local, remote := net.Pipe()
+	defer remote.Close()
+	p.waitGroup.Add(1)
+	go p.ServeConn(local)
+
+	go remote.Write([]byte("INVITE sip:bob@biloxi.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: Bob <sip:bob@biloxi.com>\r\n" +
+		"From: Alice <sip:alice@atlanta.com>;tag=1928301774\r\n" +
+		"Call-ID: a84b4c76e66710@pc33.atlanta.com\r\n" +
+		"CSeq: 314159 INVITE\r\n" +
+		"Content-Length: 0\r\n\r\n"))
+	<-p.forward
+
+	//the connection waits for its next message meanwhile
+	s.SetParseMode(PARSEMODE_TOLERANT)
+	p.Stop()
+	if p.getParseMode() != PARSEMODE_TOLERANT {
+		t.Error(p.getParseMode())
+	}
+}
@@ -0,0 +1,475 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"sip/header"
+	"strconv"
+	"sync"
+	"time"
+)
+
+////////////////////Interface//////////////////////////////
+
+// Publication is the event state a Publisher published for a resource,
+// identified at the Event State Compositor by its entity-tag, RFC 3903 §4.
+type Publication interface {
+	GetEventType() string
+	// GetETag returns the entity-tag of the state, "" until the compositor
+	// accepted it.
+	GetETag() string
+	// GetExpires returns the number of seconds left before the state
+	// expires.
+	GetExpires() int
+	IsTerminated() bool
+	SetApplicationData(applicationData interface{})
+	GetApplicationData() interface{}
+}
+
+// PublisherListener is told about the end of the publications of a
+// Publisher. It may be called from the timers of the publications as well
+// as from ProcessResponse.
+type PublisherListener interface {
+	// ProcessPublicationTerminated is called once pub is removed, refused
+	// or expired.
+	ProcessPublicationTerminated(pub Publication)
+}
+
+// Publisher sends PUBLISH requests and keeps the event states they
+// publish refreshed until they are removed, RFC 3903 §4. The application
+// hands it the responses to its PUBLISHes. A publication has one PUBLISH
+// outstanding at a time: Modify, Refresh and Remove send theirs once the
+// previous one completed.
+type Publisher interface {
+	// Publish sends publish, the initial PUBLISH of an event state.
+	Publish(publish Request) (Publication, error)
+	// Modify replaces the state of pub with body.
+	Modify(pub Publication, contentType string, body []byte) error
+	Refresh(pub Publication) error
+	Remove(pub Publication) error
+
+	// ProcessResponse handles a response to a PUBLISH of the publisher and
+	// returns its publication, nil if the response is not for one. The
+	// state is published anew when the compositor lost it, 412, and with a
+	// longer duration when it was too brief, 423.
+	ProcessResponse(responseEvent ResponseEvent) Publication
+	GetPublications() []Publication
+}
+
+////////////////////Implementation////////////////////////
+
+type publication struct {
+	mutex sync.Mutex
+
+	eventType string
+	etag      string
+	expires   int
+	expiry    time.Time
+
+	//the state published, sent anew when the compositor lost it
+	contentType string
+	body        []byte
+
+	//the last PUBLISH sent, and its transaction while it is outstanding
+	request     Request
+	transaction ClientTransaction
+	terminated  bool
+
+	//the PUBLISH asked for while one is outstanding, sent once that one
+	//completed, RFC 3903 §4.1
+	pending *pendingPublish
+
+	expiryTimer  *time.Timer
+	refreshTimer *time.Timer
+
+	applicationData interface{}
+}
+
+func (this *publication) GetEventType() string {
+	return this.eventType
+}
+
+func (this *publication) GetETag() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.etag
+}
+
+func (this *publication) GetExpires() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.terminated || this.expiry.IsZero() {
+		return 0
+	}
+	left := int(time.Until(this.expiry) / time.Second)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func (this *publication) IsTerminated() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.terminated
+}
+
+func (this *publication) SetApplicationData(applicationData interface{}) {
+	this.applicationData = applicationData
+}
+
+func (this *publication) GetApplicationData() interface{} {
+	return this.applicationData
+}
+
+// terminate stops the timers of the publication. It returns false when the
+// publication was already terminated.
+func (this *publication) terminate() bool {
+	if this.terminated {
+		return false
+	}
+	this.terminated = true
+	for _, t := range []*time.Timer{this.expiryTimer, this.refreshTimer} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	return true
+}
+
+// pendingPublish is a PUBLISH waiting for the outstanding one of its
+// publication.
+type pendingPublish struct {
+	expires   int
+	withState bool
+}
+
+type publisher struct {
+	mutex    sync.Mutex
+	provider Provider
+	listener PublisherListener
+
+	publications []*publication
+}
+
+func NewPublisher(p Provider, l PublisherListener) Publisher {
+	return &publisher{
+		provider: p,
+		listener: l,
+	}
+}
+
+func (this *publisher) Publish(publish Request) (Publication, error) {
+	if publish.GetMethod() != PUBLISH {
+		return nil, errors.New("Publisher.Publish can't send " + publish.GetMethod())
+	}
+	event := eventOf(publish)
+	if event == nil {
+		return nil, errors.New("missing or malformed Event header")
+	}
+	if len(headerValues(publish, "SIP-If-Match")) > 0 {
+		return nil, errors.New("an initial PUBLISH carries no SIP-If-Match header")
+	}
+	body, err := rawBodyBytes(publish)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, errors.New("an initial PUBLISH carries the event state")
+	}
+	pub := &publication{
+		eventType:   event.GetEventType(),
+		expires:     expiresOf(publish),
+		contentType: publish.GetHeader().Get("Content-Type"),
+		body:        body,
+		request:     publish,
+	}
+	ct := this.provider.GetNewClientTransaction(publish)
+	pub.transaction = ct
+
+	this.mutex.Lock()
+	this.publications = append(this.publications, pub)
+	this.mutex.Unlock()
+
+	if err := ct.SendRequest(); err != nil {
+		pub.mutex.Lock()
+		pub.terminate()
+		pub.mutex.Unlock()
+		this.remove(pub)
+		return nil, err
+	}
+	return pub, nil
+}
+
+func (this *publisher) Modify(pub Publication, contentType string, body []byte) error {
+	p, ok := pub.(*publication)
+	if !ok {
+		return errors.New("not a publication of the publisher")
+	}
+	if len(body) == 0 {
+		return errors.New("a modification carries the new event state")
+	}
+	p.mutex.Lock()
+	p.contentType, p.body = contentType, body
+	expires := p.expires
+	p.mutex.Unlock()
+	return this.republish(p, expires, true)
+}
+
+func (this *publisher) Refresh(pub Publication) error {
+	p, ok := pub.(*publication)
+	if !ok {
+		return errors.New("not a publication of the publisher")
+	}
+	p.mutex.Lock()
+	expires := p.expires
+	p.mutex.Unlock()
+	return this.republish(p, expires, false)
+}
+
+func (this *publisher) Remove(pub Publication) error {
+	p, ok := pub.(*publication)
+	if !ok {
+		return errors.New("not a publication of the publisher")
+	}
+	return this.republish(p, 0, false)
+}
+
+// republish sends a PUBLISH for the state of pub asking for the given
+// duration: a refresh, a modification carrying the state, or an initial
+// publication of the state when pub has no entity-tag. A PUBLISH asked for
+// while another is outstanding waits for it, RFC 3903 §4.1; it replaces the
+// one already waiting, but for a removal, and carries its state along.
+func (this *publisher) republish(pub *publication, expires int, withState bool) error {
+	pub.mutex.Lock()
+	if pub.terminated {
+		pub.mutex.Unlock()
+		return errors.New("the publication is terminated")
+	}
+	if pub.transaction != nil {
+		//nothing follows a removal
+		if pub.pending == nil || pub.pending.expires != 0 {
+			if pub.pending != nil && pub.pending.withState {
+				withState = true
+			}
+			pub.pending = &pendingPublish{expires, withState}
+		}
+		pub.mutex.Unlock()
+		return nil
+	}
+	etag := pub.etag
+	if etag == "" && expires == 0 {
+		pub.mutex.Unlock()
+		return errors.New("the state was not published yet")
+	}
+	if etag == "" {
+		withState = true
+	}
+	req, err := nextPublish(pub.request)
+	if err != nil {
+		pub.mutex.Unlock()
+		return err
+	}
+	h := req.GetHeader()
+	if etag != "" {
+		h.Set("SIP-If-Match", etag)
+	}
+	if expires >= 0 {
+		h.Set("Expires", strconv.Itoa(expires))
+	}
+	if withState {
+		req.SetBody(bytes.NewReader(pub.body))
+		req.SetContentLength(int64(len(pub.body)))
+		h.Set("Content-Type", pub.contentType)
+	}
+	ct := this.provider.GetNewClientTransaction(req)
+	pub.request = req
+	pub.transaction = ct
+	if expires == 0 && pub.refreshTimer != nil {
+		pub.refreshTimer.Stop()
+	}
+	pub.mutex.Unlock()
+	return ct.SendRequest()
+}
+
+func (this *publisher) ProcessResponse(responseEvent ResponseEvent) Publication {
+	ct := responseEvent.GetClientTransaction()
+	if ct == nil || ct.GetRequest().GetMethod() != PUBLISH {
+		return nil
+	}
+	pub := this.findByTransaction(ct)
+	if pub == nil {
+		return nil
+	}
+	resp := responseEvent.GetResponse()
+	code := resp.GetStatusCode()
+	req := ct.GetRequest()
+	removal := expiresOf(req) == 0
+
+	pub.mutex.Lock()
+	initial := pub.etag == ""
+	switch {
+	case code < 200:
+		pub.mutex.Unlock()
+		return pub
+	case code < 300 && !removal:
+		pub.transaction = nil
+		expires := expiresOf(resp)
+		if expires < 0 {
+			expires = expiresOf(req)
+		}
+		if expires < 0 {
+			expires = DEFAULT_PUBLICATION_EXPIRES
+		}
+		if h, err := parseHeader(resp, "SIP-ETag"); err == nil && h != nil {
+			pub.etag = h.(*header.SIPETag).GetETag()
+		}
+		this.schedule(pub, expires)
+		pub.mutex.Unlock()
+		this.sendPending(pub)
+		return pub
+	case code < 300:
+		//the state was removed
+		pub.terminate()
+	case code == CONDITIONAL_REQUEST_FAILED && !removal:
+		//the compositor lost the state, RFC 3903 §4.4
+		pub.transaction = nil
+		pub.etag = ""
+		pub.mutex.Unlock()
+		this.retry(pub, -1)
+		return pub
+	case code == INTERVAL_TOO_BRIEF && !removal:
+		pub.transaction = nil
+		pub.mutex.Unlock()
+		if h, err := parseHeader(resp, "Min-Expires"); err == nil && h != nil {
+			if minExpires := h.(*header.MinExpires).GetExpires(); minExpires > 0 {
+				pub.mutex.Lock()
+				pub.expires = minExpires
+				pub.mutex.Unlock()
+				this.retry(pub, minExpires)
+				return pub
+			}
+		}
+		this.sendPending(pub)
+		return pub
+	case initial || removal:
+		//the state was refused, or is gone anyway
+		pub.terminate()
+	default:
+		//a failed refresh leaves the state until it expires
+		pub.transaction = nil
+		pub.mutex.Unlock()
+		this.sendPending(pub)
+		return pub
+	}
+	pub.mutex.Unlock()
+	this.terminated(pub)
+	return pub
+}
+
+func (this *publisher) GetPublications() []Publication {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	pubs := make([]Publication, len(this.publications))
+	for i, p := range this.publications {
+		pubs[i] = p
+	}
+	return pubs
+}
+
+// retry sends pub again after a failed PUBLISH, with its state when the
+// compositor doesn't hold it.
+func (this *publisher) retry(pub *publication, expires int) {
+	pub.mutex.Lock()
+	if expires < 0 {
+		expires = pub.expires
+	}
+	pub.mutex.Unlock()
+	if err := this.republish(pub, expires, false); err != nil {
+		tracerOf(this.provider).Println("Publishing again failed:", err)
+	}
+}
+
+// sendPending sends the PUBLISH that waited for the one pub just completed.
+func (this *publisher) sendPending(pub *publication) {
+	pub.mutex.Lock()
+	pending := pub.pending
+	pub.pending = nil
+	pub.mutex.Unlock()
+	if pending == nil {
+		return
+	}
+	if err := this.republish(pub, pending.expires, pending.withState); err != nil {
+		tracerOf(this.provider).Println("Publishing again failed:", err)
+	}
+}
+
+func (this *publisher) findByTransaction(ct ClientTransaction) *publication {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, p := range this.publications {
+		if p.transaction == ct {
+			return p
+		}
+	}
+	return nil
+}
+
+// schedule (re)arms the expiry and refresh timers of pub for a duration of
+// expires seconds. pub must be locked.
+func (this *publisher) schedule(pub *publication, expires int) {
+	pub.expiry = time.Now().Add(time.Duration(expires) * time.Second)
+	for _, t := range []*time.Timer{pub.expiryTimer, pub.refreshTimer} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	pub.expiryTimer = time.AfterFunc(time.Duration(expires)*time.Second, func() {
+		pub.mutex.Lock()
+		terminated := pub.terminate()
+		pub.mutex.Unlock()
+		if terminated {
+			this.terminated(pub)
+		}
+	})
+	pub.refreshTimer = time.AfterFunc(subscriptionRefreshInterval(expires), func() {
+		if err := this.Refresh(pub); err != nil {
+			tracerOf(this.provider).Println("Refreshing publication failed:", err)
+		}
+	})
+}
+
+// terminated forgets a publication that just ended and tells the listener.
+func (this *publisher) terminated(pub *publication) {
+	this.remove(pub)
+	if this.listener != nil {
+		this.listener.ProcessPublicationTerminated(pub)
+	}
+}
+
+func (this *publisher) remove(pub *publication) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i, p := range this.publications {
+		if p == pub {
+			this.publications = append(this.publications[:i:i], this.publications[i+1:]...)
+			return
+		}
+	}
+}
+
+// nextPublish returns a PUBLISH following previous, RFC 3903 §4.1: the
+// same headers with the next CSeq and a new Via branch, without the
+// entity-tag, duration and body of previous.
+func nextPublish(previous Request) (Request, error) {
+	req, err := nextRequest(previous)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"SIP-If-Match", "Expires", "Content-Type", "Content-Length"} {
+		removeHeader(req, name)
+	}
+	req.SetBody(nil)
+	req.SetContentLength(0)
+	return req, nil
+}
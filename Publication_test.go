@@ -0,0 +1,250 @@
+package sip
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testPublisherListener struct {
+	terminated []Publication
+}
+
+func (this *testPublisherListener) ProcessPublicationTerminated(pub Publication) {
+	this.terminated = append(this.terminated, pub)
+}
+
+func testPublish(t *testing.T, event string, expires int, body string) Request {
+	headers := []string{
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bKnashds8",
+		"Max-Forwards: 70",
+		"To: <sip:bob@biloxi.com>",
+		"From: <sip:bob@biloxi.com>;tag=1234wxyz",
+		"Call-ID: 81818181@pua.example.com",
+		"CSeq: 1 PUBLISH",
+		"Event: " + event,
+	}
+	if expires >= 0 {
+		headers = append(headers, "Expires: "+strconv.Itoa(expires))
+	}
+	if body != "" {
+		headers = append(headers, "Content-Type: application/pidf+xml")
+	}
+	return testRequest(t, PUBLISH, "sip:bob@biloxi.com", headers, body)
+}
+
+// composeAll joins the bodies of the states published, to tell their order.
+func composeAll(resource string, states []EventState) ([]byte, error) {
+	var bodies [][]byte
+	for _, s := range states {
+		bodies = append(bodies, s.Body)
+	}
+	return bytes.Join(bodies, []byte("|")), nil
+}
+
+func newTestCompositor(t *testing.T) (*testProvider, EventStateCompositor, Subscription) {
+	p := &testProvider{}
+	n := NewNotifier(p)
+	esc := NewEventStateCompositor(n)
+	esc.AddEventPackage("presence", "application/pidf+xml", composeAll)
+
+	st := newTestServerTransaction(testSubscribe(t, "presence", 600, ""))
+	sub, err := n.ProcessSubscribe(*NewRequestEvent(st, st.GetRequest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, esc, sub
+}
+
+// processPublish hands req to esc and returns its response.
+func processPublish(t *testing.T, esc EventStateCompositor, req Request) Response {
+	st := newTestServerTransaction(req)
+	esc.ProcessPublish(*NewRequestEvent(st, req))
+	return st.lastResponse(t)
+}
+
+func notifyBody(t *testing.T, p *testProvider) string {
+	notify := p.lastRequest(t)
+	if notify.GetMethod() != NOTIFY {
+		t.Fatal(notify.GetMethod())
+	}
+	if notify.GetBody() == nil {
+		return ""
+	}
+	body, _ := ioutil.ReadAll(notify.GetBody())
+	return string(body)
+}
+
+func TestEventStateCompositor(t *testing.T) {
+	p, esc, _ := newTestCompositor(t)
+
+	//an initial publication
+	resp := processPublish(t, esc, testPublish(t, "presence", 600, "<open/>"))
+	etag := resp.GetHeader().Get("SIP-ETag")
+	if resp.GetStatusCode() != OK || etag == "" || resp.GetHeader().Get("Expires") != "600" {
+		t.Fatal(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if body := notifyBody(t, p); body != "<open/>" {
+		t.Error(body)
+	}
+	processPublish(t, esc, testPublish(t, "presence", -1, "<busy/>"))
+	if body := notifyBody(t, p); body != "<open/>|<busy/>" {
+		t.Error(body)
+	}
+	states := esc.GetEventStates("sip:bob@biloxi.com;transport=udp", "presence")
+	if len(states) != 2 || states[0].ETag != etag || states[1].Expires <= 600 {
+		t.Fatal(states)
+	}
+
+	//a refresh changes the entity-tag but not the state
+	notifies := len(p.transactions)
+	refresh := testPublish(t, "presence", 600, "")
+	refresh.GetHeader().Set("SIP-If-Match", etag)
+	resp = processPublish(t, esc, refresh)
+	refreshed := resp.GetHeader().Get("SIP-ETag")
+	if resp.GetStatusCode() != OK || refreshed == "" || refreshed == etag || len(p.transactions) != notifies {
+		t.Fatal(resp.GetStatusCode(), resp.GetHeader(), len(p.transactions))
+	}
+
+	//the old entity-tag is stale
+	if resp = processPublish(t, esc, refresh); resp.GetStatusCode() != CONDITIONAL_REQUEST_FAILED {
+		t.Error(resp.GetStatusCode())
+	}
+
+	//a modification makes the state the latest one
+	modify := testPublish(t, "presence", 600, "<away/>")
+	modify.GetHeader().Set("SIP-If-Match", refreshed)
+	if resp = processPublish(t, esc, modify); resp.GetStatusCode() != OK {
+		t.Fatal(resp.GetStatusCode())
+	}
+	if body := notifyBody(t, p); body != "<busy/>|<away/>" {
+		t.Error(body)
+	}
+
+	//a removal
+	remove := testPublish(t, "presence", 0, "")
+	remove.GetHeader().Set("SIP-If-Match", resp.GetHeader().Get("SIP-ETag"))
+	resp = processPublish(t, esc, remove)
+	if resp.GetStatusCode() != OK || resp.GetHeader().Get("Expires") != "0" || resp.GetHeader().Get("SIP-ETag") != "" {
+		t.Fatal(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if body := notifyBody(t, p); body != "<busy/>" {
+		t.Error(body)
+	}
+	if state, _ := esc.GetComposedState("sip:bob@biloxi.com", "presence"); string(state) != "<busy/>" {
+		t.Error(string(state))
+	}
+}
+
+func TestEventStateCompositorFailures(t *testing.T) {
+	_, esc, _ := newTestCompositor(t)
+
+	resp := processPublish(t, esc, testPublish(t, "dialog", 600, "<open/>"))
+	if resp.GetStatusCode() != BAD_EVENT || resp.GetHeader().Get("Allow-Events") != "presence" {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	resp = processPublish(t, esc, testPublish(t, "presence", 10, "<open/>"))
+	if resp.GetStatusCode() != INTERVAL_TOO_BRIEF || resp.GetHeader().Get("Min-Expires") != "60" {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if resp = processPublish(t, esc, testPublish(t, "presence", 600, "")); resp.GetStatusCode() != BAD_REQUEST {
+		t.Error(resp.GetStatusCode())
+	}
+	req := testPublish(t, "presence", 600, "<open/>")
+	req.GetHeader().Set("Content-Type", "text/plain")
+	if resp = processPublish(t, esc, req); resp.GetStatusCode() != UNSUPPORTED_MEDIA_TYPE {
+		t.Error(resp.GetStatusCode())
+	}
+
+	esc.SetMaxExpires(1800)
+	if resp = processPublish(t, esc, testPublish(t, "presence", 7200, "<open/>")); resp.GetHeader().Get("Expires") != "1800" {
+		t.Error(resp.GetHeader())
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	_, esc, _ := newTestCompositor(t)
+	p := &testProvider{}
+	l := &testPublisherListener{}
+	publisher := NewPublisher(p, l)
+
+	// send hands the last PUBLISH sent to the compositor and its response
+	// back to the publisher.
+	send := func() Request {
+		ct := p.lastTransaction(t)
+		resp := processPublish(t, esc, ct.GetRequest())
+		publisher.ProcessResponse(*NewResponseEvent(ct, resp))
+		return ct.GetRequest()
+	}
+
+	pub, err := publisher.Publish(testPublish(t, "presence", 600, "<open/>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	send()
+	etag := pub.GetETag()
+	if etag == "" || pub.GetExpires() < 599 {
+		t.Fatal(etag, pub.GetExpires())
+	}
+
+	//the refresh quotes the entity-tag
+	publisher.Refresh(pub)
+	refresh := send()
+	h := refresh.GetHeader()
+	if h.Get("SIP-If-Match") != etag || h.Get("CSeq") != "2 PUBLISH" || h.Get("Expires") != "600" ||
+		refresh.GetContentLength() != 0 || h.Get("Via") == "SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bKnashds8" {
+		t.Error(h)
+	}
+	if pub.GetETag() == etag {
+		t.Error("the entity-tag was not updated")
+	}
+
+	//a modification waits for the outstanding refresh
+	publisher.Refresh(pub)
+	sent := len(p.transactions)
+	publisher.Modify(pub, "application/pidf+xml", []byte("<closed/>"))
+	if len(p.transactions) != sent {
+		t.Fatal("sent a PUBLISH while another was outstanding")
+	}
+	send()
+	if len(p.transactions) != sent+1 || p.lastRequest(t).GetHeader().Get("CSeq") != "4 PUBLISH" {
+		t.Fatal("the modification was not sent", len(p.transactions))
+	}
+	send()
+	if states := esc.GetEventStates("sip:bob@biloxi.com", "presence"); len(states) != 1 || string(states[0].Body) != "<closed/>" {
+		t.Error(states)
+	}
+
+	//the compositor lost the state, which is published anew
+	esc.RemoveEventPackage("presence")
+	esc.AddEventPackage("presence", "application/pidf+xml", ComposeLatest)
+	publisher.Refresh(pub)
+	send()
+	republish := p.lastRequest(t)
+	if republish.GetHeader().Get("SIP-If-Match") != "" || republish.GetHeader().Get("CSeq") != "6 PUBLISH" {
+		t.Error(republish.GetHeader())
+	}
+	send()
+	if state, _ := esc.GetComposedState("sip:bob@biloxi.com", "presence"); string(state) != "<closed/>" || pub.GetETag() == "" {
+		t.Error(string(state), pub.GetETag())
+	}
+
+	if err := publisher.Remove(pub); err != nil {
+		t.Fatal(err)
+	}
+	if req := send(); req.GetHeader().Get("Expires") != "0" {
+		t.Error(req.GetHeader())
+	}
+	if !pub.IsTerminated() || len(l.terminated) != 1 || len(publisher.GetPublications()) != 0 ||
+		len(esc.GetEventStates("sip:bob@biloxi.com", "presence")) != 0 {
+		t.Error(pub.IsTerminated(), l.terminated)
+	}
+	if err := publisher.Refresh(pub); err == nil {
+		t.Error("refreshed a removed publication")
+	}
+	if _, err := publisher.Publish(testPublish(t, "presence", 600, "")); err == nil || !strings.Contains(err.Error(), "state") {
+		t.Error(err)
+	}
+}
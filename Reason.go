@@ -0,0 +1,403 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+	"strings"
+)
+
+// The protocols of the causes a Reason header carries, RFC 3326 §2.
+const (
+	REASON_PROTOCOL_SIP  = "SIP"
+	REASON_PROTOCOL_Q850 = "Q.850"
+)
+
+// The ISDN cause values of ITU-T Q.850 that RFC 3398 maps to and from SIP
+// status codes.
+const (
+	Q850_UNALLOCATED_NUMBER                = 1
+	Q850_NO_ROUTE_TO_TRANSIT_NETWORK       = 2
+	Q850_NO_ROUTE_TO_DESTINATION           = 3
+	Q850_NORMAL_CALL_CLEARING              = 16
+	Q850_USER_BUSY                         = 17
+	Q850_NO_USER_RESPONDING                = 18
+	Q850_NO_ANSWER                         = 19
+	Q850_SUBSCRIBER_ABSENT                 = 20
+	Q850_CALL_REJECTED                     = 21
+	Q850_NUMBER_CHANGED                    = 22
+	Q850_REDIRECTION                       = 23
+	Q850_EXCHANGE_ROUTING_ERROR            = 25
+	Q850_NON_SELECTED_USER_CLEARING        = 26
+	Q850_DESTINATION_OUT_OF_ORDER          = 27
+	Q850_INVALID_NUMBER_FORMAT             = 28
+	Q850_FACILITY_REJECTED                 = 29
+	Q850_NORMAL_UNSPECIFIED                = 31
+	Q850_NO_CIRCUIT_AVAILABLE              = 34
+	Q850_NETWORK_OUT_OF_ORDER              = 38
+	Q850_TEMPORARY_FAILURE                 = 41
+	Q850_SWITCHING_EQUIPMENT_CONGESTION    = 42
+	Q850_REQUESTED_CIRCUIT_NOT_AVAILABLE   = 44
+	Q850_RESOURCE_UNAVAILABLE              = 47
+	Q850_INCOMING_CALLS_BARRED_WITHIN_CUG  = 55
+	Q850_BEARER_CAPABILITY_NOT_AUTHORIZED  = 57
+	Q850_BEARER_CAPABILITY_NOT_AVAILABLE   = 58
+	Q850_SERVICE_NOT_AVAILABLE             = 63
+	Q850_BEARER_CAPABILITY_NOT_IMPLEMENTED = 65
+	Q850_ONLY_RESTRICTED_DIGITAL_AVAILABLE = 70
+	Q850_SERVICE_NOT_IMPLEMENTED           = 79
+	Q850_USER_NOT_MEMBER_OF_CUG            = 87
+	Q850_INCOMPATIBLE_DESTINATION          = 88
+	Q850_RECOVERY_ON_TIMER_EXPIRY          = 102
+	Q850_PROTOCOL_ERROR                    = 111
+	Q850_INTERWORKING                      = 127
+)
+
+var q850Phrases = map[int]string{
+	Q850_UNALLOCATED_NUMBER:                "Unallocated (unassigned) number",
+	Q850_NO_ROUTE_TO_TRANSIT_NETWORK:       "No route to specified transit network",
+	Q850_NO_ROUTE_TO_DESTINATION:           "No route to destination",
+	Q850_NORMAL_CALL_CLEARING:              "Normal call clearing",
+	Q850_USER_BUSY:                         "User busy",
+	Q850_NO_USER_RESPONDING:                "No user responding",
+	Q850_NO_ANSWER:                         "No answer from user (user alerted)",
+	Q850_SUBSCRIBER_ABSENT:                 "Subscriber absent",
+	Q850_CALL_REJECTED:                     "Call rejected",
+	Q850_NUMBER_CHANGED:                    "Number changed",
+	Q850_REDIRECTION:                       "Redirection to new destination",
+	Q850_EXCHANGE_ROUTING_ERROR:            "Exchange routing error",
+	Q850_NON_SELECTED_USER_CLEARING:        "Non-selected user clearing",
+	Q850_DESTINATION_OUT_OF_ORDER:          "Destination out of order",
+	Q850_INVALID_NUMBER_FORMAT:             "Invalid number format (address incomplete)",
+	Q850_FACILITY_REJECTED:                 "Facility rejected",
+	Q850_NORMAL_UNSPECIFIED:                "Normal, unspecified",
+	Q850_NO_CIRCUIT_AVAILABLE:              "No circuit/channel available",
+	Q850_NETWORK_OUT_OF_ORDER:              "Network out of order",
+	Q850_TEMPORARY_FAILURE:                 "Temporary failure",
+	Q850_SWITCHING_EQUIPMENT_CONGESTION:    "Switching equipment congestion",
+	Q850_REQUESTED_CIRCUIT_NOT_AVAILABLE:   "Requested circuit/channel not available",
+	Q850_RESOURCE_UNAVAILABLE:              "Resource unavailable, unspecified",
+	Q850_INCOMING_CALLS_BARRED_WITHIN_CUG:  "Incoming calls barred within CUG",
+	Q850_BEARER_CAPABILITY_NOT_AUTHORIZED:  "Bearer capability not authorized",
+	Q850_BEARER_CAPABILITY_NOT_AVAILABLE:   "Bearer capability not presently available",
+	Q850_SERVICE_NOT_AVAILABLE:             "Service or option not available, unspecified",
+	Q850_BEARER_CAPABILITY_NOT_IMPLEMENTED: "Bearer capability not implemented",
+	Q850_ONLY_RESTRICTED_DIGITAL_AVAILABLE: "Only restricted digital information bearer capability is available",
+	Q850_SERVICE_NOT_IMPLEMENTED:           "Service or option not implemented, unspecified",
+	Q850_USER_NOT_MEMBER_OF_CUG:            "User not member of CUG",
+	Q850_INCOMPATIBLE_DESTINATION:          "Incompatible destination",
+	Q850_RECOVERY_ON_TIMER_EXPIRY:          "Recovery on timer expiry",
+	Q850_PROTOCOL_ERROR:                    "Protocol error, unspecified",
+	Q850_INTERWORKING:                      "Interworking, unspecified",
+}
+
+// q850StatusCodes maps the ISDN causes to the status codes of the SIP
+// responses an interworking gateway sends, RFC 3398 §7.2.4.1.
+var q850StatusCodes = map[int]int{
+	Q850_UNALLOCATED_NUMBER:                NOT_FOUND,
+	Q850_NO_ROUTE_TO_TRANSIT_NETWORK:       NOT_FOUND,
+	Q850_NO_ROUTE_TO_DESTINATION:           NOT_FOUND,
+	Q850_USER_BUSY:                         BUSY_HERE,
+	Q850_NO_USER_RESPONDING:                REQUEST_TIMEOUT,
+	Q850_NO_ANSWER:                         TEMPORARILY_UNAVAILABLE,
+	Q850_SUBSCRIBER_ABSENT:                 TEMPORARILY_UNAVAILABLE,
+	Q850_CALL_REJECTED:                     FORBIDDEN,
+	Q850_NUMBER_CHANGED:                    GONE,
+	Q850_REDIRECTION:                       GONE,
+	Q850_NON_SELECTED_USER_CLEARING:        NOT_FOUND,
+	Q850_DESTINATION_OUT_OF_ORDER:          BAD_GATEWAY,
+	Q850_INVALID_NUMBER_FORMAT:             ADDRESS_INCOMPLETE,
+	Q850_FACILITY_REJECTED:                 NOT_IMPLEMENTED,
+	Q850_NORMAL_UNSPECIFIED:                TEMPORARILY_UNAVAILABLE,
+	Q850_NO_CIRCUIT_AVAILABLE:              SERVICE_UNAVAILABLE,
+	Q850_NETWORK_OUT_OF_ORDER:              SERVICE_UNAVAILABLE,
+	Q850_TEMPORARY_FAILURE:                 SERVICE_UNAVAILABLE,
+	Q850_SWITCHING_EQUIPMENT_CONGESTION:    SERVICE_UNAVAILABLE,
+	Q850_RESOURCE_UNAVAILABLE:              SERVICE_UNAVAILABLE,
+	Q850_INCOMING_CALLS_BARRED_WITHIN_CUG:  FORBIDDEN,
+	Q850_BEARER_CAPABILITY_NOT_AUTHORIZED:  FORBIDDEN,
+	Q850_BEARER_CAPABILITY_NOT_AVAILABLE:   SERVICE_UNAVAILABLE,
+	Q850_BEARER_CAPABILITY_NOT_IMPLEMENTED: NOT_ACCEPTABLE_HERE,
+	Q850_ONLY_RESTRICTED_DIGITAL_AVAILABLE: NOT_ACCEPTABLE_HERE,
+	Q850_SERVICE_NOT_IMPLEMENTED:           NOT_IMPLEMENTED,
+	Q850_USER_NOT_MEMBER_OF_CUG:            FORBIDDEN,
+	Q850_INCOMPATIBLE_DESTINATION:          SERVICE_UNAVAILABLE,
+	Q850_RECOVERY_ON_TIMER_EXPIRY:          SERVER_TIMEOUT,
+	Q850_PROTOCOL_ERROR:                    SERVER_INTERNAL_ERROR,
+	Q850_INTERWORKING:                      SERVER_INTERNAL_ERROR,
+}
+
+// statusCodeCauses maps the status codes of SIP responses to the ISDN
+// causes an interworking gateway releases the call with, RFC 3398 §8.2.6.1.
+var statusCodeCauses = map[int]int{
+	BAD_REQUEST:                        Q850_TEMPORARY_FAILURE,
+	UNAUTHORIZED:                       Q850_CALL_REJECTED,
+	PAYMENT_REQUIRED:                   Q850_CALL_REJECTED,
+	FORBIDDEN:                          Q850_CALL_REJECTED,
+	NOT_FOUND:                          Q850_UNALLOCATED_NUMBER,
+	METHOD_NOT_ALLOWED:                 Q850_SERVICE_NOT_AVAILABLE,
+	NOT_ACCEPTABLE:                     Q850_SERVICE_NOT_IMPLEMENTED,
+	PROXY_AUTHENTICATION_REQUIRED:      Q850_CALL_REJECTED,
+	REQUEST_TIMEOUT:                    Q850_RECOVERY_ON_TIMER_EXPIRY,
+	GONE:                               Q850_NUMBER_CHANGED,
+	REQUEST_ENTITY_TOO_LARGE:           Q850_INTERWORKING,
+	REQUEST_URI_TOO_LONG:               Q850_INTERWORKING,
+	UNSUPPORTED_MEDIA_TYPE:             Q850_SERVICE_NOT_IMPLEMENTED,
+	UNSUPPORTED_URI_SCHEME:             Q850_INTERWORKING,
+	BAD_EXTENSION:                      Q850_INTERWORKING,
+	EXTENSION_REQUIRED:                 Q850_INTERWORKING,
+	INTERVAL_TOO_BRIEF:                 Q850_INTERWORKING,
+	TEMPORARILY_UNAVAILABLE:            Q850_NO_USER_RESPONDING,
+	CALL_OR_TRANSACTION_DOES_NOT_EXIST: Q850_TEMPORARY_FAILURE,
+	LOOP_DETECTED:                      Q850_EXCHANGE_ROUTING_ERROR,
+	TOO_MANY_HOPS:                      Q850_EXCHANGE_ROUTING_ERROR,
+	ADDRESS_INCOMPLETE:                 Q850_INVALID_NUMBER_FORMAT,
+	AMBIGUOUS:                          Q850_UNALLOCATED_NUMBER,
+	BUSY_HERE:                          Q850_USER_BUSY,
+	NOT_ACCEPTABLE_HERE:                Q850_INTERWORKING,
+	SERVER_INTERNAL_ERROR:              Q850_TEMPORARY_FAILURE,
+	NOT_IMPLEMENTED:                    Q850_SERVICE_NOT_IMPLEMENTED,
+	BAD_GATEWAY:                        Q850_NETWORK_OUT_OF_ORDER,
+	SERVICE_UNAVAILABLE:                Q850_TEMPORARY_FAILURE,
+	SERVER_TIMEOUT:                     Q850_RECOVERY_ON_TIMER_EXPIRY,
+	VERSION_NOT_SUPPORTED:              Q850_INTERWORKING,
+	MESSAGE_TOO_LARGE:                  Q850_INTERWORKING,
+	PRECONDITION_FAILURE:               Q850_RESOURCE_UNAVAILABLE,
+	BUSY_EVERYWHERE:                    Q850_USER_BUSY,
+	DECLINE:                            Q850_CALL_REJECTED,
+	DOES_NOT_EXIST_ANYWHERE:            Q850_UNALLOCATED_NUMBER,
+	SESSION_NOT_ACCEPTABLE:             Q850_BEARER_CAPABILITY_NOT_AVAILABLE,
+}
+
+// ReasonCause is one cause of a Reason header, e.g.
+// Q.850;cause=17;text="User busy".
+type ReasonCause struct {
+	Protocol string
+	Cause    int
+	Text     string
+}
+
+// CAUSE_CALL_COMPLETED_ELSEWHERE tells the CANCEL of an INVITE that was
+// answered on another branch not to report a missed call, RFC 3326 §2.
+var CAUSE_CALL_COMPLETED_ELSEWHERE = ReasonCause{REASON_PROTOCOL_SIP, OK, "Call completed elsewhere"}
+
+// NewSIPCause returns the SIP cause of statusCode, whose text is its reason
+// phrase.
+func NewSIPCause(statusCode int) ReasonCause {
+	return ReasonCause{REASON_PROTOCOL_SIP, statusCode, ReasonPhrase(statusCode)}
+}
+
+// NewQ850Cause returns the Q.850 cause of cause, whose text is its Q.850
+// description.
+func NewQ850Cause(cause int) ReasonCause {
+	return ReasonCause{REASON_PROTOCOL_Q850, cause, Q850CausePhrase(cause)}
+}
+
+// String encodes the cause as the value of a Reason header.
+func (this ReasonCause) String() string {
+	reason := header.NewReason()
+	reason.SetProtocol(this.Protocol)
+	reason.SetCause(this.Cause)
+	if this.Text != "" {
+		reason.SetText(this.Text)
+	}
+	return reason.EncodeBody()
+}
+
+// Q850CausePhrase returns the description of an ISDN cause, or "" if it is
+// unknown.
+func Q850CausePhrase(cause int) string {
+	return q850Phrases[cause]
+}
+
+// Q850ToStatusCode returns the status code of the SIP response that
+// reports an ISDN cause, RFC 3398 §7.2.4.1. A cause without a mapping of
+// its own is mapped by its class. Normal call clearing returns 0, it ends a
+// call rather than rejecting it.
+func Q850ToStatusCode(cause int) int {
+	if code, ok := q850StatusCodes[cause]; ok {
+		return code
+	}
+	switch {
+	case cause == Q850_NORMAL_CALL_CLEARING:
+		return 0
+	case cause < 32:
+		return TEMPORARILY_UNAVAILABLE
+	case cause < 64:
+		return SERVICE_UNAVAILABLE
+	case cause < 80:
+		return NOT_IMPLEMENTED
+	case cause < 96:
+		return BAD_REQUEST
+	default:
+		return SERVER_INTERNAL_ERROR
+	}
+}
+
+// StatusCodeToQ850 returns the ISDN cause that reports the status code of
+// a SIP response, RFC 3398 §8.2.6.1. A 2xx is a normal call clearing, a
+// provisional response has no cause and returns 0.
+func StatusCodeToQ850(statusCode int) int {
+	if cause, ok := statusCodeCauses[statusCode]; ok {
+		return cause
+	}
+	switch {
+	case statusCode < 200:
+		return 0
+	case statusCode < 300:
+		return Q850_NORMAL_CALL_CLEARING
+	case statusCode >= 500 && statusCode < 600:
+		return Q850_TEMPORARY_FAILURE
+	default:
+		return Q850_NORMAL_UNSPECIFIED
+	}
+}
+
+// GetReasons returns the causes of the Reason headers of msg.
+func GetReasons(msg Message) ([]ReasonCause, error) {
+	headers, err := parseHeaders(msg, "Reason")
+	if err != nil {
+		return nil, err
+	}
+	var causes []ReasonCause
+	for _, h := range headers {
+		for e := h.(*header.ReasonList).Front(); e != nil; e = e.Next() {
+			r := e.Value.(*header.Reason)
+			causes = append(causes, ReasonCause{r.GetProtocol(), r.GetCause(), r.GetText()})
+		}
+	}
+	return causes, nil
+}
+
+// SetReasons replaces the Reason of msg with causes, at most one per
+// protocol, RFC 3326 §2.
+func SetReasons(msg Message, causes ...ReasonCause) error {
+	values := make([]string, 0, len(causes))
+	for i, cause := range causes {
+		for _, other := range causes[:i] {
+			if strings.EqualFold(other.Protocol, cause.Protocol) {
+				return errors.New("a Reason carries a single " + cause.Protocol + " cause")
+			}
+		}
+		values = append(values, cause.String())
+	}
+	removeHeader(msg, "Reason")
+	if len(values) > 0 {
+		msg.GetHeader().Set("Reason", strings.Join(values, ", "))
+	}
+	return nil
+}
+
+// GetQ850Cause returns the ISDN cause of msg: the Q.850 cause of its
+// Reason, or else the one its SIP cause, or the status code of a response,
+// maps to. It returns 0 when msg tells none.
+func GetQ850Cause(msg Message) (int, error) {
+	causes, err := GetReasons(msg)
+	if err != nil {
+		return 0, err
+	}
+	sipCause := 0
+	for _, cause := range causes {
+		switch strings.ToUpper(cause.Protocol) {
+		case REASON_PROTOCOL_Q850:
+			return cause.Cause, nil
+		case REASON_PROTOCOL_SIP:
+			sipCause = cause.Cause
+		}
+	}
+	if resp, ok := msg.(Response); ok && sipCause == 0 {
+		sipCause = resp.GetStatusCode()
+	}
+	return StatusCodeToQ850(sipCause), nil
+}
+
+// CreateCancelWithReason builds the CANCEL of the INVITE of ct and tells
+// why in its Reason, e.g. CAUSE_CALL_COMPLETED_ELSEWHERE.
+func CreateCancelWithReason(ct ClientTransaction, causes ...ReasonCause) (Request, error) {
+	cancel, err := ct.CreateCancel()
+	if err != nil {
+		return nil, err
+	}
+	if err := SetReasons(cancel, causes...); err != nil {
+		return nil, err
+	}
+	return cancel, nil
+}
+
+// CreateByeWithReason builds a BYE within d and tells why the call ends in
+// its Reason.
+func CreateByeWithReason(d Dialog, causes ...ReasonCause) (Request, error) {
+	bye, err := d.CreateRequest(BYE)
+	if err != nil {
+		return nil, err
+	}
+	if err := SetReasons(bye, causes...); err != nil {
+		return nil, err
+	}
+	return bye, nil
+}
+
+// CreateErrorResponse builds a 4xx-6xx response to req whose Reason
+// carries the ISDN cause of the failure, RFC 6432. A cause of 0 is mapped
+// from statusCode.
+func CreateErrorResponse(statusCode int, req Request, cause int) (Response, error) {
+	if statusCode < 400 || statusCode > 699 {
+		return nil, errors.New("not an error status code")
+	}
+	resp, err := CreateResponse(statusCode, req)
+	if err != nil {
+		return nil, err
+	}
+	if cause == 0 {
+		cause = StatusCodeToQ850(statusCode)
+	}
+	if err := SetReasons(resp, NewQ850Cause(cause)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// TranslateReason carries the cause of incoming, a message a B2BUA
+// received on one leg, to outgoing, the message it sends on the other leg
+// as a result, such as the BYE that follows a BYE, or the error response
+// that follows an error response. The Q.850 cause of incoming, received or
+// mapped, is kept. A request also gets the SIP cause of incoming; a
+// response only carries the Q.850 cause, RFC 6432. outgoing is left alone
+// when incoming tells no cause.
+func TranslateReason(incoming, outgoing Message) error {
+	causes, err := GetReasons(incoming)
+	if err != nil {
+		return err
+	}
+	var sipCause *ReasonCause
+	for i, cause := range causes {
+		if strings.EqualFold(cause.Protocol, REASON_PROTOCOL_SIP) {
+			sipCause = &causes[i]
+		}
+	}
+	if resp, ok := incoming.(Response); ok && sipCause == nil && resp.GetStatusCode() >= 300 {
+		cause := NewSIPCause(resp.GetStatusCode())
+		sipCause = &cause
+	}
+	q850, err := GetQ850Cause(incoming)
+	if err != nil {
+		return err
+	}
+
+	var translated []ReasonCause
+	if _, ok := outgoing.(Request); ok && sipCause != nil {
+		translated = append(translated, *sipCause)
+	}
+	if q850 != 0 {
+		cause := NewQ850Cause(q850)
+		for _, c := range causes {
+			if strings.EqualFold(c.Protocol, REASON_PROTOCOL_Q850) && c.Text != "" {
+				cause.Text = c.Text
+			}
+		}
+		translated = append(translated, cause)
+	}
+	if len(translated) == 0 {
+		return nil
+	}
+	return SetReasons(outgoing, translated...)
+}
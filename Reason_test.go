@@ -0,0 +1,128 @@
+package sip
+
+import (
+	"testing"
+)
+
+func TestReasonCauseMapping(t *testing.T) {
+	for cause, code := range map[int]int{
+		Q850_USER_BUSY:                BUSY_HERE,
+		Q850_UNALLOCATED_NUMBER:       NOT_FOUND,
+		Q850_NO_ANSWER:                TEMPORARILY_UNAVAILABLE,
+		Q850_RECOVERY_ON_TIMER_EXPIRY: SERVER_TIMEOUT,
+		Q850_NORMAL_CALL_CLEARING:     0,
+		//by class
+		24: TEMPORARILY_UNAVAILABLE,
+		35: SERVICE_UNAVAILABLE,
+		66: NOT_IMPLEMENTED,
+	} {
+		if c := Q850ToStatusCode(cause); c != code {
+			t.Error(cause, c)
+		}
+	}
+	for code, cause := range map[int]int{
+		BUSY_HERE:               Q850_USER_BUSY,
+		DECLINE:                 Q850_CALL_REJECTED,
+		TEMPORARILY_UNAVAILABLE: Q850_NO_USER_RESPONDING,
+		OK:                      Q850_NORMAL_CALL_CLEARING,
+		RINGING:                 0,
+		REQUEST_TERMINATED:      Q850_NORMAL_UNSPECIFIED,
+		599:                     Q850_TEMPORARY_FAILURE,
+	} {
+		if c := StatusCodeToQ850(code); c != cause {
+			t.Error(code, c)
+		}
+	}
+	if s := NewQ850Cause(Q850_USER_BUSY).String(); s != "Q.850;cause=17;text=\"User busy\"" {
+		t.Error(s)
+	}
+}
+
+func TestCancelWithReason(t *testing.T) {
+	ct := newClientTransaction(testInvite(t, false))
+	cancel, err := CreateCancelWithReason(ct, CAUSE_CALL_COMPLETED_ELSEWHERE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := cancel.GetHeader().Get("Reason"); r != "SIP;cause=200;text=\"Call completed elsewhere\"" {
+		t.Error(r)
+	}
+	causes, err := GetReasons(cancel)
+	if err != nil || len(causes) != 1 || causes[0] != CAUSE_CALL_COMPLETED_ELSEWHERE {
+		t.Error(causes, err)
+	}
+	if err := SetReasons(cancel, NewSIPCause(OK), NewSIPCause(BUSY_HERE)); err == nil {
+		t.Error("set two SIP causes")
+	}
+}
+
+func TestByeWithReason(t *testing.T) {
+	d := newTestConfirmedDialog(t, testInvite(t, true))
+	bye, err := CreateByeWithReason(d, NewSIPCause(OK), NewQ850Cause(Q850_NORMAL_CALL_CLEARING))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := bye.GetHeader().Get("Reason"); r != "SIP;cause=200;text=\"OK\", Q.850;cause=16;text=\"Normal call clearing\"" {
+		t.Error(r)
+	}
+	if cause, _ := GetQ850Cause(bye); cause != Q850_NORMAL_CALL_CLEARING {
+		t.Error(cause)
+	}
+}
+
+func TestErrorResponseWithReason(t *testing.T) {
+	invite := testInvite(t, false)
+	resp, err := CreateErrorResponse(BUSY_HERE, invite, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := resp.GetHeader().Get("Reason"); r != "Q.850;cause=17;text=\"User busy\"" {
+		t.Error(r)
+	}
+	resp, _ = CreateErrorResponse(SERVICE_UNAVAILABLE, invite, Q850_SWITCHING_EQUIPMENT_CONGESTION)
+	if cause, _ := GetQ850Cause(resp); cause != Q850_SWITCHING_EQUIPMENT_CONGESTION {
+		t.Error(cause)
+	}
+	if _, err := CreateErrorResponse(OK, invite, 0); err == nil {
+		t.Error("created a 200 error response")
+	}
+}
+
+func TestTranslateReason(t *testing.T) {
+	//the far end rejects with a cause of its own, which the B2BUA reports
+	//to the caller
+	far, _ := CreateErrorResponse(SERVICE_UNAVAILABLE, testInvite(t, false), Q850_NO_CIRCUIT_AVAILABLE)
+	near, _ := CreateResponse(Q850ToStatusCode(Q850_NO_CIRCUIT_AVAILABLE), testInvite(t, false))
+	if err := TranslateReason(far, near); err != nil {
+		t.Fatal(err)
+	}
+	if r := near.GetHeader().Get("Reason"); r != "Q.850;cause=34;text=\"No circuit/channel available\"" {
+		t.Error(r)
+	}
+
+	//a response without Reason is mapped
+	far, _ = CreateResponse(BUSY_HERE, testInvite(t, false))
+	near, _ = CreateResponse(BUSY_HERE, testInvite(t, false))
+	TranslateReason(far, near)
+	if r := near.GetHeader().Get("Reason"); r != "Q.850;cause=17;text=\"User busy\"" {
+		t.Error(r)
+	}
+
+	//a BYE with a SIP cause carries both causes to the other leg
+	d := newTestConfirmedDialog(t, testInvite(t, true))
+	farBye, _ := CreateByeWithReason(d, NewSIPCause(BUSY_EVERYWHERE))
+	nearBye, _ := d.CreateRequest(BYE)
+	TranslateReason(farBye, nearBye)
+	causes, _ := GetReasons(nearBye)
+	if len(causes) != 2 || causes[0].Cause != BUSY_EVERYWHERE || causes[1] != NewQ850Cause(Q850_USER_BUSY) {
+		t.Error(causes)
+	}
+
+	//nothing to tell
+	plain, _ := d.CreateRequest(BYE)
+	nearBye, _ = d.CreateRequest(BYE)
+	TranslateReason(plain, nearBye)
+	if r := nearBye.GetHeader().Get("Reason"); r != "" {
+		t.Error(r)
+	}
+}
@@ -0,0 +1,145 @@
+package sip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"sip/address"
+	"sip/header"
+	"strconv"
+	"strings"
+)
+
+// REFER_EXPIRES is the duration, in seconds, of the implicit subscription
+// of an accepted REFER. It leaves time for the INVITE it triggers to
+// complete, Timer C being 3 minutes.
+const REFER_EXPIRES = 180
+
+// REFER_EVENT is the event package of the implicit subscription created by
+// REFER, RFC 3515 §2.4.4.
+const REFER_EVENT = "refer"
+
+// NewBlindTransfer builds the REFER asking the remote party of d to call
+// target, RFC 5589 §6. The local party of d is named in Referred-By.
+func NewBlindTransfer(d Dialog, target string) (Request, error) {
+	return newTransfer(d, "<"+target+">")
+}
+
+// NewAttendedTransfer builds the REFER asking the remote party of d to
+// call the remote party of consultation and replace that dialog, RFC 5589
+// §7. The Replaces header embedded in the Refer-To identifies consultation
+// from the point of view of the transfer target.
+func NewAttendedTransfer(d Dialog, consultation Dialog) (Request, error) {
+	target := consultation.GetRemoteTarget()
+	if target == "" {
+		return nil, errors.New("the consultation dialog has no remote target")
+	}
+	replaces := consultation.GetCallId() +
+		";to-tag=" + consultation.GetRemoteTag() +
+		";from-tag=" + consultation.GetLocalTag()
+	return newTransfer(d, "<"+target+"?Replaces="+escapeURIHeader(replaces)+">")
+}
+
+func newTransfer(d Dialog, referTo string) (Request, error) {
+	refer, err := d.CreateRequest(REFER)
+	if err != nil {
+		return nil, err
+	}
+	refer.GetHeader().Set("Refer-To", referTo)
+	refer.GetHeader().Set("Referred-By", d.GetLocalParty())
+	return refer, nil
+}
+
+// NewReferredRequest builds the request the recipient of refer sends to the
+// Refer-To target, RFC 3515 §2.4.2. The method is INVITE unless the URI
+// has a method parameter, the headers embedded in the URI, such as
+// Replaces, are added to the request and the Referred-By of the REFER is
+// copied, RFC 3892 §3. The caller completes the request with its From,
+// Call-ID, CSeq and Via.
+func NewReferredRequest(refer Request) (Request, error) {
+	h, err := parseHeader(refer, "Refer-To")
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.New("missing Refer-To header")
+	}
+	to := h.(*header.ReferTo).GetAddress()
+	uri := to.GetURI()
+
+	method := INVITE
+	var headers []string
+	if sipURI, ok := uri.(*address.SipURIImpl); ok {
+		if m := sipURI.GetMethodParam(); m != "" {
+			method = strings.ToUpper(m)
+		}
+		if names := sipURI.GetHeaderNames(); names != nil {
+			for e := names.GetNames().Front(); e != nil; e = e.Next() {
+				name := e.Value.(string)
+				//the inverse of escapeURIHeader, a "+" stands for itself
+				value, err := url.PathUnescape(sipURI.GetHeader(name))
+				if err != nil {
+					return nil, err
+				}
+				headers = append(headers, name, value)
+			}
+		}
+		sipURI = sipURI.Clone().(*address.SipURIImpl)
+		sipURI.RemoveHeaders()
+		sipURI.RemoveMethod()
+		uri = sipURI
+	}
+
+	req := NewRequest(method, uri.String(), nil)
+	req.GetHeader().Set("To", to.String())
+	for i := 0; i < len(headers); i += 2 {
+		req.GetHeader().Add(headers[i], headers[i+1])
+	}
+	for _, v := range headerValues(refer, "Referred-By") {
+		req.GetHeader().Add("Referred-By", v)
+	}
+	return req, nil
+}
+
+// NewSipfrag returns the message/sipfrag body reporting statusCode in the
+// NOTIFYs of a REFER subscription, RFC 3515 §2.4.5.
+func NewSipfrag(statusCode int) []byte {
+	return []byte("SIP/2.0 " + strconv.Itoa(statusCode) + " " + ReasonPhrase(statusCode) + "\r\n")
+}
+
+// ParseSipfrag returns the status code reported by a message/sipfrag
+// NOTIFY of a REFER subscription.
+func ParseSipfrag(notify Request) (int, error) {
+	body, err := bodyBytes(notify)
+	if err != nil {
+		return 0, err
+	}
+	line, err := bufio.NewReader(bytes.NewReader(body)).ReadString('\n')
+	if err != nil && line == "" {
+		return 0, errors.New("empty sipfrag body")
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "SIP/2.0" {
+		return 0, errors.New("malformed sipfrag status line: " + strings.TrimSpace(line))
+	}
+	return strconv.Atoi(fields[1])
+}
+
+// noReferSub reports whether msg carries "Refer-Sub: false", RFC 4488.
+func noReferSub(msg Message) bool {
+	value := headerValues(msg, "Refer-Sub")
+	if len(value) == 0 {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(strings.SplitN(value[0], ";", 2)[0]), "false")
+}
+
+// referEvent returns the Event header of the implicit subscription of
+// refer, its id is the CSeq of the REFER.
+func referEvent(refer Request) *header.Event {
+	event := header.NewEvent()
+	event.SetEventType(REFER_EVENT)
+	event.SetEventId(strconv.Itoa(cSeqOf(refer)))
+	return event
+}
@@ -0,0 +1,180 @@
+package sip
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testRefer(t *testing.T, referTo string, extra string) Request {
+	return testRequest(t, REFER, "sip:bob@biloxi.com", []string{
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bKrefer1",
+		"Max-Forwards: 70",
+		"To: <sip:bob@biloxi.com>",
+		"From: <sip:alice@atlanta.com>;tag=xfg9",
+		"Call-ID: 2010b0e0a5@pc33.atlanta.com",
+		"CSeq: 93809823 REFER",
+		"Contact: <sip:alice@pc33.atlanta.com>",
+		"Refer-To: " + referTo,
+		"Referred-By: <sip:alice@atlanta.com>",
+		extra,
+	}, "")
+}
+
+func newTestConfirmedDialog(t *testing.T, invite Request) *dialog {
+	d, st := newTestServerDialog(t, invite)
+	st.SendResponse(sdpResponse(st.GetRequest(), OK))
+	return d
+}
+
+func TestBlindTransfer(t *testing.T) {
+	d := newTestConfirmedDialog(t, testInvite(t, true))
+	refer, err := NewBlindTransfer(d, "sip:carol@chicago.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := refer.GetHeader()
+	if refer.GetMethod() != REFER || refer.GetRequestURI() != "sip:alice@pc33.atlanta.com" ||
+		h.Get("Refer-To") != "<sip:carol@chicago.com>" || h.Get("Referred-By") != d.GetLocalParty() {
+		t.Log(refer.GetRequestURI(), h)
+		t.Fail()
+	}
+}
+
+func TestAttendedTransfer(t *testing.T) {
+	d := newTestConfirmedDialog(t, testInvite(t, true))
+	consultation := newTestConfirmedDialog(t, testInvite(t, false))
+	refer, err := NewAttendedTransfer(d, consultation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if referTo := refer.GetHeader().Get("Refer-To"); referTo != "<sip:alice@pc33.atlanta.com?Replaces="+
+		"a84b4c76e66710%40pc33.atlanta.com%3Bto-tag%3D1928301774%3Bfrom-tag%3D"+consultation.GetLocalTag()+">" {
+		t.Error(referTo)
+	}
+
+	invite, err := NewReferredRequest(refer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := invite.GetHeader()
+	replaces := "a84b4c76e66710@pc33.atlanta.com;to-tag=1928301774;from-tag=" + consultation.GetLocalTag()
+	if invite.GetMethod() != INVITE || invite.GetRequestURI() != "sip:alice@pc33.atlanta.com" ||
+		h.Get("Replaces") != replaces || h.Get("Referred-By") != d.GetLocalParty() {
+		t.Log(invite.GetMethod(), invite.GetRequestURI(), h)
+		t.Fail()
+	}
+}
+
+func TestReferredRequestMethod(t *testing.T) {
+	invite, err := NewReferredRequest(testRefer(t, "<sip:conf@example.com;method=SUBSCRIBE?Event=conference>", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invite.GetMethod() != SUBSCRIBE || invite.GetRequestURI() != "sip:conf@example.com" ||
+		invite.GetHeader().Get("Event") != "conference" {
+		t.Log(invite.GetMethod(), invite.GetRequestURI(), invite.GetHeader())
+		t.Fail()
+	}
+}
+
+func TestNotifierRefer(t *testing.T) {
+	p := &testProvider{}
+	n := NewNotifier(p)
+	st := newTestServerTransaction(testRefer(t, "<sip:carol@chicago.com>", ""))
+	sub, err := n.ProcessRefer(*NewRequestEvent(st, st.GetRequest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != ACCEPTED || resp.GetHeader().Get("Contact") != "<sip:bob@biloxi.com>" {
+		t.Log(resp.GetStatusCode(), resp.GetHeader())
+		t.Fail()
+	}
+
+	notify := p.lastRequest(t)
+	h := notify.GetHeader()
+	if h.Get("Event") != "refer;id=93809823" || !strings.HasPrefix(h.Get("Subscription-State"), "active;expires=") ||
+		h.Get("Content-Type") != "message/sipfrag" {
+		t.Log(h)
+		t.Fail()
+	}
+	if code, err := ParseSipfrag(notify); err != nil || code != TRYING {
+		t.Log(code, err)
+		t.Fail()
+	}
+
+	if err := n.NotifyReferStatus(sub, OK); err != nil {
+		t.Fatal(err)
+	}
+	notify = p.lastRequest(t)
+	if notify.GetHeader().Get("Subscription-State") != "terminated;reason=noresource" {
+		t.Log(notify.GetHeader())
+		t.Fail()
+	}
+	if code, _ := ParseSipfrag(notify); code != OK {
+		t.Log(code)
+		t.Fail()
+	}
+	if sub.GetDialog().GetState() != DIALOGSTATE_TERMINATED || len(n.GetSubscriptions(REFER_EVENT)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestNotifierReferNoSubscription(t *testing.T) {
+	p := &testProvider{}
+	n := NewNotifier(p)
+	st := newTestServerTransaction(testRefer(t, "<sip:carol@chicago.com>", "Refer-Sub: false"))
+	sub, err := n.ProcessRefer(*NewRequestEvent(st, st.GetRequest()))
+	if err != nil || sub != nil {
+		t.Fatal(sub, err)
+	}
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != ACCEPTED || resp.GetHeader().Get("Refer-Sub") != "false" || len(p.transactions) != 0 {
+		t.Log(resp.GetStatusCode(), resp.GetHeader())
+		t.Fail()
+	}
+}
+
+func TestSubscriberRefer(t *testing.T) {
+	p := &testProvider{}
+	l := &testSubscriberListener{}
+	s := NewSubscriber(p, l)
+	refer := testRefer(t, "<sip:carol@chicago.com>", "Refer-Sub: false")
+	sub, err := s.Refer(nil, refer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.GetEventType() != REFER_EVENT || sub.GetEventId() != "93809823" {
+		t.Log(sub.GetEventType(), sub.GetEventId())
+		t.Fail()
+	}
+
+	resp := newResponseFor(refer, ACCEPTED)
+	resp.GetHeader().Set("Refer-Sub", "false")
+	s.ProcessResponse(*NewResponseEvent(p.lastTransaction(t), resp))
+	if sub.GetState() != SUBSCRIPTIONSTATE_TERMINATED || len(l.terminated) != 1 || len(s.GetSubscriptions()) != 0 {
+		t.Log(sub.GetState(), l.terminated)
+		t.Fail()
+	}
+}
+
+func TestParseSipfrag(t *testing.T) {
+	for i, test := range []struct {
+		body string
+		code int
+	}{
+		{"SIP/2.0 180 Ringing\r\n", RINGING},
+		{"SIP/2.0 603 Declined\r\nContent-Length: 0\r\n\r\n", DECLINE},
+		{"INVITE sip:carol@chicago.com SIP/2.0\r\n", 0},
+	} {
+		notify := testNotify(t, "b1", i+1, "active")
+		notify.SetBody(strings.NewReader(test.body))
+		notify.SetContentLength(int64(len(test.body)))
+		code, err := ParseSipfrag(notify)
+		if code != test.code || (err != nil) != (test.code == 0) {
+			t.Log(strconv.Itoa(i), code, err)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package sip
+
+import (
+	"errors"
+	"sip/address"
+	"sip/header"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_REGISTER_EXPIRES is the duration, in seconds, of the bindings whose
+// REGISTER asks for none, RFC 3261 §10.2.1.1.
+const DEFAULT_REGISTER_EXPIRES = 3600
+
+// PATH_OPTION_TAG is the option tag of the Path extension, RFC 3327 §4.
+const PATH_OPTION_TAG = "path"
+
+// Binding is a contact address registered for an address-of-record, RFC
+// 3261 §10.3.
+type Binding struct {
+	// AddressOfRecord is the address-of-record the binding belongs to.
+	AddressOfRecord string
+	// Contact is the Contact value registered, without expires parameter.
+	Contact string
+	// URI is the contact URI the requests for AddressOfRecord are sent to.
+	URI     string
+	Expires time.Time
+	CallId  string
+	CSeq    int
+	// Path is the Path vector of the REGISTER, RFC 3327 §5.3. The requests
+	// for the binding are routed through it.
+	Path []string
+}
+
+////////////////////Interface//////////////////////////////
+
+// Registrar keeps the bindings of the addresses-of-record registered with
+// it, RFC 3261 §10.3. The application hands it the REGISTER requests it
+// receives, once they are authenticated, and looks the bindings up to route
+// the requests for an address-of-record.
+type Registrar interface {
+	// SetMinExpires sets the shortest registration accepted, shorter ones
+	// are refused with 423 Interval Too Brief.
+	SetMinExpires(seconds int)
+	// SetDefaultExpires sets the duration of the bindings whose REGISTER
+	// asks for none, DEFAULT_REGISTER_EXPIRES unless told otherwise.
+	SetDefaultExpires(seconds int)
+	// SetServiceRoute sets the Service-Route of the 2xx responses, the
+	// proxies the user agents route their requests through, RFC 3608 §6.
+	SetServiceRoute(routes ...string)
+	GetServiceRoute() []string
+
+	// ProcessRegister adds, refreshes or removes the bindings of the
+	// address-of-record in the To of a REGISTER, with the Path vector of the
+	// request, and answers it with the bindings that remain.
+	ProcessRegister(requestEvent RequestEvent) error
+	// GetBindings returns the bindings of an address-of-record that didn't
+	// expire.
+	GetBindings(addressOfRecord string) []Binding
+}
+
+////////////////////Implementation////////////////////////
+
+type registrar struct {
+	mutex          sync.Mutex
+	minExpires     int
+	defaultExpires int
+	serviceRoute   []string
+
+	bindings map[string][]Binding
+}
+
+// NewRegistrar returns a registrar without bindings. It adds the path
+// option tag to the extensions the validator of p supports.
+func NewRegistrar(p Provider) Registrar {
+	if v := p.GetValidator(); !containsFold(v.GetSupportedExtensions(), PATH_OPTION_TAG) {
+		v.SetSupportedExtensions(append(v.GetSupportedExtensions(), PATH_OPTION_TAG)...)
+	}
+	return &registrar{
+		minExpires:     DEFAULT_MIN_EXPIRES,
+		defaultExpires: DEFAULT_REGISTER_EXPIRES,
+		bindings:       make(map[string][]Binding),
+	}
+}
+
+func (this *registrar) SetMinExpires(seconds int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.minExpires = seconds
+}
+
+func (this *registrar) SetDefaultExpires(seconds int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.defaultExpires = seconds
+}
+
+func (this *registrar) SetServiceRoute(routes ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.serviceRoute = routes
+}
+
+func (this *registrar) GetServiceRoute() []string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]string(nil), this.serviceRoute...)
+}
+
+func (this *registrar) ProcessRegister(requestEvent RequestEvent) error {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != REGISTER {
+		return errors.New("Registrar.ProcessRegister can't process " + req.GetMethod())
+	}
+	h, err := parseHeader(req, "To")
+	if err != nil || h == nil {
+		st.SendResponse(newBadRequest(req, "Malformed To Header"))
+		return errors.New("malformed To header")
+	}
+	aor := addressOfRecord(h.(*header.To).GetAddress().GetURI())
+	contacts, err := parseHeaders(req, "Contact")
+	if err != nil {
+		st.SendResponse(newBadRequest(req, "Malformed Contact Header"))
+		return err
+	}
+	callId := req.GetHeader().Get("Call-ID")
+	cSeq := cSeqOf(req)
+	path := headerListEntries(req, "Path")
+
+	this.mutex.Lock()
+	now := time.Now()
+	bindings := this.liveBindings(aor, now)
+	updated := append([]Binding(nil), bindings...)
+	statusCode, reasonPhrase := OK, ""
+	for _, h := range contacts {
+		for _, c := range h.(*header.ContactList).GetContacts() {
+			contact := c.(*header.Contact)
+			if contact.GetWildCardFlag() {
+				//RFC 3261 §10.3 step 6, "*" only removes every binding
+				if len(contacts) > 1 || h.(*header.ContactList).Len() > 1 || expiresOf(req) != 0 {
+					statusCode, reasonPhrase = BAD_REQUEST, "Invalid Wildcard Contact"
+					break
+				}
+				updated = nil
+				for _, b := range bindings {
+					if b.CallId == callId && b.CSeq >= cSeq {
+						statusCode = SERVER_INTERNAL_ERROR
+						updated = bindings
+						break
+					}
+				}
+				continue
+			}
+			expires := expiresOf(req)
+			if contact.HasParameter(header.ParameterNames_EXPIRES) {
+				expires = contact.GetExpires()
+			}
+			if expires < 0 {
+				expires = this.defaultExpires
+			}
+			if expires > 0 && expires < this.minExpires {
+				statusCode = INTERVAL_TOO_BRIEF
+				break
+			}
+			contact.RemoveParameter(header.ParameterNames_EXPIRES)
+			b := Binding{
+				AddressOfRecord: aor,
+				Contact:         contact.EncodeBody(),
+				URI:             contact.GetAddress().GetURI().String(),
+				Expires:         now.Add(time.Duration(expires) * time.Second),
+				CallId:          callId,
+				CSeq:            cSeq,
+				Path:            path,
+			}
+			i := indexOfBinding(updated, b.URI)
+			if i >= 0 && updated[i].CallId == callId && updated[i].CSeq >= cSeq {
+				//RFC 3261 §10.3 step 7, an out of order REGISTER
+				statusCode = SERVER_INTERNAL_ERROR
+				break
+			}
+			if i >= 0 {
+				updated = append(updated[:i], updated[i+1:]...)
+			}
+			if expires > 0 {
+				updated = append(updated, b)
+			}
+		}
+		if statusCode != OK {
+			break
+		}
+	}
+	if statusCode == OK {
+		this.bindings[aor] = updated
+		bindings = updated
+		if len(updated) == 0 {
+			delete(this.bindings, aor)
+		}
+	}
+	minExpires := this.minExpires
+	serviceRoute := this.serviceRoute
+	this.mutex.Unlock()
+
+	if statusCode != OK {
+		var resp Response
+		if reasonPhrase != "" {
+			resp = newBadRequest(req, reasonPhrase)
+		} else {
+			resp = newResponseFor(req, statusCode)
+		}
+		if statusCode == INTERVAL_TOO_BRIEF {
+			resp.GetHeader().Set("Min-Expires", strconv.Itoa(minExpires))
+		}
+		return st.SendResponse(resp)
+	}
+
+	resp := newResponseFor(req, OK)
+	for _, b := range bindings {
+		expires := int(b.Expires.Sub(now) / time.Second)
+		resp.GetHeader().Add("Contact", b.Contact+";expires="+strconv.Itoa(expires))
+	}
+	//RFC 3327 §5.3, the Path is only returned to a user agent supporting it
+	if len(path) > 0 && containsFold(optionTagsOf(req, "Supported"), PATH_OPTION_TAG) {
+		resp.GetHeader().Set("Path", strings.Join(path, ", "))
+	}
+	if len(serviceRoute) > 0 {
+		resp.GetHeader().Set("Service-Route", strings.Join(serviceRoute, ", "))
+	}
+	return st.SendResponse(resp)
+}
+
+func (this *registrar) GetBindings(aor string) []Binding {
+	if uri, err := parseURI(aor); err == nil {
+		aor = addressOfRecord(uri)
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]Binding(nil), this.liveBindings(aor, time.Now())...)
+}
+
+// liveBindings returns the bindings of aor that didn't expire at now, and
+// forgets the others.
+func (this *registrar) liveBindings(aor string, now time.Time) []Binding {
+	var bindings []Binding
+	for _, b := range this.bindings[aor] {
+		if b.Expires.After(now) {
+			bindings = append(bindings, b)
+		}
+	}
+	if len(bindings) == 0 {
+		delete(this.bindings, aor)
+	} else {
+		this.bindings[aor] = bindings
+	}
+	return bindings
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// AddPath adds a Path header naming uri to a REGISTER forwarded by a proxy
+// on the way to the registrar, RFC 3327 §5.2. uri should carry the lr
+// parameter. When require is true, "path" is added to the Require header
+// so that a registrar not supporting Path rejects the request.
+func AddPath(register Request, uri string, require bool) error {
+	if register.GetMethod() != REGISTER {
+		return errors.New("Path is only added to a REGISTER")
+	}
+	if _, err := parseURI(uri); err != nil {
+		return err
+	}
+	path := append([]string{"<" + uri + ">"}, headerListEntries(register, "Path")...)
+	removeHeader(register, "Path")
+	register.GetHeader().Set("Path", strings.Join(path, ", "))
+	if require && !containsFold(optionTagsOf(register, "Require"), PATH_OPTION_TAG) {
+		register.GetHeader().Add("Require", PATH_OPTION_TAG)
+	}
+	return nil
+}
+
+// RouteToBinding addresses req to the contact of b, and routes it through
+// the Path vector of b as a preloaded route set, RFC 3327 §5.3.
+func RouteToBinding(req Request, b Binding) error {
+	if err := req.SetRequestURI(b.URI); err != nil {
+		return err
+	}
+	return PreloadRoute(req, b.Path...)
+}
+
+// GetServiceRoute returns the Service-Route entries of the 2xx response to
+// a REGISTER, one by one, RFC 3608 §6.1. The provider keeps them as the
+// preloaded route set of the requests outside of any dialog its
+// CreateRequest builds, until the next registration.
+func GetServiceRoute(resp Response) []string {
+	return headerListEntries(resp, "Service-Route")
+}
+
+// PreloadRoute puts routes above the Route headers of req, a request
+// outside of any dialog, RFC 3261 §8.1.2 and RFC 3608 §6.1.
+func PreloadRoute(req Request, routes ...string) error {
+	if len(routes) == 0 {
+		return nil
+	}
+	if h, err := parseHeader(req, "To"); err == nil && h != nil && h.(*header.To).GetTag() != "" {
+		return errors.New("a request within a dialog follows the route set of the dialog")
+	}
+	routes = append(append([]string(nil), routes...), headerValues(req, "Route")...)
+	removeHeader(req, "Route")
+	for _, route := range routes {
+		req.GetHeader().Add("Route", route)
+	}
+	return nil
+}
+
+// addressOfRecord returns the address-of-record of a To URI, RFC 3261
+// §10.3 step 5: the URI without its parameters and headers, with the host
+// in lower case.
+func addressOfRecord(uri address.URI) string {
+	if sipURI, ok := uri.(*address.SipURIImpl); ok {
+		aor := strings.ToLower(sipURI.GetScheme()) + ":"
+		if user := sipURI.GetUser(); user != "" {
+			aor += user + "@"
+		}
+		return aor + strings.ToLower(sipURI.GetHostPort().String())
+	}
+	return uri.String()
+}
+
+// indexOfBinding returns the index of the binding of bindings whose contact
+// is uri, -1 if there is none.
+func indexOfBinding(bindings []Binding, uri string) int {
+	for i, b := range bindings {
+		if strings.EqualFold(b.URI, uri) {
+			return i
+		}
+	}
+	return -1
+}
+
+// headerListEntries returns the entries of the named list header of msg
+// one by one, in the order they appear, such as the routes of a Path.
+func headerListEntries(msg Message, name string) []string {
+	var entries []string
+	headers, err := parseHeaders(msg, name)
+	if err != nil {
+		return nil
+	}
+	for _, h := range headers {
+		for e := h.(header.Lister).Front(); e != nil; e = e.Next() {
+			entries = append(entries, e.Value.(header.Header).EncodeBody())
+		}
+	}
+	return entries
+}
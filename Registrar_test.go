@@ -0,0 +1,163 @@
+package sip
+
+import (
+	"sip/header"
+	"strconv"
+	"testing"
+)
+
+func testRegister(t *testing.T, cSeq int, contact, extra string) Request {
+	return testRequest(t, REGISTER, "sip:registrar.home.example.com", []string{
+		"Via: SIP/2.0/UDP p1.visited.example.com;branch=z9hG4bK-p1",
+		"Via: SIP/2.0/UDP ue.visited.example.com;branch=z9hG4bK-ue",
+		"Max-Forwards: 69",
+		"To: Bob <sip:Bob@HOME.example.com>",
+		"From: Bob <sip:Bob@home.example.com>;tag=456248",
+		"Call-ID: 843817637684230@998sdasdh09",
+		"CSeq: " + strconv.Itoa(cSeq) + " REGISTER",
+		"Contact: " + contact,
+		extra,
+	}, "")
+}
+
+func TestRegistrarPath(t *testing.T) {
+	p := newProvider(nil)
+	r := NewRegistrar(p)
+	r.SetServiceRoute("<sip:orig@scscf.home.example.com;lr>")
+	if !containsFold(p.GetValidator().GetSupportedExtensions(), "path") {
+		t.Error("the validator doesn't support path")
+	}
+
+	register := testRegister(t, 1, "<sip:bob@192.0.2.4>", "Supported: path")
+	if err := AddPath(register, "sip:p1.visited.example.com;lr", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddPath(register, "sip:p2.home.example.com;lr", true); err != nil {
+		t.Fatal(err)
+	}
+	if path := register.GetHeader().Get("Path"); path != "<sip:p2.home.example.com;lr>, <sip:p1.visited.example.com;lr>" {
+		t.Error(path)
+	}
+	if require := register.GetHeader().Get("Require"); require != "path" {
+		t.Error(require)
+	}
+
+	st := newTestServerTransaction(register)
+	if err := r.ProcessRegister(*NewRequestEvent(st, register)); err != nil {
+		t.Fatal(err)
+	}
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != OK {
+		t.Fatal(resp.GetStatusCode())
+	}
+	if contact := resp.GetHeader().Get("Contact"); contact != "<sip:bob@192.0.2.4>;expires=3599" && contact != "<sip:bob@192.0.2.4>;expires=3600" {
+		t.Error(contact)
+	}
+	if resp.GetHeader().Get("Path") != register.GetHeader().Get("Path") {
+		t.Error(resp.GetHeader().Get("Path"))
+	}
+	serviceRoute := GetServiceRoute(resp)
+	if len(serviceRoute) != 1 || serviceRoute[0] != "<sip:orig@scscf.home.example.com;lr>" {
+		t.Error(serviceRoute)
+	}
+
+	bindings := r.GetBindings("sip:bob@home.example.com")
+	if len(bindings) != 0 {
+		t.Error("the user part of an address-of-record is case sensitive")
+	}
+	bindings = r.GetBindings("sip:Bob@home.example.com")
+	if len(bindings) != 1 || len(bindings[0].Path) != 2 {
+		t.Fatal(bindings)
+	}
+	invite := testInvite(t, false)
+	invite.GetHeader().Del("Route")
+	if err := RouteToBinding(invite, bindings[0]); err != nil {
+		t.Fatal(err)
+	}
+	if invite.GetRequestURI() != "sip:bob@192.0.2.4" {
+		t.Error(invite.GetRequestURI())
+	}
+	if routes := invite.GetHeader()["Route"]; len(routes) != 2 || routes[0] != "<sip:p2.home.example.com;lr>" {
+		t.Error(routes)
+	}
+
+	//the UA preloads the Service-Route on its next requests
+	options := testRegister(t, 2, "<sip:bob@192.0.2.4>", "")
+	options.SetMethod(OPTIONS)
+	options.GetHeader().Set("Route", "<sip:extra.example.com;lr>")
+	if err := PreloadRoute(options, serviceRoute...); err != nil {
+		t.Fatal(err)
+	}
+	if routes := options.GetHeader()["Route"]; len(routes) != 2 || routes[0] != serviceRoute[0] {
+		t.Error(routes)
+	}
+	options.GetHeader().Set("To", "Bob <sip:Bob@home.example.com>;tag=a6c85cf")
+	if err := PreloadRoute(options, serviceRoute...); err == nil {
+		t.Error("preloaded a route on a request within a dialog")
+	}
+}
+
+func TestRegistrarBindings(t *testing.T) {
+	r := NewRegistrar(&testProvider{})
+	register := func(cSeq int, contact, extra string) Response {
+		st := newTestServerTransaction(testRegister(t, cSeq, contact, extra))
+		r.ProcessRegister(*NewRequestEvent(st, st.GetRequest()))
+		return st.lastResponse(t)
+	}
+
+	if resp := register(1, "<sip:bob@192.0.2.4>;expires=10", ""); resp.GetStatusCode() != INTERVAL_TOO_BRIEF ||
+		resp.GetHeader().Get("Min-Expires") != "60" {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if resp := register(2, "<sip:bob@192.0.2.4>, <sip:bob@192.0.2.5>;expires=120", "Path: <sip:p1.example.com;lr>"); resp.GetStatusCode() != OK ||
+		len(resp.GetHeader()["Contact"]) != 2 || resp.GetHeader().Get("Path") != "" {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if resp := register(2, "<sip:bob@192.0.2.4>", ""); resp.GetStatusCode() != SERVER_INTERNAL_ERROR {
+		t.Error("an out of order REGISTER was accepted", resp.GetStatusCode())
+	}
+	if resp := register(3, "<sip:bob@192.0.2.5>;expires=0", ""); resp.GetStatusCode() != OK ||
+		len(resp.GetHeader()["Contact"]) != 1 {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if resp := register(4, "*", ""); resp.GetStatusCode() != BAD_REQUEST {
+		t.Error(resp.GetStatusCode())
+	}
+	if resp := register(5, "*", "Expires: 0"); resp.GetStatusCode() != OK || len(resp.GetHeader()["Contact"]) != 0 {
+		t.Error(resp.GetStatusCode(), resp.GetHeader())
+	}
+	if bindings := r.GetBindings("sip:Bob@home.example.com"); len(bindings) != 0 {
+		t.Error(bindings)
+	}
+}
+
+func TestServiceRoutePreloaded(t *testing.T) {
+	p := newTestDispatchProvider()
+	register := testRegister(t, 1, "<sip:bob@192.0.2.4>", "")
+	p.GetNewClientTransaction(register)
+	ok := newResponseFor(register, OK)
+	ok.GetHeader().Set("Service-Route", "<sip:orig@scscf.home.example.com;lr>")
+	p.dispatchResponse(ok)
+
+	h, _ := parseHeaderValue("From", "Bob <sip:Bob@home.example.com>")
+	from := h.(*header.From).GetAddress()
+	h, _ = parseHeaderValue("To", "Alice <sip:alice@atlanta.com>")
+	to := h.(*header.To).GetAddress()
+	uri, _ := parseURI("sip:alice@atlanta.com")
+	invite, err := p.CreateRequest(INVITE, uri, from, to, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes := headerValues(invite, "Route"); len(routes) != 1 || routes[0] != "<sip:orig@scscf.home.example.com;lr>" {
+		t.Error(routes)
+	}
+
+	//a registration without Service-Route forgets it
+	register = testRegister(t, 2, "<sip:bob@192.0.2.4>", "")
+	register.GetHeader().Set("Via", "SIP/2.0/UDP ue.visited.example.com;branch=z9hG4bK-ue2")
+	p.GetNewClientTransaction(register)
+	p.dispatchResponse(newResponseFor(register, OK))
+	if invite, _ = p.CreateRequest(INVITE, uri, from, to, nil); len(headerValues(invite, "Route")) != 0 {
+		t.Error(headerValues(invite, "Route"))
+	}
+}
@@ -0,0 +1,89 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+)
+
+// findReplaced returns the dialog named by the Replaces and Join headers of
+// an initial INVITE, RFC 3891 §3 and RFC 3911 §4. The dialog is matched from
+// the local point of view: its local tag is the to-tag and its remote tag
+// the from-tag. A non zero status code rejects the INVITE.
+func (this *provider) findReplaced(req Request) (replaced, joined *dialog, code int) {
+	replaces, joins := headerValues(req, "Replaces"), headerValues(req, "Join")
+	switch {
+	case len(replaces) == 0 && len(joins) == 0:
+		return nil, nil, 0
+	case len(replaces)+len(joins) > 1:
+		return nil, nil, BAD_REQUEST
+	}
+
+	var callId, toTag, fromTag string
+	earlyOnly := false
+	if len(replaces) > 0 {
+		h, err := parseHeader(req, "Replaces")
+		if err != nil || h == nil {
+			return nil, nil, BAD_REQUEST
+		}
+		r := h.(*header.Replaces)
+		callId, toTag, fromTag, earlyOnly = r.GetCallId(), r.GetToTag(), r.GetFromTag(), r.IsEarlyOnly()
+	} else {
+		h, err := parseHeader(req, "Join")
+		if err != nil || h == nil {
+			return nil, nil, BAD_REQUEST
+		}
+		j := h.(*header.Join)
+		callId, toTag, fromTag = j.GetCallId(), j.GetToTag(), j.GetFromTag()
+	}
+
+	d := this.getDialog(dialogId(callId, toTag, fromTag))
+	if d == nil {
+		return nil, nil, CALL_OR_TRANSACTION_DOES_NOT_EXIST
+	}
+	switch state := d.GetState(); {
+	case state == DIALOGSTATE_TERMINATED:
+		return nil, nil, DECLINE
+	case state == DIALOGSTATE_EARLY && d.IsServer():
+		//only the early dialogs this UA initiated can be replaced
+		return nil, nil, CALL_OR_TRANSACTION_DOES_NOT_EXIST
+	case state != DIALOGSTATE_EARLY && earlyOnly:
+		return nil, nil, BUSY_HERE
+	}
+	if len(replaces) > 0 {
+		return d, nil, 0
+	}
+	return nil, d, 0
+}
+
+// endReplaced shuts down the dialog replaced by this one once this one is
+// accepted: a confirmed dialog is ended with a BYE and an early dialog with
+// a CANCEL of its INVITE, RFC 3891 §3.
+func (this *dialog) endReplaced() error {
+	this.mutex.Lock()
+	d := this.replaced
+	this.replaced = nil
+	this.mutex.Unlock()
+	if d == nil || d.provider == nil {
+		return nil
+	}
+
+	switch d.GetState() {
+	case DIALOGSTATE_CONFIRMED:
+		bye, err := d.CreateRequest(BYE)
+		if err != nil {
+			return err
+		}
+		return d.SendRequest(d.provider.GetNewClientTransaction(bye))
+	case DIALOGSTATE_EARLY:
+		ct, ok := d.GetFirstTransaction().(ClientTransaction)
+		if !ok {
+			return errors.New("only an early dialog initiated locally can be replaced")
+		}
+		cancel, err := ct.CreateCancel()
+		if err != nil {
+			return err
+		}
+		return d.provider.GetNewClientTransaction(cancel).SendRequest()
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package sip
+
+import (
+	"testing"
+)
+
+// newTestDispatchProvider returns a provider whose requests are dispatched
+// without transport.
+func newTestDispatchProvider() *provider {
+	return newProvider(TraceOff())
+}
+
+// testClientTransaction returns the client transaction p created for a
+// request of the given method.
+func testClientTransaction(t *testing.T, p *provider, method string) ClientTransaction {
+	p.transactionMutex.Lock()
+	defer p.transactionMutex.Unlock()
+	for _, ct := range p.clientTransactions {
+		if ct.GetRequest().GetMethod() == method {
+			return ct
+		}
+	}
+	t.Fatal("no " + method + " was sent")
+	return nil
+}
+
+func testReplacesInvite(t *testing.T, headers ...string) Request {
+	return testRequest(t, INVITE, "sip:bob@biloxi.com", append([]string{
+		"Via: SIP/2.0/UDP pc33.chicago.com;branch=z9hG4bKcarol1",
+		"Max-Forwards: 70",
+		"To: Bob <sip:bob@biloxi.com>",
+		"From: Carol <sip:carol@chicago.com>;tag=8674",
+		"Call-ID: 09o8u97@pc33.chicago.com",
+		"CSeq: 1 INVITE",
+		"Contact: <sip:carol@pc33.chicago.com>",
+	}, headers...), "")
+}
+
+// dispatchTestRequest dispatches req through p and returns the event handed
+// to the listeners, or nil when the provider answered the request itself.
+func dispatchTestRequest(p *provider, req Request) *RequestEvent {
+	p.dispatchRequest(req)
+	if event, ok := p.nextEvent().(*RequestEvent); ok {
+		return event
+	}
+	return nil
+}
+
+func newTestConfirmedProviderDialog(t *testing.T, p *provider) Dialog {
+	event := dispatchTestRequest(p, testInvite(t, false))
+	st := event.GetServerTransaction()
+	d, err := p.GetNewDialog(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SendResponse(newResponseFor(st.GetRequest(), OK))
+	return d
+}
+
+func TestReplacesConfirmedDialog(t *testing.T) {
+	p := newTestDispatchProvider()
+	d := newTestConfirmedProviderDialog(t, p)
+
+	event := dispatchTestRequest(p, testReplacesInvite(t,
+		"Replaces: a84b4c76e66710@pc33.atlanta.com;to-tag="+d.GetLocalTag()+";from-tag=1928301774"))
+	if event == nil || event.GetReplacedDialog() != d || event.GetJoinedDialog() != nil {
+		t.Fatal("the Replaces header did not match the dialog")
+	}
+	st := event.GetServerTransaction()
+	if _, err := p.GetNewDialog(st); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SendResponse(newResponseFor(st.GetRequest(), OK)); err != nil {
+		t.Fatal(err)
+	}
+
+	bye := testClientTransaction(t, p, BYE).GetRequest()
+	if bye.GetMethod() != BYE || bye.GetHeader().Get("Call-ID") != "a84b4c76e66710@pc33.atlanta.com" {
+		t.Log(bye.GetMethod(), bye.GetHeader())
+		t.Fail()
+	}
+}
+
+func TestReplacesRejected(t *testing.T) {
+	p := newTestDispatchProvider()
+	d := newTestConfirmedProviderDialog(t, p)
+
+	for i, test := range []struct {
+		headers []string
+		code    int
+	}{
+		{[]string{"Replaces: a84b4c76e66710@pc33.atlanta.com;to-tag=unknown;from-tag=1928301774"}, CALL_OR_TRANSACTION_DOES_NOT_EXIST},
+		{[]string{"Replaces: a84b4c76e66710@pc33.atlanta.com;to-tag=" + d.GetLocalTag() + ";from-tag=1928301774;early-only"}, BUSY_HERE},
+		{[]string{"Replaces: a84b4c76e66710@pc33.atlanta.com;to-tag=" + d.GetLocalTag() + ";from-tag=1928301774",
+			"Join: a84b4c76e66710@pc33.atlanta.com;to-tag=" + d.GetLocalTag() + ";from-tag=1928301774"}, BAD_REQUEST},
+	} {
+		req := testReplacesInvite(t, test.headers...)
+		if event := dispatchTestRequest(p, req); event != nil {
+			t.Log(i, "the INVITE was not rejected")
+			t.Fail()
+		}
+		if replaced, _, code := p.findReplaced(req); replaced != nil || code != test.code {
+			t.Log(i, code)
+			t.Fail()
+		}
+	}
+}
+
+func TestReplacesEarlyDialog(t *testing.T) {
+	p := newTestDispatchProvider()
+	event := dispatchTestRequest(p, testInvite(t, false))
+	st := event.GetServerTransaction()
+	d, _ := p.GetNewDialog(st)
+	st.SendResponse(newResponseFor(st.GetRequest(), RINGING))
+
+	//an early dialog this UA did not initiate can't be replaced
+	replaces := "Replaces: a84b4c76e66710@pc33.atlanta.com;to-tag=" + d.GetLocalTag() + ";from-tag=1928301774;early-only"
+	if _, _, code := p.findReplaced(testReplacesInvite(t, replaces)); code != CALL_OR_TRANSACTION_DOES_NOT_EXIST {
+		t.Log(code)
+		t.Fail()
+	}
+}
+
+func TestJoin(t *testing.T) {
+	p := newTestDispatchProvider()
+	d := newTestConfirmedProviderDialog(t, p)
+
+	event := dispatchTestRequest(p, testReplacesInvite(t,
+		"Join: a84b4c76e66710@pc33.atlanta.com;to-tag="+d.GetLocalTag()+";from-tag=1928301774"))
+	if event == nil || event.GetJoinedDialog() != d || event.GetReplacedDialog() != nil {
+		t.Fatal("the Join header did not match the dialog")
+	}
+}
+
+func TestCreateCancel(t *testing.T) {
+	invite := testInvite(t, false)
+	invite.GetHeader().Set("Route", "<sip:p1.example.com;lr>")
+	cancel, err := newClientTransaction(invite).CreateCancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := cancel.GetHeader()
+	if cancel.GetMethod() != CANCEL || cancel.GetRequestURI() != invite.GetRequestURI() ||
+		h.Get("CSeq") != "314159 CANCEL" || h.Get("Via") != invite.GetHeader().Get("Via") ||
+		h.Get("From") != invite.GetHeader().Get("From") || h.Get("Route") != "<sip:p1.example.com;lr>" {
+		t.Log(cancel.GetRequestURI(), h)
+		t.Fail()
+	}
+}
@@ -30,6 +30,7 @@ const (
 	INFO      = "INFO"
 	PRACK     = "PRACK"
 	UPDATE    = "UPDATE"
+	PUBLISH   = "PUBLISH"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
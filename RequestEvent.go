@@ -19,3 +19,29 @@ func (this *RequestEvent) GetServerTransaction() ServerTransaction {
 func (this *RequestEvent) GetRequest() Request {
 	return this.request
 }
+
+func (this *RequestEvent) GetDialog() Dialog {
+	if this.transaction == nil {
+		return nil
+	}
+	return this.transaction.GetDialog()
+}
+
+// GetReplacedDialog returns the dialog named by the Replaces header of an
+// INVITE, RFC 3891. The dialog created for the INVITE ends it once the
+// INVITE is accepted.
+func (this *RequestEvent) GetReplacedDialog() Dialog {
+	if st, ok := this.transaction.(*serverTransaction); ok && st.replaces != nil {
+		return st.replaces
+	}
+	return nil
+}
+
+// GetJoinedDialog returns the dialog named by the Join header of an INVITE,
+// RFC 3911, the application mixes the new dialog with it.
+func (this *RequestEvent) GetJoinedDialog() Dialog {
+	if st, ok := this.transaction.(*serverTransaction); ok && st.joins != nil {
+		return st.joins
+	}
+	return nil
+}
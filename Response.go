@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sip/header"
 	"strings"
 )
 
@@ -39,6 +40,7 @@ const (
 	PROXY_AUTHENTICATION_REQUIRED      = 407
 	REQUEST_TIMEOUT                    = 408
 	GONE                               = 410
+	CONDITIONAL_REQUEST_FAILED         = 412
 	REQUEST_ENTITY_TOO_LARGE           = 413
 	REQUEST_URI_TOO_LONG               = 414
 	UNSUPPORTED_MEDIA_TYPE             = 415
@@ -46,6 +48,10 @@ const (
 	BAD_EXTENSION                      = 420
 	EXTENSION_REQUIRED                 = 421
 	INTERVAL_TOO_BRIEF                 = 423
+	USE_IDENTITY_HEADER                = 428
+	BAD_IDENTITY_INFO                  = 436
+	UNSUPPORTED_CREDENTIAL             = 437
+	INVALID_IDENTITY_HEADER            = 438
 	TEMPORARILY_UNAVAILABLE            = 480
 	CALL_OR_TRANSACTION_DOES_NOT_EXIST = 481
 	LOOP_DETECTED                      = 482
@@ -55,9 +61,11 @@ const (
 	BUSY_HERE                          = 486
 	REQUEST_TERMINATED                 = 487
 	NOT_ACCEPTABLE_HERE                = 488
+	BAD_INFO_PACKAGE                   = 469
 	BAD_EVENT                          = 489
 	REQUEST_PENDING                    = 491
 	UNDECIPHERABLE                     = 493
+	SECURITY_AGREEMENT_REQUIRED        = 494
 	SERVER_INTERNAL_ERROR              = 500
 	NOT_IMPLEMENTED                    = 501
 	BAD_GATEWAY                        = 502
@@ -65,12 +73,82 @@ const (
 	SERVER_TIMEOUT                     = 504
 	VERSION_NOT_SUPPORTED              = 505
 	MESSAGE_TOO_LARGE                  = 513
+	PRECONDITION_FAILURE               = 580
 	BUSY_EVERYWHERE                    = 600
 	DECLINE                            = 603
 	DOES_NOT_EXIST_ANYWHERE            = 604
 	SESSION_NOT_ACCEPTABLE             = 606
 )
 
+var reasonPhrases = map[int]string{
+	TRYING:                             "Trying",
+	RINGING:                            "Ringing",
+	CALL_IS_BEING_FORWARDED:            "Call Is Being Forwarded",
+	QUEUED:                             "Queued",
+	SESSION_PROGRESS:                   "Session Progress",
+	OK:                                 "OK",
+	ACCEPTED:                           "Accepted",
+	MULTIPLE_CHOICES:                   "Multiple Choices",
+	MOVED_PERMANENTLY:                  "Moved Permanently",
+	MOVED_TEMPORARILY:                  "Moved Temporarily",
+	USE_PROXY:                          "Use Proxy",
+	ALTERNATIVE_SERVICE:                "Alternative Service",
+	BAD_REQUEST:                        "Bad Request",
+	UNAUTHORIZED:                       "Unauthorized",
+	PAYMENT_REQUIRED:                   "Payment Required",
+	FORBIDDEN:                          "Forbidden",
+	NOT_FOUND:                          "Not Found",
+	METHOD_NOT_ALLOWED:                 "Method Not Allowed",
+	NOT_ACCEPTABLE:                     "Not Acceptable",
+	PROXY_AUTHENTICATION_REQUIRED:      "Proxy Authentication Required",
+	REQUEST_TIMEOUT:                    "Request Timeout",
+	GONE:                               "Gone",
+	CONDITIONAL_REQUEST_FAILED:         "Conditional Request Failed",
+	REQUEST_ENTITY_TOO_LARGE:           "Request Entity Too Large",
+	REQUEST_URI_TOO_LONG:               "Request-URI Too Long",
+	UNSUPPORTED_MEDIA_TYPE:             "Unsupported Media Type",
+	UNSUPPORTED_URI_SCHEME:             "Unsupported URI Scheme",
+	BAD_EXTENSION:                      "Bad Extension",
+	EXTENSION_REQUIRED:                 "Extension Required",
+	INTERVAL_TOO_BRIEF:                 "Interval Too Brief",
+	USE_IDENTITY_HEADER:                "Use Identity Header",
+	BAD_IDENTITY_INFO:                  "Bad Identity Info",
+	UNSUPPORTED_CREDENTIAL:             "Unsupported Credential",
+	INVALID_IDENTITY_HEADER:            "Invalid Identity Header",
+	TEMPORARILY_UNAVAILABLE:            "Temporarily Unavailable",
+	CALL_OR_TRANSACTION_DOES_NOT_EXIST: "Call/Transaction Does Not Exist",
+	LOOP_DETECTED:                      "Loop Detected",
+	TOO_MANY_HOPS:                      "Too Many Hops",
+	ADDRESS_INCOMPLETE:                 "Address Incomplete",
+	AMBIGUOUS:                          "Ambiguous",
+	BUSY_HERE:                          "Busy Here",
+	REQUEST_TERMINATED:                 "Request Terminated",
+	NOT_ACCEPTABLE_HERE:                "Not Acceptable Here",
+	BAD_INFO_PACKAGE:                   "Bad Info Package",
+	BAD_EVENT:                          "Bad Event",
+	REQUEST_PENDING:                    "Request Pending",
+	UNDECIPHERABLE:                     "Undecipherable",
+	SECURITY_AGREEMENT_REQUIRED:        "Security Agreement Required",
+	SERVER_INTERNAL_ERROR:              "Server Internal Error",
+	NOT_IMPLEMENTED:                    "Not Implemented",
+	BAD_GATEWAY:                        "Bad Gateway",
+	SERVICE_UNAVAILABLE:                "Service Unavailable",
+	SERVER_TIMEOUT:                     "Server Time-out",
+	VERSION_NOT_SUPPORTED:              "Version Not Supported",
+	MESSAGE_TOO_LARGE:                  "Message Too Large",
+	PRECONDITION_FAILURE:               "Precondition Failure",
+	BUSY_EVERYWHERE:                    "Busy Everywhere",
+	DECLINE:                            "Decline",
+	DOES_NOT_EXIST_ANYWHERE:            "Does Not Exist Anywhere",
+	SESSION_NOT_ACCEPTABLE:             "Not Acceptable",
+}
+
+// ReasonPhrase returns the RFC 3261 default reason phrase for statusCode,
+// or "" if the code is unknown.
+func ReasonPhrase(statusCode int) string {
+	return reasonPhrases[statusCode]
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 type response struct {
 	message
@@ -129,3 +207,23 @@ func (this *response) StartLineWrite(w io.Writer) (err error) {
 	}
 	return nil
 }
+
+//newResponseFor builds a response to req carrying the headers RFC 3261
+//§8.2.6 requires to be copied from the request. A To tag is added to
+//anything but 100 Trying when the request did not have one.
+func newResponseFor(req Request, statusCode int) *response {
+	resp := NewResponse(statusCode, ReasonPhrase(statusCode), nil)
+	for _, name := range []string{"Via", "From", "Call-ID", "CSeq", "Timestamp"} {
+		for _, v := range headerValues(req, name) {
+			resp.GetHeader().Add(name, v)
+		}
+	}
+	if h, err := parseHeader(req, "To"); err == nil && h != nil {
+		to := h.(*header.To)
+		if !to.HasTag() && statusCode != TRYING {
+			to.SetTag(generateTag())
+		}
+		resp.GetHeader().Set("To", to.EncodeBody())
+	}
+	return resp
+}
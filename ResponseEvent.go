@@ -19,3 +19,10 @@ func (this *ResponseEvent) GetClientTransaction() ClientTransaction {
 func (this *ResponseEvent) GetResponse() Response {
 	return this.response
 }
+
+func (this *ResponseEvent) GetDialog() Dialog {
+	if this.transaction == nil {
+		return nil
+	}
+	return this.transaction.GetDialog()
+}
@@ -0,0 +1,313 @@
+package sip
+
+import (
+	"errors"
+	"sip/address"
+	"sip/header"
+	"strings"
+	"sync"
+)
+
+// SEC_AGREE_OPTION_TAG is the option tag of the security mechanism
+// agreement, RFC 3329 §2.3.
+const SEC_AGREE_OPTION_TAG = "sec-agree"
+
+// The security mechanisms of RFC 3329 §2.2 and 3GPP TS 33.203.
+const (
+	SECURITY_MECHANISM_DIGEST     = "digest"
+	SECURITY_MECHANISM_TLS        = "tls"
+	SECURITY_MECHANISM_IPSEC_IKE  = "ipsec-ike"
+	SECURITY_MECHANISM_IPSEC_MAN  = "ipsec-man"
+	SECURITY_MECHANISM_IPSEC_3GPP = "ipsec-3gpp"
+)
+
+var ErrNoCommonMechanism = errors.New("the server supports none of the security mechanisms of the client")
+
+////////////////////Interface//////////////////////////////
+
+// SecurityAgreement is the client side of the agreement of a user agent
+// with its first hop on the security mechanism protecting the requests
+// between them, RFC 3329 §2.3.
+type SecurityAgreement interface {
+	// GetMechanisms returns the mechanisms the client supports.
+	GetMechanisms() []string
+
+	// OfferSecurity adds to req, the first request sent to the first hop,
+	// the Security-Client listing the mechanisms of the client, and
+	// requires sec-agree from the first hop.
+	OfferSecurity(req Request) error
+	// ProcessResponse chooses, among the Security-Server mechanisms of the
+	// 494 or 401 answering the offer, the one with the highest preference
+	// the client supports, and returns it.
+	ProcessResponse(resp Response) (string, error)
+	// GetMechanism returns the mechanism chosen, "" until the server
+	// answered.
+	GetMechanism() string
+	// ProtectRequest adds to req, a request sent with the chosen mechanism,
+	// the Security-Verify echoing the Security-Server received, and sends
+	// it over TLS when the mechanism is tls.
+	ProtectRequest(req Request) error
+}
+
+// SecurityPolicy is the server side of the agreement, the first hop of the
+// user agents, RFC 3329 §2.3.
+type SecurityPolicy interface {
+	// GetMechanisms returns the mechanisms the server supports.
+	GetMechanisms() []string
+
+	// ProcessRequest answers with 494 Security Agreement Required the
+	// requests requiring sec-agree without Security-Verify, listing the
+	// mechanisms of the server in Security-Server, and the requests whose
+	// Security-Verify isn't that list, which a man-in-the-middle removing
+	// the strongest mechanisms would have sent. It returns false when it
+	// answered the request.
+	ProcessRequest(requestEvent RequestEvent) (bool, error)
+	// AddSecurityServer lists the mechanisms of the server in resp, a
+	// 401 challenging a request offering mechanisms.
+	AddSecurityServer(resp Response)
+}
+
+////////////////////Implementation////////////////////////
+
+type securityAgreement struct {
+	mutex      sync.Mutex
+	mechanisms []header.SecurityAgreeHeader
+	server     []header.SecurityAgreeHeader
+	mechanism  string
+}
+
+// NewSecurityAgreement returns the client side of an agreement, whose
+// mechanisms are sec-mechanism values such as "tls" or "digest;d-alg=md5".
+func NewSecurityAgreement(mechanisms ...string) (SecurityAgreement, error) {
+	m, err := parseMechanisms("Security-Client", mechanisms)
+	if err != nil {
+		return nil, err
+	}
+	return &securityAgreement{mechanisms: m}, nil
+}
+
+func (this *securityAgreement) GetMechanisms() []string {
+	return encodeMechanisms(this.mechanisms)
+}
+
+func (this *securityAgreement) OfferSecurity(req Request) error {
+	removeHeader(req, "Security-Client")
+	req.GetHeader().Set("Security-Client", strings.Join(this.GetMechanisms(), ", "))
+	requireSecAgree(req)
+	return nil
+}
+
+func (this *securityAgreement) ProcessResponse(resp Response) (string, error) {
+	if resp.GetStatusCode() != SECURITY_AGREEMENT_REQUIRED && resp.GetStatusCode() != UNAUTHORIZED {
+		return "", errors.New("a security agreement is answered by 494 or 401")
+	}
+	server, err := securityMechanisms(resp, "Security-Server")
+	if err != nil {
+		return "", err
+	}
+	var chosen header.SecurityAgreeHeader
+	for _, s := range server {
+		supported := false
+		for _, m := range this.mechanisms {
+			supported = supported || strings.EqualFold(m.GetSecurityMechanism(), s.GetSecurityMechanism())
+		}
+		if supported && (chosen == nil || s.GetPreference() > chosen.GetPreference()) {
+			chosen = s
+		}
+	}
+	if chosen == nil {
+		return "", ErrNoCommonMechanism
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.server = server
+	this.mechanism = strings.ToLower(chosen.GetSecurityMechanism())
+	return this.mechanism, nil
+}
+
+func (this *securityAgreement) GetMechanism() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.mechanism
+}
+
+func (this *securityAgreement) ProtectRequest(req Request) error {
+	this.mutex.Lock()
+	server, mechanism := this.server, this.mechanism
+	this.mutex.Unlock()
+	if mechanism == "" {
+		return errors.New("no security mechanism was agreed on")
+	}
+	removeHeader(req, "Security-Verify")
+	req.GetHeader().Set("Security-Verify", strings.Join(encodeMechanisms(server), ", "))
+	requireSecAgree(req)
+	if mechanism == SECURITY_MECHANISM_TLS {
+		return useTLS(req)
+	}
+	return nil
+}
+
+type securityPolicy struct {
+	mechanisms []header.SecurityAgreeHeader
+}
+
+// NewSecurityPolicy returns the server side of an agreement, whose
+// mechanisms are sec-mechanism values with their preference, such as
+// "tls;q=0.2". It adds the sec-agree option tag to the extensions the
+// validator of p supports.
+func NewSecurityPolicy(p Provider, mechanisms ...string) (SecurityPolicy, error) {
+	m, err := parseMechanisms("Security-Server", mechanisms)
+	if err != nil {
+		return nil, err
+	}
+	if v := p.GetValidator(); !containsFold(v.GetSupportedExtensions(), SEC_AGREE_OPTION_TAG) {
+		v.SetSupportedExtensions(append(v.GetSupportedExtensions(), SEC_AGREE_OPTION_TAG)...)
+	}
+	return &securityPolicy{mechanisms: m}, nil
+}
+
+func (this *securityPolicy) GetMechanisms() []string {
+	return encodeMechanisms(this.mechanisms)
+}
+
+func (this *securityPolicy) ProcessRequest(requestEvent RequestEvent) (bool, error) {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	verify, err := securityMechanisms(req, "Security-Verify")
+	if err != nil {
+		return false, st.SendResponse(newBadRequest(req, "Malformed Security-Verify Header"))
+	}
+	if len(verify) == 0 {
+		if !containsFold(optionTagsOf(req, "Require"), SEC_AGREE_OPTION_TAG) &&
+			!containsFold(optionTagsOf(req, "Proxy-Require"), SEC_AGREE_OPTION_TAG) {
+			return true, nil
+		}
+		resp := newResponseFor(req, SECURITY_AGREEMENT_REQUIRED)
+		this.AddSecurityServer(resp)
+		return false, st.SendResponse(resp)
+	}
+	//RFC 3329 §2.3 step 5, the list echoed must be the list sent
+	if !sameMechanisms(verify, this.mechanisms) {
+		resp := newResponseFor(req, SECURITY_AGREEMENT_REQUIRED)
+		this.AddSecurityServer(resp)
+		st.SendResponse(resp)
+		return false, errors.New("the Security-Verify of the request isn't the Security-Server, a downgrade attack")
+	}
+	return true, nil
+}
+
+func (this *securityPolicy) AddSecurityServer(resp Response) {
+	removeHeader(resp, "Security-Server")
+	resp.GetHeader().Set("Security-Server", strings.Join(this.GetMechanisms(), ", "))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parseMechanisms returns the sec-mechanism values of mechanisms as the
+// named header parses them.
+func parseMechanisms(name string, mechanisms []string) ([]header.SecurityAgreeHeader, error) {
+	if len(mechanisms) == 0 {
+		return nil, errors.New("no security mechanism")
+	}
+	h, err := parseHeaderValue(name, strings.Join(mechanisms, ", "))
+	if err != nil {
+		return nil, err
+	}
+	return listMechanisms(h), nil
+}
+
+// securityMechanisms returns the mechanisms of the named Security-Client,
+// Security-Server or Security-Verify headers of msg.
+func securityMechanisms(msg Message, name string) ([]header.SecurityAgreeHeader, error) {
+	headers, err := parseHeaders(msg, name)
+	if err != nil {
+		return nil, err
+	}
+	var mechanisms []header.SecurityAgreeHeader
+	for _, h := range headers {
+		mechanisms = append(mechanisms, listMechanisms(h)...)
+	}
+	return mechanisms, nil
+}
+
+func listMechanisms(h header.Header) []header.SecurityAgreeHeader {
+	var mechanisms []header.SecurityAgreeHeader
+	for e := h.(header.Lister).Front(); e != nil; e = e.Next() {
+		mechanisms = append(mechanisms, e.Value.(header.SecurityAgreeHeader))
+	}
+	return mechanisms
+}
+
+func encodeMechanisms(mechanisms []header.SecurityAgreeHeader) []string {
+	values := make([]string, len(mechanisms))
+	for i, m := range mechanisms {
+		values[i] = m.EncodeBody()
+	}
+	return values
+}
+
+// sameMechanisms reports whether a and b hold the same mechanisms with the
+// same parameters, in any order.
+func sameMechanisms(a, b []header.SecurityAgreeHeader) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		found := false
+		for _, n := range b {
+			found = found || strings.EqualFold(m.EncodeBody(), n.EncodeBody())
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// requireSecAgree requires sec-agree from the first hop of req, RFC 3329
+// §2.3 step 1.
+func requireSecAgree(req Request) {
+	for _, name := range []string{"Require", "Proxy-Require"} {
+		if !containsFold(optionTagsOf(req, name), SEC_AGREE_OPTION_TAG) {
+			req.GetHeader().Add(name, SEC_AGREE_OPTION_TAG)
+		}
+	}
+}
+
+// useTLS sends req over TLS to its first hop, the topmost Route or else the
+// Request-URI, through the transport parameter of its URI.
+func useTLS(req Request) error {
+	routes := headerListEntries(req, "Route")
+	if len(routes) == 0 {
+		uri, err := parseURI(req.GetRequestURI())
+		if err != nil {
+			return err
+		}
+		sipURI, ok := uri.(*address.SipURIImpl)
+		if !ok {
+			return errors.New("the first hop of the request isn't a SIP URI")
+		}
+		if err := sipURI.SetTransportParam(TLS); err != nil {
+			return err
+		}
+		return req.SetRequestURI(sipURI.String())
+	}
+	h, err := parseHeaderValue("Route", routes[0])
+	if err != nil {
+		return err
+	}
+	route := h.(header.Lister).Front().Value.(header.AddressHeader)
+	sipURI, ok := route.GetAddress().GetURI().(*address.SipURIImpl)
+	if !ok {
+		return errors.New("the first hop of the request isn't a SIP URI")
+	}
+	if err := sipURI.SetTransportParam(TLS); err != nil {
+		return err
+	}
+	routes[0] = route.(header.Header).EncodeBody()
+	removeHeader(req, "Route")
+	for _, r := range routes {
+		req.GetHeader().Add("Route", r)
+	}
+	return nil
+}
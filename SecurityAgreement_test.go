@@ -0,0 +1,107 @@
+package sip
+
+import (
+	"testing"
+)
+
+func TestSecurityAgreement(t *testing.T) {
+	client, err := NewSecurityAgreement("tls", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newProvider(nil)
+	server, err := NewSecurityPolicy(p, "ipsec-ike;q=0.1", "tls;q=0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsFold(p.GetValidator().GetSupportedExtensions(), SEC_AGREE_OPTION_TAG) {
+		t.Error("the validator doesn't support sec-agree")
+	}
+
+	//RFC 3329 §3, the client offers its mechanisms
+	req := testInvite(t, false)
+	if err := client.OfferSecurity(req); err != nil {
+		t.Fatal(err)
+	}
+	h := req.GetHeader()
+	if h.Get("Security-Client") != "tls, digest" || h.Get("Require") != "sec-agree" || h.Get("Proxy-Require") != "sec-agree" {
+		t.Error(h)
+	}
+	st := newTestServerTransaction(req)
+	if ok, err := server.ProcessRequest(*NewRequestEvent(st, req)); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != SECURITY_AGREEMENT_REQUIRED || resp.GetHeader().Get("Security-Server") != "ipsec-ike;q=0.1, tls;q=0.2" {
+		t.Fatal(resp.GetStatusCode(), resp.GetHeader())
+	}
+
+	//the client picks tls and echoes the list of the server
+	if mechanism, err := client.ProcessResponse(resp); err != nil || mechanism != SECURITY_MECHANISM_TLS {
+		t.Fatal(mechanism, err)
+	}
+	req = testInvite(t, false)
+	if err := client.ProtectRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.GetRequestURI() != "sip:bob@biloxi.com;transport=tls" {
+		t.Error(req.GetRequestURI())
+	}
+	if verify := req.GetHeader().Get("Security-Verify"); verify != "ipsec-ike;q=0.1, tls;q=0.2" {
+		t.Error(verify)
+	}
+	st = newTestServerTransaction(req)
+	if ok, err := server.ProcessRequest(*NewRequestEvent(st, req)); !ok || err != nil {
+		t.Error(ok, err)
+	}
+
+	req = testInvite(t, false)
+	req.GetHeader().Set("Route", "<sip:p1.example.com;lr>, <sip:p2.example.com;lr>")
+	client.ProtectRequest(req)
+	if routes := req.GetHeader()["Route"]; len(routes) != 2 || routes[0] != "<sip:p1.example.com;lr;transport=tls>" {
+		t.Error(routes)
+	}
+	if req.GetRequestURI() != "sip:bob@biloxi.com" {
+		t.Error(req.GetRequestURI())
+	}
+
+	//a request without sec-agree goes through
+	req = testInvite(t, false)
+	st = newTestServerTransaction(req)
+	if ok, err := server.ProcessRequest(*NewRequestEvent(st, req)); !ok || err != nil {
+		t.Error(ok, err)
+	}
+}
+
+func TestSecurityAgreementDowngrade(t *testing.T) {
+	server, _ := NewSecurityPolicy(newProvider(nil), "ipsec-ike;q=0.1", "tls;q=0.2")
+
+	//a man-in-the-middle removed tls from the 494
+	client, _ := NewSecurityAgreement("tls", "ipsec-ike")
+	resp := newResponseFor(testInvite(t, false), SECURITY_AGREEMENT_REQUIRED)
+	resp.GetHeader().Set("Security-Server", "ipsec-ike;q=0.1")
+	if mechanism, err := client.ProcessResponse(resp); err != nil || mechanism != SECURITY_MECHANISM_IPSEC_IKE {
+		t.Fatal(mechanism, err)
+	}
+	req := testInvite(t, false)
+	client.ProtectRequest(req)
+	st := newTestServerTransaction(req)
+	if ok, err := server.ProcessRequest(*NewRequestEvent(st, req)); ok || err == nil {
+		t.Error("accepted a downgraded agreement")
+	}
+	if resp := st.lastResponse(t); resp.GetStatusCode() != SECURITY_AGREEMENT_REQUIRED {
+		t.Error(resp.GetStatusCode())
+	}
+
+	client, _ = NewSecurityAgreement("ipsec-man")
+	resp.GetHeader().Set("Security-Server", "ipsec-ike;q=0.1, tls;q=0.2")
+	if _, err := client.ProcessResponse(resp); err != ErrNoCommonMechanism {
+		t.Error(err)
+	}
+	if err := client.ProtectRequest(testInvite(t, false)); err == nil {
+		t.Error("protected a request without agreement")
+	}
+	if _, err := NewSecurityAgreement("tls;q=x"); err == nil {
+		t.Error("accepted a malformed mechanism")
+	}
+}
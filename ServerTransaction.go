@@ -8,6 +8,13 @@ type ServerTransaction interface {
 
 type serverTransaction struct {
 	transaction
+
+	//the dialogs named by the Replaces or Join header of an INVITE
+	replaces *dialog
+	joins    *dialog
+
+	//the last response sent, sent again to a retransmitted request
+	response Response
 }
 
 func newServerTransaction(request Request) *serverTransaction {
@@ -20,5 +27,21 @@ func newServerTransaction(request Request) *serverTransaction {
 }
 
 func (this *serverTransaction) SendResponse(resp Response) error {
+	d, ok := this.dialog.(*dialog)
+	if ok {
+		d.sendingResponse(this, resp)
+	}
+	if err := compressFor(resp, headerValues(this.request, "Accept-Encoding")); err != nil {
+		return err
+	}
+	if this.provider != nil {
+		if err := this.provider.SendResponse(resp); err != nil {
+			return err
+		}
+		this.provider.responseSent(this, resp)
+	}
+	if ok && resp.GetStatusCode()/100 == 2 && d.GetFirstTransaction() == Transaction(this) {
+		return d.endReplaced()
+	}
 	return nil
 }
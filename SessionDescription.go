@@ -0,0 +1,48 @@
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"sip/sdp"
+)
+
+var ErrNoSessionDescription = errors.New("the message carries no session description")
+
+// GetSessionDescription parses the application/sdp body of msg, or the
+// session part of its multipart body. The description is returned along
+// with the sdp.ParseErrors of a body that could only be parsed leniently.
+func GetSessionDescription(msg Message) (*sdp.SessionDescription, error) {
+	if !hasSessionBody(msg) {
+		return nil, ErrNoSessionDescription
+	}
+	m, err := GetMultipartBody(msg)
+	if err == ErrNotMultipart {
+		body, err := bodyBytes(msg)
+		if err != nil {
+			return nil, err
+		}
+		return sdp.Parse(body)
+	} else if err != nil {
+		return nil, err
+	}
+	return sdp.Parse(sessionPartOf(m).Body)
+}
+
+// sessionDescriptionOf returns the session description of msg, even when
+// it could only be parsed leniently, or nil.
+func sessionDescriptionOf(msg Message) *sdp.SessionDescription {
+	sd, err := GetSessionDescription(msg)
+	if _, lenient := err.(sdp.ParseErrors); err != nil && !lenient {
+		return nil
+	}
+	return sd
+}
+
+// SetSessionDescription makes sd the body of msg, with its Content-Type and
+// Content-Length.
+func SetSessionDescription(msg Message, sd *sdp.SessionDescription) {
+	body := sd.Marshal()
+	msg.SetBody(bytes.NewReader(body))
+	msg.SetContentLength(int64(len(body)))
+	msg.GetHeader().Set("Content-Type", sdp.CONTENT_TYPE)
+}
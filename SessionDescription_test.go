@@ -0,0 +1,34 @@
+package sip
+
+import (
+	"testing"
+)
+
+func TestSessionDescription(t *testing.T) {
+	invite := testInvite(t, true)
+	sd, err := GetSessionDescription(invite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, c := sd.FindCodec("audio", "PCMU"); m == nil || c.PayloadType != 0 {
+		t.Fatal(sd)
+	}
+
+	resp := newResponseFor(invite, OK)
+	sd.Origin.Username = "bob"
+	SetSessionDescription(resp, sd)
+	if !hasSessionBody(resp) || resp.GetHeader().Get("Content-Type") != "application/sdp" {
+		t.Log(resp.GetHeader())
+		t.Fail()
+	}
+	answer, err := GetSessionDescription(resp)
+	if err != nil || answer.Origin.Username != "bob" || answer.String() != sd.String() {
+		t.Log(answer, err)
+		t.Fail()
+	}
+
+	if _, err := GetSessionDescription(testInvite(t, false)); err != ErrNoSessionDescription {
+		t.Log(err)
+		t.Fail()
+	}
+}
@@ -15,6 +15,12 @@ type Stack interface {
 	GetProviders() []Provider
 	DeleteProvider(p Provider)
 
+	//SetParseMode sets how the providers parse the messages they receive,
+	//PARSEMODE_STRICT by default. It applies to the messages the providers
+	//start reading after it.
+	SetParseMode(mode ParseMode)
+	GetParseMode() ParseMode
+
 	Run()
 	Stop()
 }
@@ -34,6 +40,7 @@ type stack struct {
 	transports map[Transport]*transport
 	providers  map[Provider]*provider
 	tracer     Tracer
+	parseMode  ParseMode
 }
 
 func newStack(tracer Tracer) Stack {
@@ -72,6 +79,7 @@ func (this *stack) DeleteTransport(t Transport) {
 
 func (this *stack) CreateProvider() Provider {
 	p := newProvider(this.tracer)
+	p.setParseMode(this.parseMode)
 
 	this.providers[p] = p
 
@@ -94,6 +102,17 @@ func (this *stack) DeleteProvider(p Provider) {
 	delete(this.providers, p)
 }
 
+func (this *stack) SetParseMode(mode ParseMode) {
+	this.parseMode = mode
+	for _, p := range this.providers {
+		p.setParseMode(mode)
+	}
+}
+
+func (this *stack) GetParseMode() ParseMode {
+	return this.parseMode
+}
+
 func (this *stack) Run() {
 	for _, p := range this.providers {
 		go p.Run()
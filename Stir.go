@@ -0,0 +1,447 @@
+package sip
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sip/address"
+	"sip/header"
+	"sip/passport"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The verification statuses of the calling number of a request, the verstat
+// parameter a verification service adds to its URI, 3GPP TS 24.229 §7.2A.20.
+const (
+	VERSTAT_PASSED        = "TN-Validation-Passed"
+	VERSTAT_FAILED        = "TN-Validation-Failed"
+	VERSTAT_NO_VALIDATION = "No-TN-Validation"
+)
+
+// DEFAULT_IAT_FRESHNESS is how far the iat of a PASSporT may be from the
+// time it is verified, RFC 8224 §6.2.1.
+const DEFAULT_IAT_FRESHNESS = 60 * time.Second
+
+// Verification is the outcome of the verification of the Identity headers
+// of a request, RFC 8224 §6.2.
+type Verification struct {
+	// Verstat is the verification status of the calling number.
+	Verstat string
+	// PASSporT is the PASSporT that was verified, nil if none was.
+	PASSporT *passport.PASSporT
+	// StatusCode and ReasonPhrase are the response RFC 8224 §6.2.2 rejects
+	// the request with, if the application does, 0 when nothing failed.
+	StatusCode   int
+	ReasonPhrase string
+	// Err is why the verification failed.
+	Err error
+}
+
+// CertificateFetcher fetches the certificates PASSporTs are signed with.
+type CertificateFetcher interface {
+	// FetchCertificates returns the certificate at x5u, followed by the
+	// intermediate certificates chaining it to a root.
+	FetchCertificates(x5u string) ([]*x509.Certificate, error)
+}
+
+////////////////////Interface//////////////////////////////
+
+// SigningService is the authentication service of a network originating
+// calls, RFC 8224 §5, which signs them with the SHAKEN PASSporT of their
+// calling and called numbers, RFC 8588.
+type SigningService interface {
+	// SignRequest adds to req the Identity header of the PASSporT of its
+	// From and To, issued now with the attestation level attest.
+	SignRequest(req Request, attest string) error
+}
+
+// VerificationService is the verification service of a network terminating
+// calls, RFC 8224 §6.
+type VerificationService interface {
+	// SetFreshness sets how far the iat of a PASSporT may be from now,
+	// DEFAULT_IAT_FRESHNESS unless told otherwise.
+	SetFreshness(freshness time.Duration)
+	// VerifyRequest verifies the Identity headers of req: the certificate
+	// at the x5u of the PASSporT must chain to a root, sign the PASSporT,
+	// whose iat must be fresh and whose orig and dest must be the From and
+	// To of req. The calling number passes when any Identity header does.
+	VerifyRequest(req Request) Verification
+}
+
+////////////////////Implementation////////////////////////
+
+type signingService struct {
+	key *ecdsa.PrivateKey
+	x5u string
+}
+
+// NewSigningService returns a signing service signing with key, whose
+// certificate is published at x5u.
+func NewSigningService(key *ecdsa.PrivateKey, x5u string) SigningService {
+	return &signingService{key: key, x5u: x5u}
+}
+
+func (this *signingService) SignRequest(req Request, attest string) error {
+	orig, err := identityOf(req, "From")
+	if err != nil {
+		return err
+	}
+	dest, err := identityOf(req, "To")
+	if err != nil {
+		return err
+	}
+	claims := passport.Claims{Attest: attest}
+	if tn, ok := telephoneNumber(orig); ok {
+		claims.Orig.TN = tn
+	} else {
+		claims.Orig.URI = orig.String()
+	}
+	if tn, ok := telephoneNumber(dest); ok {
+		claims.Dest.TN = []string{tn}
+	} else {
+		claims.Dest.URI = []string{dest.String()}
+	}
+	p, err := passport.NewShaken(this.x5u, claims)
+	if err != nil {
+		return err
+	}
+	token, err := p.Sign(this.key)
+	if err != nil {
+		return err
+	}
+	identity := header.NewIdentity()
+	identity.SetSignedIdentityDigest(token)
+	if err := identity.SetInfo(this.x5u); err != nil {
+		return err
+	}
+	identity.SetAlgorithm(passport.ALG_ES256)
+	identity.SetPpt(passport.PPT_SHAKEN)
+	req.GetHeader().Add("Identity", identity.EncodeBody())
+	return nil
+}
+
+type verificationService struct {
+	mutex     sync.Mutex
+	fetcher   CertificateFetcher
+	roots     *x509.CertPool
+	freshness time.Duration
+}
+
+// NewVerificationService returns a verification service fetching the
+// certificates with fetcher, which must chain to roots, the certificates of
+// the certification authorities of the ecosystem.
+func NewVerificationService(fetcher CertificateFetcher, roots *x509.CertPool) VerificationService {
+	return &verificationService{
+		fetcher:   fetcher,
+		roots:     roots,
+		freshness: DEFAULT_IAT_FRESHNESS,
+	}
+}
+
+func (this *verificationService) SetFreshness(freshness time.Duration) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.freshness = freshness
+}
+
+func (this *verificationService) VerifyRequest(req Request) Verification {
+	this.mutex.Lock()
+	freshness := this.freshness
+	this.mutex.Unlock()
+
+	identities, err := parseHeaders(req, "Identity")
+	if err != nil {
+		return failedVerification(INVALID_IDENTITY_HEADER, err)
+	}
+	if len(identities) == 0 {
+		return Verification{Verstat: VERSTAT_NO_VALIDATION}
+	}
+	orig, err := identityOf(req, "From")
+	if err != nil {
+		return failedVerification(BAD_REQUEST, err)
+	}
+	dest, err := identityOf(req, "To")
+	if err != nil {
+		return failedVerification(BAD_REQUEST, err)
+	}
+	var verification Verification
+	for _, h := range identities {
+		verification = this.verify(h.(*header.Identity), orig, dest, freshness)
+		if verification.Err == nil {
+			break
+		}
+	}
+	return verification
+}
+
+// verify verifies identity, an Identity header of a request from orig to
+// dest.
+func (this *verificationService) verify(identity *header.Identity, orig, dest address.URI, freshness time.Duration) Verification {
+	if ppt := identity.GetPpt(); ppt != "" && ppt != passport.PPT_SHAKEN {
+		return failedVerification(INVALID_IDENTITY_HEADER, errors.New("unsupported PASSporT extension "+ppt))
+	}
+	if alg := identity.GetAlgorithm(); alg != "" && alg != passport.ALG_ES256 {
+		return failedVerification(UNSUPPORTED_CREDENTIAL, errors.New("unsupported algorithm "+alg))
+	}
+	token := identity.GetSignedIdentityDigest()
+	p, err := passport.Parse(token)
+	if err != nil {
+		return failedVerification(INVALID_IDENTITY_HEADER, err)
+	}
+	if info := identity.GetInfo(); info != "" && info != p.Header.X5u {
+		return failedVerification(BAD_IDENTITY_INFO, errors.New("the info parameter isn't the x5u of the PASSporT"))
+	}
+
+	certificates, err := this.fetcher.FetchCertificates(p.Header.X5u)
+	if err != nil {
+		return failedVerification(BAD_IDENTITY_INFO, err)
+	}
+	if len(certificates) == 0 {
+		return failedVerification(BAD_IDENTITY_INFO, errors.New("no certificate at "+p.Header.X5u))
+	}
+	now := time.Now()
+	intermediates := x509.NewCertPool()
+	for _, c := range certificates[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := certificates[0].Verify(x509.VerifyOptions{
+		Roots:         this.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return failedVerification(UNSUPPORTED_CREDENTIAL, err)
+	}
+	key, ok := certificates[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return failedVerification(UNSUPPORTED_CREDENTIAL, errors.New("the certificate doesn't hold an ECDSA key"))
+	}
+	if p, err = passport.Verify(token, key); err != nil {
+		return failedVerification(INVALID_IDENTITY_HEADER, err)
+	}
+
+	iat := time.Unix(p.Claims.Iat, 0)
+	if iat.Before(now.Add(-freshness)) || iat.After(now.Add(freshness)) {
+		verification := failedVerification(FORBIDDEN, errors.New("the PASSporT is stale"))
+		verification.ReasonPhrase = "Stale Date"
+		return verification
+	}
+	origTN, isTN := telephoneNumber(orig)
+	if isTN && p.Claims.Orig.TN != origTN || !isTN && !strings.EqualFold(p.Claims.Orig.URI, orig.String()) {
+		return failedVerification(INVALID_IDENTITY_HEADER, errors.New("the orig of the PASSporT isn't the From"))
+	}
+	destTN, ok := telephoneNumber(dest)
+	found := false
+	for _, tn := range p.Claims.Dest.TN {
+		found = found || ok && tn == destTN
+	}
+	for _, uri := range p.Claims.Dest.URI {
+		found = found || !ok && strings.EqualFold(uri, dest.String())
+	}
+	if !found {
+		return failedVerification(INVALID_IDENTITY_HEADER, errors.New("the dest of the PASSporT isn't the To"))
+	}
+
+	verification := Verification{Verstat: VERSTAT_PASSED, PASSporT: p}
+	if !isTN {
+		verification.Verstat = VERSTAT_NO_VALIDATION
+	}
+	return verification
+}
+
+func failedVerification(statusCode int, err error) Verification {
+	return Verification{
+		Verstat:      VERSTAT_FAILED,
+		StatusCode:   statusCode,
+		ReasonPhrase: ReasonPhrase(statusCode),
+		Err:          err,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// The defaults of the fetcher of NewHTTPCertificateFetcher: how long it waits
+// for the certificates, how large they may be and how many x5u it keeps the
+// certificates of.
+const (
+	DEFAULT_FETCH_TIMEOUT   = 5 * time.Second
+	MAX_CERTIFICATES_SIZE   = 64 << 10
+	MAX_CACHED_CERTIFICATES = 1024
+)
+
+type httpCertificateFetcher struct {
+	mutex    sync.Mutex
+	client   *http.Client
+	cache    map[string][]*x509.Certificate
+	capacity int
+}
+
+// NewHTTPCertificateFetcher returns a fetcher getting the PEM certificates
+// at the https x5u with client, one timing out after DEFAULT_FETCH_TIMEOUT
+// if nil, and keeping them until the first one expires.
+func NewHTTPCertificateFetcher(client *http.Client) CertificateFetcher {
+	if client == nil {
+		client = &http.Client{Timeout: DEFAULT_FETCH_TIMEOUT}
+	}
+	return &httpCertificateFetcher{
+		client:   client,
+		cache:    make(map[string][]*x509.Certificate),
+		capacity: MAX_CACHED_CERTIFICATES,
+	}
+}
+
+func (this *httpCertificateFetcher) FetchCertificates(x5u string) ([]*x509.Certificate, error) {
+	this.mutex.Lock()
+	certificates, ok := this.cache[x5u]
+	this.mutex.Unlock()
+	if ok && time.Now().Before(certificates[0].NotAfter) {
+		return certificates, nil
+	}
+
+	//the certificates are only trusted from where they are served
+	//authenticated, RFC 8226 §9
+	if u, err := url.Parse(x5u); err != nil || u.Scheme != "https" {
+		return nil, errors.New("not an https x5u: " + x5u)
+	}
+	resp, err := this.client.Get(x5u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("GET " + x5u + ": " + resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, MAX_CERTIFICATES_SIZE+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MAX_CERTIFICATES_SIZE {
+		return nil, errors.New("certificates too large at " + x5u)
+	}
+	certificates = nil
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, c)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("no certificate at " + x5u)
+	}
+	this.mutex.Lock()
+	this.evict()
+	this.cache[x5u] = certificates
+	this.mutex.Unlock()
+	return certificates, nil
+}
+
+// evict makes room in the cache for certificates: it drops the expired ones
+// first and then, while still full, any.
+func (this *httpCertificateFetcher) evict() {
+	if len(this.cache) < this.capacity {
+		return
+	}
+	now := time.Now()
+	for x5u, certificates := range this.cache {
+		if !now.Before(certificates[0].NotAfter) {
+			delete(this.cache, x5u)
+		}
+	}
+	for x5u := range this.cache {
+		if len(this.cache) < this.capacity {
+			break
+		}
+		delete(this.cache, x5u)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// AttachVerstat adds the verstat parameter to the telephone number URIs of
+// the From and P-Asserted-Identity of req, for the called user agent to
+// show, 3GPP TS 24.229 §5.4.3.2.
+func AttachVerstat(req Request, verstat string) error {
+	for _, name := range []string{"From", "P-Asserted-Identity"} {
+		headers, err := parseHeaders(req, name)
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			continue
+		}
+		var values []string
+		for _, h := range headers {
+			var addrs []header.AddressHeader
+			if lister, ok := h.(header.Lister); ok {
+				for e := lister.Front(); e != nil; e = e.Next() {
+					addrs = append(addrs, e.Value.(header.AddressHeader))
+				}
+			} else {
+				addrs = append(addrs, h.(header.AddressHeader))
+			}
+			for _, a := range addrs {
+				uri := a.GetAddress().GetURI()
+				if _, ok := telephoneNumber(uri); ok {
+					switch u := uri.(type) {
+					case *address.SipURIImpl:
+						u.SetParameter("verstat", verstat)
+					case *address.TelURLImpl:
+						u.SetParameter("verstat", verstat)
+					}
+				}
+				values = append(values, a.(header.Header).EncodeBody())
+			}
+		}
+		removeHeader(req, name)
+		req.GetHeader().Set(name, strings.Join(values, ", "))
+	}
+	return nil
+}
+
+// identityOf returns the URI of the named address header of msg.
+func identityOf(msg Message, name string) (address.URI, error) {
+	h, err := parseHeader(msg, name)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.New("no " + name + " header")
+	}
+	return h.(header.AddressHeader).GetAddress().GetURI(), nil
+}
+
+// telephoneNumber returns the telephone number of a tel URI, or of a SIP
+// URI whose user part is one, reduced to its digits, RFC 8224 §8.3.
+func telephoneNumber(uri address.URI) (string, bool) {
+	var number string
+	switch u := uri.(type) {
+	case *address.TelURLImpl:
+		number = u.GetPhoneNumber()
+	case *address.SipURIImpl:
+		number = strings.SplitN(u.GetUser(), ";", 2)[0]
+	default:
+		return "", false
+	}
+	var digits []byte
+	for i := 0; i < len(number); i++ {
+		switch c := number[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == '+' && i == 0, c == '-', c == '.', c == '(', c == ')':
+			//visual separators
+		default:
+			return "", false
+		}
+	}
+	return string(digits), len(digits) > 0
+}
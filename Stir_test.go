@@ -0,0 +1,214 @@
+package sip
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sip/header"
+	"sip/passport"
+	"strings"
+	"testing"
+	"time"
+)
+
+const stirX5u = "https://cert.example.org/passport.pem"
+
+type testCertificateFetcher map[string][]*x509.Certificate
+
+func (this testCertificateFetcher) FetchCertificates(x5u string) ([]*x509.Certificate, error) {
+	if certificates, ok := this[x5u]; ok {
+		return certificates, nil
+	}
+	return nil, errors.New("404 Not Found")
+}
+
+// testCertificate returns a certificate of key signed by parent with
+// parentKey, or a self-signed one when parent is nil.
+func testCertificate(t *testing.T, name string, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// testStir returns a signing service and the verification service trusting
+// its certificate, with the key of the first and the fetcher of the second.
+func testStir(t *testing.T) (SigningService, VerificationService, *ecdsa.PrivateKey, testCertificateFetcher) {
+	caKey, key := testKey(t), testKey(t)
+	ca := testCertificate(t, "STI-CA", caKey, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	fetcher := testCertificateFetcher{stirX5u: {testCertificate(t, "SHAKEN 1234", key, ca, caKey)}}
+	return NewSigningService(key, stirX5u), NewVerificationService(fetcher, roots), key, fetcher
+}
+
+func testTNInvite(t *testing.T) Request {
+	req := testInvite(t, false)
+	req.GetHeader().Set("From", "<sip:+1-215-555-0121@atlanta.com;user=phone>;tag=1928301774")
+	req.GetHeader().Set("To", "<tel:+12155550131>")
+	return req
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	signer, verifier, _, _ := testStir(t)
+	req := testTNInvite(t)
+	if err := signer.SignRequest(req, passport.ATTEST_FULL); err != nil {
+		t.Fatal(err)
+	}
+	h, err := parseHeader(req, "Identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := h.(*header.Identity)
+	if identity.GetInfo() != stirX5u || identity.GetAlgorithm() != "ES256" || identity.GetPpt() != "shaken" {
+		t.Error(identity.EncodeBody())
+	}
+
+	v := verifier.VerifyRequest(req)
+	if v.Err != nil || v.Verstat != VERSTAT_PASSED {
+		t.Fatal(v.Verstat, v.Err)
+	}
+	if c := v.PASSporT.Claims; c.Orig.TN != "12155550121" || c.Dest.TN[0] != "12155550131" || c.Attest != "A" || c.OrigId == "" {
+		t.Error(c)
+	}
+	if err := AttachVerstat(req, v.Verstat); err != nil {
+		t.Fatal(err)
+	}
+	if from := req.GetHeader().Get("From"); from != "<sip:+1-215-555-0121@atlanta.com;user=phone;verstat=TN-Validation-Passed>;tag=1928301774" {
+		t.Error(from)
+	}
+
+	//the calling number was changed on the way
+	req.GetHeader().Set("From", "<sip:+12155550199@atlanta.com;user=phone>;tag=1928301774")
+	if v := verifier.VerifyRequest(req); v.Verstat != VERSTAT_FAILED || v.StatusCode != INVALID_IDENTITY_HEADER {
+		t.Error(v.Verstat, v.StatusCode, v.Err)
+	}
+
+	req = testTNInvite(t)
+	if v := verifier.VerifyRequest(req); v.Verstat != VERSTAT_NO_VALIDATION || v.Err != nil {
+		t.Error(v.Verstat, v.Err)
+	}
+}
+
+func TestVerifyRequestFailures(t *testing.T) {
+	signer, verifier, key, fetcher := testStir(t)
+
+	//a PASSporT issued too long ago
+	p, _ := passport.NewShaken(stirX5u, passport.Claims{
+		Attest: passport.ATTEST_GATEWAY,
+		Dest:   passport.Dest{TN: []string{"12155550131"}},
+		Iat:    time.Now().Add(-2 * time.Minute).Unix(),
+		Orig:   passport.Orig{TN: "12155550121"},
+	})
+	token, _ := p.Sign(key)
+	req := testTNInvite(t)
+	req.GetHeader().Set("Identity", token+";info=<"+stirX5u+">;alg=ES256;ppt=shaken")
+	if v := verifier.VerifyRequest(req); v.StatusCode != FORBIDDEN || v.ReasonPhrase != "Stale Date" {
+		t.Error(v.StatusCode, v.ReasonPhrase, v.Err)
+	}
+	verifier.SetFreshness(5 * time.Minute)
+	if v := verifier.VerifyRequest(req); v.Verstat != VERSTAT_PASSED {
+		t.Error(v.Verstat, v.Err)
+	}
+
+	//a certificate from outside the ecosystem
+	otherKey := testKey(t)
+	fetcher["https://cert.example.net/other.pem"] = []*x509.Certificate{testCertificate(t, "SHAKEN 5678", otherKey, nil, nil)}
+	other := NewSigningService(otherKey, "https://cert.example.net/other.pem")
+	req = testTNInvite(t)
+	other.SignRequest(req, passport.ATTEST_FULL)
+	if v := verifier.VerifyRequest(req); v.StatusCode != UNSUPPORTED_CREDENTIAL {
+		t.Error(v.StatusCode, v.Err)
+	}
+	//but any valid Identity header is enough
+	signer.SignRequest(req, passport.ATTEST_FULL)
+	if v := verifier.VerifyRequest(req); v.Verstat != VERSTAT_PASSED {
+		t.Error(v.Verstat, v.Err)
+	}
+
+	req = testTNInvite(t)
+	signer.SignRequest(req, passport.ATTEST_FULL)
+	identity := req.GetHeader().Get("Identity")
+	req.GetHeader().Set("Identity", strings.Replace(identity, stirX5u, "https://cert.example.org/other.pem", 1))
+	if v := verifier.VerifyRequest(req); v.StatusCode != BAD_IDENTITY_INFO {
+		t.Error(v.StatusCode, v.Err)
+	}
+}
+
+func TestHTTPCertificateFetcher(t *testing.T) {
+	caKey, key := testKey(t), testKey(t)
+	ca := testCertificate(t, "STI-CA", caKey, nil, nil)
+	leaf := testCertificate(t, "SHAKEN 1234", key, ca, caKey)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/passport.pem", "/other.pem":
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+		case "/large.pem":
+			w.Write(bytes.Repeat([]byte{'-'}, MAX_CERTIFICATES_SIZE+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPCertificateFetcher(server.Client())
+	certificates, err := fetcher.FetchCertificates(server.URL + "/passport.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certificates) != 2 || !certificates[0].Equal(leaf) {
+		t.Error(certificates)
+	}
+	if _, err := fetcher.FetchCertificates(server.URL + "/missing.pem"); err == nil {
+		t.Error("fetched a missing certificate")
+	}
+	if _, err := fetcher.FetchCertificates(server.URL + "/large.pem"); err == nil {
+		t.Error("read certificates past MAX_CERTIFICATES_SIZE")
+	}
+	if _, err := fetcher.FetchCertificates("http" + strings.TrimPrefix(server.URL, "https") + "/passport.pem"); err == nil {
+		t.Error("fetched certificates without https")
+	}
+
+	//a full cache makes room for the new certificates
+	fetcher.(*httpCertificateFetcher).capacity = 1
+	if _, err := fetcher.FetchCertificates(server.URL + "/other.pem"); err != nil {
+		t.Fatal(err)
+	}
+	if cache := fetcher.(*httpCertificateFetcher).cache; len(cache) != 1 || cache[server.URL+"/other.pem"] == nil {
+		t.Error(cache)
+	}
+}
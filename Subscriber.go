@@ -0,0 +1,476 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+	"strconv"
+	"sync"
+	"time"
+)
+
+////////////////////Interface//////////////////////////////
+
+// SubscriberListener is told about the NOTIFYs received for the
+// subscriptions of a Subscriber and about their end. It may be called from
+// the timers of the subscriptions as well as from ProcessNotify.
+type SubscriberListener interface {
+	// ProcessNotify is called for each NOTIFY of sub once it was answered.
+	ProcessNotify(sub Subscription, notify Request)
+	// ProcessSubscriptionTerminated is called once sub is terminated by a
+	// NOTIFY, a failed SUBSCRIBE or its expiry.
+	ProcessSubscriptionTerminated(sub Subscription)
+}
+
+// Subscriber sends SUBSCRIBE requests and keeps the subscriptions they
+// create refreshed until they are unsubscribed, RFC 6665 §4.1. The
+// application hands it the responses to its SUBSCRIBEs and the NOTIFY
+// requests it receives.
+type Subscriber interface {
+	// Subscribe sends subscribe, a SUBSCRIBE outside of any dialog.
+	Subscribe(subscribe Request) (Subscription, error)
+	// Refer sends refer within d, or outside of any dialog when d is nil,
+	// and returns the implicit subscription that reports the progress of
+	// the referred request, RFC 3515. The subscription is terminated by the
+	// 2xx when the recipient agrees to "Refer-Sub: false", RFC 4488.
+	Refer(d Dialog, refer Request) (Subscription, error)
+	Refresh(sub Subscription) error
+	Unsubscribe(sub Subscription) error
+
+	// ProcessResponse handles a response to a SUBSCRIBE or REFER of the subscriber
+	// and returns its subscription, nil if the response is not for one.
+	ProcessResponse(responseEvent ResponseEvent) Subscription
+	// ProcessNotify answers a NOTIFY and returns its subscription, or nil
+	// after answering 481 when the NOTIFY matches none.
+	ProcessNotify(requestEvent RequestEvent) Subscription
+	GetSubscriptions() []Subscription
+}
+
+// NOTIFY_WAIT_INTERVAL is Timer N, how long a subscriber waits for the
+// NOTIFY that follows a SUBSCRIBE, RFC 6665 §4.1.2.4.
+const NOTIFY_WAIT_INTERVAL = 64 * 500 * time.Millisecond
+
+////////////////////Implementation////////////////////////
+
+type subscriber struct {
+	mutex    sync.Mutex
+	provider Provider
+	listener SubscriberListener
+
+	subscriptions []*subscription
+}
+
+func NewSubscriber(p Provider, l SubscriberListener) Subscriber {
+	return &subscriber{
+		provider: p,
+		listener: l,
+	}
+}
+
+func (this *subscriber) Subscribe(subscribe Request) (Subscription, error) {
+	if subscribe.GetMethod() != SUBSCRIBE {
+		return nil, errors.New("Subscriber.Subscribe can't send " + subscribe.GetMethod())
+	}
+	event := eventOf(subscribe)
+	if event == nil {
+		return nil, errors.New("missing or malformed Event header")
+	}
+	return this.send(nil, subscribe, event)
+}
+
+func (this *subscriber) Refer(d Dialog, refer Request) (Subscription, error) {
+	if refer.GetMethod() != REFER {
+		return nil, errors.New("Subscriber.Refer can't send " + refer.GetMethod())
+	}
+	if len(headerValues(refer, "Refer-To")) != 1 {
+		return nil, errors.New("a REFER carries exactly one Refer-To header")
+	}
+	return this.send(d, refer, referEvent(refer))
+}
+
+// send sends the request creating a subscription within d, or within a
+// new dialog when d is nil.
+func (this *subscriber) send(d Dialog, req Request, event *header.Event) (Subscription, error) {
+	ct := this.provider.GetNewClientTransaction(req)
+	if d == nil {
+		var err error
+		if d, err = this.provider.GetNewDialog(ct); err != nil {
+			return nil, err
+		}
+	}
+	sub := newSubscription(d.(*dialog), event, false)
+	sub.request = req
+	sub.transaction = ct
+	sub.expires = expiresOf(req)
+
+	this.mutex.Lock()
+	this.subscriptions = append(this.subscriptions, sub)
+	this.mutex.Unlock()
+
+	sub.mutex.Lock()
+	sub.waitTimer = time.AfterFunc(NOTIFY_WAIT_INTERVAL, func() {
+		this.expire(sub)
+	})
+	sub.mutex.Unlock()
+
+	if err := d.SendRequest(ct); err != nil {
+		sub.mutex.Lock()
+		sub.terminate("")
+		sub.mutex.Unlock()
+		this.remove(sub)
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (this *subscriber) Refresh(sub Subscription) error {
+	s, ok := sub.(*subscription)
+	if !ok || s.notifier {
+		return errors.New("not a subscription of the subscriber")
+	}
+	s.mutex.Lock()
+	expires := s.expires
+	s.mutex.Unlock()
+	return this.resubscribe(s, expires)
+}
+
+func (this *subscriber) Unsubscribe(sub Subscription) error {
+	s, ok := sub.(*subscription)
+	if !ok || s.notifier {
+		return errors.New("not a subscription of the subscriber")
+	}
+	return this.resubscribe(s, 0)
+}
+
+// resubscribe sends a SUBSCRIBE within the dialog of sub, asking for the
+// given duration.
+func (this *subscriber) resubscribe(sub *subscription, expires int) error {
+	sub.mutex.Lock()
+	if sub.state == SUBSCRIPTIONSTATE_TERMINATED {
+		sub.mutex.Unlock()
+		return ErrSubscriptionTerminated
+	}
+	d := sub.dialog
+	previous := sub.request
+	event := sub.eventHeader()
+	sub.mutex.Unlock()
+
+	req, err := d.CreateRequest(SUBSCRIBE)
+	if err != nil {
+		return err
+	}
+	h := req.GetHeader()
+	h.Set("Event", event)
+	if expires >= 0 {
+		h.Set("Expires", strconv.Itoa(expires))
+	}
+	if previous.GetMethod() == SUBSCRIBE {
+		for key, values := range previous.GetHeader() {
+			if !dialogHeaders[key] && h.Get(key) == "" && key != "O" && key != "M" {
+				h[key] = append([]string(nil), values...)
+			}
+		}
+	}
+	ct := this.provider.GetNewClientTransaction(req)
+
+	sub.mutex.Lock()
+	sub.request = req
+	sub.transaction = ct
+	if expires == 0 {
+		//the NOTIFY ending the subscription is expected next
+		if sub.refreshTimer != nil {
+			sub.refreshTimer.Stop()
+		}
+		if sub.waitTimer == nil {
+			sub.waitTimer = time.AfterFunc(NOTIFY_WAIT_INTERVAL, func() {
+				this.expire(sub)
+			})
+		}
+	}
+	sub.mutex.Unlock()
+	return d.SendRequest(ct)
+}
+
+// subscribeAgain sends the initial SUBSCRIBE of sub again, asking for the
+// given duration. The SUBSCRIBE refused established no dialog, the one sent
+// again creates the dialog of sub in place of the dialog of the refused one.
+func (this *subscriber) subscribeAgain(sub *subscription, expires int) error {
+	sub.mutex.Lock()
+	previous := sub.request
+	sub.mutex.Unlock()
+	req, err := nextRequest(previous)
+	if err != nil {
+		return err
+	}
+	req.GetHeader().Set("Expires", strconv.Itoa(expires))
+	ct := this.provider.GetNewClientTransaction(req)
+	d, err := this.provider.GetNewDialog(ct)
+	if err != nil {
+		return err
+	}
+	sub.mutex.Lock()
+	old := sub.dialog
+	sub.dialog = d.(*dialog)
+	sub.request = req
+	sub.transaction = ct
+	sub.mutex.Unlock()
+	releaseDialog(old, false)
+	return d.SendRequest(ct)
+}
+
+func (this *subscriber) ProcessResponse(responseEvent ResponseEvent) Subscription {
+	ct := responseEvent.GetClientTransaction()
+	if ct == nil {
+		return nil
+	}
+	method := ct.GetRequest().GetMethod()
+	if method != SUBSCRIBE && method != REFER {
+		return nil
+	}
+	sub := this.findByTransaction(ct)
+	if sub == nil {
+		return nil
+	}
+	resp := responseEvent.GetResponse()
+	code := resp.GetStatusCode()
+
+	sub.mutex.Lock()
+	initial := method == REFER || ct == sub.dialog.GetFirstTransaction()
+	switch {
+	case code < 200:
+		sub.mutex.Unlock()
+		return sub
+	case code < 300 && method == REFER && noReferSub(resp):
+		//the recipient agreed not to create a subscription
+		sub.terminate("")
+	case code < 300:
+		sub.transaction = nil
+		expires := expiresOf(resp)
+		if expires < 0 {
+			expires = expiresOf(ct.GetRequest())
+		}
+		if expires > 0 {
+			this.schedule(sub, expires)
+		}
+		sub.mutex.Unlock()
+		return sub
+	case code == INTERVAL_TOO_BRIEF && method == SUBSCRIBE && minExpiresOf(resp) > 0:
+		//RFC 6665 §4.1.2.1, the SUBSCRIBE is sent again for Min-Expires
+		minExpires := minExpiresOf(resp)
+		sub.transaction = nil
+		sub.expires = minExpires
+		sub.mutex.Unlock()
+		var err error
+		if initial {
+			err = this.subscribeAgain(sub, minExpires)
+		} else {
+			err = this.resubscribe(sub, minExpires)
+		}
+		if err == nil {
+			return sub
+		}
+		if !initial {
+			//like a failed refresh, the subscription is left until it
+			//expires
+			if err != ErrSubscriptionTerminated {
+				tracerOf(this.provider).Println("Refreshing subscription failed:", err)
+			}
+			return sub
+		}
+		tracerOf(this.provider).Println("Subscribing again failed:", err)
+		sub.mutex.Lock()
+		sub.terminate(SUBSCRIPTION_REASON_REJECTED)
+	case initial:
+		//the subscription was refused
+		sub.terminate(SUBSCRIPTION_REASON_REJECTED)
+	case code == CALL_OR_TRANSACTION_DOES_NOT_EXIST:
+		//the notifier lost the subscription, RFC 6665 §4.1.2.2
+		sub.terminate(SUBSCRIPTION_REASON_DEACTIVATED)
+	default:
+		//a failed refresh leaves the subscription until it expires
+		sub.transaction = nil
+		sub.mutex.Unlock()
+		return sub
+	}
+	sub.mutex.Unlock()
+	this.terminated(sub)
+	return sub
+}
+
+func (this *subscriber) ProcessNotify(requestEvent RequestEvent) Subscription {
+	st := requestEvent.GetServerTransaction()
+	req := requestEvent.GetRequest()
+	if req.GetMethod() != NOTIFY {
+		return nil
+	}
+	sub, err := this.findByNotify(req)
+	if err != nil || sub == nil {
+		st.SendResponse(newResponseFor(req, CALL_OR_TRANSACTION_DOES_NOT_EXIST))
+		return nil
+	}
+	if t, ok := st.(interface{ SetDialog(Dialog) }); ok && st.GetDialog() == nil {
+		//the dialog was created by this NOTIFY
+		d := sub.getDialog()
+		t.SetDialog(d)
+		if resp := d.processRequest(req); resp != nil {
+			st.SendResponse(resp)
+			return sub
+		}
+	}
+	state, err := parseSubscriptionState(req)
+	if err != nil {
+		st.SendResponse(newResponseFor(req, BAD_REQUEST))
+		return sub
+	}
+	st.SendResponse(newResponseFor(req, OK))
+
+	sub.mutex.Lock()
+	if sub.waitTimer != nil {
+		sub.waitTimer.Stop()
+		sub.waitTimer = nil
+	}
+	terminated := false
+	switch state.GetState() {
+	case "active":
+		sub.state = SUBSCRIPTIONSTATE_ACTIVE
+	case "pending":
+		sub.state = SUBSCRIPTIONSTATE_PENDING
+	case "terminated":
+		terminated = sub.terminate(state.GetReasonCode())
+	}
+	if !terminated && state.GetExpires() > 0 && sub.state != SUBSCRIPTIONSTATE_TERMINATED {
+		//the notifier has the final word on the duration, RFC 6665 §4.1.3
+		this.schedule(sub, state.GetExpires())
+	}
+	sub.mutex.Unlock()
+
+	if this.listener != nil {
+		this.listener.ProcessNotify(sub, req)
+	}
+	if terminated {
+		this.terminated(sub)
+	}
+	return sub
+}
+
+func (this *subscriber) GetSubscriptions() []Subscription {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	subs := make([]Subscription, len(this.subscriptions))
+	for i, s := range this.subscriptions {
+		subs[i] = s
+	}
+	return subs
+}
+
+func (this *subscriber) findByTransaction(ct ClientTransaction) *subscription {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, s := range this.subscriptions {
+		if s.transaction == ct {
+			return s
+		}
+	}
+	return nil
+}
+
+// findByNotify returns the subscription of a NOTIFY. A NOTIFY overtaking
+// the 2xx to the SUBSCRIBE confirms its dialog, and one sent by another
+// branch of a forked SUBSCRIBE creates a new dialog and subscription,
+// RFC 6665 §4.1.2.4.
+func (this *subscriber) findByNotify(req Request) (*subscription, error) {
+	from, to, callId, _, err := dialogHeadersOf(req)
+	if err != nil {
+		return nil, err
+	}
+	event := eventOf(req)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var early, forked *subscription
+	for _, s := range this.subscriptions {
+		d := s.getDialog()
+		if d.callId != callId || d.localTag != to.GetTag() || !s.matches(event) {
+			continue
+		}
+		switch d.GetRemoteTag() {
+		case from.GetTag():
+			return s, nil
+		case "":
+			early = s
+		default:
+			forked = s
+		}
+	}
+	switch {
+	case early != nil:
+		return early, early.getDialog().establish(req)
+	case forked != nil:
+		d, err := forked.getDialog().fork(req)
+		if err != nil {
+			return nil, err
+		}
+		sub := newSubscription(d, event, false)
+		forked.mutex.Lock()
+		sub.request = forked.request
+		sub.expires = forked.expires
+		forked.mutex.Unlock()
+		this.subscriptions = append(this.subscriptions, sub)
+		return sub, nil
+	}
+	return nil, nil
+}
+
+// schedule (re)arms the expiry and refresh timers of sub for a duration of
+// expires seconds. sub must be locked.
+func (this *subscriber) schedule(sub *subscription, expires int) {
+	sub.setExpiry(expires, func() {
+		this.expire(sub)
+	})
+	if sub.refreshTimer != nil {
+		sub.refreshTimer.Stop()
+	}
+	sub.refreshTimer = time.AfterFunc(subscriptionRefreshInterval(expires), func() {
+		if err := this.Refresh(sub); err != nil && err != ErrSubscriptionTerminated {
+			tracerOf(this.provider).Println("Refreshing subscription failed:", err)
+		}
+	})
+}
+
+// expire terminates sub when it expires, or when the NOTIFY it waits for
+// doesn't come.
+func (this *subscriber) expire(sub *subscription) {
+	sub.mutex.Lock()
+	terminated := sub.terminate(SUBSCRIPTION_REASON_TIMEOUT)
+	sub.mutex.Unlock()
+	if terminated {
+		this.terminated(sub)
+	}
+}
+
+// terminated forgets a subscription that just ended and tells the
+// listener.
+func (this *subscriber) terminated(sub *subscription) {
+	this.remove(sub)
+	if this.listener != nil {
+		this.listener.ProcessSubscriptionTerminated(sub)
+	}
+}
+
+func (this *subscriber) remove(sub *subscription) {
+	this.mutex.Lock()
+	var inUse bool
+	this.subscriptions, inUse = removeSubscription(this.subscriptions, sub)
+	this.mutex.Unlock()
+	releaseDialog(sub.getDialog(), inUse)
+}
+
+// subscriptionRefreshInterval returns when a subscription of expires
+// seconds is refreshed: early enough for a SUBSCRIBE transaction to time
+// out, 64*T1, before it expires, but not before half of it has elapsed.
+func subscriptionRefreshInterval(expires int) time.Duration {
+	d := time.Duration(expires) * time.Second
+	if d/2 > NOTIFY_WAIT_INTERVAL {
+		return d - NOTIFY_WAIT_INTERVAL
+	}
+	return d / 2
+}
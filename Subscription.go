@@ -0,0 +1,289 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+	"strconv"
+	"sync"
+	"time"
+)
+
+////////////////////Interface//////////////////////////////
+
+// Subscription is one usage of a dialog by the RFC 6665 event framework,
+// identified within the dialog by its event type and id. Both the notifier
+// and the subscriber side are represented by a Subscription.
+type Subscription interface {
+	GetDialog() Dialog
+	GetEventType() string
+	GetEventId() string
+	GetState() SubscriptionState
+	GetReason() string
+	GetExpires() int
+	IsNotifier() bool
+	SetApplicationData(applicationData interface{})
+	GetApplicationData() interface{}
+}
+
+type SubscriptionState int
+
+const (
+	SUBSCRIPTIONSTATE_NOTIFY_WAIT SubscriptionState = iota //0 subscriber only, no NOTIFY received yet
+	SUBSCRIPTIONSTATE_PENDING                              //1
+	SUBSCRIPTIONSTATE_ACTIVE                               //2
+	SUBSCRIPTIONSTATE_TERMINATED                           //3
+)
+
+// The reason codes of a terminated Subscription-State, RFC 6665 §4.1.3.
+const (
+	SUBSCRIPTION_REASON_DEACTIVATED = "deactivated"
+	SUBSCRIPTION_REASON_PROBATION   = "probation"
+	SUBSCRIPTION_REASON_REJECTED    = "rejected"
+	SUBSCRIPTION_REASON_TIMEOUT     = "timeout"
+	SUBSCRIPTION_REASON_GIVEUP      = "giveup"
+	SUBSCRIPTION_REASON_NORESOURCE  = "noresource"
+	SUBSCRIPTION_REASON_INVARIANT   = "invariant"
+)
+
+var ErrSubscriptionTerminated = errors.New("the subscription is terminated")
+
+func (this SubscriptionState) String() string {
+	switch this {
+	case SUBSCRIPTIONSTATE_PENDING:
+		return "pending"
+	case SUBSCRIPTIONSTATE_ACTIVE:
+		return "active"
+	case SUBSCRIPTIONSTATE_TERMINATED:
+		return "terminated"
+	}
+	return "notify-wait"
+}
+
+////////////////////Implementation////////////////////////
+
+type subscription struct {
+	mutex sync.Mutex
+
+	dialog    *dialog
+	eventType string
+	eventId   string
+	notifier  bool
+
+	state   SubscriptionState
+	reason  string
+	expires int
+	expiry  time.Time
+
+	//the SUBSCRIBE the subscriber refreshes, and its transaction while
+	//it is outstanding
+	request     Request
+	transaction ClientTransaction
+
+	expiryTimer  *time.Timer
+	refreshTimer *time.Timer
+	waitTimer    *time.Timer
+
+	applicationData interface{}
+}
+
+func newSubscription(d *dialog, event *header.Event, notifier bool) *subscription {
+	return &subscription{
+		dialog:    d,
+		eventType: event.GetEventType(),
+		eventId:   event.GetEventId(),
+		notifier:  notifier,
+		state:     SUBSCRIPTIONSTATE_NOTIFY_WAIT,
+	}
+}
+
+func (this *subscription) GetDialog() Dialog {
+	return this.getDialog()
+}
+
+// getDialog returns the dialog of the subscription, which the initial
+// SUBSCRIBE sent again replaces.
+func (this *subscription) getDialog() *dialog {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.dialog
+}
+
+func (this *subscription) GetEventType() string {
+	return this.eventType
+}
+
+func (this *subscription) GetEventId() string {
+	return this.eventId
+}
+
+func (this *subscription) GetState() SubscriptionState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.state
+}
+
+func (this *subscription) GetReason() string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.reason
+}
+
+// GetExpires returns the number of seconds left before the subscription
+// expires.
+func (this *subscription) GetExpires() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.remaining()
+}
+
+func (this *subscription) IsNotifier() bool {
+	return this.notifier
+}
+
+func (this *subscription) SetApplicationData(applicationData interface{}) {
+	this.applicationData = applicationData
+}
+
+func (this *subscription) GetApplicationData() interface{} {
+	return this.applicationData
+}
+
+func (this *subscription) remaining() int {
+	if this.state == SUBSCRIPTIONSTATE_TERMINATED || this.expiry.IsZero() {
+		return 0
+	}
+	left := int(time.Until(this.expiry) / time.Second)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// matches reports whether the Event header of a request or response
+// belongs to this subscription, RFC 6665 §8.2.1.
+func (this *subscription) matches(event *header.Event) bool {
+	return event != nil && event.GetEventType() == this.eventType && event.GetEventId() == this.eventId
+}
+
+// setExpiry (re)arms the expiry timer of the subscription, expired is run
+// when it fires.
+func (this *subscription) setExpiry(seconds int, expired func()) {
+	this.expires = seconds
+	this.expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	if this.expiryTimer != nil {
+		this.expiryTimer.Stop()
+	}
+	this.expiryTimer = time.AfterFunc(time.Duration(seconds)*time.Second, expired)
+}
+
+// terminate moves the subscription to the terminated state and stops its
+// timers. It returns false when the subscription was already terminated.
+func (this *subscription) terminate(reason string) bool {
+	if this.state == SUBSCRIPTIONSTATE_TERMINATED {
+		return false
+	}
+	this.state = SUBSCRIPTIONSTATE_TERMINATED
+	this.reason = reason
+	for _, t := range []*time.Timer{this.expiryTimer, this.refreshTimer, this.waitTimer} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	return true
+}
+
+// subscriptionStateHeader encodes the Subscription-State of a NOTIFY.
+func (this *subscription) subscriptionStateHeader() string {
+	value := this.state.String()
+	switch this.state {
+	case SUBSCRIPTIONSTATE_TERMINATED:
+		if this.reason != "" {
+			value += ";reason=" + this.reason
+		}
+	default:
+		value += ";expires=" + strconv.Itoa(this.remaining())
+	}
+	return value
+}
+
+// eventHeader encodes the Event header of the requests of the subscription.
+func (this *subscription) eventHeader() string {
+	if this.eventId != "" {
+		return this.eventType + ";id=" + this.eventId
+	}
+	return this.eventType
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// removeSubscription deletes sub from subs, and reports whether another
+// subscription of subs still uses its dialog.
+func removeSubscription(subs []*subscription, sub *subscription) ([]*subscription, bool) {
+	inUse := false
+	d := sub.getDialog()
+	for i := 0; i < len(subs); i++ {
+		if subs[i] == sub {
+			subs = append(subs[:i], subs[i+1:]...)
+			i--
+		} else if subs[i].getDialog() == d {
+			inUse = true
+		}
+	}
+	return subs, inUse
+}
+
+// releaseDialog ends a dialog created by a subscription once no usage is
+// left on it, RFC 6665 §4.5. A dialog created by an INVITE outlives its
+// subscriptions.
+func releaseDialog(d *dialog, inUse bool) {
+	if inUse {
+		return
+	}
+	if t := d.GetFirstTransaction(); t != nil {
+		switch t.GetRequest().GetMethod() {
+		case SUBSCRIBE, REFER, NOTIFY:
+		default:
+			return
+		}
+	}
+	d.Close()
+}
+
+// eventOf returns the Event header of msg, or nil if it has none.
+func eventOf(msg Message) *header.Event {
+	if h, err := parseHeader(msg, "Event"); err == nil && h != nil {
+		return h.(*header.Event)
+	}
+	return nil
+}
+
+// expiresOf returns the value of the Expires header of msg, or -1 if it
+// has none.
+func expiresOf(msg Message) int {
+	if h, err := parseHeader(msg, "Expires"); err == nil && h != nil {
+		return h.(*header.Expires).GetExpires()
+	}
+	return -1
+}
+
+// minExpiresOf returns the Min-Expires of msg, or -1 if it has none.
+func minExpiresOf(msg Message) int {
+	if h, err := parseHeader(msg, "Min-Expires"); err == nil && h != nil {
+		if minExpires, ok := h.(*header.MinExpires); ok {
+			return minExpires.GetExpires()
+		}
+	}
+	return -1
+}
+
+// parseSubscriptionState returns the Subscription-State header of msg.
+func parseSubscriptionState(msg Message) (*header.SubscriptionState, error) {
+	h, err := parseHeader(msg, "Subscription-State")
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, errors.New("missing Subscription-State header")
+	}
+	return h.(*header.SubscriptionState), nil
+}
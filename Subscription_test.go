@@ -0,0 +1,383 @@
+package sip
+
+import (
+	"sip/address"
+	"sip/header"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider hands out transactions and dialogs without a transport and
+// records the requests sent through it.
+type testProvider struct {
+	transactions []*clientTransaction
+}
+
+func (this *testProvider) AddTransport(Transport)    {}
+func (this *testProvider) RemoveTransport(Transport) {}
+func (this *testProvider) AddListener(Listener)      {}
+func (this *testProvider) RemoveListener(Listener)   {}
+func (this *testProvider) GetNewCallId() string      { return randomHex(8) }
+func (this *testProvider) GetValidator() Validator    { return NewValidator() }
+func (this *testProvider) SendRequest(Request) error { return nil }
+func (this *testProvider) SendResponse(Response) error {
+	return nil
+}
+
+func (this *testProvider) CreateRequest(method string, requestURI address.URI, from, to address.Address, via *header.Via) (Request, error) {
+	return CreateRequest(method, requestURI, from, to, via)
+}
+
+func (this *testProvider) GetNewClientTransaction(req Request) ClientTransaction {
+	ct := newClientTransaction(req)
+	this.transactions = append(this.transactions, ct)
+	return ct
+}
+
+func (this *testProvider) GetNewServerTransaction(req Request) ServerTransaction {
+	return newServerTransaction(req)
+}
+
+func (this *testProvider) GetNewDialog(t Transaction) (Dialog, error) {
+	switch tx := t.(type) {
+	case *clientTransaction:
+		d, err := newClientDialog(tx)
+		if err != nil {
+			return nil, err
+		}
+		tx.SetDialog(d)
+		return d, nil
+	case *testServerTransaction:
+		d, err := newServerDialog(tx.serverTransaction)
+		if err != nil {
+			return nil, err
+		}
+		tx.SetDialog(d)
+		return d, nil
+	}
+	return nil, nil
+}
+
+func (this *testProvider) lastTransaction(t *testing.T) *clientTransaction {
+	if len(this.transactions) == 0 {
+		t.Fatal("no request was sent")
+	}
+	return this.transactions[len(this.transactions)-1]
+}
+
+func (this *testProvider) lastRequest(t *testing.T) Request {
+	return this.lastTransaction(t).GetRequest()
+}
+
+// testServerTransaction records the responses sent through it.
+type testServerTransaction struct {
+	*serverTransaction
+	responses []Response
+}
+
+func newTestServerTransaction(req Request) *testServerTransaction {
+	return &testServerTransaction{serverTransaction: newServerTransaction(req)}
+}
+
+func (this *testServerTransaction) SendResponse(resp Response) error {
+	this.responses = append(this.responses, resp)
+	return this.serverTransaction.SendResponse(resp)
+}
+
+func (this *testServerTransaction) lastResponse(t *testing.T) Response {
+	if len(this.responses) == 0 {
+		t.Fatal("no response was sent")
+	}
+	return this.responses[len(this.responses)-1]
+}
+
+type testEventPackage struct {
+	state SubscriptionState
+}
+
+func (this *testEventPackage) GetEventType() string   { return "presence" }
+func (this *testEventPackage) GetDefaultExpires() int { return 3600 }
+func (this *testEventPackage) GetContentType() string { return "application/pidf+xml" }
+func (this *testEventPackage) Authorize(Subscription, Request) SubscriptionState {
+	return this.state
+}
+func (this *testEventPackage) GetNotifyBody(sub Subscription) ([]byte, error) {
+	return []byte("<presence/>"), nil
+}
+
+type testSubscriberListener struct {
+	notifies   int
+	terminated []Subscription
+}
+
+func (this *testSubscriberListener) ProcessNotify(Subscription, Request) {
+	this.notifies++
+}
+
+func (this *testSubscriberListener) ProcessSubscriptionTerminated(sub Subscription) {
+	this.terminated = append(this.terminated, sub)
+}
+
+func testSubscribe(t *testing.T, event string, expires int, toTag string) Request {
+	s := "SUBSCRIBE sip:bob@biloxi.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bKnashds7\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: <sip:bob@biloxi.com>" + toTag + "\r\n" +
+		"From: <sip:alice@atlanta.com>;tag=xfg9\r\n" +
+		"Call-ID: 2010b0e0a5@pc33.atlanta.com\r\n" +
+		"CSeq: 17766 SUBSCRIBE\r\n" +
+		"Contact: <sip:alice@pc33.atlanta.com>\r\n" +
+		"Event: " + event + "\r\n"
+	if expires >= 0 {
+		s += "Expires: " + strconv.Itoa(expires) + "\r\n"
+	}
+	s += "Content-Length: 0\r\n\r\n"
+	return readTestMessage(t, s).(Request)
+}
+
+func testNotify(t *testing.T, fromTag string, cSeq int, state string) Request {
+	s := "NOTIFY sip:alice@pc33.atlanta.com SIP/2.0\r\n" +
+		"Via: SIP/2.0/UDP " + fromTag + ".biloxi.com;branch=z9hG4bK" + fromTag + strconv.Itoa(cSeq) + "\r\n" +
+		"Max-Forwards: 70\r\n" +
+		"To: <sip:alice@atlanta.com>;tag=xfg9\r\n" +
+		"From: <sip:bob@biloxi.com>;tag=" + fromTag + "\r\n" +
+		"Call-ID: 2010b0e0a5@pc33.atlanta.com\r\n" +
+		"CSeq: " + strconv.Itoa(cSeq) + " NOTIFY\r\n" +
+		"Contact: <sip:bob@" + fromTag + ".biloxi.com>\r\n" +
+		"Event: presence\r\n" +
+		"Subscription-State: " + state + "\r\n" +
+		"Content-Length: 0\r\n\r\n"
+	return readTestMessage(t, s).(Request)
+}
+
+func newTestNotifier(state SubscriptionState) (*testProvider, Notifier) {
+	p := &testProvider{}
+	n := NewNotifier(p)
+	n.AddEventPackage(&testEventPackage{state: state})
+	return p, n
+}
+
+func TestNotifierBadEvent(t *testing.T) {
+	_, n := newTestNotifier(SUBSCRIPTIONSTATE_ACTIVE)
+	st := newTestServerTransaction(testSubscribe(t, "dialog", 600, ""))
+	if _, err := n.ProcessSubscribe(*NewRequestEvent(st, st.GetRequest())); err != ErrBadEvent {
+		t.Log(err)
+		t.Fail()
+	}
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != BAD_EVENT || resp.GetHeader().Get("Allow-Events") != "presence" {
+		t.Log(resp.GetStatusCode(), resp.GetHeader())
+		t.Fail()
+	}
+}
+
+func TestNotifierIntervalTooBrief(t *testing.T) {
+	_, n := newTestNotifier(SUBSCRIPTIONSTATE_ACTIVE)
+	st := newTestServerTransaction(testSubscribe(t, "presence", 10, ""))
+	n.ProcessSubscribe(*NewRequestEvent(st, st.GetRequest()))
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != INTERVAL_TOO_BRIEF || resp.GetHeader().Get("Min-Expires") != "60" {
+		t.Log(resp.GetStatusCode(), resp.GetHeader())
+		t.Fail()
+	}
+}
+
+func TestNotifierRejected(t *testing.T) {
+	_, n := newTestNotifier(SUBSCRIPTIONSTATE_TERMINATED)
+	st := newTestServerTransaction(testSubscribe(t, "presence", 600, ""))
+	sub, _ := n.ProcessSubscribe(*NewRequestEvent(st, st.GetRequest()))
+	if st.lastResponse(t).GetStatusCode() != FORBIDDEN || sub.GetState() != SUBSCRIPTIONSTATE_TERMINATED ||
+		sub.GetReason() != SUBSCRIPTION_REASON_REJECTED || len(n.GetSubscriptions("presence")) != 0 {
+		t.Fail()
+	}
+}
+
+func TestNotifierSubscription(t *testing.T) {
+	p, n := newTestNotifier(SUBSCRIPTIONSTATE_PENDING)
+	st := newTestServerTransaction(testSubscribe(t, "presence", 600, ""))
+	sub, err := n.ProcessSubscribe(*NewRequestEvent(st, st.GetRequest()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := st.lastResponse(t)
+	if resp.GetStatusCode() != OK || resp.GetHeader().Get("Expires") != "600" ||
+		resp.GetHeader().Get("Contact") != "<sip:bob@biloxi.com>" {
+		t.Log(resp.GetStatusCode(), resp.GetHeader())
+		t.Fail()
+	}
+	d := sub.GetDialog()
+	if d.GetState() != DIALOGSTATE_CONFIRMED || !strings.HasSuffix(resp.GetHeader().Get("To"), d.GetLocalTag()) {
+		t.Log(d.GetState(), resp.GetHeader().Get("To"))
+		t.Fail()
+	}
+
+	notify := p.lastRequest(t)
+	h := notify.GetHeader()
+	if notify.GetMethod() != NOTIFY || notify.GetRequestURI() != "sip:alice@pc33.atlanta.com" ||
+		h.Get("Event") != "presence" || !strings.HasPrefix(h.Get("Subscription-State"), "pending;expires=") ||
+		h.Get("Content-Type") != "application/pidf+xml" {
+		t.Log(notify.GetRequestURI(), h)
+		t.Fail()
+	}
+
+	n.SetState(sub, SUBSCRIPTIONSTATE_ACTIVE, "")
+	if h := p.lastRequest(t).GetHeader(); !strings.HasPrefix(h.Get("Subscription-State"), "active;expires=") {
+		t.Log(h.Get("Subscription-State"))
+		t.Fail()
+	}
+
+	//the subscriber ends the subscription
+	unsubscribe := testSubscribe(t, "presence", 0, ";tag="+d.GetLocalTag())
+	unsubscribeSt := newTestServerTransaction(unsubscribe)
+	unsubscribeSt.SetDialog(d)
+	if same, _ := n.ProcessSubscribe(*NewRequestEvent(unsubscribeSt, unsubscribe)); same != sub {
+		t.Fatal("the unsubscription did not match the subscription")
+	}
+	if unsubscribeSt.lastResponse(t).GetStatusCode() != OK ||
+		p.lastRequest(t).GetHeader().Get("Subscription-State") != "terminated;reason=timeout" {
+		t.Log(p.lastRequest(t).GetHeader())
+		t.Fail()
+	}
+	if sub.GetState() != SUBSCRIPTIONSTATE_TERMINATED || d.GetState() != DIALOGSTATE_TERMINATED ||
+		len(n.GetSubscriptions("presence")) != 0 {
+		t.Fail()
+	}
+	if err := n.SetState(sub, SUBSCRIPTIONSTATE_ACTIVE, ""); err != ErrSubscriptionTerminated {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestSubscriberForkedNotify(t *testing.T) {
+	p := &testProvider{}
+	l := &testSubscriberListener{}
+	s := NewSubscriber(p, l)
+	sub, err := s.Subscribe(testSubscribe(t, "presence", 600, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.GetState() != SUBSCRIPTIONSTATE_NOTIFY_WAIT {
+		t.Fatal(sub.GetState())
+	}
+
+	//the first NOTIFY overtakes the 2xx and confirms the dialog
+	st := newTestServerTransaction(testNotify(t, "b1", 1, "active;expires=600"))
+	if s.ProcessNotify(*NewRequestEvent(st, st.GetRequest())) != sub {
+		t.Fatal("the NOTIFY did not match the subscription")
+	}
+	if st.lastResponse(t).GetStatusCode() != OK || sub.GetState() != SUBSCRIPTIONSTATE_ACTIVE ||
+		sub.GetDialog().GetRemoteTag() != "b1" || sub.GetDialog().GetState() != DIALOGSTATE_CONFIRMED ||
+		sub.GetDialog().GetRemoteTarget() != "sip:bob@b1.biloxi.com" {
+		t.Log(sub.GetState(), sub.GetDialog().GetDialogId())
+		t.Fail()
+	}
+
+	//another branch of the SUBSCRIBE answers as well
+	st = newTestServerTransaction(testNotify(t, "b2", 1, "pending"))
+	forked := s.ProcessNotify(*NewRequestEvent(st, st.GetRequest()))
+	if forked == nil || forked == sub || forked.GetDialog().GetRemoteTag() != "b2" ||
+		forked.GetState() != SUBSCRIPTIONSTATE_PENDING || len(s.GetSubscriptions()) != 2 {
+		t.Fatal(forked)
+	}
+
+	st = newTestServerTransaction(testNotify(t, "b2", 2, "terminated;reason=rejected"))
+	s.ProcessNotify(*NewRequestEvent(st, st.GetRequest()))
+	if forked.GetState() != SUBSCRIPTIONSTATE_TERMINATED || forked.GetReason() != SUBSCRIPTION_REASON_REJECTED ||
+		len(l.terminated) != 1 || l.terminated[0] != forked || l.notifies != 3 || len(s.GetSubscriptions()) != 1 {
+		t.Log(forked.GetState(), l.terminated, l.notifies)
+		t.Fail()
+	}
+
+	//a NOTIFY matching no subscription
+	st = newTestServerTransaction(readTestMessage(t, "NOTIFY sip:alice@pc33.atlanta.com SIP/2.0\r\n"+
+		"Via: SIP/2.0/UDP biloxi.com;branch=z9hG4bK3\r\n"+
+		"To: <sip:alice@atlanta.com>;tag=other\r\n"+
+		"From: <sip:bob@biloxi.com>;tag=b1\r\n"+
+		"Call-ID: 2010b0e0a5@pc33.atlanta.com\r\n"+
+		"CSeq: 3 NOTIFY\r\n"+
+		"Event: presence\r\n"+
+		"Subscription-State: active\r\n"+
+		"Content-Length: 0\r\n\r\n").(Request))
+	if s.ProcessNotify(*NewRequestEvent(st, st.GetRequest())) != nil ||
+		st.lastResponse(t).GetStatusCode() != CALL_OR_TRANSACTION_DOES_NOT_EXIST {
+		t.Fail()
+	}
+}
+
+func TestSubscriberRefresh(t *testing.T) {
+	p := &testProvider{}
+	s := NewSubscriber(p, nil)
+	sub, _ := s.Subscribe(testSubscribe(t, "presence", 600, ""))
+	st := newTestServerTransaction(testNotify(t, "b1", 1, "active;expires=600"))
+	s.ProcessNotify(*NewRequestEvent(st, st.GetRequest()))
+
+	if err := s.Refresh(sub); err != nil {
+		t.Fatal(err)
+	}
+	ct := p.lastTransaction(t)
+	refresh := ct.GetRequest()
+	h := refresh.GetHeader()
+	if refresh.GetMethod() != SUBSCRIBE || refresh.GetRequestURI() != "sip:bob@b1.biloxi.com" ||
+		h.Get("CSeq") != "17767 SUBSCRIBE" || h.Get("Event") != "presence" || h.Get("Expires") != "600" ||
+		!strings.HasSuffix(h.Get("To"), ";tag=b1") {
+		t.Log(refresh.GetRequestURI(), h)
+		t.Fail()
+	}
+
+	//the refresh is refused for a lost subscription
+	resp := newResponseFor(refresh, CALL_OR_TRANSACTION_DOES_NOT_EXIST)
+	s.ProcessResponse(*NewResponseEvent(ct, resp))
+	if sub.GetState() != SUBSCRIPTIONSTATE_TERMINATED || sub.GetReason() != SUBSCRIPTION_REASON_DEACTIVATED {
+		t.Log(sub.GetState(), sub.GetReason())
+		t.Fail()
+	}
+}
+
+func TestSubscriberIntervalTooBrief(t *testing.T) {
+	p := &testProvider{}
+	s := NewSubscriber(p, nil)
+	sub, _ := s.Subscribe(testSubscribe(t, "presence", 60, ""))
+	first := sub.GetDialog()
+	ct := p.lastTransaction(t)
+	resp := newResponseFor(ct.GetRequest(), INTERVAL_TOO_BRIEF)
+	resp.GetHeader().Set("Min-Expires", "3600")
+	s.ProcessResponse(*NewResponseEvent(ct, resp))
+
+	//the initial SUBSCRIBE is sent again, for the dialog of the subscription
+	retry := p.lastTransaction(t)
+	h := retry.GetRequest().GetHeader()
+	if retry == ct || h.Get("Expires") != "3600" || h.Get("CSeq") != "17767 SUBSCRIBE" ||
+		branchOf(retry.GetRequest()) == branchOf(ct.GetRequest()) || sub.GetState() != SUBSCRIPTIONSTATE_NOTIFY_WAIT ||
+		sub.GetDialog().GetFirstTransaction() != Transaction(retry) {
+		t.Fatal(sub.GetState(), h)
+	}
+	if first.GetState() != DIALOGSTATE_TERMINATED {
+		t.Error("the dialog of the refused SUBSCRIBE was left", first.GetState())
+	}
+	st := newTestServerTransaction(testNotify(t, "b1", 1, "active;expires=3600"))
+	if s.ProcessNotify(*NewRequestEvent(st, st.GetRequest())) != sub || sub.GetState() != SUBSCRIPTIONSTATE_ACTIVE {
+		t.Error(sub.GetState())
+	}
+
+	//without Min-Expires, the subscription is refused
+	sub, _ = s.Subscribe(testSubscribe(t, "presence", 60, ""))
+	ct = p.lastTransaction(t)
+	s.ProcessResponse(*NewResponseEvent(ct, newResponseFor(ct.GetRequest(), INTERVAL_TOO_BRIEF)))
+	if sub.GetState() != SUBSCRIPTIONSTATE_TERMINATED || sub.GetReason() != SUBSCRIPTION_REASON_REJECTED {
+		t.Error(sub.GetState(), sub.GetReason())
+	}
+}
+
+func TestSubscriptionRefreshInterval(t *testing.T) {
+	if d := subscriptionRefreshInterval(3600); d != 3600*time.Second-NOTIFY_WAIT_INTERVAL {
+		t.Log(d)
+		t.Fail()
+	}
+	if d := subscriptionRefreshInterval(60); d != 30*time.Second {
+		t.Log(d)
+		t.Fail()
+	}
+}
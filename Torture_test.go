@@ -0,0 +1,1325 @@
+package sip
+
+import (
+	"bufio"
+	"sip/header"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The torture messages of RFC 4475 and the IPv6 ones of RFC 5118, with the
+// values the parsers have to decode from the valid ones and the way the
+// stack has to turn down the invalid ones.
+
+// torture joins the lines of a message with CRLF.
+func torture(lines ...string) string {
+	return strings.Join(lines, "\r\n")
+}
+
+// tortureSDP returns the 132+2*len(ip) octets SDP body most of the
+// messages of RFC 4475 carry.
+func tortureSDP(ip string) string {
+	return torture(
+		"v=0",
+		"o=mhandley 29739 7272939 IN IP4 "+ip,
+		"s=-",
+		"c=IN IP4 "+ip,
+		"t=0 0",
+		"m=audio 49217 RTP/AVP 0 12",
+		"m=video 3227 RTP/AVP 31",
+		"a=rtpmap:31 LPC",
+		"")
+}
+
+// tortureList returns the values of a header that may be parsed as a list.
+func tortureList(h header.Header) []header.Header {
+	l, ok := h.(header.SIPHeaderLister)
+	if !ok {
+		return []header.Header{h}
+	}
+	var headers []header.Header
+	for e := l.Front(); e != nil; e = e.Next() {
+		headers = append(headers, e.Value.(header.Header))
+	}
+	return headers
+}
+
+// tortureHeaders returns the typed values of the named header, or fails.
+func tortureHeaders(t *testing.T, msg Message, name string) []header.Header {
+	headers, err := parseHeaders(msg, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []header.Header
+	for _, h := range headers {
+		values = append(values, tortureList(h)...)
+	}
+	return values
+}
+
+func tortureVias(t *testing.T, msg Message) []*header.Via {
+	var vias []*header.Via
+	for _, h := range tortureHeaders(t, msg, "Via") {
+		vias = append(vias, h.(*header.Via))
+	}
+	return vias
+}
+
+var longRequest = func() string {
+	vias := make([]string, 0, 34)
+	vias = append(vias, "Via: SIP/2.0/TCP sip33.example.com")
+	for i := 32; i > 0; i-- {
+		vias = append(vias, []string{"v", "V"}[i%2]+": SIP/2.0/TCP sip"+strconv.Itoa(i)+".example.com")
+	}
+	lines := []string{
+		"INVITE sip:user@example.com SIP/2.0",
+		"To: \"I have a user name of " + strings.Repeat("extreme", 10) + " proportion\"<sip:user@example.com:6000;unknownparam1=very" + strings.Repeat("long", 20) + "value;longparam" + strings.Repeat("name", 25) + "=shortvalue;very" + strings.Repeat("long", 25) + "ParameterNameWithNoValue>",
+		"F: sip:" + strings.Repeat("amazinglylongcallername", 5) + "@example.net;tag=12" + strings.Repeat("982", 10) + "424;unknownheaderparam" + strings.Repeat("name", 20) + "=unknowheaderparam" + strings.Repeat("value", 15) + ";unknownValueless" + strings.Repeat("paramname", 10),
+		"Call-ID: longreq.one" + strings.Repeat("really", 100) + "longcallid",
+		"CSeq: 3882340 INVITE",
+		"Unknown-" + strings.Repeat("Long", 20) + "-Name: unknown-" + strings.Repeat("long", 20) + "-value; unknown-" + strings.Repeat("long", 20) + "-parameter-name = unknown-" + strings.Repeat("long", 20) + "-parameter-value",
+	}
+	lines = append(lines, vias...)
+	lines = append(lines,
+		"Max-Forwards: 70",
+		"Contact: <sip:"+strings.Repeat("amazinglylongcallername", 5)+"@host5.example.net>",
+		"Content-Type: application/sdp",
+		"l: 150",
+		"",
+		tortureSDP("192.0.2.1"))
+	return torture(lines...)
+}()
+
+// a CMS signature, binary content that the body carries as is
+const tortureSignature = "0\x82\x01\x88\x06\t*\x86H\x86\xf7\r\x01\x07\x02\xa0\x82\x01y0\x82\x01u\x02\x01\x011\x0b0\t\x06\x05+\x0e\x03\x02\x1a\x05\x00\r\n\x00\xff"
+
+var multipartRequest = func() string {
+	body := torture(
+		"--7a9cbec02ceef655",
+		"Content-Type: text/plain",
+		"Content-Transfer-Encoding: binary",
+		"",
+		"Hello",
+		"--7a9cbec02ceef655",
+		"Content-Type: application/octet-stream",
+		"Content-Transfer-Encoding: binary",
+		"",
+		tortureSignature,
+		"--7a9cbec02ceef655--",
+		"")
+	return torture(
+		"MESSAGE sip:kumiko@example.org SIP/2.0",
+		"Via: SIP/2.0/UDP 127.0.0.1:5070;branch=z9hG4bK-d87543-4dade06d0bdb11ee-1--d87543-;rport",
+		"Max-Forwards: 70",
+		"Route: <sip:127.0.0.1:5080>",
+		"Identity: r5mwreLuyDRYBi/0TiPwEsY3rEVsk/G2WxhgTV1PF7hHuLIK0YWVKZhKv9Mj8UeXqkMVbnVq37CD+813gvYjcBUaZngQmXc9WNZSDNGCzA+fWl9MEUHWIZo1CeJebdY/XlgKeTa0Olvq0rt70Q5jiSfbqMJmQFteeivUhkMWYUA=",
+		"Contact: <sip:fluffy@127.0.0.1:5070>",
+		"To: <sip:kumiko@example.org>",
+		"From: <sip:fluffy@example.com>;tag=2fb0dcc9",
+		"Call-ID: 3d9485ad0c49859b@Zmx1ZmZ5LW1hYy0xNi5sb2NhbA..",
+		"CSeq: 1 MESSAGE",
+		"Content-Transfer-Encoding: binary",
+		"Content-Type: multipart/mixed;boundary=7a9cbec02ceef655",
+		"Date: Sat, 15 Oct 2005 04:44:56 GMT",
+		"User-Agent: SIPimp.org/0.2.5 (curses)",
+		"Content-Length: "+strconv.Itoa(len(body)),
+		"",
+		body)
+}()
+
+// The valid messages of RFC 4475 §3.1.1, §3.3 and §3.4, and of RFC 5118.
+var validTortureMessages = []struct {
+	name  string
+	msg   string
+	check func(t *testing.T, msg Message)
+}{
+	{"wsinv", torture(
+		"INVITE sip:vivekg@chair-dnrc.example.com;unknownparam SIP/2.0",
+		"TO :",
+		" sip:vivekg@chair-dnrc.example.com ;   tag    = 1918181833n",
+		"from   : \"J Rosenberg \\\\\\\"\"       <sip:jdrosen@example.com>",
+		"  ;",
+		"  tag = 98asjd8",
+		"MaX-fOrWaRdS: 0068",
+		"Call-ID: wsinv.ndaksdj@192.0.2.1",
+		"Content-Length   : 150",
+		"cseq: 0009",
+		"  INVITE",
+		"Via  : SIP  /   2.0",
+		" /UDP",
+		"    192.0.2.2;branch=390skdjuw",
+		"s :",
+		"NewFangledHeader:   newfangled value",
+		" continued newfangled value",
+		"UnknownHeaderWithUnusualValue: ;;,,;;,;",
+		"Content-Type: application/sdp",
+		"Route:",
+		" <sip:services.example.com;lr;unknownwith=value;unknown-no-value>",
+		"v:  SIP  / 2.0  / TCP     spindle.example.com   ;",
+		"  branch  =   z9hG4bK9ikj8  ,",
+		" SIP  /    2.0   / UDP  192.168.255.111   ; branch=",
+		" z9hG4bK30239",
+		"m:\"Quoted string \\\"\\\"\" <sip:jdrosen@example.com> ; newparam =",
+		"      newvalue ;",
+		"  secondparam ; q = 0.33",
+		"",
+		torture(
+			"v=0",
+			"o=mhandley 29739 7272939 IN IP4 192.0.2.3",
+			"s=-",
+			"c=IN IP4 192.0.2.4",
+			"t=0 0",
+			"m=audio 49217 RTP/AVP 0 12",
+			"m=video 3227 RTP/AVP 31",
+			"a=rtpmap:31 LPC",
+			"")),
+		func(t *testing.T, msg Message) {
+			to := tortureHeaders(t, msg, "To")[0].(*header.To)
+			if to.GetTag() != "1918181833n" {
+				t.Error("To tag", to.GetTag())
+			}
+			from := tortureHeaders(t, msg, "From")[0].(*header.From)
+			if from.GetTag() != "98asjd8" || from.GetDisplayName() != "J Rosenberg \\\\\\\"" {
+				t.Errorf("From %q %q", from.GetDisplayName(), from.GetTag())
+			}
+			if mf := tortureHeaders(t, msg, "Max-Forwards")[0].(*header.MaxForwards); mf.GetMaxForwards() != 68 {
+				t.Error("Max-Forwards", mf.GetMaxForwards())
+			}
+			cseq := tortureHeaders(t, msg, "CSeq")[0].(*header.CSeq)
+			if cseq.GetSequenceNumber() != 9 || cseq.GetMethod() != INVITE {
+				t.Error("CSeq", cseq.GetSequenceNumber(), cseq.GetMethod())
+			}
+			vias := tortureVias(t, msg)
+			if len(vias) != 3 || vias[0].GetHost() != "192.0.2.2" || vias[0].GetBranch() != "390skdjuw" ||
+				vias[1].GetTransport() != "TCP" || vias[1].GetHost() != "spindle.example.com" || vias[1].GetBranch() != "z9hG4bK9ikj8" ||
+				vias[2].GetHost() != "192.168.255.111" || vias[2].GetBranch() != "z9hG4bK30239" {
+				t.Error("Via", vias)
+			}
+			contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact)
+			if contact.GetQValue() != 0.33 || contact.GetParameter("newparam") != "newvalue" || !contact.HasParameter("secondparam") {
+				t.Error("Contact", contact)
+			}
+			if v := msg.GetHeader().Get("NewFangledHeader"); v != "newfangled value continued newfangled value" {
+				t.Errorf("NewFangledHeader %q", v)
+			}
+			if v, ok := msg.GetHeader()["Subject"]; !ok || v[0] != "" {
+				t.Errorf("Subject %q", v)
+			}
+		}},
+
+	{"intmeth", torture(
+		"!interesting-Method0123456789_*+`.%indeed'~ sip:1_unusual.URI~(to-be!sure)&isn't+it$/crazy?,/;;*:&it+has=1,weird!*pas$wo~d_too.(doesn't-it)@example.com SIP/2.0",
+		"Via: SIP/2.0/TCP host1.example.com;branch=z9hG4bK-.!%66*_+`'~",
+		"To: \"BEL:\\\x07 NUL:\\\x00 DEL:\\\x7f\" <sip:1_unusual.URI~(to-be!sure)&isn't+it$/crazy?,/;;*@example.com>",
+		"From: token1~` token2'+_ token3*%!.- <sip:mundane@example.com>;fromParam''~+*_!.-%=\"\u0440\u0430\u0431\u043e\u0442\u0430\u044e\u0449\u0438\u0439\";tag=_token~1'+`*%!-.",
+		"Call-ID: intmeth.word%ZK-!.*_+'@word`~)(><:\\/\"][?}{",
+		"CSeq: 139122385 !interesting-Method0123456789_*+`.%indeed'~",
+		"Max-Forwards: 255",
+		"extensionHeader-!.%*+_`'~:\ufeff\u5927\u505c\u96fb",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			req := msg.(Request)
+			cseq := tortureHeaders(t, msg, "CSeq")[0].(*header.CSeq)
+			if req.GetMethod() != "!interesting-Method0123456789_*+`.%indeed'~" || cseq.GetMethod() != req.GetMethod() {
+				t.Error("method", req.GetMethod(), cseq.GetMethod())
+			}
+			if req.GetRequestURI() != "sip:1_unusual.URI~(to-be!sure)&isn't+it$/crazy?,/;;*:&it+has=1,weird!*pas$wo~d_too.(doesn't-it)@example.com" {
+				t.Error("Request-URI", req.GetRequestURI())
+			}
+			if vias := tortureVias(t, msg); vias[0].GetBranch() != "z9hG4bK-.!%66*_+`'~" {
+				t.Error("branch", vias[0].GetBranch())
+			}
+			if to := tortureHeaders(t, msg, "To")[0].(*header.To); to.GetDisplayName() != "BEL:\\\x07 NUL:\\\x00 DEL:\\\x7f" {
+				t.Errorf("To %q", to.GetDisplayName())
+			}
+			from := tortureHeaders(t, msg, "From")[0].(*header.From)
+			if from.GetTag() != "_token~1'+`*%!-." || from.GetDisplayName() != "token1~` token2'+_ token3*%!.-" {
+				t.Errorf("From %q %q", from.GetDisplayName(), from.GetTag())
+			}
+			if id := tortureHeaders(t, msg, "Call-ID")[0].(*header.CallID); id.GetCallId() != "intmeth.word%ZK-!.*_+'@word`~)(><:\\/\"][?}{" {
+				t.Error("Call-ID", id.GetCallId())
+			}
+			if mf := tortureHeaders(t, msg, "Max-Forwards")[0].(*header.MaxForwards); mf.GetMaxForwards() != 255 {
+				t.Error("Max-Forwards", mf.GetMaxForwards())
+			}
+			if v := msg.GetHeader()[CanonicalHeaderKey("extensionHeader-!.%*+_`'~")]; len(v) != 1 || v[0] != "\ufeff\u5927\u505c\u96fb" {
+				t.Errorf("extension header %q", v)
+			}
+		}},
+
+	{"esc01", torture(
+		"INVITE sip:sips%3Auser%40example.com@example.net SIP/2.0",
+		"To: sip:%75se%72@example.com",
+		"From: <sip:I%20have%20spaces@example.net>;tag=938",
+		"Max-Forwards: 87",
+		"i: esc01.239409asdfakjkn23onasd0-3234",
+		"CSeq: 234234 INVITE",
+		"Via: SIP/2.0/UDP host5.example.net;branch=z9hG4bKkdjuw",
+		"C: application/sdp",
+		"Contact:",
+		"  <sip:cal%6Cer@host5.example.net;%6C%72;n%61me=v%61lue%25%34%31>",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.1")),
+		func(t *testing.T, msg Message) {
+			to := tortureHeaders(t, msg, "To")[0].(*header.To)
+			if to.GetUserAtHostPort() != "%75se%72@example.com" {
+				t.Error("To", to.GetUserAtHostPort())
+			}
+			contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact)
+			if !strings.Contains(contact.GetAddress().GetURI().String(), "n%61me=v%61lue%25%34%31") {
+				t.Error("Contact", contact.GetAddress().GetURI())
+			}
+		}},
+
+	{"escnull", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: sip:null-%00-null@example.com",
+		"From: sip:null-%00-null@example.com;tag=839923423",
+		"Max-Forwards: 70",
+		"Call-ID: escnull.39203ndfvkjdasfkq3w4otrq0adsfdfnavd",
+		"CSeq: 14398234 REGISTER",
+		"Via: SIP/2.0/UDP host5.example.com;branch=z9hG4bKkdjuw",
+		"Contact: <sip:%00@host5.example.com>",
+		"Contact: <sip:%00%00@host5.example.com>",
+		"L:0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if contacts := tortureHeaders(t, msg, "Contact"); len(contacts) != 2 {
+				t.Error("Contact", contacts)
+			}
+			if from := tortureHeaders(t, msg, "From")[0].(*header.From); from.GetTag() != "839923423" {
+				t.Error("From", from.GetTag())
+			}
+		}},
+
+	{"esc02", torture(
+		"RE%47IST%45R sip:registrar.example.com SIP/2.0",
+		"To: \"%Z%45\" <sip:resource@example.com>",
+		"From: \"%Z%45\" <sip:resource@example.com>;tag=f232jadfj23",
+		"Call-ID: esc02.asdfnqwo34rq23i34jrjasdcnl23nrlknsdf",
+		"Via: SIP/2.0/TCP host.example.com;branch=z9hG4bK209793",
+		"Contact: <sip:alias1@host1.example.com>",
+		"Contact: <sip:alias2@host2.example.com>",
+		"Contact: <sip:alias3@host3.example.com>",
+		"Max-Forwards: 68",
+		"l: 0",
+		"CSeq: 29344 RE%47IST%45R",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			//the method is not REGISTER, % is no escape in a method
+			if method := msg.(Request).GetMethod(); method != "RE%47IST%45R" {
+				t.Error("method", method)
+			}
+			if cseq := tortureHeaders(t, msg, "CSeq")[0].(*header.CSeq); cseq.GetMethod() != "RE%47IST%45R" {
+				t.Error("CSeq", cseq.GetMethod())
+			}
+			if to := tortureHeaders(t, msg, "To")[0].(*header.To); to.GetDisplayName() != "%Z%45" {
+				t.Error("To", to.GetDisplayName())
+			}
+			if contacts := tortureHeaders(t, msg, "Contact"); len(contacts) != 3 {
+				t.Error("Contact", contacts)
+			}
+		}},
+
+	{"lwsdisp", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: caller<sip:caller@example.com>;tag=323",
+		"Max-Forwards: 70",
+		"Call-ID: lwsdisp.1234abcd@funky.example.com",
+		"CSeq: 60 OPTIONS",
+		"Via: SIP/2.0/UDP funky.example.com;branch=z9hG4bKkdjuw",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			from := tortureHeaders(t, msg, "From")[0].(*header.From)
+			if from.GetDisplayName() != "caller" || from.GetTag() != "323" {
+				t.Errorf("From %q %q", from.GetDisplayName(), from.GetTag())
+			}
+		}},
+
+	{"longreq", longRequest,
+		func(t *testing.T, msg Message) {
+			if vias := tortureVias(t, msg); len(vias) != 33 || vias[32].GetHost() != "sip1.example.com" {
+				t.Error("Via", len(vias))
+			}
+			id := tortureHeaders(t, msg, "Call-ID")[0].(*header.CallID)
+			if len(id.GetCallId()) != len("longreq.one")+600+len("longcallid") {
+				t.Error("Call-ID", id.GetCallId())
+			}
+			from := tortureHeaders(t, msg, "From")[0].(*header.From)
+			if from.GetTag() != "12"+strings.Repeat("982", 10)+"424" {
+				t.Error("From", from.GetTag())
+			}
+			to := tortureHeaders(t, msg, "To")[0].(*header.To)
+			if to.GetDisplayName() != "I have a user name of "+strings.Repeat("extreme", 10)+" proportion" {
+				t.Error("To", to.GetDisplayName())
+			}
+		}},
+
+	{"dblreq", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: sip:j.user@example.com",
+		"From: sip:j.user@example.com;tag=43251j3j324",
+		"Max-Forwards: 8",
+		"I: dblreq.0ha0isndaksdj99sdfafnl3lk233412",
+		"Contact: sip:j.user@host.example.com",
+		"CSeq: 8 REGISTER",
+		"Via: SIP/2.0/UDP 192.0.2.125;branch=z9hG4bKkdjuw23492",
+		"Content-Length: 0",
+		"",
+		"",
+		"INVITE sip:joe@example.com SIP/2.0",
+		"t: sip:joe@example.com",
+		"From: sip:caller@example.net;tag=141334",
+		"Max-Forwards: 8",
+		"Call-ID: dblreq.0ha0isnda977644900765@192.0.2.15",
+		"CSeq: 8 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.15;branch=z9hG4bKkdjuw380234",
+		"Content-Type: application/sdp",
+		"Content-Length: 152",
+		"",
+		tortureSDP("192.0.2.15")),
+		func(t *testing.T, msg Message) {
+			//the octets after the Content-Length are not part of the message
+			if msg.(Request).GetMethod() != REGISTER || msg.GetContentLength() != 0 {
+				t.Error(msg.(Request).GetMethod(), msg.GetContentLength())
+			}
+		}},
+
+	{"semiuri", torture(
+		"OPTIONS sip:user;par=u%40example.net@example.com SIP/2.0",
+		"To: sip:j_user@example.com",
+		"From: sip:caller@example.org;tag=33242",
+		"Max-Forwards: 3",
+		"Call-ID: semiuri.0ha0isndaksdj",
+		"CSeq: 8 OPTIONS",
+		"Accept: application/sdp, application/pkcs7-mime,",
+		"        multipart/mixed, multipart/signed,",
+		"        message/sip, message/sipfrag",
+		"Via: SIP/2.0/UDP 192.0.2.1;branch=z9hG4bKkdjuw",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if accepts := tortureHeaders(t, msg, "Accept"); len(accepts) != 6 || accepts[5].(*header.Accept).GetContentSubType() != "sipfrag" {
+				t.Error("Accept", accepts)
+			}
+		}},
+
+	{"transports", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: <sip:caller@example.com>;tag=323",
+		"Max-Forwards: 70",
+		"Call-ID:  transports.kijh4akdnaqjkwendsasfdj",
+		"Accept: application/sdp",
+		"CSeq: 60 OPTIONS",
+		"Via: SIP/2.0/UDP t1.example.com;branch=z9hG4bKkdjuw",
+		"Via: SIP/2.0/SCTP t2.example.com;branch=z9hG4bKklasjdhf",
+		"Via: SIP/2.0/TLS t3.example.com;branch=z9hG4bK2980unddj",
+		"Via: SIP/2.0/UNKNOWN t4.example.com;branch=z9hG4bKasd0f3en",
+		"Via: SIP/2.0/TCP t5.example.com;branch=z9hG4bK0a9idfnee",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			var transports []string
+			for _, via := range tortureVias(t, msg) {
+				transports = append(transports, via.GetTransport())
+			}
+			if strings.Join(transports, " ") != "UDP SCTP TLS UNKNOWN TCP" {
+				t.Error("Via", transports)
+			}
+		}},
+
+	{"mpart01", multipartRequest,
+		func(t *testing.T, msg Message) {
+			body, err := GetMultipartBody(msg)
+			if err != nil || len(body.Parts) != 2 || string(body.Parts[0].Body) != "Hello" {
+				t.Fatal(body, err)
+			}
+			if content := body.Parts[1].Body; string(content) != tortureSignature {
+				t.Errorf("%q", content)
+			}
+		}},
+
+	{"unreason", torture(
+		"SIP/2.0 200 = 2**3 * 5**2 \u043d\u043e \u0441\u0442\u043e \u0434\u0435\u0432\u044f\u043d\u043e\u0441\u0442\u043e \u0434\u0435\u0432\u044f\u0442\u044c - \u043f\u0440\u043e\u0441\u0442\u043e\u0435",
+		"Via: SIP/2.0/UDP 192.0.2.198;branch=z9hG4bK1324923",
+		"Call-ID: unreason.1234ksdfak3j2erwedfsASdf",
+		"CSeq: 35 INVITE",
+		"From: sip:user@example.com;tag=11141343",
+		"To: sip:user@example.edu;tag=2229",
+		"Content-Length: 154",
+		"Content-Type: application/sdp",
+		"Contact: <sip:user@host198.example.com>",
+		"",
+		tortureSDP("192.0.2.198")),
+		func(t *testing.T, msg Message) {
+			resp := msg.(Response)
+			if resp.GetStatusCode() != 200 || resp.GetReasonPhrase() != "= 2**3 * 5**2 \u043d\u043e \u0441\u0442\u043e \u0434\u0435\u0432\u044f\u043d\u043e\u0441\u0442\u043e \u0434\u0435\u0432\u044f\u0442\u044c - \u043f\u0440\u043e\u0441\u0442\u043e\u0435" {
+				t.Error(resp.GetStatusCode(), resp.GetReasonPhrase())
+			}
+		}},
+
+	{"noreason", torture(
+		"SIP/2.0 100 ",
+		"Via: SIP/2.0/UDP 192.0.2.105;branch=z9hG4bK2398ndaoe",
+		"Call-ID: noreason.asndj203insdf99223ndf",
+		"CSeq: 35 INVITE",
+		"From: <sip:user@example.com>;tag=39ansfi3",
+		"To: <sip:user@example.edu>;tag=902jndnke3",
+		"Content-Length: 0",
+		"Contact: <sip:user@host105.example.com>",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if resp := msg.(Response); resp.GetStatusCode() != 100 || resp.GetReasonPhrase() != "" {
+				t.Errorf("%d %q", resp.GetStatusCode(), resp.GetReasonPhrase())
+			}
+		}},
+
+	//§3.2.1, a request from an RFC 2543 element, matched without branch
+	{"badbranch", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.org;tag=33242",
+		"Max-Forwards: 3",
+		"Via: SIP/2.0/UDP 192.0.2.1;branch=z9hG4bK",
+		"Accept: application/sdp",
+		"Call-ID: badbranch.sadonfo23i420jv0as0derf3j3n",
+		"CSeq: 8 OPTIONS",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if vias := tortureVias(t, msg); vias[0].GetBranch() != "z9hG4bK" {
+				t.Error("branch", vias[0].GetBranch())
+			}
+		}},
+
+	//§3.3.4, the registrar turns the URIs down, not the parser
+	{"unksm2", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: isbn:2983792873",
+		"From: <http://www.example.com>;tag=3234233",
+		"Call-ID: unksm2.daksdj@hyphenated-host.example.com",
+		"CSeq: 234902 REGISTER",
+		"Max-Forwards: 70",
+		"Via: SIP/2.0/UDP 192.0.2.21:5060;branch=z9hG4bKkdjuw",
+		"Contact: <name:John_Smith>",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if to := tortureHeaders(t, msg, "To")[0].(*header.To); to.GetAddress().GetURI().String() != "isbn:2983792873" {
+				t.Error("To", to.GetAddress().GetURI())
+			}
+			if contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact); contact.GetAddress().GetURI().GetScheme() != "name" {
+				t.Error("Contact", contact.GetAddress().GetURI())
+			}
+		}},
+
+	//§3.3.7, the Authorization is checked by the application
+	{"regaut01", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: sip:j.user@example.com",
+		"From: sip:j.user@example.com;tag=87321hj23128",
+		"Max-Forwards: 8",
+		"Call-ID: regaut01.0ha0isndaksdj",
+		"CSeq: 9338 REGISTER",
+		"Via: SIP/2.0/TCP 192.0.2.253;branch=z9hG4bKkdjuw",
+		"Authorization: NoOneKnowsThisScheme opaque-data=here",
+		"Content-Length:0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if auth := tortureHeaders(t, msg, "Authorization")[0].(*header.Authorization); auth.GetScheme() != "NoOneKnowsThisScheme" {
+				t.Error("Authorization", auth.GetScheme())
+			}
+		}},
+
+	//§3.3.11, the broadcast Via is the concern of the UA, not the parser
+	{"bcast", torture(
+		"SIP/2.0 200 OK",
+		"Via: SIP/2.0/UDP 192.0.2.198;branch=z9hG4bK1324923",
+		"Via: SIP/2.0/UDP 255.255.255.255;branch=z9hG4bK1saber23",
+		"Call-ID: bcast.0384840201234ksdfak3j2erwedfsASdf",
+		"CSeq: 35 INVITE",
+		"From: sip:user@example.com;tag=11141343",
+		"To: sip:user@example.edu;tag=2229",
+		"Content-Length: 154",
+		"Content-Type: application/sdp",
+		"Contact: <sip:user@host28.example.com>",
+		"",
+		tortureSDP("192.0.2.198")),
+		func(t *testing.T, msg Message) {
+			if vias := tortureVias(t, msg); len(vias) != 2 || vias[1].GetHost() != "255.255.255.255" {
+				t.Error("Via", vias)
+			}
+		}},
+
+	//§3.3.12, a UAS answers a request with Max-Forwards 0
+	{"zeromf", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=3ghsd41",
+		"Call-ID: zeromf.jfasdlfnm2o2l43r5u0asdfas",
+		"CSeq: 39234321 OPTIONS",
+		"Via: SIP/2.0/UDP host1.example.com;branch=z9hG4bKkdjuw2349i",
+		"Max-Forwards: 0",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if mf := tortureHeaders(t, msg, "Max-Forwards")[0].(*header.MaxForwards); !mf.HasReachedZero() {
+				t.Error("Max-Forwards", mf.GetMaxForwards())
+			}
+		}},
+
+	{"cparam01", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"Via: SIP/2.0/UDP saturn.example.com:5060;branch=z9hG4bKkdjuw",
+		"Max-Forwards: 70",
+		"From: sip:watson@example.com;tag=DkfVgjkrtMwaerKKpe",
+		"To: sip:watson@example.com",
+		"Call-ID: cparam01.70710@saturn.example.com",
+		"CSeq: 2 REGISTER",
+		"Contact: sip:+19725552222@gw1.example.net;unknownparam",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			//without <>, the parameter belongs to the header
+			contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact)
+			if !contact.HasParameter("unknownparam") || strings.Contains(contact.GetAddress().GetURI().String(), "unknownparam") {
+				t.Error("Contact", contact)
+			}
+		}},
+
+	{"cparam02", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"Via: SIP/2.0/UDP saturn.example.com:5060;branch=z9hG4bKkdjuw",
+		"Max-Forwards: 70",
+		"From: sip:watson@example.com;tag=838293",
+		"To: sip:watson@example.com",
+		"Call-ID: cparam02.70710@saturn.example.com",
+		"CSeq: 3 REGISTER",
+		"Contact: <sip:+19725552222@gw1.example.net;unknownparam>",
+		"l: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			//within <>, the parameter belongs to the URI
+			contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact)
+			if contact.HasParameter("unknownparam") || !strings.Contains(contact.GetAddress().GetURI().String(), "unknownparam") {
+				t.Error("Contact", contact)
+			}
+		}},
+
+	{"regescrt", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=8",
+		"Max-Forwards: 70",
+		"Call-ID: regescrt.k345asrl3fdbv@192.0.2.1",
+		"CSeq: 14398234 REGISTER",
+		"Via: SIP/2.0/UDP host5.example.com;branch=z9hG4bKkdjuw",
+		"M: <sip:user@example.com?Route=%3Csip:sip.example.com%3E>",
+		"L:0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact)
+			if !strings.Contains(contact.GetAddress().GetURI().String(), "Route=%3Csip:sip.example.com%3E") {
+				t.Error("Contact", contact.GetAddress().GetURI())
+			}
+		}},
+
+	//§3.4.1, RFC 2543 syntax is still accepted
+	{"inv2543", torture(
+		"INVITE sip:UserB@example.com SIP/2.0",
+		"Via: SIP/2.0/UDP iftgw.example.com",
+		"From: <sip:+13035551111@ift.client.example.net;user=phone>",
+		"Record-Route: <sip:UserB@example.com;maddr=ss1.example.com>",
+		"To: sip:+16505552222@ss1.example.net;user=phone",
+		"Call-ID: inv2543.1717@ift.client.example.com",
+		"CSeq: 56 INVITE",
+		"Content-Type: application/sdp",
+		"",
+		tortureSDP("192.0.2.5")),
+		func(t *testing.T, msg Message) {
+			if vias := tortureVias(t, msg); vias[0].GetBranch() != "" {
+				t.Error("branch", vias[0].GetBranch())
+			}
+			if to := tortureHeaders(t, msg, "To")[0].(*header.To); !to.HasParameter("user") {
+				t.Error("To", to)
+			}
+		}},
+
+	//RFC 5118 §4.1
+	{"ipv6-good", torture(
+		"REGISTER sip:[2001:db8::10] SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Max-Forwards: 70",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::1]>",
+		"CSeq: 98176 REGISTER",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if vias := tortureVias(t, msg); vias[0].GetHost() != "[2001:db8::9:1]" {
+				t.Error("Via", vias[0].GetHost())
+			}
+			if contact := tortureHeaders(t, msg, "Contact")[0].(*header.Contact); contact.GetAddress().GetURI().String() != "sip:caller@[2001:db8::1]" {
+				t.Error("Contact", contact.GetAddress().GetURI())
+			}
+		}},
+
+	//RFC 5118 §4.3, the port is part of the address
+	{"port-ambiguous", torture(
+		"REGISTER sip:[2001:db8::10:5070] SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::1]>",
+		"Max-Forwards: 70",
+		"CSeq: 98176 REGISTER",
+		"Content-Length: 0",
+		"",
+		""), nil},
+
+	//RFC 5118 §4.4
+	{"port-unambiguous", torture(
+		"REGISTER sip:[2001:db8::10]:5070 SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1]:5070;branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::1]>",
+		"Max-Forwards: 70",
+		"CSeq: 98176 REGISTER",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if via := tortureVias(t, msg)[0]; via.GetHost() != "[2001:db8::9:1]" || via.GetPort() != 5070 {
+				t.Error("Via", via.GetHost(), via.GetPort())
+			}
+		}},
+
+	//RFC 5118 §4.5
+	{"via-received-param-with-delim", torture(
+		"BYE sip:[2001:db8::10] SIP/2.0",
+		"To: sip:user@example.com;tag=bd76ya",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];received=[2001:db8::9:255];branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Max-Forwards: 70",
+		"CSeq: 321 BYE",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if via := tortureVias(t, msg)[0]; via.GetReceived() != "[2001:db8::9:255]" || via.GetBranch() != "z9hG4bKas3-111" {
+				t.Error("Via", via.GetReceived(), via.GetBranch())
+			}
+		}},
+
+	//RFC 5118 §4.6
+	{"via-received-param-no-delim", torture(
+		"OPTIONS sip:[2001:db8::10] SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];received=2001:db8::9:255;branch=z9hG4bKas3",
+		"Call-ID: SSG95523997077@hlau_4100",
+		"Max-Forwards: 70",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::9:1]>",
+		"CSeq: 921 OPTIONS",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if via := tortureVias(t, msg)[0]; via.GetReceived() != "2001:db8::9:255" || via.GetBranch() != "z9hG4bKas3" {
+				t.Error("Via", via.GetReceived(), via.GetBranch())
+			}
+		}},
+
+	//RFC 5118 §4.7
+	{"ipv4-mapped", torture(
+		"INVITE sip:user@[2001:db8::10] SIP/2.0",
+		"To: sip:user@[2001:db8::10]",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [::ffff:192.0.2.10]:19823;branch=z9hG4bKbh19",
+		"Via: SIP/2.0/UDP [::ffff:192.0.2.2]:10820;branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Contact: \"T. desk phone\" <sip:ted@[::ffff:192.0.2.2]>",
+		"CSeq: 612 INVITE",
+		"Max-Forwards: 70",
+		"Content-Type: application/sdp",
+		"Content-Length: 168",
+		"",
+		torture(
+			"v=0",
+			"o=assistant 971731711378798081 0 IN IP6 ::ffff:192.0.2.2",
+			"s=Call me soon, please!",
+			"c=IN IP6 ::ffff:192.0.2.2",
+			"t=0 0",
+			"m=audio 5000 RTP/AVP 0",
+			"a=rtpmap:0 PCMU/8000",
+			"")),
+		func(t *testing.T, msg Message) {
+			if via := tortureVias(t, msg)[1]; via.GetHost() != "[::ffff:192.0.2.2]" || via.GetPort() != 10820 {
+				t.Error("Via", via.GetHost(), via.GetPort())
+			}
+		}},
+
+	//RFC 5118 §4.9
+	{"ipv6-correct-abnf-2-colons", torture(
+		"OPTIONS sip:user@[2001:db8::192.0.2.1] SIP/2.0",
+		"To: sip:user@[2001:db8::192.0.2.1]",
+		"From: sip:user@example.com;tag=810x2",
+		"Via: SIP/2.0/UDP lab1.east.example.com;branch=z9hG4bKas3-111",
+		"Call-ID: G9559905523997077@hlau_4100",
+		"CSeq: 689 OPTIONS",
+		"Max-Forwards: 70",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			if to := tortureHeaders(t, msg, "To")[0].(*header.To); to.GetAddress().GetURI().String() != "sip:user@[2001:db8::192.0.2.1]" {
+				t.Error("To", to.GetAddress().GetURI())
+			}
+		}},
+
+	//RFC 5118 §4.10
+	{"mult-ip-in-header", torture(
+		"BYE sip:user@host.example.net SIP/2.0",
+		"Via: SIP/2.0/UDP [2001:db8::9:1]:6050;branch=z9hG4bKas3-111",
+		"Via: SIP/2.0/UDP 192.0.2.1;branch=z9hG4bKjhja8781hjuaij65144",
+		"Via: SIP/2.0/TCP [2001:db8::9:255];branch=z9hG4bK451jj;received=192.0.2.200",
+		"Call-ID: 997077@lau_4100",
+		"Max-Forwards: 70",
+		"CSeq: 89187 BYE",
+		"To: sip:user@example.net;tag=9817--94",
+		"From: sip:user@example.com;tag=81x2",
+		"Content-Length: 0",
+		"",
+		""),
+		func(t *testing.T, msg Message) {
+			vias := tortureVias(t, msg)
+			if len(vias) != 3 || vias[0].GetPort() != 6050 || vias[2].GetReceived() != "192.0.2.200" {
+				t.Error("Via", vias)
+			}
+		}},
+
+	//RFC 5118 §4.11
+	{"mult-ip-in-sdp", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::9:1]>",
+		"CSeq: 8612 INVITE",
+		"Max-Forwards: 70",
+		"Content-Type: application/sdp",
+		"Content-Length: 181",
+		"",
+		torture(
+			"v=0",
+			"o=bob 280744730 28977631 IN IP4 host.example.com",
+			"s=",
+			"t=0 0",
+			"m=audio 22334 RTP/AVP 0",
+			"c=IN IP4 192.0.2.1",
+			"m=video 6024 RTP/AVP 107",
+			"c=IN IP6 2001:db8::1",
+			"a=rtpmap:107 H263-1998/90000",
+			"")), nil},
+}
+
+func TestTortureValid(t *testing.T) {
+	for _, tv := range validTortureMessages {
+		t.Run(tv.name, func(t *testing.T) {
+			msg, err := ReadMessage(bufio.NewReader(strings.NewReader(tv.msg)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			//every header the stack has a parser for has to parse
+			for name, values := range msg.GetHeader() {
+				for _, v := range values {
+					if _, err := parseHeaderValue(name, v); err != nil {
+						t.Errorf("%s: %v", name, err)
+					}
+				}
+			}
+			body, err := rawBodyBytes(msg)
+			if err != nil || int64(len(body)) != msg.GetContentLength() {
+				t.Errorf("body of %d octets out of %d: %v", len(body), msg.GetContentLength(), err)
+			}
+			if tv.check != nil {
+				tv.check(t, msg)
+			}
+		})
+	}
+}
+
+// How an invalid message has to be turned down.
+const (
+	// ReadMessage returns an error
+	rejectRead = -1 - iota
+	// the typed parser of a header returns an error
+	rejectHeader
+	// reading the body returns an error
+	rejectBody
+	// the validator drops the message
+	rejectDrop
+)
+
+// The invalid messages of RFC 4475 §3.1.2 and §3.3, and of RFC 5118, with
+// the header that is malformed or the status code of the response that
+// turns them down.
+var invalidTortureMessages = []struct {
+	name   string
+	msg    string
+	reject int
+	header string
+}{
+	{"badinv01", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"To: sip:j.user@example.com",
+		"From: sip:caller@example.net;tag=134161461246",
+		"Max-Forwards: 7",
+		"Call-ID: badinv01.0ha0isndaksdjasdf3234nas",
+		"CSeq: 8 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.15;;,;,,",
+		"Contact: \"Joe\" <sip:joe@example.org>;;;;",
+		"Content-Length: 152",
+		"Content-Type: application/sdp",
+		"",
+		tortureSDP("192.0.2.15")), rejectHeader, "Via"},
+
+	{"clerr", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"Max-Forwards: 80",
+		"To: sip:j.user@example.com",
+		"From: sip:caller@example.net;tag=93942939o2",
+		"Contact: <sip:caller@hungry.example.net>",
+		"Call-ID: clerr.0ha0isndaksdjweiafasdk3",
+		"CSeq: 8 INVITE",
+		"Via: SIP/2.0/UDP host5.example.com;branch=z9hG4bK-39234-23523",
+		"Content-Type: application/sdp",
+		"Content-Length: 9999",
+		"",
+		tortureSDP("192.0.2.155")), rejectBody, ""},
+
+	{"scalar02", torture(
+		"INVITE sip:vivekg@chair-dnrc.example.com SIP/2.0",
+		"Via: SIP/2.0/UDP 192.0.2.253;branch=z9hG4bKkdjuw",
+		"Max-Forwards: 70",
+		"From: J Rosenberg \\\\\\\"  <sip:jdrosen@example.com>",
+		"  ;",
+		"  tag = 98asjd8",
+		"To: <sip:vivekg@chair-dnrc.example.com>",
+		"Call-ID: scalar02.23o0pd9vanlq3wnrlnewofjas9ui32",
+		"CSeq: 8 INVITE",
+		"Content-Length: -999",
+		"Contact: <sip:jdrosen@biloxi.example.com>",
+		"",
+		tortureSDP("192.0.2.253")), rejectRead, "Content-Length"},
+
+	{"scalarlg", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"Via: SIP/2.0/TCP host129.example.com;branch=z9hG4bK342sdfoi3",
+		"To: <sip:user@example.com>",
+		"From: <sip:user@example.com>;tag=239232jh3",
+		"CSeq: 36893488147419103232 REGISTER",
+		"Call-ID: scalar01.23o0pd9vanlq3wnrlnewofjas9ui32",
+		"Max-Forwards: 300",
+		"Expires: 1"+strings.Repeat("0", 90),
+		"Contact: <sip:user@host129.example.com>",
+		"  ;expires=280297596632815",
+		"Content-Length: 0",
+		"",
+		""), BAD_REQUEST, "CSeq"},
+
+	{"scalarlg-response", torture(
+		"SIP/2.0 503 Service Unavailable",
+		"Via: SIP/2.0/TCP host129.example.com;branch=z9hG4bKzzxdiwo34sw;received=192.0.2.129",
+		"To: <sip:user@example.com>",
+		"From: <sip:other@example.net>;tag=2easdjfejw",
+		"CSeq: 9292394834772304023312 OPTIONS",
+		"Call-ID: scalarlg.noase0of0234hn2qofoaf0232aewf2394r",
+		"Retry-After: 949302838503028349304023988",
+		"Warning: 1812 overture \"In Progress\"",
+		"Content-Length: 0",
+		"",
+		""), rejectDrop, "CSeq"},
+
+	{"quotbal", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"To: \"Mr. J. User <sip:j.user@example.com>",
+		"From: sip:caller@example.net;tag=93334",
+		"Max-Forwards: 10",
+		"Call-ID: quotbal.aksdj",
+		"Contact: <sip:caller@host59.example.net>",
+		"CSeq: 8 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.59:5050;branch=z9hG4bKkdjuw39234",
+		"Content-Type: application/sdp",
+		"Content-Length: 152",
+		"",
+		tortureSDP("192.0.2.15")), BAD_REQUEST, "To"},
+
+	{"ltgtruri", torture(
+		"INVITE <sip:user@example.com> SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=39291",
+		"Max-Forwards: 23",
+		"Call-ID: ltgtruri.1@192.0.2.5",
+		"CSeq: 1 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.5",
+		"Contact: <sip:caller@host5.example.net>",
+		"Content-Type: application/sdp",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.5")), rejectRead, ""},
+
+	{"lwsruri", torture(
+		"INVITE sip:user@example.com; lr SIP/2.0",
+		"To: sip:user@example.com;tag=3xfe-9921883-z9f",
+		"From: sip:caller@example.net;tag=231413434",
+		"Max-Forwards: 5",
+		"Call-ID: lwsruri.asdfasdoeoi2323-asdfwrn23-asd834rk423",
+		"CSeq: 2130706432 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.1:5060;branch=z9hG4bKkdjuw2395",
+		"Contact: <sip:caller@host1.example.net>",
+		"Content-Type: application/sdp",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.1")), rejectRead, ""},
+
+	{"lwsstart", torture(
+		"INVITE  sip:user@example.com  SIP/2.0",
+		"Max-Forwards: 8",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=8814",
+		"Call-ID: lwsstart.dfknq234oi243099adsdfnawe3@example.com",
+		"CSeq: 1893884 INVITE",
+		"Via: SIP/2.0/UDP host1.example.com;branch=z9hG4bKkdjuw3923",
+		"Contact: <sip:caller@host1.example.net>",
+		"Content-Type: application/sdp",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.1")), rejectRead, ""},
+
+	{"trws", torture(
+		"OPTIONS sip:remote-target@example.com SIP/2.0  ",
+		"Via: SIP/2.0/TCP host1.example.com;branch=z9hG4bK299342093",
+		"To: <sip:remote-target@example.com>",
+		"From: <sip:local-resource@example.com>;tag=329429089",
+		"Call-ID: trws.oicu34958239neffasdhr2345r",
+		"Accept: application/sdp",
+		"CSeq: 238923 OPTIONS",
+		"Max-Forwards: 70",
+		"Content-Length: 0",
+		"",
+		""), rejectRead, ""},
+
+	{"escruri", torture(
+		"INVITE sip:user@example.com?Route=%3Csip:example.com%3E SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=341518",
+		"Max-Forwards: 7",
+		"Contact: <sip:caller@host39923.example.net>",
+		"Call-ID: escruri.23940-asdfhj-aje3br-234q098w-fawerh2q-h4n5",
+		"CSeq: 149209342 INVITE",
+		"Via: SIP/2.0/UDP host-of-the-hour.example.com;branch=z9hG4bKkdjuw",
+		"Content-Type: application/sdp",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.1")), BAD_REQUEST, ""},
+
+	{"baddate", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=2234923",
+		"Max-Forwards: 70",
+		"Call-ID: baddate.239423mnsadf3j23lj42--sedfnm234",
+		"CSeq: 1392934 INVITE",
+		"Via: SIP/2.0/UDP host.example.com;branch=z9hG4bKkdjuw",
+		"Date: Fri, 01 Jan 2010 16:00:00 EST",
+		"Contact: <sip:caller@host5.example.net>",
+		"Content-Type: application/sdp",
+		"Content-Length: 150",
+		"",
+		tortureSDP("192.0.2.5")), rejectHeader, "Date"},
+
+	{"regbadct", torture(
+		"REGISTER sip:example.com SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=998332",
+		"Max-Forwards: 70",
+		"Call-ID: regbadct.k345asrl3fdbv@10.0.0.1",
+		"CSeq: 1 REGISTER",
+		"Via: SIP/2.0/UDP 135.180.130.133:5060;branch=z9hG4bKkdjuw",
+		"Contact: sip:user@example.com?Route=%3Csip:sip.example.com%3E",
+		"l: 0",
+		"",
+		""), rejectHeader, "Contact"},
+
+	{"badaspec", torture(
+		"OPTIONS sip:user@example.org SIP/2.0",
+		"Via: SIP/2.0/UDP host4.example.com:5060;branch=z9hG4bKkdju43234",
+		"Max-Forwards: 70",
+		"From: \"Bell, Alexander\" <sip:a.g.bell@example.com>;tag=433423",
+		"To: \"Watson, Thomas\" < sip:t.watson@example.org >",
+		"Call-ID: badaspec.sdf0234n2nds0a099u23h3hnnw009cdkne3",
+		"Accept: application/sdp",
+		"CSeq: 3923239 OPTIONS",
+		"l: 0",
+		"",
+		""), BAD_REQUEST, "To"},
+
+	{"baddn", torture(
+		"OPTIONS sip:t.watson@example.org SIP/2.0",
+		"Via:     SIP/2.0/UDP c.example.com:5060;branch=z9hG4bKkdjuw",
+		"Max-Forwards:      70",
+		"From:    Bell, Alexander <sip:a.g.bell@example.com>;tag=43",
+		"To:      Watson, Thomas <sip:t.watson@example.org>",
+		"Call-ID: baddn.31415@c.example.com",
+		"Accept: application/sdp",
+		"CSeq:    3923239 OPTIONS",
+		"l: 0",
+		"",
+		""), BAD_REQUEST, "To"},
+
+	{"badvers", torture(
+		"OPTIONS sip:t.watson@example.org SIP/7.0",
+		"Via:     SIP/7.0/UDP c.example.com;branch=z9hG4bKkdjuw",
+		"Max-Forwards:     70",
+		"From:    A. Bell <sip:a.g.bell@example.com>;tag=qweoiqpe",
+		"To:      T. Watson <sip:t.watson@example.org>",
+		"Call-ID: badvers.31417@c.example.com",
+		"CSeq:    1 OPTIONS",
+		"l: 0",
+		"",
+		""), VERSION_NOT_SUPPORTED, ""},
+
+	{"mismatch01", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:j.user@example.com",
+		"From: sip:caller@example.net;tag=34525",
+		"Max-Forwards: 6",
+		"Call-ID: mismatch01.dj0234sxdfl3",
+		"CSeq: 8 INVITE",
+		"Via: SIP/2.0/UDP host.example.com;branch=z9hG4bKkdjuw",
+		"l: 0",
+		"",
+		""), BAD_REQUEST, ""},
+
+	{"mismatch02", torture(
+		"NEWMETHOD sip:user@example.com SIP/2.0",
+		"To: sip:j.user@example.com",
+		"From: sip:caller@example.net;tag=34525",
+		"Max-Forwards: 6",
+		"Call-ID: mismatch02.dj0234sxdfl3",
+		"CSeq: 8 INVITE",
+		"Contact: <sip:caller@host.example.net>",
+		"Via: SIP/2.0/UDP host.example.net;branch=z9hG4bKkdjuw",
+		"Content-Type: application/sdp",
+		"l: 150",
+		"",
+		tortureSDP("192.0.2.1")), BAD_REQUEST, ""},
+
+	{"bigcode", torture(
+		"SIP/2.0 4294967301 better not break the receiver",
+		"Via: SIP/2.0/UDP 192.0.2.105;branch=z9hG4bK2398ndaoe",
+		"Call-ID: bigcode.asdof3uj203asdnf3429uasdhfas3ehjasdfas9i",
+		"CSeq: 353494 INVITE",
+		"From: <sip:user@example.com>;tag=39ansfi3",
+		"To: <sip:user@example.edu>;tag=902jndnke3",
+		"Content-Length: 0",
+		"Contact: <sip:user@host105.example.com>",
+		"",
+		""), rejectRead, ""},
+
+	{"insuf", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"CSeq: 193942 INVITE",
+		"Via: SIP/2.0/UDP 192.0.2.95;branch=z9hG4bKkdj.insuf",
+		"Content-Type: application/sdp",
+		"l: 152",
+		"",
+		tortureSDP("192.0.2.95")), BAD_REQUEST, ""},
+
+	{"unkscm", torture(
+		"OPTIONS nobodyKnowsThisScheme:totallyopaquecontent SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=384",
+		"Max-Forwards: 3",
+		"Call-ID: unkscm.nasdfasser0q239nwsdfasdkl34",
+		"CSeq: 3923423 OPTIONS",
+		"Via: SIP/2.0/TCP host9.example.com;branch=z9hG4bKkdjuw39234",
+		"Content-Length: 0",
+		"",
+		""), UNSUPPORTED_URI_SCHEME, ""},
+
+	{"novelsc", torture(
+		"OPTIONS soap.beep://192.0.2.103:3002 SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:caller@example.net;tag=384",
+		"Max-Forwards: 3",
+		"Call-ID: novelsc.asdfasser0q239nwsdfasdkl34",
+		"CSeq: 3923423 OPTIONS",
+		"Via: SIP/2.0/TCP host9.example.com;branch=z9hG4bKkdjuw39234",
+		"Content-Length: 0",
+		"",
+		""), UNSUPPORTED_URI_SCHEME, ""},
+
+	{"bext01", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"To: sip:j_user@example.com",
+		"From: sip:caller@example.net;tag=242etr",
+		"Max-Forwards: 6",
+		"Call-ID: bext01.0ha0isndaksdj",
+		"Require: nothingSupportsThis, nothingSupportsThisEither",
+		"Proxy-Require: noProxiesSupportThis, norDoAnyProxiesSupportThis",
+		"CSeq: 8 OPTIONS",
+		"Via: SIP/2.0/TLS fold-and-staple.example.com;branch=z9hG4bKkdjuw",
+		"Content-Length: 0",
+		"",
+		""), BAD_EXTENSION, ""},
+
+	{"invut", torture(
+		"INVITE sip:user@example.com SIP/2.0",
+		"Contact: <sip:caller@host5.example.net>",
+		"To: sip:j.user@example.com",
+		"From: sip:caller@example.net;tag=8392034",
+		"Max-Forwards: 70",
+		"Call-ID: invut.0ha0isndaksdjadsfij34n23d",
+		"CSeq: 235448 INVITE",
+		"Via: SIP/2.0/UDP somehost.example.com;branch=z9hG4bKkdjuw",
+		"Content-Type: application/unknownformat",
+		"Content-Length: 40",
+		"",
+		"<audio>",
+		" <pcmu port=\"443\"/>",
+		"</audio>",
+		""), UNSUPPORTED_MEDIA_TYPE, ""},
+
+	{"multi01", torture(
+		"INVITE sip:user@company.com SIP/2.0",
+		"Contact: <sip:caller@host25.example.net>",
+		"Via: SIP/2.0/UDP 192.0.2.25;branch=z9hG4bKkdjuw",
+		"Max-Forwards: 70",
+		"CSeq: 5 INVITE",
+		"Call-ID: multi01.98asdh@192.0.2.1",
+		"CSeq: 59 INVITE",
+		"Call-ID: multi01.98asdh@192.0.2.2",
+		"From: sip:caller@example.com;tag=3413415",
+		"To: sip:user@example.com",
+		"To: sip:other@example.net",
+		"From: sip:caller@example.net;tag=2923420123",
+		"Content-Type: application/sdp",
+		"l: 154",
+		"",
+		tortureSDP("192.0.2.111")), BAD_REQUEST, ""},
+
+	{"mcl01", torture(
+		"OPTIONS sip:user@example.com SIP/2.0",
+		"Via: SIP/2.0/UDP host5.example.net;branch=z9hG4bK293423",
+		"To: sip:user@example.com",
+		"From: sip:other@example.net;tag=3923942",
+		"Call-ID: mcl01.fhn2323orihawfdoa3o4r52o3irsdf",
+		"CSeq: 15932 OPTIONS",
+		"Content-Length: 13",
+		"Max-Forwards: 60",
+		"Content-Length: 5",
+		"Content-Type: text/plain",
+		"",
+		"There's no way to know how many octets are supposed to be here.",
+		""), rejectRead, ""},
+
+	//RFC 5118 §4.2, an IPv6 reference without brackets
+	{"ipv6-bad", torture(
+		"REGISTER sip:2001:db8::10 SIP/2.0",
+		"To: sip:user@example.com",
+		"From: sip:user@example.com;tag=81x2",
+		"Via: SIP/2.0/UDP [2001:db8::9:1];branch=z9hG4bKas3-111",
+		"Call-ID: SSG9559905523997077@hlau_4100",
+		"Max-Forwards: 70",
+		"Contact: \"Caller\" <sip:caller@[2001:db8::1]>",
+		"CSeq: 98176 REGISTER",
+		"Content-Length: 0",
+		"",
+		""), BAD_REQUEST, ""},
+
+	//RFC 5118 §4.8, three colons in a row
+	{"ipv6-bug-abnf-3-colons", torture(
+		"OPTIONS sip:user@[2001:db8:::192.0.2.1] SIP/2.0",
+		"To: sip:user@[2001:db8:::192.0.2.1]",
+		"From: sip:user@example.com;tag=810x2",
+		"Via: SIP/2.0/UDP lab1.east.example.com;branch=z9hG4bKas3-111",
+		"Call-ID: G9559905523997077@hlau_4100",
+		"CSeq: 689 OPTIONS",
+		"Max-Forwards: 70",
+		"Content-Length: 0",
+		"",
+		""), BAD_REQUEST, "To"},
+}
+
+func TestTortureInvalid(t *testing.T) {
+	v := NewValidator()
+	v.SetAcceptedContentTypes("application/sdp", "multipart/*", "text/plain")
+	for _, tv := range invalidTortureMessages {
+		t.Run(tv.name, func(t *testing.T) {
+			msg, err := ReadMessage(bufio.NewReader(strings.NewReader(tv.msg)))
+			if tv.reject == rejectRead {
+				if err == nil {
+					t.Error("accepted")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if tv.reject == rejectHeader {
+				if _, err := parseHeaders(msg, tv.header); err == nil {
+					t.Error(tv.header, "accepted")
+				}
+				return
+			}
+			body, err := rawBodyBytes(msg)
+			if tv.reject == rejectBody {
+				if err == nil {
+					t.Errorf("body of %d octets accepted", len(body))
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if req, ok := msg.(Request); ok {
+				resp, err := v.ValidateRequest(req)
+				if err != nil || resp == nil || resp.GetStatusCode() != tv.reject {
+					t.Error(resp, err)
+				}
+			} else if err := v.ValidateResponse(msg.(Response)); err == nil || tv.reject != rejectDrop {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -35,3 +35,12 @@ func (this *nilTracer) Println(a ...interface{}) {
 }
 func (this *nilTracer) Printf(format string, a ...interface{}) {
 }
+
+// tracerOf returns the tracer of p, one that traces nothing for a provider
+// of the application or none.
+func tracerOf(p Provider) Tracer {
+	if p, ok := p.(*provider); ok && p != nil && p.tracer != nil {
+		return p.tracer
+	}
+	return TraceOff()
+}
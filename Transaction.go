@@ -29,6 +29,7 @@ type transaction struct {
 	branchId         string
 	request          Request
 	quit             chan bool
+	provider         *provider
 }
 
 func (this *transaction) GetDialog() Dialog {
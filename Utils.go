@@ -0,0 +1,21 @@
+package sip
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// randomHex returns n random bytes encoded as lower case hex digits.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// generateTag returns a new From/To tag, RFC 3261 §19.3 asks for
+// at least 32 bits of randomness.
+func generateTag() string {
+	return randomHex(4)
+}
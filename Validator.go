@@ -0,0 +1,297 @@
+package sip
+
+import (
+	"errors"
+	"sip/header"
+	"sip/parser"
+	"strings"
+	"sync"
+)
+
+////////////////////Interface//////////////////////////////
+
+// ValidationRule checks a received request and returns the response that
+// rejects it, or nil when the request passes.
+type ValidationRule func(req Request) Response
+
+// Validator checks the messages received by a provider before they reach
+// their transaction, dialog or listeners, RFC 3261 §8.2 and §16.3. A
+// request that fails is answered with the matching error response, a
+// response that fails is dropped.
+type Validator interface {
+	// ValidateRequest returns the response rejecting req, or nil when req
+	// is acceptable. An invalid ACK, or a request without Via that a
+	// response can't reach, is reported by ErrUnanswerable.
+	ValidateRequest(req Request) (Response, error)
+	// ValidateResponse returns why resp has to be dropped, or nil.
+	ValidateResponse(resp Response) error
+
+	// AddRule adds a check run after the ones of the validator itself.
+	AddRule(rule ValidationRule)
+
+	// SetSupportedSchemes sets the Request-URI schemes accepted, the others
+	// are answered with 416.
+	SetSupportedSchemes(schemes ...string)
+	GetSupportedSchemes() []string
+	// SetSupportedExtensions sets the option tags a Require or
+	// Proxy-Require header may list, the others are answered with 420 and
+	// an Unsupported header.
+	SetSupportedExtensions(optionTags ...string)
+	GetSupportedExtensions() []string
+	// SetAcceptedContentTypes sets the body types accepted, the others are
+	// answered with 415 and an Accept header. Any type is accepted when
+	// none is set.
+	SetAcceptedContentTypes(contentTypes ...string)
+	GetAcceptedContentTypes() []string
+}
+
+var ErrUnanswerable = errors.New("the request is invalid and can't be answered")
+
+// mandatoryHeaders are the headers every request carries, RFC 3261 §8.1.1.
+// Max-Forwards is not required in responses.
+var mandatoryHeaders = []string{"To", "From", "CSeq", "Call-ID", "Max-Forwards", "Via"}
+
+////////////////////Implementation////////////////////////
+
+type validator struct {
+	mutex        sync.RWMutex
+	schemes      []string
+	extensions   []string
+	contentTypes []string
+	rules        []ValidationRule
+}
+
+// NewValidator returns a validator accepting sip, sips and tel
+// Request-URIs, the extensions the stack implements and any body type.
+func NewValidator() Validator {
+	return &validator{
+		schemes:    []string{"sip", "sips", "tel"},
+		extensions: []string{"replaces", "join", "norefersub"},
+	}
+}
+
+func (this *validator) ValidateRequest(req Request) (Response, error) {
+	if len(headerValues(req, "Via")) == 0 {
+		return nil, ErrUnanswerable
+	}
+	resp := this.check(req)
+	if resp == nil {
+		this.mutex.RLock()
+		rules := this.rules
+		this.mutex.RUnlock()
+		for _, rule := range rules {
+			if resp = rule(req); resp != nil {
+				break
+			}
+		}
+	}
+	if resp != nil && req.GetMethod() == ACK {
+		return nil, ErrUnanswerable
+	}
+	return resp, nil
+}
+
+// check runs the checks of RFC 3261 §8.2, and the ones §16.3 adds, in the
+// order they give them.
+func (this *validator) check(req Request) Response {
+	for _, name := range mandatoryHeaders {
+		if len(headerValues(req, name)) == 0 {
+			return newBadRequest(req, "Missing "+name+" Header")
+		}
+	}
+	for _, name := range []string{"To", "From", "CSeq", "Call-ID", "Max-Forwards"} {
+		if len(headerValues(req, name)) > 1 {
+			return newBadRequest(req, "Multiple "+name+" Headers")
+		}
+	}
+	for _, name := range []string{"To", "From", "CSeq", "Call-ID", "Max-Forwards"} {
+		//the reason phrase names the header, the ParseError says where
+		//it broke
+		if _, err := parseHeader(req, name); err != nil {
+			return newBadRequest(req, "Malformed "+name+" Header")
+		}
+	}
+	h, err := parseHeader(req, "CSeq")
+	cSeq, ok := h.(*header.CSeq)
+	if err != nil || !ok {
+		return newBadRequest(req, "Malformed CSeq Header")
+	}
+	if cSeq.GetMethod() != req.GetMethod() {
+		return newBadRequest(req, "CSeq Method Does Not Match")
+	}
+
+	//RFC 3261 §8.2.2
+	if req.GetSIPVersion() != "SIP/2.0" {
+		return newResponseFor(req, VERSION_NOT_SUPPORTED)
+	}
+
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	//RFC 3261 §8.2.2.1
+	scheme := strings.ToLower(strings.SplitN(req.GetRequestURI(), ":", 2)[0])
+	if !containsFold(this.schemes, scheme) {
+		return newResponseFor(req, UNSUPPORTED_URI_SCHEME)
+	}
+	if scheme == "sip" || scheme == "sips" {
+		p := parser.NewURLParser(req.GetRequestURI())
+		if _, err := p.Parse(); err != nil || p.GetLexer().HasMoreChars() {
+			return newBadRequest(req, "Malformed Request-URI")
+		}
+		//RFC 3261 §19.1.5, headers are not allowed in a Request-URI
+		if strings.ContainsRune(req.GetRequestURI(), '?') {
+			return newBadRequest(req, "Request-URI With Headers")
+		}
+	}
+
+	//RFC 3261 §16.3 step 3, an OPTIONS may still be answered
+	h, _ = parseHeader(req, "Max-Forwards")
+	if mf, ok := h.(*header.MaxForwards); ok && mf.HasReachedZero() && req.GetMethod() != OPTIONS {
+		return newResponseFor(req, TOO_MANY_HOPS)
+	}
+
+	//RFC 3261 §8.2.2.3 and §16.3 step 4, a CANCEL or ACK is never rejected
+	//for its Require or Proxy-Require
+	if req.GetMethod() != CANCEL && req.GetMethod() != ACK {
+		for _, name := range []string{"Require", "Proxy-Require"} {
+			var unsupported []string
+			for _, tag := range optionTagsOf(req, name) {
+				if !containsFold(this.extensions, tag) {
+					unsupported = append(unsupported, tag)
+				}
+			}
+			if len(unsupported) > 0 {
+				resp := newResponseFor(req, BAD_EXTENSION)
+				resp.GetHeader().Set("Unsupported", strings.Join(unsupported, ", "))
+				return resp
+			}
+		}
+	}
+
+	//RFC 3261 §8.2.3
+	if req.GetContentLength() > 0 {
+		if decodedTooLarge(req) {
+			return newResponseFor(req, REQUEST_ENTITY_TOO_LARGE)
+		}
+		if len(contentCodings(req)) > 0 {
+			//ReadMessage decodes the codings we know
+			resp := newResponseFor(req, UNSUPPORTED_MEDIA_TYPE)
+			resp.GetHeader().Set("Accept-Encoding", strings.Join(acceptedEncodings, ", "))
+			return resp
+		}
+		contentType := headerValues(req, "Content-Type")
+		if len(this.contentTypes) > 0 && (len(contentType) == 0 || !this.accepts(contentType[0])) {
+			resp := newResponseFor(req, UNSUPPORTED_MEDIA_TYPE)
+			resp.GetHeader().Set("Accept", strings.Join(this.contentTypes, ", "))
+			return resp
+		}
+	}
+	return nil
+}
+
+// accepts reports whether contentType matches one of the accepted types,
+// which may be a type/* range.
+func (this *validator) accepts(contentType string) bool {
+	mediaType := mediaTypeOf(contentType)
+	for _, accepted := range this.contentTypes {
+		accepted = strings.ToLower(accepted)
+		if accepted == mediaType || accepted == "*/*" ||
+			strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(accepted, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *validator) ValidateResponse(resp Response) error {
+	for _, name := range mandatoryHeaders {
+		if name != "Max-Forwards" && len(headerValues(resp, name)) == 0 {
+			return errors.New("missing " + name + " header")
+		}
+	}
+	if resp.GetStatusCode() < 100 || resp.GetStatusCode() > 699 {
+		return errors.New("invalid status code")
+	}
+	if h, err := parseHeader(resp, "CSeq"); err != nil || h == nil {
+		return errors.New("malformed CSeq header")
+	}
+	if decodedTooLarge(resp) {
+		return ErrBodyTooLarge
+	}
+	return nil
+}
+
+func (this *validator) AddRule(rule ValidationRule) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.rules = append(this.rules, rule)
+}
+
+func (this *validator) SetSupportedSchemes(schemes ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.schemes = schemes
+}
+
+func (this *validator) GetSupportedSchemes() []string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return append([]string(nil), this.schemes...)
+}
+
+func (this *validator) SetSupportedExtensions(optionTags ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.extensions = optionTags
+}
+
+func (this *validator) GetSupportedExtensions() []string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return append([]string(nil), this.extensions...)
+}
+
+func (this *validator) SetAcceptedContentTypes(contentTypes ...string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.contentTypes = contentTypes
+}
+
+func (this *validator) GetAcceptedContentTypes() []string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return append([]string(nil), this.contentTypes...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// newBadRequest returns a 400 whose reason phrase says what is wrong with
+// req, RFC 3261 §8.2.2.
+func newBadRequest(req Request, reasonPhrase string) Response {
+	resp := newResponseFor(req, BAD_REQUEST)
+	resp.SetReasonPhrase("Bad Request - " + reasonPhrase)
+	return resp
+}
+
+// optionTagsOf returns the option tags listed by the named header, such as
+// Require, Proxy-Require or Supported.
+func optionTagsOf(msg Message, name string) []string {
+	var tags []string
+	for _, v := range headerValues(msg, name) {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package sip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	v := NewValidator()
+	v.SetAcceptedContentTypes("application/sdp", "multipart/*")
+	if resp, err := v.ValidateRequest(testInvite(t, true)); resp != nil || err != nil {
+		t.Fatal(resp, err)
+	}
+
+	for i, test := range []struct {
+		change func(h Header)
+		code   int
+		header string
+	}{
+		{func(h Header) { h.Del("Max-Forwards") }, BAD_REQUEST, ""},
+		{func(h Header) { h.Set("CSeq", "314159 BYE") }, BAD_REQUEST, ""},
+		{func(h Header) { h.Set("CSeq", "INVITE") }, BAD_REQUEST, ""},
+		{func(h Header) { h.Del("Call-ID") }, BAD_REQUEST, ""},
+		{func(h Header) { h.Add("To", "<sip:carol@chicago.com>") }, BAD_REQUEST, ""},
+		{func(h Header) { h.Set("Require", "replaces, foo, bar") }, BAD_EXTENSION, "Unsupported: foo, bar"},
+		{func(h Header) { h.Set("Proxy-Require", "foo") }, BAD_EXTENSION, "Unsupported: foo"},
+		{func(h Header) { h.Set("Max-Forwards", "0") }, TOO_MANY_HOPS, ""},
+		{func(h Header) { h.Set("Content-Type", "text/plain") }, UNSUPPORTED_MEDIA_TYPE, "Accept: application/sdp, multipart/*"},
+		{func(h Header) { h.Set("Content-Encoding", "br") }, UNSUPPORTED_MEDIA_TYPE, "Accept-Encoding: gzip, deflate, identity"},
+	} {
+		req := testInvite(t, true)
+		test.change(req.GetHeader())
+		resp, err := v.ValidateRequest(req)
+		if err != nil || resp == nil || resp.GetStatusCode() != test.code {
+			t.Log(i, resp, err)
+			t.Fail()
+			continue
+		}
+		if test.header != "" {
+			kv := strings.SplitN(test.header, ": ", 2)
+			if resp.GetHeader().Get(kv[0]) != kv[1] {
+				t.Log(i, resp.GetHeader())
+				t.Fail()
+			}
+		}
+	}
+
+	req := testInvite(t, false)
+	req.SetRequestURI("im:bob@biloxi.com")
+	if resp, _ := v.ValidateRequest(req); resp == nil || resp.GetStatusCode() != UNSUPPORTED_URI_SCHEME {
+		t.Log(resp)
+		t.Fail()
+	}
+
+	//an OPTIONS that can't be forwarded further is still answered
+	options := testInvite(t, false)
+	options.SetMethod(OPTIONS)
+	options.GetHeader().Set("CSeq", "314159 OPTIONS")
+	options.GetHeader().Set("Max-Forwards", "0")
+	if resp, err := v.ValidateRequest(options); resp != nil || err != nil {
+		t.Error(resp, err)
+	}
+
+	//multipart bodies are accepted through multipart/*
+	invite := testInvite(t, false)
+	m := NewMultipartBody(MULTIPART_MIXED)
+	m.AddPart(NewBodyPart("application/sdp", []byte(testSDP)))
+	SetMultipartBody(invite, m)
+	if resp, _ := v.ValidateRequest(invite); resp != nil {
+		t.Log(resp)
+		t.Fail()
+	}
+
+	//an ACK is never answered, a request without Via can't be
+	ack := testInvite(t, false)
+	ack.SetMethod(ACK)
+	if _, err := v.ValidateRequest(ack); err != ErrUnanswerable {
+		t.Fail()
+	}
+	invite.GetHeader().Del("Via")
+	if _, err := v.ValidateRequest(invite); err != ErrUnanswerable {
+		t.Fail()
+	}
+}
+
+func TestValidationRule(t *testing.T) {
+	p := newTestDispatchProvider()
+	p.GetValidator().AddRule(func(req Request) Response {
+		if req.GetHeader().Get("Subject") == "" {
+			return newResponseFor(req, FORBIDDEN)
+		}
+		return nil
+	})
+	if event := dispatchTestRequest(p, testInvite(t, false)); event != nil {
+		t.Fatal("the INVITE was not rejected")
+	}
+	//a new INVITE, not a retransmission of the rejected one
+	invite := testInvite(t, false)
+	invite.GetHeader().Set("Via", "SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhdt")
+	invite.GetHeader().Set("Subject", "lunch")
+	if event := dispatchTestRequest(p, invite); event == nil {
+		t.Fatal("the INVITE was rejected")
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	v := NewValidator()
+	resp := newResponseFor(testInvite(t, false), OK)
+	if err := v.ValidateResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	resp.GetHeader().Del("CSeq")
+	if err := v.ValidateResponse(resp); err == nil {
+		t.Fail()
+	}
+}
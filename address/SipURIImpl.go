@@ -925,7 +925,9 @@ func (this *SipURIImpl) SetTransportParam(transport string) error {
 		return errors.New("NullPointerException: null arg")
 	}
 	if strings.ToUpper(transport) == "UDP" ||
-		strings.ToUpper(transport) == "TCP" {
+		strings.ToUpper(transport) == "TCP" ||
+		strings.ToUpper(transport) == "TLS" ||
+		strings.ToUpper(transport) == "SCTP" {
 		nv := core.NewNameValue(core.SIPTransportNames_TRANSPORT, strings.ToLower(transport))
 		this.uriParms.Delete(core.SIPTransportNames_TRANSPORT)
 		this.uriParms.AddNameValue(nv)
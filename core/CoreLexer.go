@@ -2,7 +2,6 @@ package core
 
 import (
 	"bytes"
-	"errors"
 	"strconv"
 	"strings"
 )
@@ -206,7 +205,7 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 		if tok == CORELEXER_ID {
 			// Generic ID sought.
 			if !this.StartsId() {
-				return nil, errors.New("ParseException: ID expected")
+				return nil, this.CreateParseError("ID", "ID expected")
 			}
 			id := this.GetNextId()
 			this.currentMatch = &Token{}
@@ -216,7 +215,7 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 			nexttok := this.GetNextId()
 			cur, ok := this.currentLexer[strings.ToUpper(nexttok)]
 			if !ok || cur != tok {
-				return nil, errors.New("ParseException: Unexpected Token")
+				return nil, this.CreateParseError(this.LookupToken(tok), "Unexpected Token")
 			}
 			this.currentMatch = &Token{}
 			this.currentMatch.tokenValue = nexttok
@@ -226,11 +225,11 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 		// Character classes.
 		next, err := this.LookAheadK(0)
 		if err != nil {
-			return nil, errors.New("ParseException: Expecting DIGIT")
+			return nil, this.CreateParseError(charClassName(tok), "unexpected EOL")
 		}
 		if tok == CORELEXER_DIGIT {
 			if !this.IsDigit(next) {
-				return nil, errors.New("ParseException: Expecting DIGIT")
+				return nil, this.CreateParseError("DIGIT", "unexpected char")
 			}
 			this.currentMatch = &Token{}
 			this.currentMatch.tokenValue = string(next)
@@ -238,7 +237,7 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 			this.ConsumeK(1)
 		} else if tok == CORELEXER_ALPHA {
 			if !this.IsAlpha(next) {
-				return nil, errors.New("ParseException: Expecting ALPHA")
+				return nil, this.CreateParseError("ALPHA", "unexpected char")
 			}
 			this.currentMatch = &Token{}
 			this.currentMatch.tokenValue = string(next)
@@ -250,7 +249,7 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 		ch := byte(tok)
 		next, err := this.LookAheadK(0)
 		if err != nil {
-			return nil, errors.New("ParseException: Expecting DIGIT")
+			return nil, this.CreateParseError(strconv.Quote(string(ch)), "unexpected EOL")
 		}
 		if next == ch {
 			this.currentMatch = &Token{}
@@ -258,12 +257,23 @@ func (this *CoreLexer) Match(tok int) (t *Token, ParseException error) {
 			this.currentMatch.tokenType = tok
 			this.ConsumeK(1)
 		} else {
-			return nil, errors.New("ParseException: Expecting")
+			return nil, this.CreateParseError(strconv.Quote(string(ch)), "unexpected char")
 		}
 	}
 	return this.currentMatch, nil
 }
 
+// charClassName names a character class token in a ParseError.
+func charClassName(tok int) string {
+	switch tok {
+	case CORELEXER_DIGIT:
+		return "DIGIT"
+	case CORELEXER_ALPHA:
+		return "ALPHA"
+	}
+	return strconv.Itoa(tok)
+}
+
 func (this *CoreLexer) SPorHT() {
 	var ch byte
 
@@ -272,6 +282,13 @@ func (this *CoreLexer) SPorHT() {
 	}
 }
 
+/** Reports whether ch may appear in a token, RFC 3261 §25.1.
+ */
+func IsTokenChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' ||
+		strings.IndexByte("-.!%*_+`'~", ch) >= 0
+}
+
 func (this *CoreLexer) StartsId() bool {
 	nextChar, err := this.LookAheadK(0)
 	if err != nil {
@@ -484,10 +501,10 @@ func (this *CoreLexer) Number() (n int, ParseException error) {
 
 	next, err := this.LookAheadK(0)
 	if err != nil {
-		return -1, err
+		return -1, this.CreateParseError("DIGIT", "unexpected EOL")
 	}
 	if !this.IsDigit(next) {
-		return -1, errors.New("ParseException: unexpected token \"" + string(next) + "\"")
+		return -1, this.CreateParseError("DIGIT", "unexpected token \""+string(next)+"\"")
 	}
 
 	retval.WriteByte(next)
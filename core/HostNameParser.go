@@ -2,7 +2,8 @@ package core
 
 import (
 	"bytes"
-	"errors"
+	"net"
+	"strings"
 )
 
 /** SIPParser for host names.
@@ -80,6 +81,12 @@ func (this *HostNameParser) Ipv6Reference() (s string, ParseException error) {
 			this.lexer.ConsumeK(1)
 			retval.WriteByte(la)
 		} else if la == ']' {
+			// RFC 5118 §4.8, the reference has to be an IPv6address of
+			// RFC 4291, "2001:db8:::192.0.2.1" is not
+			if ref := retval.String(); len(ref) < 2 || strings.IndexByte(ref[1:], '[') >= 0 ||
+				!strings.Contains(ref, ":") || net.ParseIP(ref[1:]) == nil {
+				return ref, this.CreateParseError("IPv6address", "Illegal IPv6 reference")
+			}
 			this.lexer.ConsumeK(1)
 			retval.WriteByte(la)
 			return retval.String(), nil
@@ -88,7 +95,7 @@ func (this *HostNameParser) Ipv6Reference() (s string, ParseException error) {
 		}
 	}
 
-	return retval.String(), errors.New("ParseException: Illegal Host name")
+	return retval.String(), this.CreateParseError("]", "Illegal Host name")
 }
 
 func (this *HostNameParser) GetHost() (h *Host, err error) {
@@ -132,7 +139,7 @@ func (this *HostNameParser) GetHost() (h *Host, err error) {
 	hostname := hname.String()
 
 	if hostname == "" {
-		return nil, errors.New("ParseException: Illegal Host name")
+		return nil, this.CreateParseError("host", "Illegal Host name")
 	} else {
 		return NewHost(hostname), nil
 	}
@@ -76,6 +76,35 @@ const SIPHeaderNames_SUBSCRIPTION_STATE = "Subscription-State"   //48
 const SIPHeaderNames_EVENT = "Event"                             //44
 const SIPHeaderNames_ALLOW_EVENTS = "Allow-Events"               //45
 const SIPHeaderNames_REFER_TO = "Refer-To"                       //46
+const SIPHeaderNames_REFERRED_BY = "Referred-By"                 //47
+const SIPHeaderNames_REPLACES = "Replaces"                       //48
+const SIPHeaderNames_JOIN = "Join"                               //49
+
+const SIPHeaderNames_P_ASSERTED_IDENTITY = "P-Asserted-Identity"                     //50
+const SIPHeaderNames_P_PREFERRED_IDENTITY = "P-Preferred-Identity"                   //51
+const SIPHeaderNames_PRIVACY = "Privacy"                                             //52
+const SIPHeaderNames_PATH = "Path"                                                   //53
+const SIPHeaderNames_SERVICE_ROUTE = "Service-Route"                                 //54
+const SIPHeaderNames_HISTORY_INFO = "History-Info"                                   //55
+const SIPHeaderNames_DIVERSION = "Diversion"                                         //56
+const SIPHeaderNames_IDENTITY = "Identity"                                           //57
+const SIPHeaderNames_SECURITY_CLIENT = "Security-Client"                             //58
+const SIPHeaderNames_SECURITY_SERVER = "Security-Server"                             //59
+const SIPHeaderNames_SECURITY_VERIFY = "Security-Verify"                             //60
+const SIPHeaderNames_ACCEPT_CONTACT = "Accept-Contact"                               //61
+const SIPHeaderNames_REJECT_CONTACT = "Reject-Contact"                               //62
+const SIPHeaderNames_REQUEST_DISPOSITION = "Request-Disposition"                     //63
+const SIPHeaderNames_SIP_ETAG = "SIP-ETag"                                           //64
+const SIPHeaderNames_SIP_IF_MATCH = "SIP-If-Match"                                   //65
+const SIPHeaderNames_P_ACCESS_NETWORK_INFO = "P-Access-Network-Info"                 //66
+const SIPHeaderNames_P_CHARGING_VECTOR = "P-Charging-Vector"                         //67
+const SIPHeaderNames_P_CHARGING_FUNCTION_ADDRESSES = "P-Charging-Function-Addresses" //68
+const SIPHeaderNames_P_VISITED_NETWORK_ID = "P-Visited-Network-ID"                   //69
+const SIPHeaderNames_P_ASSOCIATED_URI = "P-Associated-URI"                           //70
+const SIPHeaderNames_P_CALLED_PARTY_ID = "P-Called-Party-ID"                         //71
+const SIPHeaderNames_INFO_PACKAGE = "Info-Package"                                   //72
+const SIPHeaderNames_RECV_INFO = "Recv-Info"                                         //73
+
 const SIPHeaderNames_K = "K"
 const SIPHeaderNames_C = "C"
 const SIPHeaderNames_E = "E"
@@ -87,6 +116,10 @@ const SIPHeaderNames_S = "S"
 const SIPHeaderNames_T = "T"
 const SIPHeaderNames_V = "V"
 const SIPHeaderNames_R = "R"
+const SIPHeaderNames_B = "B"
+const SIPHeaderNames_A = "A"
+const SIPHeaderNames_J = "J"
+const SIPHeaderNames_D = "D"
 
 const SIPMethodNames_INVITE = "INVITE"
 const SIPMethodNames_ACK = "ACK"
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+/** The error returned by the lexers and parsers. It says which header
+ * failed to parse, the raw input, the byte offset in the input where the
+ * parser gave up and, when known, the token it expected there.
+ */
+type ParseError struct {
+	// the name of the header, as received, or "" for a start line
+	HeaderName string
+	// the raw header line or start line
+	Line string
+	// the byte offset in Line where parsing failed
+	Offset int
+	// the token expected at Offset, or ""
+	Expected string
+	Reason   string
+}
+
+/** Creates a ParseError for a failure at offset in line. The header name
+ * is taken from the line.
+ */
+func NewParseError(line string, offset int, expected, reason string) *ParseError {
+	this := &ParseError{
+		Line:     line,
+		Offset:   offset,
+		Expected: expected,
+		Reason:   reason,
+	}
+	if colon := strings.IndexByte(line, ':'); colon > 0 {
+		name := strings.TrimSpace(line[:colon])
+		if !strings.ContainsAny(name, " \t<\"") {
+			this.HeaderName = name
+		}
+	}
+	return this
+}
+
+func (this *ParseError) Error() string {
+	s := "ParseException: "
+	if this.HeaderName != "" {
+		s += this.HeaderName + ": "
+	}
+	s += this.Reason
+	if this.Expected != "" {
+		s += ", expecting " + this.Expected
+	}
+	return s + " at offset " + strconv.Itoa(this.Offset) + " in " + strconv.Quote(strings.TrimRight(this.Line, "\r\n"))
+}
+
+/** Creates a ParseError at the current position of the lexer.
+ */
+func (this *CoreLexer) CreateParseError(expected, reason string) *ParseError {
+	return NewParseError(this.buffer, this.ptr, expected, reason)
+}
+
+/** Creates a ParseError at the current position of the lexer of the parser.
+ */
+func (this *CoreParser) CreateParseError(expected, reason string) *ParseError {
+	return NewParseError(this.lexer.GetBuffer(), this.lexer.GetPtr(), expected, reason)
+}
+
+/** Returns err as a ParseError at the current position of the lexer of the
+ * parser, unless it is one already.
+ */
+func (this *CoreParser) ToParseError(err error) *ParseError {
+	if pe, ok := err.(*ParseError); ok {
+		return pe
+	}
+	return this.CreateParseError("", strings.TrimPrefix(err.Error(), "ParseException: "))
+}
@@ -0,0 +1,45 @@
+package header
+
+/**
+ * This interface represents the Accept-Contact SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3841.txt">RFC3841</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * Each value of the Accept-Contact header is a feature preference of the
+ * caller: the feature tags of RFC 3840, as parameters, that the contacts the
+ * request is routed to should have. The contacts matching the most
+ * preferences are tried first. With the require parameter a contact not
+ * matching the preference is discarded, and with the explicit parameter only
+ * a contact explicitly indicating the features matches.
+ * <p>
+ * For Example:<br>
+ * <code>Accept-Contact: *;audio;video;methods="INVITE,BYE";require;explicit</code>
+ *
+ * @see RejectContactHeader
+ * @see RequestDispositionHeader
+ */
+type AcceptContactHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets whether the contacts not matching the preference are discarded.
+	 */
+	SetRequireFlag(require bool)
+
+	/**
+	 * Gets whether the contacts not matching the preference are discarded.
+	 */
+	IsRequired() bool
+
+	/**
+	 * Sets whether only the contacts explicitly indicating the features of
+	 * the preference match it.
+	 */
+	SetExplicitFlag(explicit bool)
+
+	/**
+	 * Gets whether only the contacts explicitly indicating the features of
+	 * the preference match it.
+	 */
+	IsExplicit() bool
+}
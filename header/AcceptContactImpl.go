@@ -0,0 +1,63 @@
+package header
+
+import (
+	"bytes"
+	"sip/core"
+)
+
+/**
+ * The Accept-Contact SIP header, one ac-value.
+ */
+type AcceptContact struct {
+	Parameters
+}
+
+/** Creates a new instance of AcceptContact */
+func NewAcceptContact() *AcceptContact {
+	this := &AcceptContact{}
+	this.Parameters.super(core.SIPHeaderNames_ACCEPT_CONTACT)
+	return this
+}
+
+func (this *AcceptContact) SetRequireFlag(require bool) {
+	if require {
+		this.SetParameter(ParameterNames_REQUIRE, "")
+	} else {
+		this.RemoveParameter(ParameterNames_REQUIRE)
+	}
+}
+
+func (this *AcceptContact) IsRequired() bool {
+	return this.HasParameter(ParameterNames_REQUIRE)
+}
+
+func (this *AcceptContact) SetExplicitFlag(explicit bool) {
+	if explicit {
+		this.SetParameter(ParameterNames_EXPLICIT, "")
+	} else {
+		this.RemoveParameter(ParameterNames_EXPLICIT)
+	}
+}
+
+func (this *AcceptContact) IsExplicit() bool {
+	return this.HasParameter(ParameterNames_EXPLICIT)
+}
+
+func (this *AcceptContact) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Encode the body of this header (the stuff that follows headerName).
+ * A.K.A headerValue.
+ */
+func (this *AcceptContact) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(core.SIPSeparatorNames_STAR)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
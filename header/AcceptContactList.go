@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* List of Accept-Contact headers.
+ */
+type AcceptContactList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewAcceptContactList() *AcceptContactList {
+	this := &AcceptContactList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_ACCEPT_CONTACT)
+	return this
+}
@@ -82,7 +82,8 @@ func (this *Contact) GetContactList() *ContactList {
  * @return boolean
  */
 func (this *Contact) GetWildCardFlag() bool {
-	return this.wildCardFlag
+	//SetAddress clears the flag of a wild card set by SetWildCardFlag
+	return this.wildCardFlag || this.addr != nil && this.addr.IsWildcard()
 }
 
 /** get the address field.
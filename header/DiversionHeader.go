@@ -0,0 +1,44 @@
+package header
+
+/**
+ * This interface represents the Diversion SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc5806.txt">RFC5806</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Diversion header names the users a call was diverted from, the most
+ * recent diversion first, with the reason of the diversion and the number of
+ * diversions it stands for. It predates History-Info, which replaces it, but
+ * many trunks only speak Diversion.
+ * <p>
+ * For Example:<br>
+ * <code>Diversion: &lt;sip:bob@example.com&gt;;reason=user-busy;counter=1</code>
+ *
+ * @see HistoryInfoHeader
+ */
+type DiversionHeader interface {
+	AddressHeader
+	ParametersHeader
+
+	/**
+	 * Sets the reason of the diversion, such as "user-busy" or "no-answer".
+	 */
+	SetReason(reason string) (ParseException error)
+
+	/**
+	 * Gets the reason of the diversion.
+	 *
+	 * @return the reason, "" if the entry has none
+	 */
+	GetReason() string
+
+	/**
+	 * Sets the number of diversions this entry stands for.
+	 */
+	SetCounter(counter int) (InvalidArgumentException error)
+
+	/**
+	 * Gets the number of diversions this entry stands for, 1 when the
+	 * counter parameter is absent.
+	 */
+	GetCounter() int
+}
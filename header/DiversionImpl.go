@@ -0,0 +1,125 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/address"
+	"sip/core"
+	"strconv"
+	"strings"
+)
+
+/**
+* Diversion SIP Header, one diversion.
+ */
+type Diversion struct {
+	AddressParameters
+}
+
+/** constructor
+ * @param addr address to set
+ */
+func NewDiversionFromAddress(addr address.Address) *Diversion {
+	this := &Diversion{}
+	this.AddressParameters.super(core.SIPHeaderNames_DIVERSION)
+	this.addr = addr
+	return this
+}
+
+/** default Constructor.
+ */
+func NewDiversion() *Diversion {
+	this := &Diversion{}
+	this.AddressParameters.super(core.SIPHeaderNames_DIVERSION)
+	return this
+}
+
+/**
+ * Sets the reason of the diversion, such as "user-busy" or "no-answer".
+ */
+func (this *Diversion) SetReason(reason string) (ParseException error) {
+	if reason == "" {
+		return errors.New("NullPointerException: the reason parameter is null")
+	}
+	return this.SetParameter(ParameterNames_REASON, reason)
+}
+
+/**
+ * Gets the reason of the diversion.
+ *
+ * @return the reason, "" if the entry has none
+ */
+func (this *Diversion) GetReason() string {
+	return strings.Trim(this.GetParameter(ParameterNames_REASON), "\"")
+}
+
+/**
+ * Sets the number of diversions this entry stands for.
+ */
+func (this *Diversion) SetCounter(counter int) (InvalidArgumentException error) {
+	if counter < 1 {
+		return errors.New("InvalidArgumentException: the counter must be positive")
+	}
+	return this.SetParameter(ParameterNames_COUNTER, strconv.Itoa(counter))
+}
+
+/**
+ * Gets the number of diversions this entry stands for, 1 when the counter
+ * parameter is absent.
+ */
+func (this *Diversion) GetCounter() int {
+	if counter, err := strconv.Atoi(this.GetParameter(ParameterNames_COUNTER)); err == nil {
+		return counter
+	}
+	return 1
+}
+
+/**
+ * Gets the maximum number of diversions allowed, 0 when the limit parameter
+ * is absent.
+ */
+func (this *Diversion) GetLimit() int {
+	limit, _ := strconv.Atoi(this.GetParameter(ParameterNames_LIMIT))
+	return limit
+}
+
+/**
+ * Gets the privacy parameter: "full", "name", "uri" or "off".
+ */
+func (this *Diversion) GetPrivacy() string {
+	return strings.Trim(this.GetParameter(ParameterNames_PRIVACY), "\"")
+}
+
+/**
+ * Gets the screen parameter, "yes" when the diverting user was verified.
+ */
+func (this *Diversion) GetScreen() string {
+	return strings.Trim(this.GetParameter(ParameterNames_SCREEN), "\"")
+}
+
+func (this *Diversion) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode the header content into a String.
+ * @return String
+ */
+func (this *Diversion) EncodeBody() string {
+	var encoding bytes.Buffer
+	addr, _ := this.addr.(*address.AddressImpl)
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_LESS_THAN)
+	}
+	encoding.WriteString(this.addr.String())
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_GREATER_THAN)
+	}
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
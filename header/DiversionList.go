@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* Diversion List of SIP headers (a collection of Addresses)
+ */
+type DiversionList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewDiversionList() *DiversionList {
+	this := &DiversionList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_DIVERSION)
+	return this
+}
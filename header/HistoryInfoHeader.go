@@ -0,0 +1,47 @@
+package header
+
+/**
+ * This interface represents the History-Info SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc7044.txt">RFC7044</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The History-Info header records the targets a request was sent to, one
+ * entry per target. The index parameter places the entry in the tree of
+ * retargetings: "1.1.2" is the second target the request sent to "1.1" was
+ * retargeted to. The rc, mp and np parameters give the index of the entry
+ * the target was derived from, and how: by a change of contact, a mapping to
+ * another user, or no change. The Reason header embedded in the URI of an
+ * entry says why the request sent to that target was retargeted.
+ * <p>
+ * For Example:<br>
+ * <code>History-Info: &lt;sip:bob@example.com?Reason=SIP%3Bcause%3D302&gt;;index=1,
+ * &lt;sip:carol@example.com&gt;;index=1.1;mp=1</code>
+ *
+ * @see DiversionHeader
+ */
+type HistoryInfoHeader interface {
+	AddressHeader
+	ParametersHeader
+
+	/**
+	 * Sets the index of this entry.
+	 *
+	 * @param index - the index, dot separated numbers such as "1.2.1"
+	 * @throws ParseException if the index is malformed
+	 */
+	SetIndex(index string) (ParseException error)
+
+	/**
+	 * Gets the index of this entry.
+	 *
+	 * @return the index, "" if the entry has none
+	 */
+	GetIndex() string
+
+	/**
+	 * Gets the Reason header embedded in the URI of this entry, unescaped.
+	 *
+	 * @return the Reason, "" if the entry has none
+	 */
+	GetReason() string
+}
@@ -0,0 +1,166 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"sip/address"
+	"sip/core"
+	"strings"
+)
+
+/**
+* History-Info SIP Header, one hi-entry.
+ */
+type HistoryInfo struct {
+	AddressParameters
+}
+
+/** constructor
+ * @param addr address to set
+ */
+func NewHistoryInfoFromAddress(addr address.Address) *HistoryInfo {
+	this := &HistoryInfo{}
+	this.AddressParameters.super(core.SIPHeaderNames_HISTORY_INFO)
+	this.addr = addr
+	return this
+}
+
+/** default Constructor.
+ */
+func NewHistoryInfo() *HistoryInfo {
+	this := &HistoryInfo{}
+	this.AddressParameters.super(core.SIPHeaderNames_HISTORY_INFO)
+	return this
+}
+
+/**
+ * Sets the index of this entry.
+ *
+ * @param index - the index, dot separated numbers such as "1.2.1"
+ * @throws ParseException if the index is malformed
+ */
+func (this *HistoryInfo) SetIndex(index string) (ParseException error) {
+	if !IsHistoryInfoIndex(index) {
+		return errors.New("ParseException: malformed History-Info index " + index)
+	}
+	return this.SetParameter(ParameterNames_INDEX, index)
+}
+
+/**
+ * Gets the index of this entry.
+ *
+ * @return the index, "" if the entry has none
+ */
+func (this *HistoryInfo) GetIndex() string {
+	return this.GetParameter(ParameterNames_INDEX)
+}
+
+/**
+ * Sets the rc parameter, the index of the entry this target was derived
+ * from by a change of contact, such as a registrar does.
+ */
+func (this *HistoryInfo) SetRc(index string) (ParseException error) {
+	if !IsHistoryInfoIndex(index) {
+		return errors.New("ParseException: malformed History-Info index " + index)
+	}
+	return this.SetParameter(ParameterNames_RC, index)
+}
+
+func (this *HistoryInfo) GetRc() string {
+	return this.GetParameter(ParameterNames_RC)
+}
+
+/**
+ * Sets the mp parameter, the index of the entry this target was mapped
+ * from, such as a call forwarding does.
+ */
+func (this *HistoryInfo) SetMp(index string) (ParseException error) {
+	if !IsHistoryInfoIndex(index) {
+		return errors.New("ParseException: malformed History-Info index " + index)
+	}
+	return this.SetParameter(ParameterNames_MP, index)
+}
+
+func (this *HistoryInfo) GetMp() string {
+	return this.GetParameter(ParameterNames_MP)
+}
+
+/**
+ * Sets the np parameter, the index of the entry this target was taken from
+ * without change.
+ */
+func (this *HistoryInfo) SetNp(index string) (ParseException error) {
+	if !IsHistoryInfoIndex(index) {
+		return errors.New("ParseException: malformed History-Info index " + index)
+	}
+	return this.SetParameter(ParameterNames_NP, index)
+}
+
+func (this *HistoryInfo) GetNp() string {
+	return this.GetParameter(ParameterNames_NP)
+}
+
+/**
+ * Gets the Reason header embedded in the URI of this entry, unescaped.
+ *
+ * @return the Reason, "" if the entry has none
+ */
+func (this *HistoryInfo) GetReason() string {
+	if this.addr == nil {
+		return ""
+	}
+	uri, ok := this.addr.GetURI().(*address.SipURIImpl)
+	if !ok {
+		return ""
+	}
+	for e := uri.GetHeaderNames().Front(); e != nil; e = e.Next() {
+		nv := e.Value.(*core.NameValue)
+		if strings.EqualFold(nv.GetName(), core.SIPHeaderNames_REASON) {
+			reason, err := url.PathUnescape(uri.GetHeader(nv.GetName()))
+			if err != nil {
+				return ""
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+func (this *HistoryInfo) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode the header content into a String.
+ * @return String
+ */
+func (this *HistoryInfo) EncodeBody() string {
+	var encoding bytes.Buffer
+	addr, _ := this.addr.(*address.AddressImpl)
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_LESS_THAN)
+	}
+	encoding.WriteString(this.addr.String())
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_GREATER_THAN)
+	}
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
+
+/** Tells whether index is a History-Info index, 1*DIGIT *(DOT 1*DIGIT).
+ */
+func IsHistoryInfoIndex(index string) bool {
+	for _, n := range strings.Split(index, ".") {
+		if n == "" || strings.Trim(n, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
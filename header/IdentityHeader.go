@@ -0,0 +1,71 @@
+package header
+
+/**
+ * This interface represents the Identity SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc8224.txt">RFC8224</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Identity header carries a signature over the identities of the
+ * originator and destination of a request, a PASSporT in its compact JWS
+ * form, from which the receiver of the request learns whether the
+ * originator is allowed to use the telephone number in the From. The info
+ * parameter points to the certificate of the signer, and the ppt parameter
+ * names the PASSporT extension the signature was made with, such as
+ * "shaken".
+ * <p>
+ * A request may carry several Identity headers, each with its own
+ * signature.
+ * <p>
+ * For Example:<br>
+ * <code>Identity: eyJhbGciOiJFUzI1NiJ9.eyJpYXQiOjB9.c2lnbmF0dXJl;
+ * info=&lt;https://cert.example.org/passport.cer&gt;;alg=ES256;ppt=shaken</code>
+ */
+type IdentityHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the signed identity digest, the PASSporT of the request.
+	 */
+	SetSignedIdentityDigest(digest string) (ParseException error)
+
+	/**
+	 * Gets the signed identity digest of this header.
+	 *
+	 * @return the PASSporT, in its compact JWS form
+	 */
+	GetSignedIdentityDigest() string
+
+	/**
+	 * Sets the URI of the certificate of the signer.
+	 */
+	SetInfo(uri string) (ParseException error)
+
+	/**
+	 * Gets the URI of the certificate of the signer.
+	 *
+	 * @return the URI, without its angle brackets
+	 */
+	GetInfo() string
+
+	/**
+	 * Sets the signature algorithm, "ES256" for SHAKEN.
+	 */
+	SetAlgorithm(alg string) (ParseException error)
+
+	/**
+	 * Gets the signature algorithm, "" when the header leaves it to the
+	 * PASSporT.
+	 */
+	GetAlgorithm() string
+
+	/**
+	 * Sets the PASSporT extension the signature was made with.
+	 */
+	SetPpt(ppt string) (ParseException error)
+
+	/**
+	 * Gets the PASSporT extension the signature was made with, "" for a
+	 * PASSporT without extension.
+	 */
+	GetPpt() string
+}
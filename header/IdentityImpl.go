@@ -0,0 +1,106 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+	"strings"
+)
+
+/**
+ * The Identity SIP header, RFC 8224.
+ */
+type Identity struct {
+	Parameters
+
+	digest string
+}
+
+/** Creates a new instance of Identity */
+func NewIdentity() *Identity {
+	this := &Identity{}
+	this.Parameters.super(core.SIPHeaderNames_IDENTITY)
+	return this
+}
+
+/** Set the signed identity digest.
+ *
+ *@param digest -- the PASSporT of the request.
+ */
+func (this *Identity) SetSignedIdentityDigest(digest string) (ParseException error) {
+	if digest == "" {
+		return errors.New("ParseException: the signed identity digest is empty")
+	}
+	this.digest = digest
+	return nil
+}
+
+/** Get the signed identity digest.
+ */
+func (this *Identity) GetSignedIdentityDigest() string {
+	return this.digest
+}
+
+/** Set the info parameter.
+ *
+ *@param uri -- the URI of the certificate of the signer.
+ */
+func (this *Identity) SetInfo(uri string) (ParseException error) {
+	if uri == "" {
+		return errors.New("ParseException: the info URI is empty")
+	}
+	this.SetParameter(ParameterNames_INFO, "<"+uri+">")
+	return nil
+}
+
+/** Get the info parameter, without its angle brackets.
+ */
+func (this *Identity) GetInfo() string {
+	info := this.GetParameter(ParameterNames_INFO)
+	return strings.TrimSuffix(strings.TrimPrefix(info, "<"), ">")
+}
+
+/** Set the alg parameter.
+ */
+func (this *Identity) SetAlgorithm(alg string) (ParseException error) {
+	this.SetParameter(ParameterNames_ALG, alg)
+	return nil
+}
+
+/** Get the alg parameter.
+ */
+func (this *Identity) GetAlgorithm() string {
+	return this.GetParameter(ParameterNames_ALG)
+}
+
+/** Set the ppt parameter.
+ */
+func (this *Identity) SetPpt(ppt string) (ParseException error) {
+	this.SetParameter(ParameterNames_PPT, ppt)
+	return nil
+}
+
+/** Get the ppt parameter.
+ */
+func (this *Identity) GetPpt() string {
+	return this.GetParameter(ParameterNames_PPT)
+}
+
+func (this *Identity) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Encode the body of this header (the stuff that follows headerName).
+ * A.K.A headerValue.
+ */
+func (this *Identity) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(this.digest)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
@@ -0,0 +1,29 @@
+package header
+
+/**
+ * This interface represents the Info-Package SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc6086.txt">RFC6086</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Info-Package header tells the Info Package an INFO request is sent
+ * for, which defines how the receiver interprets its body. An INFO without
+ * Info-Package is a legacy INFO usage of RFC 2976.
+ * <p>
+ * For Example:<br>
+ * <code>Info-Package: foo</code>
+ */
+type InfoPackageHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the name of the Info Package, such as "dtmf".
+	 *
+	 * @throws ParseException if the name is empty
+	 */
+	SetPackageName(packageName string) (ParseException error)
+
+	/**
+	 * Gets the name of the Info Package of this header.
+	 */
+	GetPackageName() string
+}
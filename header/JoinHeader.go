@@ -0,0 +1,51 @@
+package header
+
+/**
+ * This interface represents the Join SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3911.txt">RFC3911</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Join header is carried by an INVITE to join the new dialog with an
+ * existing one, typically into a conference. It identifies the existing dialog
+ * the same way as the Replaces header: by its Call-ID, the tag of its To header
+ * and the tag of its From header.
+ */
+type JoinHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the Call-ID of the dialog to join.
+	 *
+	 * @param callId - the Call-ID of the dialog to join
+	 * @throws ParseException which signals that an error has been reached
+	 * unexpectedly while parsing the callId value.
+	 */
+	SetCallId(callId string) (ParseException error)
+
+	/**
+	 * Gets the Call-ID of the dialog to join.
+	 *
+	 * @return the Call-ID of the dialog to join
+	 */
+	GetCallId() string
+
+	/**
+	 * Sets the to-tag parameter, the tag of the To header of the dialog.
+	 */
+	SetToTag(tag string) (ParseException error)
+
+	/**
+	 * Gets the to-tag parameter, the tag of the To header of the dialog.
+	 */
+	GetToTag() string
+
+	/**
+	 * Sets the from-tag parameter, the tag of the From header of the dialog.
+	 */
+	SetFromTag(tag string) (ParseException error)
+
+	/**
+	 * Gets the from-tag parameter, the tag of the From header of the dialog.
+	 */
+	GetFromTag() string
+}
@@ -0,0 +1,102 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+)
+
+/**
+* Join SIP Header.
+ */
+type Join struct {
+	Parameters
+
+	callId string
+}
+
+/** Creates a new instance of Join */
+func NewJoin() *Join {
+	this := &Join{}
+	this.Parameters.super(core.SIPHeaderNames_JOIN)
+	return this
+}
+
+/**
+ * Sets the Call-ID of the dialog to join.
+ *
+ * @param callId - the Call-ID of the dialog to join
+ * @throws ParseException which signals that an error has been reached
+ * unexpectedly while parsing the callId value.
+ */
+func (this *Join) SetCallId(callId string) (ParseException error) {
+	if callId == "" {
+		return errors.New("NullPointerException: the callId is null")
+	}
+	this.callId = callId
+	return nil
+}
+
+/**
+ * Gets the Call-ID of the dialog to join.
+ *
+ * @return the Call-ID of the dialog to join
+ */
+func (this *Join) GetCallId() string {
+	return this.callId
+}
+
+/**
+ * Sets the to-tag parameter, the tag of the To header of the dialog.
+ */
+func (this *Join) SetToTag(tag string) (ParseException error) {
+	if tag == "" {
+		return errors.New("NullPointerException: the to-tag is null")
+	}
+	return this.SetParameter(ParameterNames_TO_TAG, tag)
+}
+
+/**
+ * Gets the to-tag parameter, the tag of the To header of the dialog.
+ */
+func (this *Join) GetToTag() string {
+	return this.GetParameter(ParameterNames_TO_TAG)
+}
+
+/**
+ * Sets the from-tag parameter, the tag of the From header of the dialog.
+ */
+func (this *Join) SetFromTag(tag string) (ParseException error) {
+	if tag == "" {
+		return errors.New("NullPointerException: the from-tag is null")
+	}
+	return this.SetParameter(ParameterNames_FROM_TAG, tag)
+}
+
+/**
+ * Gets the from-tag parameter, the tag of the From header of the dialog.
+ */
+func (this *Join) GetFromTag() string {
+	return this.GetParameter(ParameterNames_FROM_TAG)
+}
+
+func (this *Join) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON + core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode in canonical form.
+ * @return String
+ */
+func (this *Join) EncodeBody() string {
+	var encoding bytes.Buffer
+
+	encoding.WriteString(this.callId)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+
+	return encoding.String()
+}
@@ -0,0 +1,51 @@
+package header
+
+/**
+ * This interface represents the P-Access-Network-Info SIP header, as
+ * defined by <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>,
+ * this header is not part of RFC3261.
+ * <p>
+ * The P-Access-Network-Info header is inserted by a user agent, or by the
+ * proxy serving it with the network-provided parameter, to tell the IMS
+ * network the access technology it uses, the access-type or access-class,
+ * and where it is attached, such as the cell identifier of the radio
+ * access network in the cgi-3gpp or utran-cell-id-3gpp parameter.
+ * <p>
+ * For Example:<br>
+ * <code>P-Access-Network-Info: 3GPP-UTRAN-TDD;
+ * utran-cell-id-3gpp=23456789ABCDE</code>
+ */
+type PAccessNetworkInfoHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the access-type or access-class, such as "3GPP-E-UTRAN-FDD" or
+	 * "IEEE-802.11".
+	 *
+	 * @throws ParseException if the access type is empty
+	 */
+	SetAccessType(accessType string) (ParseException error)
+
+	/**
+	 * Gets the access-type or access-class of this header.
+	 */
+	GetAccessType() string
+
+	/**
+	 * Sets the network-provided parameter, telling the access information
+	 * was inserted by the network rather than by the user agent.
+	 */
+	SetNetworkProvided(networkProvided bool)
+
+	/**
+	 * Tells whether the access information was inserted by the network.
+	 */
+	IsNetworkProvided() bool
+
+	/**
+	 * Gets the identifier of the cell the user agent is attached to, the
+	 * value of the utran-cell-id-3gpp or else of the cgi-3gpp parameter,
+	 * without quotes. Returns "" when the header has neither.
+	 */
+	GetCellId() string
+}
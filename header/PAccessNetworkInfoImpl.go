@@ -0,0 +1,78 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+	"strings"
+)
+
+/**
+* P-Access-Network-Info SIP Header, one access-net-spec.
+ */
+type PAccessNetworkInfo struct {
+	Parameters
+
+	/** access-type or access-class field
+	 */
+	accessType string
+}
+
+/** default constructor
+ */
+func NewPAccessNetworkInfo() *PAccessNetworkInfo {
+	this := &PAccessNetworkInfo{}
+	this.Parameters.super(core.SIPHeaderNames_P_ACCESS_NETWORK_INFO)
+	return this
+}
+
+func (this *PAccessNetworkInfo) SetAccessType(accessType string) (ParseException error) {
+	if accessType == "" {
+		return errors.New("NullPointerException: the accessType parameter is null")
+	}
+	this.accessType = accessType
+	return nil
+}
+
+func (this *PAccessNetworkInfo) GetAccessType() string {
+	return this.accessType
+}
+
+func (this *PAccessNetworkInfo) SetNetworkProvided(networkProvided bool) {
+	if networkProvided {
+		this.SetParameter(ParameterNames_NETWORK_PROVIDED, "")
+	} else {
+		this.RemoveParameter(ParameterNames_NETWORK_PROVIDED)
+	}
+}
+
+func (this *PAccessNetworkInfo) IsNetworkProvided() bool {
+	return this.HasParameter(ParameterNames_NETWORK_PROVIDED)
+}
+
+func (this *PAccessNetworkInfo) GetCellId() string {
+	for _, name := range []string{ParameterNames_UTRAN_CELL_ID_3GPP, ParameterNames_CGI_3GPP} {
+		if this.HasParameter(name) {
+			return strings.Trim(this.GetParameter(name), "\"")
+		}
+	}
+	return ""
+}
+
+func (this *PAccessNetworkInfo) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *PAccessNetworkInfo) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(this.accessType)
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
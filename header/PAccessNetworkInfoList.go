@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* P-Access-Network-Info List of SIP headers
+ */
+type PAccessNetworkInfoList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewPAccessNetworkInfoList() *PAccessNetworkInfoList {
+	this := &PAccessNetworkInfoList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_P_ACCESS_NETWORK_INFO)
+	return this
+}
@@ -0,0 +1,23 @@
+package header
+
+/**
+ * This interface represents the P-Asserted-Identity SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3325.txt">RFC3325</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The P-Asserted-Identity header carries the identity of the user sending a
+ * message, as a proxy of the trust domain authenticated it. It may hold one
+ * sip or sips URI and one tel URI. A proxy removes it from the messages it
+ * receives from outside the trust domain.
+ * <p>
+ * For Example:<br>
+ * <code>P-Asserted-Identity: "Cullen Jennings" &lt;sip:fluffy@cisco.com&gt;,
+ * &lt;tel:+14085264000&gt;</code>
+ *
+ * @see PPreferredIdentityHeader
+ * @see PrivacyHeader
+ */
+type PAssertedIdentityHeader interface {
+	AddressHeader
+	ParametersHeader
+}
@@ -0,0 +1,59 @@
+package header
+
+import (
+	"bytes"
+	"sip/address"
+	"sip/core"
+)
+
+/**
+* P-Asserted-Identity SIP Header.
+ */
+type PAssertedIdentity struct {
+	AddressParameters
+}
+
+/** constructor
+ * @param addr address to set
+ */
+func NewPAssertedIdentityFromAddress(addr address.Address) *PAssertedIdentity {
+	this := &PAssertedIdentity{}
+	this.AddressParameters.super(core.SIPHeaderNames_P_ASSERTED_IDENTITY)
+	this.addr = addr
+	return this
+}
+
+/** default Constructor.
+ */
+func NewPAssertedIdentity() *PAssertedIdentity {
+	this := &PAssertedIdentity{}
+	this.AddressParameters.super(core.SIPHeaderNames_P_ASSERTED_IDENTITY)
+	return this
+}
+
+func (this *PAssertedIdentity) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode the header content into a String.
+ * @return String
+ */
+func (this *PAssertedIdentity) EncodeBody() string {
+	var encoding bytes.Buffer
+	addr, _ := this.addr.(*address.AddressImpl)
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_LESS_THAN)
+	}
+	encoding.WriteString(this.addr.String())
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_GREATER_THAN)
+	}
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
@@ -0,0 +1,20 @@
+package header
+
+/**
+ * This interface represents the P-Associated-URI SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The P-Associated-URI header is returned by a registrar in the 2xx to a
+ * REGISTER. It lists the other public user identities associated with the
+ * address-of-record registered, in the order the user agent should use
+ * them, the first one being the default.
+ * <p>
+ * For Example:<br>
+ * <code>P-Associated-URI: &lt;sip:user1-business@example.com&gt;,
+ * &lt;sip:user1-family@example.com&gt;</code>
+ */
+type PAssociatedURIHeader interface {
+	AddressHeader
+	ParametersHeader
+}
@@ -0,0 +1,59 @@
+package header
+
+import (
+	"bytes"
+	"sip/address"
+	"sip/core"
+)
+
+/**
+* P-Associated-URI SIP Header.
+ */
+type PAssociatedURI struct {
+	AddressParameters
+}
+
+/** constructor
+ * @param addr address to set
+ */
+func NewPAssociatedURIFromAddress(addr address.Address) *PAssociatedURI {
+	this := &PAssociatedURI{}
+	this.AddressParameters.super(core.SIPHeaderNames_P_ASSOCIATED_URI)
+	this.addr = addr
+	return this
+}
+
+/** default Constructor.
+ */
+func NewPAssociatedURI() *PAssociatedURI {
+	this := &PAssociatedURI{}
+	this.AddressParameters.super(core.SIPHeaderNames_P_ASSOCIATED_URI)
+	return this
+}
+
+func (this *PAssociatedURI) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode the header content into a String.
+ * @return String
+ */
+func (this *PAssociatedURI) EncodeBody() string {
+	var encoding bytes.Buffer
+	addr, _ := this.addr.(*address.AddressImpl)
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_LESS_THAN)
+	}
+	encoding.WriteString(this.addr.String())
+	if addr.GetAddressType() == address.ADDRESS_SPEC {
+		encoding.WriteString(core.SIPSeparatorNames_GREATER_THAN)
+	}
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
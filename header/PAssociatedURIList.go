@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* P-Associated-URI List of SIP headers
+ */
+type PAssociatedURIList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewPAssociatedURIList() *PAssociatedURIList {
+	this := &PAssociatedURIList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_P_ASSOCIATED_URI)
+	return this
+}
@@ -0,0 +1,19 @@
+package header
+
+/**
+ * This interface represents the P-Called-Party-ID SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The P-Called-Party-ID header is inserted by the proxy serving the callee,
+ * which replaced the Request-URI with a registered contact. It keeps the
+ * public user identity the request was addressed to, so that a user agent
+ * registered with several identities learns which one was called.
+ * <p>
+ * For Example:<br>
+ * <code>P-Called-Party-ID: &lt;sip:user1-business@example.com&gt;</code>
+ */
+type PCalledPartyIDHeader interface {
+	AddressHeader
+	ParametersHeader
+}
@@ -0,0 +1,42 @@
+package header
+
+/**
+ * This interface represents the P-Charging-Function-Addresses SIP header,
+ * as defined by <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>,
+ * this header is not part of RFC3261.
+ * <p>
+ * The P-Charging-Function-Addresses header tells the network elements of an
+ * IMS network where to send their charging information: the addresses of
+ * the Charging Collection Functions, for offline charging, and of the Event
+ * Charging Functions, for online charging, each of them listed by order of
+ * preference and repeated for redundancy.
+ * <p>
+ * For Example:<br>
+ * <code>P-Charging-Function-Addresses: ccf=192.1.1.1; ccf=192.1.1.2;
+ * ecf=192.1.1.3</code>
+ */
+type PChargingFunctionAddressesHeader interface {
+	ParametersHeader
+
+	/**
+	 * Adds the address of a Charging Collection Function.
+	 */
+	AddCCF(address string)
+
+	/**
+	 * Gets the addresses of the Charging Collection Functions, in order of
+	 * preference.
+	 */
+	GetCCFs() []string
+
+	/**
+	 * Adds the address of an Event Charging Function.
+	 */
+	AddECF(address string)
+
+	/**
+	 * Gets the addresses of the Event Charging Functions, in order of
+	 * preference.
+	 */
+	GetECFs() []string
+}
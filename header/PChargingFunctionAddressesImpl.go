@@ -0,0 +1,62 @@
+package header
+
+import (
+	"sip/core"
+)
+
+/**
+* P-Charging-Function-Addresses SIP Header. Its ccf and ecf parameters may
+* be repeated.
+ */
+type PChargingFunctionAddresses struct {
+	Parameters
+}
+
+/** default constructor
+ */
+func NewPChargingFunctionAddresses() *PChargingFunctionAddresses {
+	this := &PChargingFunctionAddresses{}
+	this.Parameters.super(core.SIPHeaderNames_P_CHARGING_FUNCTION_ADDRESSES)
+	return this
+}
+
+func (this *PChargingFunctionAddresses) AddCCF(address string) {
+	this.parameters.AddNameAndValue(ParameterNames_CCF, address)
+}
+
+func (this *PChargingFunctionAddresses) GetCCFs() []string {
+	return this.values(ParameterNames_CCF)
+}
+
+func (this *PChargingFunctionAddresses) AddECF(address string) {
+	this.parameters.AddNameAndValue(ParameterNames_ECF, address)
+}
+
+func (this *PChargingFunctionAddresses) GetECFs() []string {
+	return this.values(ParameterNames_ECF)
+}
+
+// values returns the values of every named parameter, in order.
+func (this *PChargingFunctionAddresses) values(name string) []string {
+	var values []string
+	for e := this.parameters.Front(); e != nil; e = e.Next() {
+		if nv := e.Value.(*core.NameValue); nv.GetName() == name {
+			if v, ok := nv.GetValue().(string); ok {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func (this *PChargingFunctionAddresses) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *PChargingFunctionAddresses) EncodeBody() string {
+	return this.parameters.String()
+}
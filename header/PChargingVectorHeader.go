@@ -0,0 +1,73 @@
+package header
+
+/**
+ * This interface represents the P-Charging-Vector SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The P-Charging-Vector header carries the charging correlation information
+ * of an IMS session: the IMS Charging Identifier, ICID, shared by every
+ * network element the session goes through, the address of the element
+ * that generated it, and the Inter Operator Identifiers, IOIs, of the
+ * originating, terminating and transit networks.
+ * <p>
+ * For Example:<br>
+ * <code>P-Charging-Vector: icid-value=1234bc9876e;
+ * icid-generated-at=192.0.6.8; orig-ioi=home1.net</code>
+ */
+type PChargingVectorHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the IMS Charging Identifier, the icid-value parameter.
+	 *
+	 * @throws ParseException if the ICID is empty
+	 */
+	SetICID(icid string) (ParseException error)
+
+	/**
+	 * Gets the IMS Charging Identifier of this header.
+	 */
+	GetICID() string
+
+	/**
+	 * Sets the address of the element that generated the ICID, the
+	 * icid-generated-at parameter.
+	 */
+	SetICIDGeneratedAt(host string)
+
+	/**
+	 * Gets the address of the element that generated the ICID.
+	 */
+	GetICIDGeneratedAt() string
+
+	/**
+	 * Sets the Inter Operator Identifier of the originating network.
+	 */
+	SetOrigIOI(ioi string)
+
+	/**
+	 * Gets the Inter Operator Identifier of the originating network.
+	 */
+	GetOrigIOI() string
+
+	/**
+	 * Sets the Inter Operator Identifier of the terminating network.
+	 */
+	SetTermIOI(ioi string)
+
+	/**
+	 * Gets the Inter Operator Identifier of the terminating network.
+	 */
+	GetTermIOI() string
+
+	/**
+	 * Sets the Inter Operator Identifiers of the transit networks.
+	 */
+	SetTransitIOI(ioi string)
+
+	/**
+	 * Gets the Inter Operator Identifiers of the transit networks.
+	 */
+	GetTransitIOI() string
+}
@@ -0,0 +1,100 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+)
+
+/**
+* P-Charging-Vector SIP Header.
+ */
+type PChargingVector struct {
+	Parameters
+}
+
+/** default constructor
+ */
+func NewPChargingVector() *PChargingVector {
+	this := &PChargingVector{}
+	this.Parameters.super(core.SIPHeaderNames_P_CHARGING_VECTOR)
+	return this
+}
+
+func (this *PChargingVector) SetICID(icid string) (ParseException error) {
+	if icid == "" {
+		return errors.New("NullPointerException: the icid parameter is null")
+	}
+	return this.SetParameter(ParameterNames_ICID_VALUE, icid)
+}
+
+func (this *PChargingVector) GetICID() string {
+	return this.GetParameter(ParameterNames_ICID_VALUE)
+}
+
+func (this *PChargingVector) SetICIDGeneratedAt(host string) {
+	this.setOrRemove(ParameterNames_ICID_GEN_ADDR, host)
+}
+
+func (this *PChargingVector) GetICIDGeneratedAt() string {
+	return this.GetParameter(ParameterNames_ICID_GEN_ADDR)
+}
+
+func (this *PChargingVector) SetOrigIOI(ioi string) {
+	this.setOrRemove(ParameterNames_ORIG_IOI, ioi)
+}
+
+func (this *PChargingVector) GetOrigIOI() string {
+	return this.GetParameter(ParameterNames_ORIG_IOI)
+}
+
+func (this *PChargingVector) SetTermIOI(ioi string) {
+	this.setOrRemove(ParameterNames_TERM_IOI, ioi)
+}
+
+func (this *PChargingVector) GetTermIOI() string {
+	return this.GetParameter(ParameterNames_TERM_IOI)
+}
+
+func (this *PChargingVector) SetTransitIOI(ioi string) {
+	this.setOrRemove(ParameterNames_TRANSIT_IOI, ioi)
+}
+
+func (this *PChargingVector) GetTransitIOI() string {
+	return this.GetParameter(ParameterNames_TRANSIT_IOI)
+}
+
+// setOrRemove sets the named parameter, or removes it when value is empty.
+func (this *PChargingVector) setOrRemove(name, value string) {
+	if value == "" {
+		this.RemoveParameter(name)
+	} else {
+		this.SetParameter(name, value)
+	}
+}
+
+func (this *PChargingVector) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form, the icid-value first.
+ * @return body encoded as a string.
+ */
+func (this *PChargingVector) EncodeBody() string {
+	var encoding bytes.Buffer
+	if icid := this.GetNameValue(ParameterNames_ICID_VALUE); icid != nil {
+		encoding.WriteString(icid.String())
+	}
+	for e := this.parameters.Front(); e != nil; e = e.Next() {
+		nv := e.Value.(*core.NameValue)
+		if nv.GetName() == ParameterNames_ICID_VALUE {
+			continue
+		}
+		if encoding.Len() > 0 {
+			encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		}
+		encoding.WriteString(nv.String())
+	}
+	return encoding.String()
+}
@@ -0,0 +1,20 @@
+package header
+
+/**
+ * This interface represents the P-Preferred-Identity SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3325.txt">RFC3325</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * A user agent sends the P-Preferred-Identity header to a proxy of the trust
+ * domain to choose which of its identities the proxy asserts. Once the user
+ * is authenticated, the proxy replaces it with a P-Asserted-Identity header.
+ * <p>
+ * For Example:<br>
+ * <code>P-Preferred-Identity: "Cullen Jennings" &lt;sip:fluffy@cisco.com&gt;</code>
+ *
+ * @see PAssertedIdentityHeader
+ */
+type PPreferredIdentityHeader interface {
+	AddressHeader
+	ParametersHeader
+}
@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* P-Preferred-Identity List of SIP headers (a collection of Addresses)
+ */
+type PPreferredIdentityList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewPPreferredIdentityList() *PPreferredIdentityList {
+	this := &PPreferredIdentityList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_P_PREFERRED_IDENTITY)
+	return this
+}
@@ -0,0 +1,30 @@
+package header
+
+/**
+ * This interface represents the P-Visited-Network-ID SIP header, as defined
+ * by <a href = "http://www.ietf.org/rfc/rfc7315.txt">RFC7315</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The P-Visited-Network-ID header is inserted by a proxy of a visited
+ * network into the REGISTER of a roaming user agent, to tell its home
+ * network the network the user agent registers from. The identifier is a
+ * token, such as a domain name, or a quoted string.
+ * <p>
+ * For Example:<br>
+ * <code>P-Visited-Network-ID: other.net, "Visited network number 1"</code>
+ */
+type PVisitedNetworkIDHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the identifier of the visited network, without quotes.
+	 *
+	 * @throws ParseException if the identifier is empty
+	 */
+	SetVisitedNetworkID(networkId string) (ParseException error)
+
+	/**
+	 * Gets the identifier of the visited network, without quotes.
+	 */
+	GetVisitedNetworkID() string
+}
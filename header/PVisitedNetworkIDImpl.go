@@ -0,0 +1,68 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+	"strings"
+)
+
+/**
+* P-Visited-Network-ID SIP Header, one vnetwork-spec.
+ */
+type PVisitedNetworkID struct {
+	Parameters
+
+	/** network identifier field, without quotes
+	 */
+	networkId string
+}
+
+/** default constructor
+ */
+func NewPVisitedNetworkID() *PVisitedNetworkID {
+	this := &PVisitedNetworkID{}
+	this.Parameters.super(core.SIPHeaderNames_P_VISITED_NETWORK_ID)
+	return this
+}
+
+func (this *PVisitedNetworkID) SetVisitedNetworkID(networkId string) (ParseException error) {
+	if networkId == "" {
+		return errors.New("NullPointerException: the networkId parameter is null")
+	}
+	this.networkId = networkId
+	return nil
+}
+
+func (this *PVisitedNetworkID) GetVisitedNetworkID() string {
+	return this.networkId
+}
+
+func (this *PVisitedNetworkID) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form, the identifier being quoted
+ * unless it is a token.
+ * @return body encoded as a string.
+ */
+func (this *PVisitedNetworkID) EncodeBody() string {
+	var encoding bytes.Buffer
+	token := true
+	for i := 0; i < len(this.networkId); i++ {
+		token = token && core.IsTokenChar(this.networkId[i])
+	}
+	if token {
+		encoding.WriteString(this.networkId)
+	} else {
+		encoding.WriteString(core.SIPSeparatorNames_DOUBLE_QUOTE)
+		encoding.WriteString(strings.Replace(strings.Replace(this.networkId, "\\", "\\\\", -1), "\"", "\\\"", -1))
+		encoding.WriteString(core.SIPSeparatorNames_DOUBLE_QUOTE)
+	}
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* P-Visited-Network-ID List of SIP headers
+ */
+type PVisitedNetworkIDList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewPVisitedNetworkIDList() *PVisitedNetworkIDList {
+	this := &PVisitedNetworkIDList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_P_VISITED_NETWORK_ID)
+	return this
+}
@@ -57,6 +57,37 @@ const ParameterNames_TRANSPORT = "transport"
 const ParameterNames_TEXT = "text"
 const ParameterNames_CAUSE = "cause"
 const ParameterNames_ID = "id"
+const ParameterNames_TO_TAG = "to-tag"
+const ParameterNames_FROM_TAG = "from-tag"
+const ParameterNames_EARLY_ONLY = "early-only"
+const ParameterNames_INDEX = "index"
+const ParameterNames_RC = "rc"
+const ParameterNames_MP = "mp"
+const ParameterNames_NP = "np"
+const ParameterNames_REASON = "reason"
+const ParameterNames_COUNTER = "counter"
+const ParameterNames_LIMIT = "limit"
+const ParameterNames_PRIVACY = "privacy"
+const ParameterNames_SCREEN = "screen"
+const ParameterNames_ALG = "alg"
+const ParameterNames_PPT = "ppt"
+const ParameterNames_D_ALG = "d-alg"
+const ParameterNames_D_QOP = "d-qop"
+const ParameterNames_D_VER = "d-ver"
+const ParameterNames_REQUIRE = "require"
+const ParameterNames_EXPLICIT = "explicit"
+const ParameterNames_ICID_VALUE = "icid-value"
+const ParameterNames_ICID_GEN_ADDR = "icid-generated-at"
+const ParameterNames_ORIG_IOI = "orig-ioi"
+const ParameterNames_TERM_IOI = "term-ioi"
+const ParameterNames_TRANSIT_IOI = "transit-ioi"
+const ParameterNames_CCF = "ccf"
+const ParameterNames_ECF = "ecf"
+const ParameterNames_NETWORK_PROVIDED = "network-provided"
+const ParameterNames_CGI_3GPP = "cgi-3gpp"
+const ParameterNames_UTRAN_CELL_ID_3GPP = "utran-cell-id-3gpp"
+const ParameterNames_DSL_LOCATION = "dsl-location"
+const ParameterNames_I_WLAN_NODE_ID = "i-wlan-node-id"
 
 const SIPConstants_DEFAULT_ENCODING = "UTF-8"
 const SIPConstants_DEFAULT_PORT = 5060
@@ -114,7 +145,7 @@ func (this *Parameters) GetParameter(name string) string {
  *
  */
 func (this *Parameters) GetParameterValue(name string) string {
-	return this.parameters.GetValue(name).(string)
+	return this.parameters.GetParameter(name)
 }
 
 /**
@@ -168,11 +199,16 @@ func (this *Parameters) RemoveParameter(name string) {
  *
  */
 func (this *Parameters) SetParameter(name, value string) (ParseException error) {
+	//a zero-length value sets a flag parameter, encoded without "="
+	var v interface{}
+	if value != "" {
+		v = value
+	}
 	nv := this.parameters.GetNameValue(name)
 	if nv != nil {
-		nv.SetValue(value)
+		nv.SetValue(v)
 	} else {
-		nv = core.NewNameValue(name, value)
+		nv = core.NewNameValue(name, v)
 		this.parameters.AddNameValue(nv)
 	}
 	return nil
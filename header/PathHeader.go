@@ -0,0 +1,23 @@
+package header
+
+/**
+ * This interface represents the Path SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3327.txt">RFC3327</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * A proxy on the way from a user agent to its registrar adds a Path header to
+ * the REGISTER, naming itself. The registrar stores the resulting Path vector
+ * with the bindings of the REGISTER, and routes the requests for the
+ * registered contacts through it, as a preloaded route set.
+ * <p>
+ * For Example:<br>
+ * <code>Path: &lt;sip:P2.EXAMPLEHOME.COM;lr&gt;,
+ * &lt;sip:P1.EXAMPLEVISITED.COM;lr&gt;</code>
+ *
+ * @see ServiceRouteHeader
+ * @see RouteHeader
+ */
+type PathHeader interface {
+	AddressHeader
+	ParametersHeader
+}
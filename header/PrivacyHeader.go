@@ -0,0 +1,36 @@
+package header
+
+/**
+ * This interface represents the Privacy SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3323.txt">RFC3323</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Privacy header lists the privacy a user asks of the network: "header"
+ * to hide the headers revealing the user, "session" to hide the media
+ * addresses, "user" to anonymize the user level headers, "id" to withhold
+ * the asserted identity outside the trust domain
+ * (<a href = "http://www.ietf.org/rfc/rfc3325.txt">RFC3325</a>), "none" to
+ * ask for no privacy and "critical" to fail the request rather than send it
+ * without the privacy asked.
+ * <p>
+ * For Example:<br>
+ * <code>Privacy: id;user</code>
+ */
+type PrivacyHeader interface {
+	Header
+
+	/**
+	 * Sets the privacy value of this PrivacyHeader.
+	 *
+	 * @param privacy - the priv-value, such as "id" or "header"
+	 * @throws ParseException if the privacy value is empty
+	 */
+	SetPrivacy(privacy string) (ParseException error)
+
+	/**
+	 * Gets the privacy value of this PrivacyHeader.
+	 *
+	 * @return the priv-value of this PrivacyHeader
+	 */
+	GetPrivacy() string
+}
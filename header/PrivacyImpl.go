@@ -0,0 +1,70 @@
+package header
+
+import (
+	"errors"
+	"sip/core"
+)
+
+/**
+* Privacy SIP Header, one priv-value.
+ */
+type Privacy struct {
+	SIPHeader
+
+	/** priv-value field
+	 */
+	privacy string
+}
+
+/** default constructor
+ */
+func NewPrivacy() *Privacy {
+	this := &Privacy{}
+	this.SIPHeader.super(core.SIPHeaderNames_PRIVACY)
+	return this
+}
+
+/** constructor
+ * @param privacy String to set
+ */
+func NewPrivacyFromString(privacy string) *Privacy {
+	this := &Privacy{}
+	this.SIPHeader.super(core.SIPHeaderNames_PRIVACY)
+	this.privacy = privacy
+	return this
+}
+
+/**
+ * Sets the privacy value of this PrivacyHeader.
+ *
+ * @param privacy - the priv-value, such as "id" or "header"
+ * @throws ParseException if the privacy value is empty
+ */
+func (this *Privacy) SetPrivacy(privacy string) (ParseException error) {
+	if privacy == "" {
+		return errors.New("NullPointerException: the privacy parameter is null")
+	}
+	this.privacy = privacy
+	return nil
+}
+
+/**
+ * Gets the privacy value of this PrivacyHeader.
+ *
+ * @return the priv-value of this PrivacyHeader
+ */
+func (this *Privacy) GetPrivacy() string {
+	return this.privacy
+}
+
+func (this *Privacy) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *Privacy) EncodeBody() string {
+	return this.privacy
+}
@@ -0,0 +1,55 @@
+package header
+
+import (
+	"bytes"
+	"container/list"
+	"sip/core"
+)
+
+/**
+* List of Privacy headers. The priv-values of a Privacy header are separated
+* by semicolons rather than commas.
+ */
+type PrivacyList struct {
+	SIPHeaderList
+}
+
+/** default constructor
+ */
+func NewPrivacyList() *PrivacyList {
+	this := &PrivacyList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_PRIVACY)
+	return this
+}
+
+/**
+ * Gets the priv-values of the list.
+ *
+ * @return List of String objects, one per priv-value
+ */
+func (this *PrivacyList) GetPrivacy() *list.List {
+	ll := list.New()
+	for e := this.Front(); e != nil; e = e.Next() {
+		ll.PushBack(e.Value.(*Privacy).GetPrivacy())
+	}
+	return ll
+}
+
+func (this *PrivacyList) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Encode the priv-values, separated by semicolons.
+ * @return body encoded as a string.
+ */
+func (this *PrivacyList) EncodeBody() string {
+	var encoding bytes.Buffer
+	for e := this.Front(); e != nil; e = e.Next() {
+		encoding.WriteString(e.Value.(*Privacy).EncodeBody())
+		if e.Next() != nil {
+			encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		}
+	}
+	return encoding.String()
+}
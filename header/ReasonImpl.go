@@ -2,8 +2,10 @@ package header
 
 import (
 	"bytes"
+	"errors"
 	"sip/core"
 	"strconv"
+	"strings"
 )
 
 /**
@@ -36,7 +38,10 @@ func (this *Reason) GetCause() int {
  *@param cause - cause to Set.
  */
 func (this *Reason) SetCause(cause int) (InvalidArgumentException error) {
-	this.parameters.AddNameAndValue("cause", strconv.Itoa(cause))
+	if cause < 0 {
+		return errors.New("InvalidArgumentException: the cause is negative")
+	}
+	this.SetParameter(ParameterNames_CAUSE, strconv.Itoa(cause))
 	return nil
 }
 
@@ -58,22 +63,33 @@ func (this *Reason) GetProtocol() string {
 	return this.protocol
 }
 
-/** Set the text.
+/** Set the text, which is quoted and escaped.
  *
  *@param text -- string text to Set.
  */
 func (this *Reason) SetText(text string) (ParseException error) {
-	this.parameters.AddNameAndValue("text", text)
+	this.SetQuotedParameter(ParameterNames_TEXT, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text))
 	return nil
 }
 
-/** Get the text.
+/** Get the text, without its quotes and escapes.
  *
  *@return text parameter.
  *
  */
 func (this *Reason) GetText() string {
-	return this.parameters.GetParameter("text")
+	text := this.parameters.GetParameter(ParameterNames_TEXT)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	var unescaped bytes.Buffer
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) {
+			i++
+		}
+		unescaped.WriteByte(text[i])
+	}
+	return unescaped.String()
 }
 
 /** Set the cause.
@@ -0,0 +1,31 @@
+package header
+
+/**
+ * This interface represents the Recv-Info SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc6086.txt">RFC6086</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Recv-Info header lists the Info Packages a user agent is willing to
+ * receive INFO requests for within the invite dialog usage. It is sent in
+ * the INVITE, the reliable provisional and the 2xx responses to it, and in
+ * the target refresh requests and responses. An empty Recv-Info tells the
+ * user agent receives no Info Package.
+ * <p>
+ * For Example:<br>
+ * <code>Recv-Info: dtmf, foo</code>
+ */
+type RecvInfoHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the name of an Info Package the user agent receives.
+	 *
+	 * @throws ParseException if the name is empty
+	 */
+	SetPackageName(packageName string) (ParseException error)
+
+	/**
+	 * Gets the name of the Info Package of this header.
+	 */
+	GetPackageName() string
+}
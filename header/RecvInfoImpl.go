@@ -0,0 +1,56 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+)
+
+/**
+* Recv-Info SIP Header, one info-package-type.
+ */
+type RecvInfo struct {
+	Parameters
+
+	/** info-package-name field
+	 */
+	packageName string
+}
+
+/** default constructor
+ */
+func NewRecvInfo() *RecvInfo {
+	this := &RecvInfo{}
+	this.Parameters.super(core.SIPHeaderNames_RECV_INFO)
+	return this
+}
+
+func (this *RecvInfo) SetPackageName(packageName string) (ParseException error) {
+	if packageName == "" {
+		return errors.New("NullPointerException: the packageName parameter is null")
+	}
+	this.packageName = packageName
+	return nil
+}
+
+func (this *RecvInfo) GetPackageName() string {
+	return this.packageName
+}
+
+func (this *RecvInfo) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *RecvInfo) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(this.packageName)
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
@@ -0,0 +1,29 @@
+package header
+
+import "sip/core"
+
+/**
+* Recv-Info List of SIP headers, which may be empty.
+ */
+type RecvInfoList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewRecvInfoList() *RecvInfoList {
+	this := &RecvInfoList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_RECV_INFO)
+	return this
+}
+
+/**
+ * Gets the names of the Info Packages listed in this header.
+ */
+func (this *RecvInfoList) GetPackageNames() []string {
+	var names []string
+	for e := this.Front(); e != nil; e = e.Next() {
+		names = append(names, e.Value.(*RecvInfo).GetPackageName())
+	}
+	return names
+}
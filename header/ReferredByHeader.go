@@ -0,0 +1,16 @@
+package header
+
+/**
+ * This interface represents the Referred-By SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3892.txt">RFC3892</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Referred-By header identifies the party that sent a REFER request. The
+ * REFER recipient copies it into the request it sends to the Refer-To target,
+ * so that the target learns who referred the request. The optional "cid"
+ * parameter names the body part carrying a Referred-By token.
+ */
+type ReferredByHeader interface {
+	AddressHeader
+	ParametersHeader
+}
@@ -0,0 +1,19 @@
+package header
+
+/**
+ * This interface represents the Reject-Contact SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3841.txt">RFC3841</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * Each value of the Reject-Contact header is a set of RFC 3840 feature tags,
+ * as parameters: a contact indicating all of them, with matching values, is
+ * not tried.
+ * <p>
+ * For Example:<br>
+ * <code>Reject-Contact: *;actor="msg-taker";video</code>
+ *
+ * @see AcceptContactHeader
+ */
+type RejectContactHeader interface {
+	ParametersHeader
+}
@@ -0,0 +1,39 @@
+package header
+
+import (
+	"bytes"
+	"sip/core"
+)
+
+/**
+ * The Reject-Contact SIP header, one rc-value.
+ */
+type RejectContact struct {
+	Parameters
+}
+
+/** Creates a new instance of RejectContact */
+func NewRejectContact() *RejectContact {
+	this := &RejectContact{}
+	this.Parameters.super(core.SIPHeaderNames_REJECT_CONTACT)
+	return this
+}
+
+func (this *RejectContact) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Encode the body of this header (the stuff that follows headerName).
+ * A.K.A headerValue.
+ */
+func (this *RejectContact) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(core.SIPSeparatorNames_STAR)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
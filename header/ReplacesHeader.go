@@ -0,0 +1,64 @@
+package header
+
+/**
+ * This interface represents the Replaces SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3891.txt">RFC3891</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * The Replaces header is carried by an INVITE to logically replace an existing
+ * dialog with the new one. It identifies the dialog to replace by its Call-ID,
+ * the tag of its To header and the tag of its From header, from the point of
+ * view of the recipient's peer. The "early-only" flag asks the recipient to
+ * replace the dialog only if it is still in the early state.
+ * <p>
+ * Attended transfer embeds a Replaces header in the Refer-To URI of a REFER.
+ */
+type ReplacesHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the Call-ID of the dialog to replace.
+	 *
+	 * @param callId - the Call-ID of the dialog to replace
+	 * @throws ParseException which signals that an error has been reached
+	 * unexpectedly while parsing the callId value.
+	 */
+	SetCallId(callId string) (ParseException error)
+
+	/**
+	 * Gets the Call-ID of the dialog to replace.
+	 *
+	 * @return the Call-ID of the dialog to replace
+	 */
+	GetCallId() string
+
+	/**
+	 * Sets the to-tag parameter, the tag of the To header of the dialog.
+	 */
+	SetToTag(tag string) (ParseException error)
+
+	/**
+	 * Gets the to-tag parameter, the tag of the To header of the dialog.
+	 */
+	GetToTag() string
+
+	/**
+	 * Sets the from-tag parameter, the tag of the From header of the dialog.
+	 */
+	SetFromTag(tag string) (ParseException error)
+
+	/**
+	 * Gets the from-tag parameter, the tag of the From header of the dialog.
+	 */
+	GetFromTag() string
+
+	/**
+	 * Sets or clears the early-only flag.
+	 */
+	SetEarlyOnly(earlyOnly bool)
+
+	/**
+	 * Tells whether only an early dialog may be replaced.
+	 */
+	IsEarlyOnly() bool
+}
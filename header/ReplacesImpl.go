@@ -0,0 +1,119 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+)
+
+/**
+* Replaces SIP Header.
+ */
+type Replaces struct {
+	Parameters
+
+	callId string
+}
+
+/** Creates a new instance of Replaces */
+func NewReplaces() *Replaces {
+	this := &Replaces{}
+	this.Parameters.super(core.SIPHeaderNames_REPLACES)
+	return this
+}
+
+/**
+ * Sets the Call-ID of the dialog to replace.
+ *
+ * @param callId - the Call-ID of the dialog to replace
+ * @throws ParseException which signals that an error has been reached
+ * unexpectedly while parsing the callId value.
+ */
+func (this *Replaces) SetCallId(callId string) (ParseException error) {
+	if callId == "" {
+		return errors.New("NullPointerException: the callId is null")
+	}
+	this.callId = callId
+	return nil
+}
+
+/**
+ * Gets the Call-ID of the dialog to replace.
+ *
+ * @return the Call-ID of the dialog to replace
+ */
+func (this *Replaces) GetCallId() string {
+	return this.callId
+}
+
+/**
+ * Sets the to-tag parameter, the tag of the To header of the dialog.
+ */
+func (this *Replaces) SetToTag(tag string) (ParseException error) {
+	if tag == "" {
+		return errors.New("NullPointerException: the to-tag is null")
+	}
+	return this.SetParameter(ParameterNames_TO_TAG, tag)
+}
+
+/**
+ * Gets the to-tag parameter, the tag of the To header of the dialog.
+ */
+func (this *Replaces) GetToTag() string {
+	return this.GetParameter(ParameterNames_TO_TAG)
+}
+
+/**
+ * Sets the from-tag parameter, the tag of the From header of the dialog.
+ */
+func (this *Replaces) SetFromTag(tag string) (ParseException error) {
+	if tag == "" {
+		return errors.New("NullPointerException: the from-tag is null")
+	}
+	return this.SetParameter(ParameterNames_FROM_TAG, tag)
+}
+
+/**
+ * Gets the from-tag parameter, the tag of the From header of the dialog.
+ */
+func (this *Replaces) GetFromTag() string {
+	return this.GetParameter(ParameterNames_FROM_TAG)
+}
+
+/**
+ * Sets or clears the early-only flag.
+ */
+func (this *Replaces) SetEarlyOnly(earlyOnly bool) {
+	this.RemoveParameter(ParameterNames_EARLY_ONLY)
+	if earlyOnly {
+		this.SetParameterFromNameValue(core.NewNameValue(ParameterNames_EARLY_ONLY, nil))
+	}
+}
+
+/**
+ * Tells whether only an early dialog may be replaced.
+ */
+func (this *Replaces) IsEarlyOnly() bool {
+	return this.HasParameter(ParameterNames_EARLY_ONLY)
+}
+
+func (this *Replaces) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON + core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/**
+ * Encode in canonical form.
+ * @return String
+ */
+func (this *Replaces) EncodeBody() string {
+	var encoding bytes.Buffer
+
+	encoding.WriteString(this.callId)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+
+	return encoding.String()
+}
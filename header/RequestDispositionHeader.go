@@ -0,0 +1,31 @@
+package header
+
+/**
+ * This interface represents the Request-Disposition SIP header, as defined
+ * by <a href = "http://www.ietf.org/rfc/rfc3841.txt">RFC3841</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The Request-Disposition header holds the directives of the caller to the
+ * proxies on how to handle the request: whether to proxy or redirect it,
+ * whether to fork it, in parallel or sequentially, whether to recurse on
+ * the 3xx responses, whether to cancel the pending branches when one
+ * answers and whether to queue the request when the callee is busy.
+ * <p>
+ * For Example:<br>
+ * <code>Request-Disposition: proxy, recurse, parallel</code>
+ *
+ * @see AcceptContactHeader
+ */
+type RequestDispositionHeader interface {
+	Header
+
+	/**
+	 * Sets the directive, such as "redirect" or "sequential".
+	 */
+	SetDirective(directive string) (ParseException error)
+
+	/**
+	 * Gets the directive of this header.
+	 */
+	GetDirective() string
+}
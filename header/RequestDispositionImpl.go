@@ -0,0 +1,58 @@
+package header
+
+import (
+	"errors"
+	"sip/core"
+)
+
+/**
+* Request-Disposition SIP Header, one directive.
+ */
+type RequestDisposition struct {
+	SIPHeader
+
+	/** directive field
+	 */
+	directive string
+}
+
+/** default constructor
+ */
+func NewRequestDisposition() *RequestDisposition {
+	this := &RequestDisposition{}
+	this.SIPHeader.super(core.SIPHeaderNames_REQUEST_DISPOSITION)
+	return this
+}
+
+/**
+ * Sets the directive of this RequestDispositionHeader.
+ *
+ * @param directive - the directive, such as "proxy" or "no-fork"
+ * @throws ParseException if the directive is empty
+ */
+func (this *RequestDisposition) SetDirective(directive string) (ParseException error) {
+	if directive == "" {
+		return errors.New("NullPointerException: the directive parameter is null")
+	}
+	this.directive = directive
+	return nil
+}
+
+/**
+ * Gets the directive of this RequestDispositionHeader.
+ */
+func (this *RequestDisposition) GetDirective() string {
+	return this.directive
+}
+
+func (this *RequestDisposition) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *RequestDisposition) EncodeBody() string {
+	return this.directive
+}
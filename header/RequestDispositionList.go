@@ -0,0 +1,18 @@
+package header
+
+import "sip/core"
+
+/**
+* List of Request-Disposition headers.
+ */
+type RequestDispositionList struct {
+	SIPHeaderList
+}
+
+/** Default constructor
+ */
+func NewRequestDispositionList() *RequestDispositionList {
+	this := &RequestDispositionList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_REQUEST_DISPOSITION)
+	return this
+}
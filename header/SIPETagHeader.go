@@ -0,0 +1,33 @@
+package header
+
+/**
+ * This interface represents the SIP-ETag SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3903.txt">RFC3903</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The SIP-ETag header is returned by an Event State Compositor in the 2xx
+ * response to a PUBLISH. It holds the entity-tag the compositor assigned to
+ * the event state published, which the publisher quotes in the SIP-If-Match
+ * of the requests refreshing, modifying or removing that state.
+ * <p>
+ * For Example:<br>
+ * <code>SIP-ETag: dx200xyz</code>
+ *
+ * @see SIPIfMatchHeader
+ */
+type SIPETagHeader interface {
+	Header
+
+	/**
+	 * Sets the entity-tag of this header.
+	 *
+	 * @param etag - the entity-tag, a token
+	 * @throws ParseException if the entity-tag is empty
+	 */
+	SetETag(etag string) (ParseException error)
+
+	/**
+	 * Gets the entity-tag of this header.
+	 */
+	GetETag() string
+}
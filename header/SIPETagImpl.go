@@ -0,0 +1,58 @@
+package header
+
+import (
+	"errors"
+	"sip/core"
+)
+
+/**
+* SIP-ETag SIP Header.
+ */
+type SIPETag struct {
+	SIPHeader
+
+	/** entity-tag field
+	 */
+	etag string
+}
+
+/** default constructor
+ */
+func NewSIPETag() *SIPETag {
+	this := &SIPETag{}
+	this.SIPHeader.super(core.SIPHeaderNames_SIP_ETAG)
+	return this
+}
+
+/**
+ * Sets the entity-tag of this SIPETagHeader.
+ *
+ * @param etag - the entity-tag, a token
+ * @throws ParseException if the entity-tag is empty
+ */
+func (this *SIPETag) SetETag(etag string) (ParseException error) {
+	if etag == "" {
+		return errors.New("NullPointerException: the etag parameter is null")
+	}
+	this.etag = etag
+	return nil
+}
+
+/**
+ * Gets the entity-tag of this SIPETagHeader.
+ */
+func (this *SIPETag) GetETag() string {
+	return this.etag
+}
+
+func (this *SIPETag) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *SIPETag) EncodeBody() string {
+	return this.etag
+}
@@ -0,0 +1,33 @@
+package header
+
+/**
+ * This interface represents the SIP-If-Match SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3903.txt">RFC3903</a>, this header
+ * is not part of RFC3261.
+ * <p>
+ * The SIP-If-Match header makes a PUBLISH conditional: it applies to the
+ * event state whose entity-tag, as returned in a SIP-ETag, it holds. The
+ * Event State Compositor answers 412 (Conditional Request Failed) when it
+ * holds no such state.
+ * <p>
+ * For Example:<br>
+ * <code>SIP-If-Match: dx200xyz</code>
+ *
+ * @see SIPETagHeader
+ */
+type SIPIfMatchHeader interface {
+	Header
+
+	/**
+	 * Sets the entity-tag of this header.
+	 *
+	 * @param etag - the entity-tag, a token
+	 * @throws ParseException if the entity-tag is empty
+	 */
+	SetETag(etag string) (ParseException error)
+
+	/**
+	 * Gets the entity-tag of this header.
+	 */
+	GetETag() string
+}
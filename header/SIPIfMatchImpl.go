@@ -0,0 +1,58 @@
+package header
+
+import (
+	"errors"
+	"sip/core"
+)
+
+/**
+* SIP-If-Match SIP Header.
+ */
+type SIPIfMatch struct {
+	SIPHeader
+
+	/** entity-tag field
+	 */
+	etag string
+}
+
+/** default constructor
+ */
+func NewSIPIfMatch() *SIPIfMatch {
+	this := &SIPIfMatch{}
+	this.SIPHeader.super(core.SIPHeaderNames_SIP_IF_MATCH)
+	return this
+}
+
+/**
+ * Sets the entity-tag of this SIPIfMatchHeader.
+ *
+ * @param etag - the entity-tag, a token
+ * @throws ParseException if the entity-tag is empty
+ */
+func (this *SIPIfMatch) SetETag(etag string) (ParseException error) {
+	if etag == "" {
+		return errors.New("NullPointerException: the etag parameter is null")
+	}
+	this.etag = etag
+	return nil
+}
+
+/**
+ * Gets the entity-tag of this SIPIfMatchHeader.
+ */
+func (this *SIPIfMatch) GetETag() string {
+	return this.etag
+}
+
+func (this *SIPIfMatch) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Return body encoded in canonical form.
+ * @return body encoded as a string.
+ */
+func (this *SIPIfMatch) EncodeBody() string {
+	return this.etag
+}
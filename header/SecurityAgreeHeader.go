@@ -0,0 +1,87 @@
+package header
+
+/**
+ * This interface represents the security mechanisms of the
+ * Security-Client, Security-Server and Security-Verify SIP headers, as
+ * defined by <a href = "http://www.ietf.org/rfc/rfc3329.txt">RFC3329</a>,
+ * these headers are not part of RFC3261.
+ * <p>
+ * The headers let a user agent and its first hop agree on the security
+ * mechanism protecting the requests between them: the client lists the
+ * mechanisms it supports in Security-Client, the server answers with its own
+ * in Security-Server, and the client echoes them in Security-Verify on the
+ * protected requests, so that the server detects a man-in-the-middle having
+ * removed the strongest mechanisms.
+ * <p>
+ * For Example:<br>
+ * <code>Security-Server: tls;q=0.2, digest;d-alg=md5;q=0.1</code>
+ *
+ * @see SecurityClientHeader
+ * @see SecurityServerHeader
+ * @see SecurityVerifyHeader
+ */
+type SecurityAgreeHeader interface {
+	ParametersHeader
+
+	/**
+	 * Sets the name of the security mechanism, such as "tls" or "digest".
+	 */
+	SetSecurityMechanism(mechanism string) (ParseException error)
+
+	/**
+	 * Gets the name of the security mechanism.
+	 */
+	GetSecurityMechanism() string
+
+	/**
+	 * Sets the preference of the mechanism, between 0 and 1.
+	 */
+	SetPreference(q float32) (InvalidArgumentException error)
+
+	/**
+	 * Gets the preference of the mechanism.
+	 *
+	 * @return the q parameter, -1 when the mechanism has none
+	 */
+	GetPreference() float32
+
+	/**
+	 * Gets the digest algorithm of the digest mechanism, the d-alg
+	 * parameter.
+	 */
+	GetDigestAlgorithm() string
+
+	/**
+	 * Gets the quality of protection of the digest mechanism, the d-qop
+	 * parameter.
+	 */
+	GetDigestQop() string
+
+	/**
+	 * Gets the digest of the Security-Server of the server, the d-ver
+	 * parameter, by which a client using the digest mechanism echoes it.
+	 */
+	GetDigestVerify() string
+}
+
+/**
+ * The Security-Client header, the security mechanisms a client supports.
+ */
+type SecurityClientHeader interface {
+	SecurityAgreeHeader
+}
+
+/**
+ * The Security-Server header, the security mechanisms a server supports.
+ */
+type SecurityServerHeader interface {
+	SecurityAgreeHeader
+}
+
+/**
+ * The Security-Verify header, the Security-Server a client received,
+ * echoed on the requests it protects.
+ */
+type SecurityVerifyHeader interface {
+	SecurityAgreeHeader
+}
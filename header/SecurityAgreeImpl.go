@@ -0,0 +1,135 @@
+package header
+
+import (
+	"bytes"
+	"errors"
+	"sip/core"
+	"strconv"
+	"strings"
+)
+
+/**
+ * A security mechanism of a Security-Client, Security-Server or
+ * Security-Verify header, RFC 3329 §2.2.
+ */
+type SecurityAgree struct {
+	Parameters
+
+	mechanism string
+}
+
+func (this *SecurityAgree) super(hname string) {
+	this.Parameters.super(hname)
+}
+
+/** Set the name of the security mechanism.
+ */
+func (this *SecurityAgree) SetSecurityMechanism(mechanism string) (ParseException error) {
+	if mechanism == "" {
+		return errors.New("ParseException: the security mechanism is empty")
+	}
+	this.mechanism = mechanism
+	return nil
+}
+
+/** Get the name of the security mechanism.
+ */
+func (this *SecurityAgree) GetSecurityMechanism() string {
+	return this.mechanism
+}
+
+/** Set the preference, the q parameter.
+ */
+func (this *SecurityAgree) SetPreference(q float32) (InvalidArgumentException error) {
+	if q < 0.0 || q > 1.0 {
+		return errors.New("qvalue out of range!")
+	}
+	this.SetParameter(ParameterNames_Q, strconv.FormatFloat(float64(q), 'f', -1, 32))
+	return nil
+}
+
+/** Get the preference, -1 if the parameter has not been set.
+ */
+func (this *SecurityAgree) GetPreference() float32 {
+	if !this.HasParameter(ParameterNames_Q) {
+		return -1
+	}
+	q, _ := strconv.ParseFloat(this.GetParameterValue(ParameterNames_Q), 32)
+	return float32(q)
+}
+
+/** Get the d-alg parameter.
+ */
+func (this *SecurityAgree) GetDigestAlgorithm() string {
+	return this.GetParameter(ParameterNames_D_ALG)
+}
+
+/** Get the d-qop parameter.
+ */
+func (this *SecurityAgree) GetDigestQop() string {
+	return this.GetParameter(ParameterNames_D_QOP)
+}
+
+/** Get the d-ver parameter, without its quotes.
+ */
+func (this *SecurityAgree) GetDigestVerify() string {
+	return strings.Trim(this.GetParameter(ParameterNames_D_VER), "\"")
+}
+
+func (this *SecurityAgree) String() string {
+	return this.headerName + core.SIPSeparatorNames_COLON +
+		core.SIPSeparatorNames_SP + this.EncodeBody() + core.SIPSeparatorNames_NEWLINE
+}
+
+/** Encode the body of this header (the stuff that follows headerName).
+ * A.K.A headerValue.
+ */
+func (this *SecurityAgree) EncodeBody() string {
+	var encoding bytes.Buffer
+	encoding.WriteString(this.mechanism)
+
+	if this.parameters != nil && this.parameters.Len() > 0 {
+		encoding.WriteString(core.SIPSeparatorNames_SEMICOLON)
+		encoding.WriteString(this.parameters.String())
+	}
+	return encoding.String()
+}
+
+/**
+ * The Security-Client header.
+ */
+type SecurityClient struct {
+	SecurityAgree
+}
+
+func NewSecurityClient() *SecurityClient {
+	this := &SecurityClient{}
+	this.SecurityAgree.super(core.SIPHeaderNames_SECURITY_CLIENT)
+	return this
+}
+
+/**
+ * The Security-Server header.
+ */
+type SecurityServer struct {
+	SecurityAgree
+}
+
+func NewSecurityServer() *SecurityServer {
+	this := &SecurityServer{}
+	this.SecurityAgree.super(core.SIPHeaderNames_SECURITY_SERVER)
+	return this
+}
+
+/**
+ * The Security-Verify header.
+ */
+type SecurityVerify struct {
+	SecurityAgree
+}
+
+func NewSecurityVerify() *SecurityVerify {
+	this := &SecurityVerify{}
+	this.SecurityAgree.super(core.SIPHeaderNames_SECURITY_VERIFY)
+	return this
+}
@@ -0,0 +1,42 @@
+package header
+
+import "sip/core"
+
+/**
+* List of Security-Client headers.
+ */
+type SecurityClientList struct {
+	SIPHeaderList
+}
+
+func NewSecurityClientList() *SecurityClientList {
+	this := &SecurityClientList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_SECURITY_CLIENT)
+	return this
+}
+
+/**
+* List of Security-Server headers.
+ */
+type SecurityServerList struct {
+	SIPHeaderList
+}
+
+func NewSecurityServerList() *SecurityServerList {
+	this := &SecurityServerList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_SECURITY_SERVER)
+	return this
+}
+
+/**
+* List of Security-Verify headers.
+ */
+type SecurityVerifyList struct {
+	SIPHeaderList
+}
+
+func NewSecurityVerifyList() *SecurityVerifyList {
+	this := &SecurityVerifyList{}
+	this.SIPHeaderList.super(core.SIPHeaderNames_SECURITY_VERIFY)
+	return this
+}
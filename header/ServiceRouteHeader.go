@@ -0,0 +1,23 @@
+package header
+
+/**
+ * This interface represents the Service-Route SIP header, as defined by
+ * <a href = "http://www.ietf.org/rfc/rfc3608.txt">RFC3608</a>, this header is
+ * not part of RFC3261.
+ * <p>
+ * A registrar returns the Service-Route header in the 2xx response to a
+ * REGISTER. The user agent uses it as the preloaded route set of the requests
+ * it sends outside of any dialog, so that they go through the home proxies
+ * of the service.
+ * <p>
+ * For Example:<br>
+ * <code>Service-Route: &lt;sip:P2.HOME.EXAMPLE.COM;lr&gt;,
+ * &lt;sip:HSP.HOME.EXAMPLE.COM;lr&gt;</code>
+ *
+ * @see PathHeader
+ * @see RouteHeader
+ */
+type ServiceRouteHeader interface {
+	AddressHeader
+	ParametersHeader
+}
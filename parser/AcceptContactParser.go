@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Accept-Contact header, a list of "*" with feature parameters.
+ */
+type AcceptContactParser struct {
+	ParametersParser
+}
+
+/** Constructor
+ * @param acceptContact message to parse to set
+ */
+func NewAcceptContactParser(acceptContact string) *AcceptContactParser {
+	this := &AcceptContactParser{}
+	this.ParametersParser.super(acceptContact)
+	return this
+}
+
+func NewAcceptContactParserFromLexer(lexer core.Lexer) *AcceptContactParser {
+	this := &AcceptContactParser{}
+	this.ParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Accept-Contact List Object
+ * @return SIPHeader the Accept-Contact List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *AcceptContactParser) Parse() (sh header.Header, ParseException error) {
+	acceptContactList := header.NewAcceptContactList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_ACCEPT_CONTACT)
+	for {
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch != '*' {
+			return nil, this.CreateParseException("expected '*'")
+		}
+		lexer.ConsumeK(1)
+		acceptContact := header.NewAcceptContact()
+		if ParseException = this.ParametersParser.Parse(acceptContact); ParseException != nil {
+			return nil, ParseException
+		}
+		acceptContactList.PushBack(acceptContact)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return acceptContactList, nil
+}
@@ -49,7 +49,6 @@ func (this *AddressParser) NameAddr() (addr *address.AddressImpl, ParseException
 	if ch, _ = lexer.LookAheadK(0); ch == '<' {
 		lexer.Match('<')
 		lexer.SelectLexer("sip_urlLexer")
-		lexer.SPorHT()
 		uriParser := NewURLParserFromLexer(lexer)
 		if uri, ParseException = uriParser.UriReference(); ParseException != nil {
 			return nil, ParseException
@@ -57,8 +56,9 @@ func (this *AddressParser) NameAddr() (addr *address.AddressImpl, ParseException
 		addr = address.NewAddressImpl()
 		addr.SetAddressType(address.NAME_ADDR)
 		addr.SetURI(uri)
-		lexer.SPorHT()
-		lexer.Match('>')
+		if _, ParseException = lexer.Match('>'); ParseException != nil {
+			return nil, ParseException
+		}
 		return addr, nil
 	} else {
 		addr = address.NewAddressImpl()
@@ -73,18 +73,26 @@ func (this *AddressParser) NameAddr() (addr *address.AddressImpl, ParseException
 			if name, ParseException = lexer.GetNextTokenByDelim('<'); ParseException != nil {
 				return nil, ParseException
 			}
+			// an unquoted display name is *(token LWS)
+			for i := 0; i < len(name); i++ {
+				if !core.IsTokenChar(name[i]) && strings.IndexByte(" \t\r\n", name[i]) < 0 {
+					return nil, this.CreateParseException("unquoted display name with a non-token character")
+				}
+			}
 		}
 		addr.SetDisplayName(strings.TrimSpace(name))
-		lexer.Match('<')
-		lexer.SPorHT()
+		if _, ParseException = lexer.Match('<'); ParseException != nil {
+			return nil, ParseException
+		}
 		uriParser := NewURLParserFromLexer(lexer)
 		if uri, ParseException = uriParser.UriReference(); ParseException != nil {
 			return nil, ParseException
 		}
 		addr.SetAddressType(address.NAME_ADDR)
 		addr.SetURI(uri)
-		lexer.SPorHT()
-		lexer.Match('>')
+		if _, ParseException = lexer.Match('>'); ParseException != nil {
+			return nil, ParseException
+		}
 		return addr, nil
 	}
 }
@@ -117,7 +125,7 @@ func (this *AddressParser) Address() (retval *address.AddressImpl, ParseExceptio
 		uriParser := NewURLParserFromLexer(lexer)
 
 		var uri address.URI
-		if uri, ParseException = uriParser.UriReference(); ParseException != nil {
+		if uri, ParseException = uriParser.UriReferenceInBrackets(false); ParseException != nil {
 			return nil, ParseException
 		}
 		retval.SetAddressType(address.ADDRESS_SPEC)
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func TestAcceptContactParser(t *testing.T) {
+	var tvi = []string{
+		"Accept-Contact: *;audio;require\n",
+		"a: *;video;methods=\"INVITE,BYE\";explicit, *;+sip.instance=\"<urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6>\"\n",
+	}
+	var tvo = []string{
+		"Accept-Contact: *;audio;require\n",
+		"Accept-Contact: *;video;methods=\"INVITE,BYE\";explicit,*;+sip.instance=\"<urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6>\"\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewAcceptContactParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	h, _ := NewAcceptContactParser(tvi[0]).Parse()
+	if ac := h.(*header.AcceptContactList).Front().Value.(*header.AcceptContact); !ac.IsRequired() || ac.IsExplicit() {
+		t.Error(ac.EncodeBody())
+	}
+	if _, err := NewAcceptContactParser("Accept-Contact: <sip:bob@example.com>;audio\n").Parse(); err == nil {
+		t.Error("parsed an address as an ac-value")
+	}
+}
+
+func TestRejectContactParser(t *testing.T) {
+	shp := NewRejectContactParser("Reject-Contact: *;actor=\"msg-taker\";video\n")
+	testHeaderParser(t, shp, "Reject-Contact: *;actor=\"msg-taker\";video\n")
+}
+
+func TestRequestDispositionParser(t *testing.T) {
+	shp := NewRequestDispositionParser("Request-Disposition: proxy , Recurse, sequential\n")
+	testHeaderParser(t, shp, "Request-Disposition: proxy,recurse,sequential\n")
+	if _, err := NewRequestDispositionParser("Request-Disposition: maybe\n").Parse(); err == nil {
+		t.Error("parsed an unknown directive")
+	}
+}
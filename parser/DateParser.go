@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"errors"
 	"sip/core"
 	"sip/header"
 	"strings"
@@ -42,7 +41,7 @@ func (this *DateParser) Parse() (sh header.Header, ParseException error) {
 		return nil, ParseException
 	}
 	if t.Location().String() != "GMT" {
-		return nil, errors.New("GMT is only acceptable time zone")
+		return nil, this.CreateParseError("GMT", "GMT is only acceptable time zone")
 	}
 	retval := header.NewDate()
 	retval.SetDate(&t)
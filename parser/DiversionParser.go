@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strconv"
+)
+
+/** Parser for the Diversion header, a list of name-addr with diversion parameters.
+ */
+type DiversionParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param diversion message to parse to set
+ */
+func NewDiversionParser(diversion string) *DiversionParser {
+	this := &DiversionParser{}
+	this.AddressParametersParser.super(diversion)
+	return this
+}
+
+func NewDiversionParserFromLexer(lexer core.Lexer) *DiversionParser {
+	this := &DiversionParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Diversion List Object
+ * @return SIPHeader the Diversion List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *DiversionParser) Parse() (sh header.Header, ParseException error) {
+	diversionList := header.NewDiversionList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_DIVERSION)
+	for {
+		diversion := header.NewDiversion()
+		if ParseException = this.AddressParametersParser.Parse(diversion); ParseException != nil {
+			return nil, ParseException
+		}
+		for _, name := range []string{header.ParameterNames_COUNTER, header.ParameterNames_LIMIT} {
+			if diversion.HasParameter(name) {
+				if _, ParseException = strconv.Atoi(diversion.GetParameter(name)); ParseException != nil {
+					return nil, this.CreateParseError(name, "malformed Diversion "+name+" parameter")
+				}
+			}
+		}
+		diversionList.PushBack(diversion)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return diversionList, nil
+}
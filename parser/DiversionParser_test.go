@@ -0,0 +1,27 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestDiversionParser(t *testing.T) {
+	var tvi = []string{
+		"Diversion: <sip:bob@example.com>;reason=user-busy\n",
+		"Diversion: Carol <sip:carol@example.com>;reason=no-answer;counter=1;privacy=off;screen=no, " +
+			"<sip:bob@example.com>;reason=unconditional;counter=2;limit=5\n",
+	}
+	var tvo = []string{
+		"Diversion: <sip:bob@example.com>;reason=user-busy\n",
+		"Diversion: \"Carol\" <sip:carol@example.com>;reason=no-answer;counter=1;privacy=off;screen=no," +
+			"<sip:bob@example.com>;reason=unconditional;counter=2;limit=5\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewDiversionParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	if _, err := NewDiversionParser("Diversion: <sip:bob@example.com>;counter=many\n").Parse(); err == nil {
+		t.Error("parsed a malformed counter")
+	}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strings"
+)
+
+/** Parser for the generic-params of RFC 3261 §25.1 whose values may be a
+ * host, such as an IPv6 reference, which the parameters parser doesn't
+ * read. The P-headers of RFC 7315 use them.
+ */
+type GenericParamsParser struct {
+	ParametersParser
+}
+
+func (this *GenericParamsParser) super(buffer string) {
+	this.ParametersParser.super(buffer)
+}
+
+func (this *GenericParamsParser) superFromLexer(lexer core.Lexer) {
+	this.ParametersParser.superFromLexer(lexer)
+}
+
+/** parse the *(SEMI generic-param) following a value into h. The
+ * parameters named in repeatable may occur more than once.
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *GenericParamsParser) genericParams(h header.ParametersHeader, repeatable ...string) (ParseException error) {
+	lexer := this.GetLexer()
+	lexer.SPorHT()
+	for ch, _ := lexer.LookAheadK(0); ch == ';'; ch, _ = lexer.LookAheadK(0) {
+		lexer.ConsumeK(1)
+		if ParseException = this.genericParam(h, repeatable...); ParseException != nil {
+			return ParseException
+		}
+		lexer.SPorHT()
+	}
+	return nil
+}
+
+/** parse one generic-param, token [ EQUAL gen-value ], into h.
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *GenericParamsParser) genericParam(h header.ParametersHeader, repeatable ...string) (ParseException error) {
+	lexer := this.GetLexer()
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+		return ParseException
+	}
+	name := lexer.GetNextToken().GetTokenValue()
+	lexer.SPorHT()
+	value := ""
+	if ch, _ := lexer.LookAheadK(0); ch == '=' {
+		lexer.ConsumeK(1)
+		lexer.SPorHT()
+		if value, ParseException = this.genValue(); ParseException != nil {
+			return ParseException
+		}
+	}
+	for _, r := range repeatable {
+		if strings.EqualFold(r, name) {
+			h.GetParameters().AddNameAndValue(name, value)
+			return nil
+		}
+	}
+	return h.SetParameter(name, value)
+}
+
+/** parse a gen-value: a token, a host or a quoted string, which keeps its
+ * quotes.
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *GenericParamsParser) genValue() (value string, ParseException error) {
+	lexer := this.GetLexer()
+	if ch, _ := lexer.LookAheadK(0); ch == '"' {
+		quoted, err := lexer.QuotedString()
+		if err != nil {
+			return "", this.CreateParseException("unterminated quoted string")
+		}
+		return "\"" + quoted + "\"", nil
+	}
+	var host []byte
+	for ch, _ := lexer.LookAheadK(0); core.IsTokenChar(ch) || ch == ':' || ch == '[' || ch == ']'; ch, _ = lexer.LookAheadK(0) {
+		lexer.ConsumeK(1)
+		host = append(host, ch)
+	}
+	if len(host) == 0 {
+		return "", this.CreateParseException("missing parameter value")
+	}
+	return string(host), nil
+}
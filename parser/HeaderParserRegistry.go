@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+/** A ParserFactory returns the parser of a header line, "Name: value\n".
+ * The parser of a custom header returns a value implementing header.Header.
+ * To round-trip through a message, its EncodeBody returns the value as it
+ * goes on the wire, String returns "Name: " + EncodeBody() + "\r\n", and
+ * GetValue and GetHeaderValue return EncodeBody(). A type embedding
+ * header.SIPHeader has to override String, GetHeaderValue and GetValue,
+ * the ones of SIPHeader don't see the EncodeBody of the outer type. A
+ * message carries the header as the string EncodeBody returns, set with
+ * Header.Set(name, h.EncodeBody()).
+ */
+type ParserFactory func(line string) Parser
+
+// the registered factories, indexed by lower case header name
+var headerParsers = struct {
+	sync.RWMutex
+	factories map[string]ParserFactory
+	//the aliases of a header name, compact form included, both lower case
+	aliases map[string][]string
+	//the lower case name an alias stands for
+	aliasOf map[string]string
+	//the name of a header, as it was registered
+	names map[string]string
+}{
+	factories: make(map[string]ParserFactory),
+	aliases:   make(map[string][]string),
+	aliasOf:   make(map[string]string),
+	names:     make(map[string]string),
+}
+
+/** Registers the parser factory of the named header, and of its compact
+ * form unless compactName is "". A header the stack parses itself can't be
+ * registered, the stack relies on the types of its parsers, nor can its
+ * compact forms. A name or compact form already standing for another
+ * registered header is an error. It is safe to call concurrently with CreateParser.
+ */
+func RegisterHeaderParser(name, compactName string, factory ParserFactory) error {
+	name = strings.TrimSpace(name)
+	if name == "" || factory == nil {
+		return errors.New("a header parser needs a name and a factory")
+	}
+	if isBuiltinHeader(name) {
+		return errors.New("the stack parses " + name + " itself")
+	}
+	headerParsers.Lock()
+	defer headerParsers.Unlock()
+	key := strings.ToLower(name)
+	if other, ok := headerParsers.aliasOf[key]; ok {
+		return errors.New(name + " is an alias of " + headerParsers.names[other])
+	}
+	compactName = strings.ToLower(strings.TrimSpace(compactName))
+	if compactName != "" {
+		if err := checkAlias(compactName, key); err != nil {
+			return err
+		}
+	}
+	headerParsers.factories[key] = factory
+	headerParsers.names[key] = name
+	if compactName != "" {
+		registerAlias(compactName, key)
+	}
+	return nil
+}
+
+/** Registers alias as another name of a registered header, such as the
+ * name a vendor used before the header was standardized. An alias is
+ * parsed by the parser of the header it stands for, the one registered
+ * last. An alias standing for another registered header, or for a
+ * header or compact form the stack parses itself, is an error.
+ */
+func RegisterHeaderAlias(alias, name string) error {
+	headerParsers.Lock()
+	defer headerParsers.Unlock()
+	key := strings.ToLower(name)
+	if _, ok := headerParsers.factories[key]; !ok {
+		return errors.New("no parser is registered for " + name)
+	}
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if err := checkAlias(alias, key); err != nil {
+		return err
+	}
+	registerAlias(alias, key)
+	return nil
+}
+
+// checkAlias returns why alias can't stand for the header of the lower case
+// name key, nil if it can.
+func checkAlias(alias, key string) error {
+	if alias == key {
+		return errors.New(alias + " is the name of the header")
+	}
+	if isBuiltinHeader(alias) {
+		return errors.New("the stack parses " + alias + " itself")
+	}
+	if _, ok := headerParsers.factories[alias]; ok {
+		return errors.New(alias + " is the name of another header")
+	}
+	if other, ok := headerParsers.aliasOf[alias]; ok && other != key {
+		return errors.New(alias + " is an alias of " + headerParsers.names[other])
+	}
+	return nil
+}
+
+func registerAlias(alias, key string) {
+	if _, ok := headerParsers.aliasOf[alias]; ok {
+		return
+	}
+	headerParsers.aliasOf[alias] = key
+	headerParsers.aliases[key] = append(headerParsers.aliases[key], alias)
+}
+
+/** Removes the parser of the named header and of its aliases.
+ */
+func UnregisterHeaderParser(name string) {
+	headerParsers.Lock()
+	defer headerParsers.Unlock()
+	key := strings.ToLower(name)
+	if _, ok := headerParsers.factories[key]; !ok {
+		return
+	}
+	for _, alias := range headerParsers.aliases[key] {
+		delete(headerParsers.aliasOf, alias)
+	}
+	delete(headerParsers.aliases, key)
+	delete(headerParsers.factories, key)
+	delete(headerParsers.names, key)
+}
+
+/** Returns the aliases registered for the named header, in lower case.
+ */
+func GetHeaderAliases(name string) []string {
+	headerParsers.RLock()
+	defer headerParsers.RUnlock()
+	return append([]string(nil), headerParsers.aliases[strings.ToLower(name)]...)
+}
+
+/** Returns the registered factory of a header name or alias.
+ */
+func lookupHeaderParser(name string) (ParserFactory, bool) {
+	headerParsers.RLock()
+	defer headerParsers.RUnlock()
+	key := strings.ToLower(name)
+	if target, ok := headerParsers.aliasOf[key]; ok {
+		key = target
+	}
+	factory, ok := headerParsers.factories[key]
+	return factory, ok
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"fmt"
+	"sip/core"
+	"sip/header"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// AccountCode is a vendor header, "X-Account-Code: 1234;site=paris".
+type AccountCode struct {
+	header.SIPHeader
+	Code string
+	Site string
+}
+
+func (this *AccountCode) EncodeBody() string {
+	if this.Site == "" {
+		return this.Code
+	}
+	return this.Code + ";site=" + this.Site
+}
+
+func (this *AccountCode) String() string {
+	return this.GetHeaderName() + ": " + this.EncodeBody() + "\r\n"
+}
+
+func (this *AccountCode) GetHeaderValue() string { return this.EncodeBody() }
+func (this *AccountCode) GetValue() string       { return this.EncodeBody() }
+func (this *AccountCode) Clone() interface{} {
+	clone := *this
+	return &clone
+}
+
+type accountCodeParser struct {
+	line string
+}
+
+func (this *accountCodeParser) Parse() (header.Header, error) {
+	var lexer SIPLexer
+	value := strings.TrimSpace(lexer.GetHeaderValue(this.line))
+	if value == "" {
+		return nil, core.NewParseError(this.line, len(this.line), "code", "empty account code")
+	}
+	h := &AccountCode{}
+	h.SetHeaderName("X-Account-Code")
+	fields := strings.SplitN(value, ";site=", 2)
+	h.Code = fields[0]
+	if len(fields) == 2 {
+		h.Site = fields[1]
+	}
+	return h, nil
+}
+
+func newAccountCodeParser(line string) Parser {
+	return &accountCodeParser{line: line}
+}
+
+func ExampleRegisterHeaderParser() {
+	RegisterHeaderParser("X-Account-Code", "", newAccountCodeParser)
+	defer UnregisterHeaderParser("X-Account-Code")
+
+	p, _ := CreateParser("X-Account-Code: 1234;site=paris\n")
+	h, _ := p.Parse()
+	code := h.(*AccountCode)
+	code.Site = "lyon"
+	fmt.Print(code.String())
+	// Output: X-Account-Code: 1234;site=lyon
+}
+
+func TestHeaderParserRegistry(t *testing.T) {
+	if err := RegisterHeaderParser("X-Account-Code", "y", newAccountCodeParser); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterHeaderParser("X-Account-Code")
+	if err := RegisterHeaderAlias("X-Acct", "x-account-code"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterHeaderAlias("X-Foo", "X-Unknown"); err == nil {
+		t.Fail()
+	}
+
+	for _, line := range []string{"X-Account-Code: 1234\n", "y: 1234\n", "x-acct: 1234\n"} {
+		p, err := CreateParser(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := p.Parse()
+		if code, ok := h.(*AccountCode); err != nil || !ok || code.Code != "1234" {
+			t.Errorf("%q: %#v %v", line, h, err)
+		}
+	}
+	p, _ := CreateParser("y: \t\n")
+	if _, err := p.Parse(); err == nil {
+		t.Fail()
+	}
+	if aliases := GetHeaderAliases("X-ACCOUNT-CODE"); len(aliases) != 2 || aliases[0] != "y" || aliases[1] != "x-acct" {
+		t.Error(aliases)
+	}
+
+	//a name or alias of the header stands for no other
+	if err := RegisterHeaderParser("X-Other", "y", newAccountCodeParser); err == nil {
+		t.Error("registered the compact form of another header")
+	}
+	if _, ok := lookupHeaderParser("X-Other"); ok {
+		t.Error("registered a header whose compact form was refused")
+	}
+	if err := RegisterHeaderParser("X-Acct", "", newAccountCodeParser); err == nil {
+		t.Error("registered a header named as an alias")
+	}
+	RegisterHeaderParser("X-Other", "", newAccountCodeParser)
+	defer UnregisterHeaderParser("X-Other")
+	if err := RegisterHeaderAlias("x-acct", "X-Other"); err == nil {
+		t.Error("registered the alias of another header")
+	}
+	if err := RegisterHeaderAlias("X-Other", "X-Account-Code"); err == nil {
+		t.Error("registered the name of another header as an alias")
+	}
+	for _, alias := range []string{"v", "i", "From", "r"} {
+		if err := RegisterHeaderAlias(alias, "X-Other"); err == nil {
+			t.Error("registered the header the stack parses as an alias:", alias)
+		}
+	}
+	if err := RegisterHeaderParser("X-Foo", "v", newAccountCodeParser); err == nil {
+		UnregisterHeaderParser("X-Foo")
+		t.Error("registered the compact form of Via")
+	}
+	p, _ = CreateParser("v: SIP/2.0/UDP pc33.atlanta.com;branch=z9hG4bK776asdhds\n")
+	if h, _ := p.Parse(); h == nil || h.GetName() != "Via" {
+		t.Errorf("%#v", h)
+	}
+
+	//the aliases follow the factory registered last
+	var replaced bool
+	RegisterHeaderParser("X-Account-Code", "y", func(line string) Parser {
+		replaced = true
+		return newAccountCodeParser(line)
+	})
+	CreateParser("x-acct: 1234\n")
+	if !replaced {
+		t.Error("the alias kept the replaced factory")
+	}
+
+	//concurrent registrations and lookups
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("X-Test-%d", i)
+			RegisterHeaderParser(name, "", newAccountCodeParser)
+			CreateParser(name + ": 1\n")
+			UnregisterHeaderParser(name)
+		}(i)
+	}
+	wg.Wait()
+
+	UnregisterHeaderParser("X-Account-Code")
+	p, _ = CreateParser("y: 1234\n")
+	if h, _ := p.Parse(); h == nil {
+		t.Fail()
+	} else if _, ok := h.(*header.Extension); !ok {
+		t.Errorf("%#v", h)
+	}
+}
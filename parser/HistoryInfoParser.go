@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the History-Info header, a list of hi-entry.
+ */
+type HistoryInfoParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param historyInfo message to parse to set
+ */
+func NewHistoryInfoParser(historyInfo string) *HistoryInfoParser {
+	this := &HistoryInfoParser{}
+	this.AddressParametersParser.super(historyInfo)
+	return this
+}
+
+func NewHistoryInfoParserFromLexer(lexer core.Lexer) *HistoryInfoParser {
+	this := &HistoryInfoParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the HistoryInfo List Object
+ * @return SIPHeader the HistoryInfo List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *HistoryInfoParser) Parse() (sh header.Header, ParseException error) {
+	historyInfoList := header.NewHistoryInfoList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_HISTORY_INFO)
+	for {
+		historyInfo := header.NewHistoryInfo()
+		if ParseException = this.AddressParametersParser.Parse(historyInfo); ParseException != nil {
+			return nil, ParseException
+		}
+		//RFC 7044 §5.1, every entry has an index
+		if !header.IsHistoryInfoIndex(historyInfo.GetIndex()) {
+			return nil, this.CreateParseError("index", "missing or malformed History-Info index")
+		}
+		for _, name := range []string{header.ParameterNames_RC, header.ParameterNames_MP, header.ParameterNames_NP} {
+			if historyInfo.HasParameter(name) && !header.IsHistoryInfoIndex(historyInfo.GetParameter(name)) {
+				return nil, this.CreateParseError(name, "malformed History-Info "+name+" parameter")
+			}
+		}
+		historyInfoList.PushBack(historyInfo)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return historyInfoList, nil
+}
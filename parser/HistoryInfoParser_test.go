@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestHistoryInfoParser(t *testing.T) {
+	var tvi = []string{
+		"History-Info: <sip:bob@example.com>;index=1\n",
+		"History-Info: <sip:bob@example.com?Reason=SIP%3Bcause%3D302>;index=1, " +
+			"<sip:carol@example.com>;index=1.1;mp=1\n",
+		"History-Info: \"Office\" <sip:office@192.0.2.5>;index=1.2.1;rc=1.2 , <sip:vm@example.com>;index=1.2.2;np=1.2\n",
+	}
+	var tvo = []string{
+		"History-Info: <sip:bob@example.com>;index=1\n",
+		"History-Info: <sip:bob@example.com?Reason=SIP%3Bcause%3D302>;index=1," +
+			"<sip:carol@example.com>;index=1.1;mp=1\n",
+		"History-Info: \"Office\" <sip:office@192.0.2.5>;index=1.2.1;rc=1.2,<sip:vm@example.com>;index=1.2.2;np=1.2\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewHistoryInfoParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	for _, s := range []string{
+		"History-Info: <sip:bob@example.com>\n",
+		"History-Info: <sip:bob@example.com>;index=1..2\n",
+		"History-Info: <sip:bob@example.com>;index=1.1;mp=x\n",
+	} {
+		if _, err := NewHistoryInfoParser(s).Parse(); err == nil {
+			t.Error("parsed", s)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Identity header, RFC 8224 §4.
+ */
+type IdentityParser struct {
+	ParametersParser
+}
+
+/** Constructor
+ * @param identity message to parse to set
+ */
+func NewIdentityParser(identity string) *IdentityParser {
+	this := &IdentityParser{}
+	this.ParametersParser.super(identity)
+	return this
+}
+
+func NewIdentityParserFromLexer(lexer core.Lexer) *IdentityParser {
+	this := &IdentityParser{}
+	this.ParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Identity Object
+ * @return SIPHeader the Identity object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *IdentityParser) Parse() (sh header.Header, ParseException error) {
+	identity := header.NewIdentity()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_IDENTITY)
+	lexer.SPorHT()
+	//the compact JWS of RFC 8224, or the base64 signature of RFC 4474
+	var digest []byte
+	for ch, _ := lexer.LookAheadK(0); isDigestChar(ch); ch, _ = lexer.LookAheadK(0) {
+		lexer.ConsumeK(1)
+		digest = append(digest, ch)
+	}
+	if ParseException = identity.SetSignedIdentityDigest(string(digest)); ParseException != nil {
+		return nil, this.CreateParseException("missing signed identity digest")
+	}
+
+	//the info parameter is an URI between angle brackets, which the
+	//parameters parser doesn't read
+	lexer.SPorHT()
+	ch, _ := lexer.LookAheadK(0)
+	for ch == ';' {
+		lexer.ConsumeK(1)
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return nil, ParseException
+		}
+		name := lexer.GetNextToken().GetTokenValue()
+		lexer.SPorHT()
+		value := ""
+		if ch, _ = lexer.LookAheadK(0); ch == '=' {
+			lexer.ConsumeK(1)
+			lexer.SPorHT()
+			if ch, _ = lexer.LookAheadK(0); ch == '<' {
+				lexer.ConsumeK(1)
+				uri, err := lexer.GetString('>')
+				if err != nil {
+					return nil, this.CreateParseException("unterminated " + name + " URI")
+				}
+				value = "<" + uri + ">"
+			} else if ch == '"' {
+				quoted, err := lexer.QuotedString()
+				if err != nil {
+					return nil, err
+				}
+				value = "\"" + quoted + "\""
+			} else {
+				if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+					return nil, ParseException
+				}
+				value = lexer.GetNextToken().GetTokenValue()
+			}
+		}
+		identity.SetParameter(name, value)
+		lexer.SPorHT()
+		ch, _ = lexer.LookAheadK(0)
+	}
+	if ch != '\n' {
+		return nil, this.CreateParseException("unexpected char")
+	}
+	if info := identity.GetParameter(header.ParameterNames_INFO); info != "" && info[0] != '<' {
+		return nil, this.CreateParseException("the info parameter is not an URI between angle brackets")
+	}
+
+	return identity, nil
+}
+
+func isDigestChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' ||
+		ch == '-' || ch == '_' || ch == '.' || ch == '+' || ch == '/' || ch == '='
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Info-Package header.
+ */
+type InfoPackageParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param infoPackage message to parse to set
+ */
+func NewInfoPackageParser(infoPackage string) *InfoPackageParser {
+	this := &InfoPackageParser{}
+	this.GenericParamsParser.super(infoPackage)
+	return this
+}
+
+func NewInfoPackageParserFromLexer(lexer core.Lexer) *InfoPackageParser {
+	this := &InfoPackageParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Info-Package Object
+ * @return SIPHeader the Info-Package object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *InfoPackageParser) Parse() (sh header.Header, ParseException error) {
+	infoPackage := header.NewInfoPackage()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_INFO_PACKAGE)
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+		return nil, ParseException
+	}
+	infoPackage.SetPackageName(lexer.GetNextToken().GetTokenValue())
+	if ParseException = this.genericParams(infoPackage); ParseException != nil {
+		return nil, ParseException
+	}
+	if ch, _ := lexer.LookAheadK(0); ch != '\n' {
+		return nil, this.CreateParseException("unexpected char")
+	}
+
+	return infoPackage, nil
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func TestInfoPackageParser(t *testing.T) {
+	var tvi = []string{
+		"Info-Package: dtmf\n",
+		"Info-Package:  foo ; bar=1\n",
+	}
+	var tvo = []string{
+		"Info-Package: dtmf\n",
+		"Info-Package: foo;bar=1\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewInfoPackageParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+	if _, err := NewInfoPackageParser("Info-Package: foo, bar\n").Parse(); err == nil {
+		t.Error("parsed two packages")
+	}
+}
+
+func TestRecvInfoParser(t *testing.T) {
+	var tvi = []string{
+		"Recv-Info: dtmf , foo;bar\n",
+		"Recv-Info: \n",
+	}
+	var tvo = []string{
+		"Recv-Info: dtmf,foo;bar\n",
+		"Recv-Info:\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewRecvInfoParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	sh, err := NewRecvInfoParser("Recv-Info: dtmf, foo\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := sh.(*header.RecvInfoList).GetPackageNames(); len(names) != 2 || names[1] != "foo" {
+		t.Error(names)
+	}
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strings"
+)
+
+/** SIPParser for Join header.
+ */
+type JoinParser struct {
+	ParametersParser
+}
+
+/**
+ * Creates a new instance of JoinParser
+ * @param join the header to parse
+ */
+func NewJoinParser(join string) *JoinParser {
+	this := &JoinParser{}
+	this.ParametersParser.super(join)
+	return this
+}
+
+/** Constructor
+ * @param lexer the lexer to use to parse the header
+ */
+func NewJoinParserFromLexer(lexer core.Lexer) *JoinParser {
+	this := &JoinParser{}
+	this.ParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message
+ * @return Header (Join object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *JoinParser) Parse() (sh header.Header, ParseException error) {
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_JOIN)
+	lexer.SPorHT()
+
+	join := header.NewJoin()
+	if ParseException = join.SetCallId(strings.TrimSpace(lexer.ByteStringNoSemicolon())); ParseException != nil {
+		return nil, ParseException
+	}
+	if ParseException = this.ParametersParser.Parse(join); ParseException != nil {
+		return nil, ParseException
+	}
+	if join.GetToTag() == "" || join.GetFromTag() == "" {
+		return nil, this.CreateParseError("to-tag and from-tag", "Join requires the to-tag and from-tag parameters")
+	}
+
+	lexer.SPorHT()
+	lexer.Match('\n')
+
+	return join, nil
+}
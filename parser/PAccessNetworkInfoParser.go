@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Access-Network-Info header.
+ */
+type PAccessNetworkInfoParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param pAccessNetworkInfo message to parse to set
+ */
+func NewPAccessNetworkInfoParser(pAccessNetworkInfo string) *PAccessNetworkInfoParser {
+	this := &PAccessNetworkInfoParser{}
+	this.GenericParamsParser.super(pAccessNetworkInfo)
+	return this
+}
+
+func NewPAccessNetworkInfoParserFromLexer(lexer core.Lexer) *PAccessNetworkInfoParser {
+	this := &PAccessNetworkInfoParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the P-Access-Network-Info List Object
+ * @return SIPHeader the P-Access-Network-Info List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PAccessNetworkInfoParser) Parse() (sh header.Header, ParseException error) {
+	pAccessNetworkInfoList := header.NewPAccessNetworkInfoList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_ACCESS_NETWORK_INFO)
+	for {
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return nil, ParseException
+		}
+		pAccessNetworkInfo := header.NewPAccessNetworkInfo()
+		pAccessNetworkInfo.SetAccessType(lexer.GetNextToken().GetTokenValue())
+		if ParseException = this.genericParams(pAccessNetworkInfo); ParseException != nil {
+			return nil, ParseException
+		}
+		pAccessNetworkInfoList.PushBack(pAccessNetworkInfo)
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pAccessNetworkInfoList, nil
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Asserted-Identity header, a list of name-addr or addr-spec.
+ */
+type PAssertedIdentityParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param pAssertedIdentity message to parse to set
+ */
+func NewPAssertedIdentityParser(pAssertedIdentity string) *PAssertedIdentityParser {
+	this := &PAssertedIdentityParser{}
+	this.AddressParametersParser.super(pAssertedIdentity)
+	return this
+}
+
+func NewPAssertedIdentityParserFromLexer(lexer core.Lexer) *PAssertedIdentityParser {
+	this := &PAssertedIdentityParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the PAssertedIdentity List Object
+ * @return SIPHeader the PAssertedIdentity List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PAssertedIdentityParser) Parse() (sh header.Header, ParseException error) {
+	pAssertedIdentityList := header.NewPAssertedIdentityList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_ASSERTED_IDENTITY)
+	for {
+		pAssertedIdentity := header.NewPAssertedIdentity()
+		if ParseException = this.AddressParametersParser.Parse(pAssertedIdentity); ParseException != nil {
+			return nil, ParseException
+		}
+		pAssertedIdentityList.PushBack(pAssertedIdentity)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pAssertedIdentityList, nil
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Associated-URI header, a list of name-addr or addr-spec.
+ */
+type PAssociatedURIParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param pAssociatedURI message to parse to set
+ */
+func NewPAssociatedURIParser(pAssociatedURI string) *PAssociatedURIParser {
+	this := &PAssociatedURIParser{}
+	this.AddressParametersParser.super(pAssociatedURI)
+	return this
+}
+
+func NewPAssociatedURIParserFromLexer(lexer core.Lexer) *PAssociatedURIParser {
+	this := &PAssociatedURIParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the PAssociatedURI List Object
+ * @return SIPHeader the PAssociatedURI List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PAssociatedURIParser) Parse() (sh header.Header, ParseException error) {
+	pAssociatedURIList := header.NewPAssociatedURIList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_ASSOCIATED_URI)
+	for {
+		pAssociatedURI := header.NewPAssociatedURI()
+		if ParseException = this.AddressParametersParser.Parse(pAssociatedURI); ParseException != nil {
+			return nil, ParseException
+		}
+		pAssociatedURIList.PushBack(pAssociatedURI)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pAssociatedURIList, nil
+}
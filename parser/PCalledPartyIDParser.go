@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Called-Party-ID header, a name-addr with parameters.
+ */
+type PCalledPartyIDParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param pCalledPartyID message to parse to set
+ */
+func NewPCalledPartyIDParser(pCalledPartyID string) *PCalledPartyIDParser {
+	this := &PCalledPartyIDParser{}
+	this.AddressParametersParser.super(pCalledPartyID)
+	return this
+}
+
+func NewPCalledPartyIDParserFromLexer(lexer core.Lexer) *PCalledPartyIDParser {
+	this := &PCalledPartyIDParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the P-Called-Party-ID Object
+ * @return SIPHeader the P-Called-Party-ID object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PCalledPartyIDParser) Parse() (sh header.Header, ParseException error) {
+	pCalledPartyID := header.NewPCalledPartyID()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_CALLED_PARTY_ID)
+	if ParseException = this.AddressParametersParser.Parse(pCalledPartyID); ParseException != nil {
+		return nil, ParseException
+	}
+	lexer.SPorHT()
+	if ch, _ := lexer.LookAheadK(0); ch != '\n' {
+		return nil, this.CreateParseException("unexpected char")
+	}
+
+	return pCalledPartyID, nil
+}
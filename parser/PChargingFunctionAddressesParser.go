@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Charging-Function-Addresses header.
+ */
+type PChargingFunctionAddressesParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param pChargingFunctionAddresses message to parse to set
+ */
+func NewPChargingFunctionAddressesParser(pChargingFunctionAddresses string) *PChargingFunctionAddressesParser {
+	this := &PChargingFunctionAddressesParser{}
+	this.GenericParamsParser.super(pChargingFunctionAddresses)
+	return this
+}
+
+func NewPChargingFunctionAddressesParserFromLexer(lexer core.Lexer) *PChargingFunctionAddressesParser {
+	this := &PChargingFunctionAddressesParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the P-Charging-Function-Addresses Object
+ * @return SIPHeader the P-Charging-Function-Addresses object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PChargingFunctionAddressesParser) Parse() (sh header.Header, ParseException error) {
+	pChargingFunctionAddresses := header.NewPChargingFunctionAddresses()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_CHARGING_FUNCTION_ADDRESSES)
+	repeatable := []string{header.ParameterNames_CCF, header.ParameterNames_ECF}
+	if ParseException = this.genericParam(pChargingFunctionAddresses, repeatable...); ParseException != nil {
+		return nil, ParseException
+	}
+	if ParseException = this.genericParams(pChargingFunctionAddresses, repeatable...); ParseException != nil {
+		return nil, ParseException
+	}
+	if ch, _ := lexer.LookAheadK(0); ch != '\n' {
+		return nil, this.CreateParseException("unexpected char")
+	}
+
+	return pChargingFunctionAddresses, nil
+}
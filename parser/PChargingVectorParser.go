@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Charging-Vector header.
+ */
+type PChargingVectorParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param pChargingVector message to parse to set
+ */
+func NewPChargingVectorParser(pChargingVector string) *PChargingVectorParser {
+	this := &PChargingVectorParser{}
+	this.GenericParamsParser.super(pChargingVector)
+	return this
+}
+
+func NewPChargingVectorParserFromLexer(lexer core.Lexer) *PChargingVectorParser {
+	this := &PChargingVectorParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the P-Charging-Vector Object
+ * @return SIPHeader the P-Charging-Vector object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PChargingVectorParser) Parse() (sh header.Header, ParseException error) {
+	pChargingVector := header.NewPChargingVector()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_CHARGING_VECTOR)
+	if ParseException = this.genericParam(pChargingVector); ParseException != nil {
+		return nil, ParseException
+	}
+	if ParseException = this.genericParams(pChargingVector); ParseException != nil {
+		return nil, ParseException
+	}
+	if ch, _ := lexer.LookAheadK(0); ch != '\n' {
+		return nil, this.CreateParseException("unexpected char")
+	}
+	if pChargingVector.GetICID() == "" {
+		return nil, this.CreateParseException("missing icid-value")
+	}
+
+	return pChargingVector, nil
+}
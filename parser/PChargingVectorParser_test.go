@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func TestPAccessNetworkInfoParser(t *testing.T) {
+	var tvi = []string{
+		"P-Access-Network-Info: 3GPP-UTRAN-TDD; utran-cell-id-3gpp=23456789ABCDE\n",
+		"P-Access-Network-Info: IEEE-802.11;i-wlan-node-id=ffffffeeeeee , ADSL;dsl-location=\"Ecully\";network-provided\n",
+	}
+	var tvo = []string{
+		"P-Access-Network-Info: 3GPP-UTRAN-TDD;utran-cell-id-3gpp=23456789ABCDE\n",
+		"P-Access-Network-Info: IEEE-802.11;i-wlan-node-id=ffffffeeeeee,ADSL;dsl-location=\"Ecully\";network-provided\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPAccessNetworkInfoParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
+
+func TestPChargingVectorParser(t *testing.T) {
+	var tvi = []string{
+		"P-Charging-Vector: icid-value=1234bc9876e; icid-generated-at=192.0.6.8; orig-ioi=home1.net\n",
+		"P-Charging-Vector: icid-value=\"AyretyU0dm+6O2IrT5tAFrbHLso=023551024\";icid-generated-at=[2001:db8::1];term-ioi=home2.net\n",
+	}
+	var tvo = []string{
+		"P-Charging-Vector: icid-value=1234bc9876e;icid-generated-at=192.0.6.8;orig-ioi=home1.net\n",
+		"P-Charging-Vector: icid-value=\"AyretyU0dm+6O2IrT5tAFrbHLso=023551024\";icid-generated-at=[2001:db8::1];term-ioi=home2.net\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPChargingVectorParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	sh, err := NewPChargingVectorParser("P-Charging-Vector: orig-ioi=home1.net;icid-value=1234bc9876e\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcv := sh.(*header.PChargingVector); pcv.GetICID() != "1234bc9876e" || pcv.GetOrigIOI() != "home1.net" {
+		t.Error(pcv.String())
+	}
+	if _, err := NewPChargingVectorParser("P-Charging-Vector: orig-ioi=home1.net\n").Parse(); err == nil {
+		t.Error("parsed a vector without icid-value")
+	}
+}
+
+func TestPChargingFunctionAddressesParser(t *testing.T) {
+	shp := NewPChargingFunctionAddressesParser("P-Charging-Function-Addresses: ccf=192.1.1.1; ccf=192.1.1.2; ecf=192.1.1.3; ecf=[2001:db8::2]\n")
+	testHeaderParser(t, shp, "P-Charging-Function-Addresses: ccf=192.1.1.1;ccf=192.1.1.2;ecf=192.1.1.3;ecf=[2001:db8::2]\n")
+
+	sh, err := NewPChargingFunctionAddressesParser("P-Charging-Function-Addresses: ccf=192.1.1.1;ecf=192.1.1.3;ccf=192.1.1.2\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcfa := sh.(*header.PChargingFunctionAddresses)
+	if ccfs := pcfa.GetCCFs(); len(ccfs) != 2 || ccfs[1] != "192.1.1.2" {
+		t.Error(ccfs)
+	}
+	if ecfs := pcfa.GetECFs(); len(ecfs) != 1 || ecfs[0] != "192.1.1.3" {
+		t.Error(ecfs)
+	}
+}
+
+func TestPVisitedNetworkIDParser(t *testing.T) {
+	var tvi = []string{
+		"P-Visited-Network-ID: other.net, \"Visited network number 1\"\n",
+		"P-Visited-Network-ID: \"Net \\\"A\\\"\";rank=1\n",
+	}
+	var tvo = []string{
+		"P-Visited-Network-ID: other.net,\"Visited network number 1\"\n",
+		"P-Visited-Network-ID: \"Net \\\"A\\\"\";rank=1\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPVisitedNetworkIDParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	sh, err := NewPVisitedNetworkIDParser("P-Visited-Network-ID: \"Net \\\"A\\\"\"\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := sh.(*header.PVisitedNetworkIDList).Front().Value.(*header.PVisitedNetworkID).GetVisitedNetworkID(); id != "Net \"A\"" {
+		t.Error(id)
+	}
+}
+
+func TestPAssociatedURIParser(t *testing.T) {
+	var tvi = []string{
+		"P-Associated-URI: <sip:user1_public2@home1.net>, <sip:+1-212-555-1234@home1.net;user=phone>\n",
+		"P-Associated-URI: \"User 1\" <tel:+1-212-555-1234>\n",
+	}
+	var tvo = []string{
+		"P-Associated-URI: <sip:user1_public2@home1.net>,<sip:+1-212-555-1234@home1.net;user=phone>\n",
+		"P-Associated-URI: \"User 1\" <tel:+1-212-555-1234>\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPAssociatedURIParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
+
+func TestPCalledPartyIDParser(t *testing.T) {
+	shp := NewPCalledPartyIDParser("P-Called-Party-ID: sip:user1-business@example.com\n")
+	testHeaderParser(t, shp, "P-Called-Party-ID: <sip:user1-business@example.com>\n")
+	if _, err := NewPCalledPartyIDParser("P-Called-Party-ID: <sip:a@example.com>, <sip:b@example.com>\n").Parse(); err == nil {
+		t.Error("parsed two identities")
+	}
+}
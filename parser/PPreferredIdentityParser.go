@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Preferred-Identity header, a list of name-addr or addr-spec.
+ */
+type PPreferredIdentityParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param pPreferredIdentity message to parse to set
+ */
+func NewPPreferredIdentityParser(pPreferredIdentity string) *PPreferredIdentityParser {
+	this := &PPreferredIdentityParser{}
+	this.AddressParametersParser.super(pPreferredIdentity)
+	return this
+}
+
+func NewPPreferredIdentityParserFromLexer(lexer core.Lexer) *PPreferredIdentityParser {
+	this := &PPreferredIdentityParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the PPreferredIdentity List Object
+ * @return SIPHeader the PPreferredIdentity List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PPreferredIdentityParser) Parse() (sh header.Header, ParseException error) {
+	pPreferredIdentityList := header.NewPPreferredIdentityList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_PREFERRED_IDENTITY)
+	for {
+		pPreferredIdentity := header.NewPPreferredIdentity()
+		if ParseException = this.AddressParametersParser.Parse(pPreferredIdentity); ParseException != nil {
+			return nil, ParseException
+		}
+		pPreferredIdentityList.PushBack(pPreferredIdentity)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pPreferredIdentityList, nil
+}
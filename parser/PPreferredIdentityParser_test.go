@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestPPreferredIdentityParser(t *testing.T) {
+	var tvi = []string{
+		"P-Preferred-Identity: \"Cullen Jennings\" <sip:fluffy@cisco.com>\n",
+		"P-Preferred-Identity: <sip:fluffy@cisco.com>, <tel:+14085264000>\n",
+	}
+	var tvo = []string{
+		"P-Preferred-Identity: \"Cullen Jennings\" <sip:fluffy@cisco.com>\n",
+		"P-Preferred-Identity: <sip:fluffy@cisco.com>,<tel:+14085264000>\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPPreferredIdentityParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bytes"
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the P-Visited-Network-ID header.
+ */
+type PVisitedNetworkIDParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param pVisitedNetworkID message to parse to set
+ */
+func NewPVisitedNetworkIDParser(pVisitedNetworkID string) *PVisitedNetworkIDParser {
+	this := &PVisitedNetworkIDParser{}
+	this.GenericParamsParser.super(pVisitedNetworkID)
+	return this
+}
+
+func NewPVisitedNetworkIDParserFromLexer(lexer core.Lexer) *PVisitedNetworkIDParser {
+	this := &PVisitedNetworkIDParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the P-Visited-Network-ID List Object
+ * @return SIPHeader the P-Visited-Network-ID List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PVisitedNetworkIDParser) Parse() (sh header.Header, ParseException error) {
+	pVisitedNetworkIDList := header.NewPVisitedNetworkIDList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_P_VISITED_NETWORK_ID)
+	for {
+		lexer.SPorHT()
+		var networkId string
+		if ch, _ = lexer.LookAheadK(0); ch == '"' {
+			quoted, err := lexer.QuotedString()
+			if err != nil {
+				return nil, this.CreateParseException("unterminated quoted string")
+			}
+			networkId = unquote(quoted)
+		} else {
+			if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+				return nil, ParseException
+			}
+			networkId = lexer.GetNextToken().GetTokenValue()
+		}
+		pVisitedNetworkID := header.NewPVisitedNetworkID()
+		if ParseException = pVisitedNetworkID.SetVisitedNetworkID(networkId); ParseException != nil {
+			return nil, this.CreateParseException("empty network identifier")
+		}
+		if ParseException = this.genericParams(pVisitedNetworkID); ParseException != nil {
+			return nil, ParseException
+		}
+		pVisitedNetworkIDList.PushBack(pVisitedNetworkID)
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pVisitedNetworkIDList, nil
+}
+
+/** unquote removes the backslashes of the quoted-pairs the lexer keeps in
+ * a quoted string.
+ */
+func unquote(quoted string) string {
+	var s bytes.Buffer
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] == '\\' && i+1 < len(quoted) {
+			i++
+		}
+		s.WriteByte(quoted[i])
+	}
+	return s.String()
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"sip/core"
+	"strings"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	var tvi = []struct {
+		line     string
+		name     string
+		offset   int
+		expected string
+	}{
+		{"Via: SIP/2.0 pc33.atlanta.com\n", "Via", 29, "host"},
+		{"CSeq: abc INVITE\n", "CSeq", 6, "DIGIT"},
+		{"Max-Forwards: x70\n", "Max-Forwards", 14, "DIGIT"},
+		{"Replaces: 12345@example.com;to-tag=1\n", "Replaces", 36, "to-tag and from-tag"},
+		{"l: \n", "l", 3, "DIGIT"},
+	}
+
+	for _, tv := range tvi {
+		p, err := CreateParser(tv.line)
+		if err == nil {
+			_, err = p.Parse()
+		}
+		pe, ok := err.(*core.ParseError)
+		if !ok {
+			t.Errorf("%q: %v is not a ParseError", tv.line, err)
+			continue
+		}
+		if pe.HeaderName != tv.name || pe.Line != tv.line || pe.Offset != tv.offset || pe.Expected != tv.expected {
+			t.Errorf("%q: %#v", tv.line, pe)
+		}
+		if !strings.HasPrefix(pe.Error(), "ParseException: "+tv.name) {
+			t.Error(pe.Error())
+		}
+	}
+}
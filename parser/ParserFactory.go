@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"errors"
 	"sip/core"
+	"sip/header"
 	"strings"
 )
 
@@ -10,16 +10,33 @@ import (
 * returns a header parser for the given name.
  */
 
-/** create a parser for a header. This is the parser factory.
+/** create a parser for a header. This is the parser factory. The parsers
+ * registered with RegisterHeaderParser come first, headers nobody parses
+ * are returned as an Extension.
  */
 func CreateParser(line string) (parser Parser, ParseException error) {
 	var lexer SIPLexer
 	headerName := strings.TrimSpace(strings.ToLower(lexer.GetHeaderName(line)))
 	headerValue := lexer.GetHeaderValue(line)
 	if headerName == "" || headerValue == "" {
-		return nil, errors.New("ParseException: The header name or value is null")
+		return nil, core.NewParseError(line, 0, "", "The header name or value is null")
 	}
 
+	if factory, ok := lookupHeaderParser(headerName); ok {
+		return &parseErrorParser{Parser: factory(line), line: line}, nil
+	}
+	if parser = builtinParser(headerName, line); parser == nil {
+		// Just generate a generic SIPHeader. We define
+		// parsers only for the headers of builtinParser.
+		parser = NewHeaderParser(line)
+	}
+	return &parseErrorParser{Parser: parser, line: line}, nil
+}
+
+/** Returns the parser of the stack for the lower case header name or
+ * compact form, nil for a header the stack doesn't parse.
+ */
+func builtinParser(headerName, line string) (parser Parser) {
 	switch headerName {
 	case strings.ToLower(core.SIPHeaderNames_REPLY_TO):
 		parser = NewReplyToParser(line)
@@ -147,11 +164,111 @@ func CreateParser(line string) (parser Parser, ParseException error) {
 		parser = NewAcceptParser(line)
 	case strings.ToLower(core.SIPHeaderNames_REFER_TO):
 		parser = NewReferToParser(line)
-	default:
-		// Just generate a generic SIPHeader. We define
-		// parsers only for the above.
-		parser = NewHeaderParser(line)
+	case strings.ToLower(core.SIPHeaderNames_REFERRED_BY):
+		parser = NewReferredByParser(line)
+	case "r":
+		parser = NewReferToParser(line)
+	case "b":
+		parser = NewReferredByParser(line)
+	case strings.ToLower(core.SIPHeaderNames_REPLACES):
+		parser = NewReplacesParser(line)
+	case strings.ToLower(core.SIPHeaderNames_JOIN):
+		parser = NewJoinParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_ASSERTED_IDENTITY):
+		parser = NewPAssertedIdentityParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_PREFERRED_IDENTITY):
+		parser = NewPPreferredIdentityParser(line)
+	case strings.ToLower(core.SIPHeaderNames_PRIVACY):
+		parser = NewPrivacyParser(line)
+	case strings.ToLower(core.SIPHeaderNames_PATH):
+		parser = NewPathParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SERVICE_ROUTE):
+		parser = NewServiceRouteParser(line)
+	case strings.ToLower(core.SIPHeaderNames_HISTORY_INFO):
+		parser = NewHistoryInfoParser(line)
+	case strings.ToLower(core.SIPHeaderNames_DIVERSION):
+		parser = NewDiversionParser(line)
+	case strings.ToLower(core.SIPHeaderNames_IDENTITY):
+		parser = NewIdentityParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SECURITY_CLIENT):
+		parser = NewSecurityClientParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SECURITY_SERVER):
+		parser = NewSecurityServerParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SECURITY_VERIFY):
+		parser = NewSecurityVerifyParser(line)
+	case strings.ToLower(core.SIPHeaderNames_ACCEPT_CONTACT):
+		parser = NewAcceptContactParser(line)
+	case "a":
+		parser = NewAcceptContactParser(line)
+	case strings.ToLower(core.SIPHeaderNames_REJECT_CONTACT):
+		parser = NewRejectContactParser(line)
+	case "j":
+		parser = NewRejectContactParser(line)
+	case strings.ToLower(core.SIPHeaderNames_REQUEST_DISPOSITION):
+		parser = NewRequestDispositionParser(line)
+	case "d":
+		parser = NewRequestDispositionParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SIP_ETAG):
+		parser = NewSIPETagParser(line)
+	case strings.ToLower(core.SIPHeaderNames_SIP_IF_MATCH):
+		parser = NewSIPIfMatchParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_ACCESS_NETWORK_INFO):
+		parser = NewPAccessNetworkInfoParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_CHARGING_VECTOR):
+		parser = NewPChargingVectorParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_CHARGING_FUNCTION_ADDRESSES):
+		parser = NewPChargingFunctionAddressesParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_VISITED_NETWORK_ID):
+		parser = NewPVisitedNetworkIDParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_ASSOCIATED_URI):
+		parser = NewPAssociatedURIParser(line)
+	case strings.ToLower(core.SIPHeaderNames_P_CALLED_PARTY_ID):
+		parser = NewPCalledPartyIDParser(line)
+	case strings.ToLower(core.SIPHeaderNames_INFO_PACKAGE):
+		parser = NewInfoPackageParser(line)
+	case strings.ToLower(core.SIPHeaderNames_RECV_INFO):
+		parser = NewRecvInfoParser(line)
 	}
+	return parser
+}
 
-	return parser, nil
+/** Tells whether the stack parses the header name or compact form itself.
+ */
+func isBuiltinHeader(name string) bool {
+	return builtinParser(strings.ToLower(name), "") != nil
+}
+
+/** Makes sure that the parser returns a core.ParseError pointing into the
+ * header line, whatever the sub-parser or lexer that failed.
+ */
+type parseErrorParser struct {
+	Parser
+	line string
+}
+
+func (this *parseErrorParser) Parse() (sh header.Header, ParseException error) {
+	if sh, ParseException = this.Parser.Parse(); ParseException == nil {
+		return sh, nil
+	}
+	pe, ok := ParseException.(*core.ParseError)
+	if !ok {
+		if p, isCore := this.Parser.(interface {
+			ToParseError(error) *core.ParseError
+		}); isCore {
+			pe = p.ToParseError(ParseException)
+		} else {
+			pe = core.NewParseError(this.line, 0, "", strings.TrimPrefix(ParseException.Error(), "ParseException: "))
+		}
+	}
+	if pe.Line != this.line {
+		//a sub-parser worked on a part of the line
+		if i := strings.Index(this.line, pe.Line); i >= 0 {
+			pe.Offset += i
+		}
+		pe.Line = this.line
+	}
+	if pe.HeaderName == "" {
+		pe.HeaderName = strings.TrimSpace(new(SIPLexer).GetHeaderName(this.line))
+	}
+	return nil, pe
 }
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Path header, a list of name-addr with parameters.
+ */
+type PathParser struct {
+	AddressParametersParser
+}
+
+/** Constructor
+ * @param path message to parse to set
+ */
+func NewPathParser(path string) *PathParser {
+	this := &PathParser{}
+	this.AddressParametersParser.super(path)
+	return this
+}
+
+func NewPathParserFromLexer(lexer core.Lexer) *PathParser {
+	this := &PathParser{}
+	this.AddressParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Path List Object
+ * @return SIPHeader the Path List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *PathParser) Parse() (sh header.Header, ParseException error) {
+	pathList := header.NewPathList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_PATH)
+	for {
+		path := header.NewPath()
+		if ParseException = this.AddressParametersParser.Parse(path); ParseException != nil {
+			return nil, ParseException
+		}
+		pathList.PushBack(path)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+			lexer.SPorHT()
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return pathList, nil
+}
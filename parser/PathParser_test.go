@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestPathParser(t *testing.T) {
+	var tvi = []string{
+		"Path: <sip:P1.EXAMPLEVISITED.COM;lr>\n",
+		"Path: <sip:P2.EXAMPLEHOME.COM;lr>, <sip:P1.EXAMPLEVISITED.COM;lr>\n",
+		"Path: <sip:pcscf.visited.net;lr>;ob\n",
+	}
+	var tvo = []string{
+		"Path: <sip:P1.EXAMPLEVISITED.COM;lr>\n",
+		"Path: <sip:P2.EXAMPLEHOME.COM;lr>,<sip:P1.EXAMPLEVISITED.COM;lr>\n",
+		"Path: <sip:pcscf.visited.net;lr>;ob\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPathParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Privacy header, priv-values separated by semicolons.
+ */
+type PrivacyParser struct {
+	HeaderParser
+}
+
+/** Creates a new instance of PrivacyParser
+ * @param privacy the header to parse
+ */
+func NewPrivacyParser(privacy string) *PrivacyParser {
+	this := &PrivacyParser{}
+	this.HeaderParser.super(privacy)
+	return this
+}
+
+/** Constructor
+ * @param lexer the lexer to use to parse the header
+ */
+func NewPrivacyParserFromLexer(lexer core.Lexer) *PrivacyParser {
+	this := &PrivacyParser{}
+	this.HeaderParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the Privacy String header
+ * @return Header (PrivacyList object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *PrivacyParser) Parse() (sh header.Header, ParseException error) {
+	privacyList := header.NewPrivacyList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_PRIVACY)
+
+	for {
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return nil, ParseException
+		}
+		privacy := header.NewPrivacyFromString(lexer.GetNextToken().GetTokenValue())
+		privacyList.PushBack(privacy)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ';' {
+			lexer.Match(';')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return privacyList, nil
+}
@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestPrivacyParser(t *testing.T) {
+	var tvi = []string{
+		"Privacy: id\n",
+		"Privacy: id;user\n",
+		"Privacy: header ; session ; critical\n",
+	}
+	var tvo = []string{
+		"Privacy: id\n",
+		"Privacy: id;user\n",
+		"Privacy: header;session;critical\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewPrivacyParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Recv-Info header.
+ */
+type RecvInfoParser struct {
+	GenericParamsParser
+}
+
+/** Constructor
+ * @param recvInfo message to parse to set
+ */
+func NewRecvInfoParser(recvInfo string) *RecvInfoParser {
+	this := &RecvInfoParser{}
+	this.GenericParamsParser.super(recvInfo)
+	return this
+}
+
+func NewRecvInfoParserFromLexer(lexer core.Lexer) *RecvInfoParser {
+	this := &RecvInfoParser{}
+	this.GenericParamsParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Recv-Info List Object
+ * @return SIPHeader the Recv-Info List object, empty when the user agent
+ * receives no Info Package
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *RecvInfoParser) Parse() (sh header.Header, ParseException error) {
+	recvInfoList := header.NewRecvInfoList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_RECV_INFO)
+	lexer.SPorHT()
+	if ch, _ = lexer.LookAheadK(0); ch == '\n' {
+		return recvInfoList, nil
+	}
+	for {
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return nil, ParseException
+		}
+		recvInfo := header.NewRecvInfo()
+		recvInfo.SetPackageName(lexer.GetNextToken().GetTokenValue())
+		if ParseException = this.genericParams(recvInfo); ParseException != nil {
+			return nil, ParseException
+		}
+		recvInfoList.PushBack(recvInfo)
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return recvInfoList, nil
+}
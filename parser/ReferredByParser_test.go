@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestReferredByParser(t *testing.T) {
+	var tvi = []string{
+		"Referred-By: <sip:referrer@referrer.example>;cid=\"20398823.2UWQFN309shb3@referrer.example\"\n",
+		"Referred-By: Alice <sip:alice@atlanta.example.com>\n",
+		"b: sip:alice@atlanta.example.com\n",
+	}
+	var tvo = []string{
+		"Referred-By: <sip:referrer@referrer.example>;cid=\"20398823.2UWQFN309shb3@referrer.example\"\n",
+		"Referred-By: \"Alice\" <sip:alice@atlanta.example.com>\n",
+		"Referred-By: <sip:alice@atlanta.example.com>\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewReferredByParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+}
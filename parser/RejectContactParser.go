@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Reject-Contact header, a list of "*" with feature parameters.
+ */
+type RejectContactParser struct {
+	ParametersParser
+}
+
+/** Constructor
+ * @param rejectContact message to parse to set
+ */
+func NewRejectContactParser(rejectContact string) *RejectContactParser {
+	this := &RejectContactParser{}
+	this.ParametersParser.super(rejectContact)
+	return this
+}
+
+func NewRejectContactParserFromLexer(lexer core.Lexer) *RejectContactParser {
+	this := &RejectContactParser{}
+	this.ParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Reject-Contact List Object
+ * @return SIPHeader the Reject-Contact List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *RejectContactParser) Parse() (sh header.Header, ParseException error) {
+	rejectContactList := header.NewRejectContactList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_REJECT_CONTACT)
+	for {
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch != '*' {
+			return nil, this.CreateParseException("expected '*'")
+		}
+		lexer.ConsumeK(1)
+		rejectContact := header.NewRejectContact()
+		if ParseException = this.ParametersParser.Parse(rejectContact); ParseException != nil {
+			return nil, ParseException
+		}
+		rejectContactList.PushBack(rejectContact)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return rejectContactList, nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strings"
+)
+
+/** SIPParser for Replaces header.
+ */
+type ReplacesParser struct {
+	ParametersParser
+}
+
+/**
+ * Creates a new instance of ReplacesParser
+ * @param replaces the header to parse
+ */
+func NewReplacesParser(replaces string) *ReplacesParser {
+	this := &ReplacesParser{}
+	this.ParametersParser.super(replaces)
+	return this
+}
+
+/** Constructor
+ * @param lexer the lexer to use to parse the header
+ */
+func NewReplacesParserFromLexer(lexer core.Lexer) *ReplacesParser {
+	this := &ReplacesParser{}
+	this.ParametersParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message
+ * @return Header (Replaces object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *ReplacesParser) Parse() (sh header.Header, ParseException error) {
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_REPLACES)
+	lexer.SPorHT()
+
+	replaces := header.NewReplaces()
+	if ParseException = replaces.SetCallId(strings.TrimSpace(lexer.ByteStringNoSemicolon())); ParseException != nil {
+		return nil, ParseException
+	}
+	if ParseException = this.ParametersParser.Parse(replaces); ParseException != nil {
+		return nil, ParseException
+	}
+	if replaces.GetToTag() == "" || replaces.GetFromTag() == "" {
+		return nil, this.CreateParseError("to-tag and from-tag", "Replaces requires the to-tag and from-tag parameters")
+	}
+	//early-only is a flag, not a parameter with an empty value
+	if replaces.IsEarlyOnly() {
+		replaces.SetEarlyOnly(true)
+	}
+
+	lexer.SPorHT()
+	lexer.Match('\n')
+
+	return replaces, nil
+}
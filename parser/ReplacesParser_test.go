@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestReplacesParser(t *testing.T) {
+	var tvi = []string{
+		"Replaces: 425928@bobster.example.org;to-tag=7743;from-tag=6472\n",
+		"Replaces: 98732@sip.example.com ;from-tag=r33th4x0r ;to-tag=ff87ff\n",
+		"Replaces: 12adf2f34456gs5;to-tag=12345;from-tag=54321;early-only\n",
+	}
+	var tvo = []string{
+		"Replaces: 425928@bobster.example.org;to-tag=7743;from-tag=6472\n",
+		"Replaces: 98732@sip.example.com;from-tag=r33th4x0r;to-tag=ff87ff\n",
+		"Replaces: 12adf2f34456gs5;to-tag=12345;from-tag=54321;early-only\n",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		shp := NewReplacesParser(tvi[i])
+		testHeaderParser(t, shp, tvo[i])
+	}
+
+	if _, err := NewReplacesParser("Replaces: 425928@bobster.example.org;to-tag=7743\n").Parse(); err == nil {
+		t.Log("a Replaces without from-tag was accepted")
+		t.Fail()
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strings"
+)
+
+/** the directives of RFC 3841 §10.
+ */
+var requestDispositionDirectives = []string{
+	"proxy", "redirect", "cancel", "no-cancel", "fork", "no-fork",
+	"recurse", "no-recurse", "parallel", "sequential", "queue", "no-queue",
+}
+
+/** Parser for the Request-Disposition header.
+ */
+type RequestDispositionParser struct {
+	HeaderParser
+}
+
+/** Constructor
+ * @param requestDisposition message to parse to set
+ */
+func NewRequestDispositionParser(requestDisposition string) *RequestDispositionParser {
+	this := &RequestDispositionParser{}
+	this.HeaderParser.super(requestDisposition)
+	return this
+}
+
+func NewRequestDispositionParserFromLexer(lexer core.Lexer) *RequestDispositionParser {
+	this := &RequestDispositionParser{}
+	this.HeaderParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the Request-Disposition String header
+ * @return Header (RequestDispositionList object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *RequestDispositionParser) Parse() (sh header.Header, ParseException error) {
+	requestDispositionList := header.NewRequestDispositionList()
+
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_REQUEST_DISPOSITION)
+
+	for {
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return nil, ParseException
+		}
+		directive := strings.ToLower(lexer.GetNextToken().GetTokenValue())
+		known := false
+		for _, d := range requestDispositionDirectives {
+			known = known || d == directive
+		}
+		if !known {
+			return nil, this.CreateParseException("unknown directive " + directive)
+		}
+		requestDisposition := header.NewRequestDisposition()
+		requestDisposition.SetDirective(directive)
+		requestDispositionList.PushBack(requestDisposition)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return nil, this.CreateParseException("unexpected char")
+		}
+	}
+
+	return requestDispositionList, nil
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the SIP-ETag header.
+ */
+type SIPETagParser struct {
+	HeaderParser
+}
+
+/** Constructor
+ * @param etag message to parse to set
+ */
+func NewSIPETagParser(etag string) *SIPETagParser {
+	this := &SIPETagParser{}
+	this.HeaderParser.super(etag)
+	return this
+}
+
+func NewSIPETagParserFromLexer(lexer core.Lexer) *SIPETagParser {
+	this := &SIPETagParser{}
+	this.HeaderParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the SIP-ETag String header
+ * @return Header (SIPETag object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *SIPETagParser) Parse() (sh header.Header, ParseException error) {
+	etag := header.NewSIPETag()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_SIP_ETAG)
+
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+		return nil, ParseException
+	}
+	etag.SetETag(lexer.GetNextToken().GetTokenValue())
+
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match('\n'); ParseException != nil {
+		return nil, ParseException
+	}
+
+	return etag, nil
+}
@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestSIPETagParser(t *testing.T) {
+	shp := NewSIPETagParser("SIP-ETag: dx200xyz\n")
+	testHeaderParser(t, shp, "SIP-ETag: dx200xyz\n")
+	if _, err := NewSIPETagParser("SIP-ETag: dx200xyz kwq123\n").Parse(); err == nil {
+		t.Error("parsed two entity-tags")
+	}
+}
+
+func TestSIPIfMatchParser(t *testing.T) {
+	shp := NewSIPIfMatchParser("SIP-If-Match:  kwj449x \n")
+	testHeaderParser(t, shp, "SIP-If-Match: kwj449x\n")
+	if _, err := NewSIPIfMatchParser("SIP-If-Match: \n").Parse(); err == nil {
+		t.Error("parsed a missing entity-tag")
+	}
+}
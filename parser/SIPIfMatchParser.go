@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the SIP-If-Match header.
+ */
+type SIPIfMatchParser struct {
+	HeaderParser
+}
+
+/** Constructor
+ * @param ifMatch message to parse to set
+ */
+func NewSIPIfMatchParser(ifMatch string) *SIPIfMatchParser {
+	this := &SIPIfMatchParser{}
+	this.HeaderParser.super(ifMatch)
+	return this
+}
+
+func NewSIPIfMatchParserFromLexer(lexer core.Lexer) *SIPIfMatchParser {
+	this := &SIPIfMatchParser{}
+	this.HeaderParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the SIP-If-Match String header
+ * @return Header (SIPIfMatch object)
+ * @throws SIPParseException if the message does not respect the spec.
+ */
+func (this *SIPIfMatchParser) Parse() (sh header.Header, ParseException error) {
+	ifMatch := header.NewSIPIfMatch()
+
+	lexer := this.GetLexer()
+	this.HeaderName(TokenTypes_SIP_IF_MATCH)
+
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+		return nil, ParseException
+	}
+	ifMatch.SetETag(lexer.GetNextToken().GetTokenValue())
+
+	lexer.SPorHT()
+	if _, ParseException = lexer.Match('\n'); ParseException != nil {
+		return nil, ParseException
+	}
+
+	return ifMatch, nil
+}
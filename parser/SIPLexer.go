@@ -86,6 +86,33 @@ func (this *SIPLexer) SelectLexer(lexerName string) {
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_FROM), TokenTypes_FROM)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_TO), TokenTypes_TO)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_REFER_TO), TokenTypes_REFER_TO)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_REFERRED_BY), TokenTypes_REFERRED_BY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_REPLACES), TokenTypes_REPLACES)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_JOIN), TokenTypes_JOIN)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_ASSERTED_IDENTITY), TokenTypes_P_ASSERTED_IDENTITY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_PREFERRED_IDENTITY), TokenTypes_P_PREFERRED_IDENTITY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_PRIVACY), TokenTypes_PRIVACY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_PATH), TokenTypes_PATH)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SERVICE_ROUTE), TokenTypes_SERVICE_ROUTE)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_HISTORY_INFO), TokenTypes_HISTORY_INFO)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_DIVERSION), TokenTypes_DIVERSION)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_IDENTITY), TokenTypes_IDENTITY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SECURITY_CLIENT), TokenTypes_SECURITY_CLIENT)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SECURITY_SERVER), TokenTypes_SECURITY_SERVER)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SECURITY_VERIFY), TokenTypes_SECURITY_VERIFY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_ACCEPT_CONTACT), TokenTypes_ACCEPT_CONTACT)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_REJECT_CONTACT), TokenTypes_REJECT_CONTACT)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_REQUEST_DISPOSITION), TokenTypes_REQUEST_DISPOSITION)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SIP_ETAG), TokenTypes_SIP_ETAG)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SIP_IF_MATCH), TokenTypes_SIP_IF_MATCH)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_ACCESS_NETWORK_INFO), TokenTypes_P_ACCESS_NETWORK_INFO)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_CHARGING_VECTOR), TokenTypes_P_CHARGING_VECTOR)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_CHARGING_FUNCTION_ADDRESSES), TokenTypes_P_CHARGING_FUNCTION_ADDRESSES)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_VISITED_NETWORK_ID), TokenTypes_P_VISITED_NETWORK_ID)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_ASSOCIATED_URI), TokenTypes_P_ASSOCIATED_URI)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_P_CALLED_PARTY_ID), TokenTypes_P_CALLED_PARTY_ID)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_INFO_PACKAGE), TokenTypes_INFO_PACKAGE)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_RECV_INFO), TokenTypes_RECV_INFO)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_VIA), TokenTypes_VIA)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_USER_AGENT), TokenTypes_USER_AGENT)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_SERVER), TokenTypes_SERVER)
@@ -132,6 +159,10 @@ func (this *SIPLexer) SelectLexer(lexerName string) {
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_T), TokenTypes_TO)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_V), TokenTypes_VIA)
 			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_R), TokenTypes_REFER_TO)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_B), TokenTypes_REFERRED_BY)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_A), TokenTypes_ACCEPT_CONTACT)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_J), TokenTypes_REJECT_CONTACT)
+			this.AddKeyword(strings.ToUpper(core.SIPHeaderNames_D), TokenTypes_REQUEST_DISPOSITION)
 		} else if lexerName == "status_lineLexer" {
 			this.AddKeyword(strings.ToUpper(core.SIPTransportNames_SIP), TokenTypes_SIP)
 		} else if lexerName == "request_lineLexer" {
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"errors"
 	"sip/core"
 )
 
@@ -76,6 +75,33 @@ const TokenTypes_EVENT = TokenTypes_START + 63
 const TokenTypes_AUTHENTICATION_INFO = TokenTypes_START + 64
 const TokenTypes_ALLOW_EVENTS = TokenTypes_START + 65
 const TokenTypes_REFER_TO = TokenTypes_START + 66
+const TokenTypes_REFERRED_BY = TokenTypes_START + 67
+const TokenTypes_REPLACES = TokenTypes_START + 68
+const TokenTypes_JOIN = TokenTypes_START + 69
+const TokenTypes_P_ASSERTED_IDENTITY = TokenTypes_START + 70
+const TokenTypes_P_PREFERRED_IDENTITY = TokenTypes_START + 71
+const TokenTypes_PRIVACY = TokenTypes_START + 72
+const TokenTypes_PATH = TokenTypes_START + 73
+const TokenTypes_SERVICE_ROUTE = TokenTypes_START + 74
+const TokenTypes_HISTORY_INFO = TokenTypes_START + 75
+const TokenTypes_DIVERSION = TokenTypes_START + 76
+const TokenTypes_IDENTITY = TokenTypes_START + 77
+const TokenTypes_SECURITY_CLIENT = TokenTypes_START + 78
+const TokenTypes_SECURITY_SERVER = TokenTypes_START + 79
+const TokenTypes_SECURITY_VERIFY = TokenTypes_START + 80
+const TokenTypes_ACCEPT_CONTACT = TokenTypes_START + 81
+const TokenTypes_REJECT_CONTACT = TokenTypes_START + 82
+const TokenTypes_REQUEST_DISPOSITION = TokenTypes_START + 83
+const TokenTypes_SIP_ETAG = TokenTypes_START + 84
+const TokenTypes_SIP_IF_MATCH = TokenTypes_START + 85
+const TokenTypes_P_ACCESS_NETWORK_INFO = TokenTypes_START + 86
+const TokenTypes_P_CHARGING_VECTOR = TokenTypes_START + 87
+const TokenTypes_P_CHARGING_FUNCTION_ADDRESSES = TokenTypes_START + 88
+const TokenTypes_P_VISITED_NETWORK_ID = TokenTypes_START + 89
+const TokenTypes_P_ASSOCIATED_URI = TokenTypes_START + 90
+const TokenTypes_P_CALLED_PARTY_ID = TokenTypes_START + 91
+const TokenTypes_INFO_PACKAGE = TokenTypes_START + 92
+const TokenTypes_RECV_INFO = TokenTypes_START + 93
 const TokenTypes_ALPHA = core.CORELEXER_ALPHA
 const TokenTypes_DIGIT = core.CORELEXER_DIGIT
 const TokenTypes_ID = core.CORELEXER_ID
@@ -136,7 +162,7 @@ func (this *SIPParser) super(buffer string) {
 }
 
 func (this *SIPParser) CreateParseException(exceptionString string) (ParseException error) {
-	return errors.New("ParseException: " + this.GetLexer().GetBuffer() + ":" + exceptionString) // + this.GetLexer().GetPtr());
+	return this.CreateParseError("", exceptionString)
 }
 
 func (this *SIPParser) SipVersion() (s string, ParseException error) {
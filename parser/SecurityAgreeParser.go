@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+	"strconv"
+)
+
+/** Parser for the sec-mechanism lists of the Security-Client,
+ * Security-Server and Security-Verify headers, RFC 3329 §2.2.
+ */
+type SecurityAgreeParser struct {
+	ParametersParser
+}
+
+func (this *SecurityAgreeParser) super(buffer string) {
+	this.ParametersParser.super(buffer)
+}
+
+func (this *SecurityAgreeParser) superFromLexer(lexer core.Lexer) {
+	this.ParametersParser.superFromLexer(lexer)
+}
+
+/** parse the mechanisms of the header named by tokenType into list, with
+ * newMechanism creating each of them.
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *SecurityAgreeParser) parse(tokenType int, list header.Lister, newMechanism func() header.SecurityAgreeHeader) (ParseException error) {
+	var ch byte
+	lexer := this.GetLexer()
+	this.HeaderName(tokenType)
+	for {
+		lexer.SPorHT()
+		if _, ParseException = lexer.Match(TokenTypes_ID); ParseException != nil {
+			return ParseException
+		}
+		mechanism := newMechanism()
+		mechanism.SetSecurityMechanism(lexer.GetNextToken().GetTokenValue())
+		if ParseException = this.ParametersParser.Parse(mechanism); ParseException != nil {
+			return ParseException
+		}
+		if mechanism.HasParameter(header.ParameterNames_Q) {
+			if q, err := strconv.ParseFloat(mechanism.GetParameter(header.ParameterNames_Q), 32); err != nil || q < 0 || q > 1 {
+				return this.CreateParseException("malformed preference")
+			}
+		}
+		list.PushBack(mechanism)
+		lexer.SPorHT()
+		if ch, _ = lexer.LookAheadK(0); ch == ',' {
+			lexer.Match(',')
+		} else if ch == '\n' {
+			break
+		} else {
+			return this.CreateParseException("unexpected char")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"sip/header"
+	"testing"
+)
+
+func TestSecurityAgreeParser(t *testing.T) {
+	shp := NewSecurityClientParser("Security-Client: ipsec-ike;q=0.1, tls ; q=0.2\n")
+	testHeaderParser(t, shp, "Security-Client: ipsec-ike;q=0.1,tls;q=0.2\n")
+
+	shp2 := NewSecurityServerParser("Security-Server: digest;d-alg=md5;d-qop=auth-int;q=0.1\n")
+	testHeaderParser(t, shp2, "Security-Server: digest;d-alg=md5;d-qop=auth-int;q=0.1\n")
+
+	shp3 := NewSecurityVerifyParser("Security-Verify: digest;d-ver=\"0123456789abcdef0123456789abcdef\";q=0.1\n")
+	testHeaderParser(t, shp3, "Security-Verify: digest;d-ver=\"0123456789abcdef0123456789abcdef\";q=0.1\n")
+
+	h, err := NewSecurityVerifyParser("Security-Verify: digest;d-ver=\"0123456789abcdef0123456789abcdef\";q=0.1\n").Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := h.(*header.SecurityVerifyList).Front().Value.(*header.SecurityVerify)
+	if verify.GetSecurityMechanism() != "digest" || verify.GetPreference() != 0.1 || verify.GetDigestVerify() != "0123456789abcdef0123456789abcdef" {
+		t.Error(verify.EncodeBody())
+	}
+	if _, err := NewSecurityClientParser("Security-Client: tls;q=2\n").Parse(); err == nil {
+		t.Error("parsed a preference out of range")
+	}
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Security-Client header.
+ */
+type SecurityClientParser struct {
+	SecurityAgreeParser
+}
+
+/** Constructor
+ * @param securityClient message to parse to set
+ */
+func NewSecurityClientParser(securityClient string) *SecurityClientParser {
+	this := &SecurityClientParser{}
+	this.SecurityAgreeParser.super(securityClient)
+	return this
+}
+
+func NewSecurityClientParserFromLexer(lexer core.Lexer) *SecurityClientParser {
+	this := &SecurityClientParser{}
+	this.SecurityAgreeParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Security-Client List Object
+ * @return SIPHeader the Security-Client List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *SecurityClientParser) Parse() (sh header.Header, ParseException error) {
+	securityClientList := header.NewSecurityClientList()
+	ParseException = this.parse(TokenTypes_SECURITY_CLIENT, securityClientList, func() header.SecurityAgreeHeader {
+		return header.NewSecurityClient()
+	})
+	if ParseException != nil {
+		return nil, ParseException
+	}
+	return securityClientList, nil
+}
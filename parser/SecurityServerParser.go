@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Security-Server header.
+ */
+type SecurityServerParser struct {
+	SecurityAgreeParser
+}
+
+/** Constructor
+ * @param securityServer message to parse to set
+ */
+func NewSecurityServerParser(securityServer string) *SecurityServerParser {
+	this := &SecurityServerParser{}
+	this.SecurityAgreeParser.super(securityServer)
+	return this
+}
+
+func NewSecurityServerParserFromLexer(lexer core.Lexer) *SecurityServerParser {
+	this := &SecurityServerParser{}
+	this.SecurityAgreeParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Security-Server List Object
+ * @return SIPHeader the Security-Server List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *SecurityServerParser) Parse() (sh header.Header, ParseException error) {
+	securityServerList := header.NewSecurityServerList()
+	ParseException = this.parse(TokenTypes_SECURITY_SERVER, securityServerList, func() header.SecurityAgreeHeader {
+		return header.NewSecurityServer()
+	})
+	if ParseException != nil {
+		return nil, ParseException
+	}
+	return securityServerList, nil
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"sip/core"
+	"sip/header"
+)
+
+/** Parser for the Security-Verify header.
+ */
+type SecurityVerifyParser struct {
+	SecurityAgreeParser
+}
+
+/** Constructor
+ * @param securityVerify message to parse to set
+ */
+func NewSecurityVerifyParser(securityVerify string) *SecurityVerifyParser {
+	this := &SecurityVerifyParser{}
+	this.SecurityAgreeParser.super(securityVerify)
+	return this
+}
+
+func NewSecurityVerifyParserFromLexer(lexer core.Lexer) *SecurityVerifyParser {
+	this := &SecurityVerifyParser{}
+	this.SecurityAgreeParser.superFromLexer(lexer)
+	return this
+}
+
+/** parse the String message and generate the Security-Verify List Object
+ * @return SIPHeader the Security-Verify List object
+ * @throws ParseException if errors occur during the parsing
+ */
+func (this *SecurityVerifyParser) Parse() (sh header.Header, ParseException error) {
+	securityVerifyList := header.NewSecurityVerifyList()
+	ParseException = this.parse(TokenTypes_SECURITY_VERIFY, securityVerifyList, func() header.SecurityAgreeHeader {
+		return header.NewSecurityVerify()
+	})
+	if ParseException != nil {
+		return nil, ParseException
+	}
+	return securityVerifyList, nil
+}
@@ -84,7 +84,7 @@ func (this *URLParser) ParamNameOrValue() (s string, ParseException error) {
 	var retval bytes.Buffer
 	for this.GetLexer().HasMoreChars() {
 		next, _ := this.GetLexer().LookAheadK(0)
-		if next == '[' || next == '[' || next == '/' ||
+		if next == '[' || next == ']' || next == '/' ||
 			next == ':' || next == '&' || next == '+' ||
 			next == '$' || this.IsUnreserved(next) {
 			retval.WriteByte(next)
@@ -228,6 +228,14 @@ func (this *URLParser) UricString() string {
  *@throws ParsException if there was a problem parsing.
  */
 func (this *URLParser) UriReference() (url address.URI, ParseException error) {
+	return this.UriReferenceInBrackets(true)
+}
+
+/** Parse and return a structure for a generic URL. Out of the brackets of
+ * a name-addr, the parameters following a SIP URL are the parameters of
+ * the header, RFC 3261 §20, and are left to the caller.
+ */
+func (this *URLParser) UriReferenceInBrackets(inBrackets bool) (url address.URI, ParseException error) {
 	var retval address.URI
 	vect, _ := this.GetLexer().PeekNextTokenK(2)
 	t1 := vect[0]
@@ -235,7 +243,7 @@ func (this *URLParser) UriReference() (url address.URI, ParseException error) {
 
 	if t1.GetTokenType() == TokenTypes_SIP {
 		if t2.GetTokenType() == ':' {
-			if retval, ParseException = this.SipURL(); ParseException != nil {
+			if retval, ParseException = this.SipURLInBrackets(inBrackets); ParseException != nil {
 				return nil, ParseException
 			}
 		} else {
@@ -401,6 +409,13 @@ func (this *URLParser) TelURL() (telUrl *address.TelURLImpl, ParseException erro
  */
 
 func (this *URLParser) SipURL() (sipurl *address.SipURIImpl, ParseException error) {
+	return this.SipURLInBrackets(true)
+}
+
+/** Parse and return a structure for a SIP URL, leaving the parameters and
+ * headers that follow it to the caller unless the URL is in brackets.
+ */
+func (this *URLParser) SipURLInBrackets(inBrackets bool) (sipurl *address.SipURIImpl, ParseException error) {
 	retval := address.NewSipURIImpl()
 
 	this.GetLexer().Match(TokenTypes_SIP)
@@ -441,6 +456,9 @@ func (this *URLParser) SipURL() (sipurl *address.SipURIImpl, ParseException erro
 		}
 	}
 	this.GetLexer().SelectLexer("charLexer")
+	if !inBrackets {
+		return retval, nil
+	}
 	for this.GetLexer().HasMoreChars() {
 		if la, _ := this.GetLexer().LookAheadK(0); la != ';' {
 			break
@@ -0,0 +1,213 @@
+// Package passport creates and verifies PASSporTs, the signed tokens of the
+// identities of a call, RFC 8225, with the SHAKEN extension of RFC 8588.
+package passport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// The values of the header of a PASSporT, RFC 8225 §4 and RFC 8588 §5.
+const (
+	ALG_ES256  = "ES256"
+	TYP        = "passport"
+	PPT_SHAKEN = "shaken"
+)
+
+// The attestation levels of a SHAKEN PASSporT, RFC 8588 §4.
+const (
+	// ATTEST_FULL is a caller the signer authenticated, entitled to the
+	// calling number.
+	ATTEST_FULL = "A"
+	// ATTEST_PARTIAL is a caller the signer authenticated, whose right to
+	// the calling number it couldn't establish.
+	ATTEST_PARTIAL = "B"
+	// ATTEST_GATEWAY is a call the signer only saw enter its network.
+	ATTEST_GATEWAY = "C"
+)
+
+// Header is the JOSE header of a PASSporT. Its fields are in lexicographic
+// order, so that its JSON is in the canonical form of RFC 8225 §9.
+type Header struct {
+	Alg string `json:"alg"`
+	Ppt string `json:"ppt,omitempty"`
+	Typ string `json:"typ"`
+	// X5u is the URL of the certificate of the signer.
+	X5u string `json:"x5u"`
+}
+
+// Orig is the identity of the originator of a call, a telephone number or
+// an URI, RFC 8225 §5.2.1.
+type Orig struct {
+	TN  string `json:"tn,omitempty"`
+	URI string `json:"uri,omitempty"`
+}
+
+// Dest is the identities of the destinations of a call, RFC 8225 §5.2.1.
+type Dest struct {
+	TN  []string `json:"tn,omitempty"`
+	URI []string `json:"uri,omitempty"`
+}
+
+// Claims is the payload of a PASSporT, in lexicographic order as Header.
+type Claims struct {
+	// Attest is the attestation level of a SHAKEN PASSporT.
+	Attest string `json:"attest,omitempty"`
+	Dest   Dest   `json:"dest"`
+	// Iat is the time the PASSporT was issued, in seconds since the epoch.
+	Iat  int64 `json:"iat"`
+	Orig Orig  `json:"orig"`
+	// OrigId is the UUID the signer gives the call, to trace it back, in a
+	// SHAKEN PASSporT.
+	OrigId string `json:"origid,omitempty"`
+}
+
+// PASSporT is a Personal Assertion Token.
+type PASSporT struct {
+	Header Header
+	Claims Claims
+}
+
+// NewShaken returns the SHAKEN PASSporT of a call, issued now, whose signer
+// certificate is at x5u. A random origid is given to the claims that have
+// none.
+func NewShaken(x5u string, claims Claims) (*PASSporT, error) {
+	switch claims.Attest {
+	case ATTEST_FULL, ATTEST_PARTIAL, ATTEST_GATEWAY:
+	default:
+		return nil, errors.New("unknown attestation level " + claims.Attest)
+	}
+	if claims.Iat == 0 {
+		claims.Iat = time.Now().Unix()
+	}
+	if claims.OrigId == "" {
+		uuid, err := NewUUID()
+		if err != nil {
+			return nil, err
+		}
+		claims.OrigId = uuid
+	}
+	return &PASSporT{
+		Header: Header{Alg: ALG_ES256, Ppt: PPT_SHAKEN, Typ: TYP, X5u: x5u},
+		Claims: claims,
+	}, nil
+}
+
+// Sign returns the PASSporT signed with key, in the full form of a compact
+// JWS, RFC 7515 §7.1: the base64url of its header, of its claims and of
+// their signature, separated by dots.
+func (this *PASSporT) Sign(key *ecdsa.PrivateKey) (string, error) {
+	if this.Header.Alg != ALG_ES256 {
+		return "", errors.New("unsupported algorithm " + this.Header.Alg)
+	}
+	if key.Curve != elliptic.P256() {
+		return "", errors.New("ES256 signs with a P-256 key")
+	}
+	header, err := json.Marshal(this.Header)
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(this.Claims)
+	if err != nil {
+		return "", err
+	}
+	input := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	//RFC 7518 §3.4, R and S as 32 byte big-endian integers
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return input + "." + encode(signature), nil
+}
+
+// Parse returns the PASSporT of token, a compact JWS in full form, without
+// verifying its signature.
+func Parse(token string) (*PASSporT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("a PASSporT has three parts")
+	}
+	if parts[1] == "" {
+		return nil, errors.New("a PASSporT in compact form can't be parsed without its claims")
+	}
+	this := &PASSporT{}
+	for i, v := range []interface{}{&this.Header, &this.Claims} {
+		b, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, v); err != nil {
+			return nil, err
+		}
+	}
+	if this.Header.Typ != TYP {
+		return nil, errors.New("not a PASSporT: typ " + this.Header.Typ)
+	}
+	return this, nil
+}
+
+// Verify returns the PASSporT of token once its ES256 signature is verified
+// with key. The signature covers the header and claims as they were
+// received, whatever their JSON form.
+func Verify(token string, key *ecdsa.PublicKey) (*PASSporT, error) {
+	this, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if this.Header.Alg != ALG_ES256 {
+		return nil, errors.New("unsupported algorithm " + this.Header.Alg)
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("ES256 verifies with a P-256 key")
+	}
+	i := strings.LastIndex(token, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != 64 {
+		return nil, errors.New("malformed ES256 signature")
+	}
+	digest := sha256.Sum256([]byte(token[:i]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return nil, errors.New("the signature of the PASSporT is invalid")
+	}
+	return this, nil
+}
+
+// NewUUID returns a random UUID, RFC 4122 §4.4.
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	const hex = "0123456789abcdef"
+	var uuid []byte
+	for i, c := range b {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			uuid = append(uuid, '-')
+		}
+		uuid = append(uuid, hex[c>>4], hex[c&15])
+	}
+	return string(uuid), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
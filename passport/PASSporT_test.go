@@ -0,0 +1,77 @@
+package passport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewShaken("https://cert.example.org/passport.cer", Claims{
+		Attest: ATTEST_FULL,
+		Dest:   Dest{TN: []string{"12155550131"}},
+		Iat:    1443208345,
+		Orig:   Orig{TN: "12155550121"},
+		OrigId: "123e4567-e89b-12d3-a456-426655440000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := p.Sign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the canonical form of RFC 8588 §6
+	parts := strings.Split(token, ".")
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if string(header) != `{"alg":"ES256","ppt":"shaken","typ":"passport","x5u":"https://cert.example.org/passport.cer"}` {
+		t.Error(string(header))
+	}
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if string(claims) != `{"attest":"A","dest":{"tn":["12155550131"]},"iat":1443208345,"orig":{"tn":"12155550121"},"origid":"123e4567-e89b-12d3-a456-426655440000"}` {
+		t.Error(string(claims))
+	}
+
+	verified, err := Verify(token, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Claims.Orig.TN != "12155550121" || verified.Header.X5u != "https://cert.example.org/passport.cer" {
+		t.Error(verified)
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := Verify(token, &other.PublicKey); err == nil {
+		t.Error("verified with another key")
+	}
+	p.Claims.Orig.TN = "12155550199"
+	forged, _ := p.Sign(other)
+	forged = strings.Join([]string{parts[0], strings.Split(forged, ".")[1], parts[2]}, ".")
+	if _, err := Verify(forged, &key.PublicKey); err == nil {
+		t.Error("verified altered claims")
+	}
+	if _, err := Parse(parts[0] + ".." + parts[2]); err == nil {
+		t.Error("parsed a compact form")
+	}
+}
+
+func TestNewShaken(t *testing.T) {
+	p, err := NewShaken("https://cert.example.org/passport.cer", Claims{Attest: ATTEST_GATEWAY})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Claims.Iat == 0 || len(p.Claims.OrigId) != 36 || p.Claims.OrigId[14] != '4' {
+		t.Error(p.Claims)
+	}
+	if _, err := NewShaken("https://cert.example.org/passport.cer", Claims{Attest: "D"}); err == nil {
+		t.Error("accepted an unknown attestation level")
+	}
+}
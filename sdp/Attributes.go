@@ -0,0 +1,277 @@
+package sdp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Direction is the direction attribute of a media stream, RFC 8866 §6.7.
+type Direction string
+
+const (
+	SENDRECV Direction = "sendrecv"
+	SENDONLY Direction = "sendonly"
+	RECVONLY Direction = "recvonly"
+	INACTIVE Direction = "inactive"
+)
+
+// The attributes given a typed access.
+const (
+	ATTRIBUTE_RTPMAP    = "rtpmap"
+	ATTRIBUTE_FMTP      = "fmtp"
+	ATTRIBUTE_PTIME     = "ptime"
+	ATTRIBUTE_MAXPTIME  = "maxptime"
+	ATTRIBUTE_CANDIDATE = "candidate"
+)
+
+// Reverse returns the direction the answerer uses for an offered direction,
+// RFC 3264 §6.1.
+func (this Direction) Reverse() Direction {
+	switch this {
+	case SENDONLY:
+		return RECVONLY
+	case RECVONLY:
+		return SENDONLY
+	}
+	return this
+}
+
+// Sends reports whether media flows from the side using this direction.
+func (this Direction) Sends() bool {
+	return this == SENDRECV || this == SENDONLY
+}
+
+// Receives reports whether media flows to the side using this direction.
+func (this Direction) Receives() bool {
+	return this == SENDRECV || this == RECVONLY
+}
+
+func direction(attributes []*Attribute) (Direction, bool) {
+	for _, a := range attributes {
+		switch d := Direction(a.Name); d {
+		case SENDRECV, SENDONLY, RECVONLY, INACTIVE:
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// GetDirection returns the direction attribute of the media, and whether it
+// has one. SessionDescription.GetDirection falls back on the session-level
+// direction.
+func (this *Media) GetDirection() (Direction, bool) {
+	return direction(this.Attributes)
+}
+
+// SetDirection replaces the direction attribute of the media.
+func (this *Media) SetDirection(d Direction) {
+	for _, other := range []Direction{SENDRECV, SENDONLY, RECVONLY, INACTIVE} {
+		if other != d {
+			this.RemoveAttribute(string(other))
+		}
+	}
+	this.SetAttribute(string(d), "")
+}
+
+// GetPtime returns the ptime attribute of the media in milliseconds, 0 when
+// it has none.
+func (this *Media) GetPtime() int {
+	if v, ok := this.GetAttribute(ATTRIBUTE_PTIME); ok {
+		if ptime, err := strconv.ParseFloat(v, 64); err == nil {
+			return int(ptime)
+		}
+	}
+	return 0
+}
+
+// SetPtime replaces the ptime attribute of the media.
+func (this *Media) SetPtime(milliseconds int) {
+	this.SetAttribute(ATTRIBUTE_PTIME, strconv.Itoa(milliseconds))
+}
+
+// RtpMap is an rtpmap attribute, RFC 8866 §6.6.
+type RtpMap struct {
+	PayloadType        int
+	EncodingName       string
+	ClockRate          int
+	EncodingParameters string //the number of audio channels
+}
+
+// ParseRtpMap parses the value of an rtpmap attribute, e.g.
+// "96 opus/48000/2".
+func ParseRtpMap(value string) (*RtpMap, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return nil, errors.New("malformed rtpmap: " + value)
+	}
+	pt, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, errors.New("malformed rtpmap payload type: " + fields[0])
+	}
+	encoding := strings.SplitN(fields[1], "/", 3)
+	if len(encoding) < 2 {
+		return nil, errors.New("malformed rtpmap encoding: " + fields[1])
+	}
+	clockRate, err := strconv.Atoi(encoding[1])
+	if err != nil {
+		return nil, errors.New("malformed rtpmap clock rate: " + encoding[1])
+	}
+	rtpMap := &RtpMap{PayloadType: pt, EncodingName: encoding[0], ClockRate: clockRate}
+	if len(encoding) == 3 {
+		rtpMap.EncodingParameters = encoding[2]
+	}
+	return rtpMap, nil
+}
+
+func (this *RtpMap) String() string {
+	s := strconv.Itoa(this.PayloadType) + " " + this.EncodingName + "/" + strconv.Itoa(this.ClockRate)
+	if this.EncodingParameters != "" {
+		s += "/" + this.EncodingParameters
+	}
+	return s
+}
+
+// GetRtpMap returns the rtpmap attribute of a payload type of the media,
+// or nil if it has none.
+func (this *Media) GetRtpMap(payloadType int) *RtpMap {
+	for _, v := range this.GetAttributes(ATTRIBUTE_RTPMAP) {
+		if rtpMap, err := ParseRtpMap(v); err == nil && rtpMap.PayloadType == payloadType {
+			return rtpMap
+		}
+	}
+	return nil
+}
+
+// GetFmtp returns the format parameters of a payload type of the media, the
+// fmtp attribute without the payload type.
+func (this *Media) GetFmtp(payloadType int) string {
+	prefix := strconv.Itoa(payloadType) + " "
+	for _, v := range this.GetAttributes(ATTRIBUTE_FMTP) {
+		if strings.HasPrefix(v, prefix) {
+			return strings.TrimSpace(v[len(prefix):])
+		}
+	}
+	return ""
+}
+
+// Codec is an RTP payload format offered by a media description.
+type Codec struct {
+	PayloadType int
+	Name        string
+	ClockRate   int
+	Channels    int
+	Fmtp        string
+}
+
+func (this *Codec) String() string {
+	s := this.Name + "/" + strconv.Itoa(this.ClockRate)
+	if this.Channels > 1 {
+		s += "/" + strconv.Itoa(this.Channels)
+	}
+	return s
+}
+
+// staticPayloadTypes are the payload types RFC 3551 §6 assigns, which may be
+// used without rtpmap attribute.
+var staticPayloadTypes = map[int]Codec{
+	0:  {Name: "PCMU", ClockRate: 8000, Channels: 1},
+	3:  {Name: "GSM", ClockRate: 8000, Channels: 1},
+	4:  {Name: "G723", ClockRate: 8000, Channels: 1},
+	5:  {Name: "DVI4", ClockRate: 8000, Channels: 1},
+	6:  {Name: "DVI4", ClockRate: 16000, Channels: 1},
+	7:  {Name: "LPC", ClockRate: 8000, Channels: 1},
+	8:  {Name: "PCMA", ClockRate: 8000, Channels: 1},
+	9:  {Name: "G722", ClockRate: 8000, Channels: 1},
+	10: {Name: "L16", ClockRate: 44100, Channels: 2},
+	11: {Name: "L16", ClockRate: 44100, Channels: 1},
+	12: {Name: "QCELP", ClockRate: 8000, Channels: 1},
+	13: {Name: "CN", ClockRate: 8000, Channels: 1},
+	14: {Name: "MPA", ClockRate: 90000},
+	15: {Name: "G728", ClockRate: 8000, Channels: 1},
+	16: {Name: "DVI4", ClockRate: 11025, Channels: 1},
+	17: {Name: "DVI4", ClockRate: 22050, Channels: 1},
+	18: {Name: "G729", ClockRate: 8000, Channels: 1},
+	25: {Name: "CelB", ClockRate: 90000},
+	26: {Name: "JPEG", ClockRate: 90000},
+	28: {Name: "nv", ClockRate: 90000},
+	31: {Name: "H261", ClockRate: 90000},
+	32: {Name: "MPV", ClockRate: 90000},
+	33: {Name: "MP2T", ClockRate: 90000},
+	34: {Name: "H263", ClockRate: 90000},
+}
+
+// GetCodecs returns the codecs of the media in order of preference, the
+// order of its formats. Static payload types without rtpmap get their
+// RFC 3551 encoding, dynamic ones without rtpmap are left out.
+func (this *Media) GetCodecs() []*Codec {
+	var codecs []*Codec
+	for _, format := range this.Formats {
+		pt, err := strconv.Atoi(format)
+		if err != nil {
+			continue
+		}
+		var codec Codec
+		if rtpMap := this.GetRtpMap(pt); rtpMap != nil {
+			codec = Codec{Name: rtpMap.EncodingName, ClockRate: rtpMap.ClockRate, Channels: 1}
+			if channels, err := strconv.Atoi(rtpMap.EncodingParameters); err == nil {
+				codec.Channels = channels
+			}
+		} else if static, ok := staticPayloadTypes[pt]; ok {
+			codec = static
+		} else {
+			continue
+		}
+		codec.PayloadType = pt
+		codec.Fmtp = this.GetFmtp(pt)
+		codecs = append(codecs, &codec)
+	}
+	return codecs
+}
+
+// FindCodec returns the first codec of the media with the given encoding
+// name, compared case-insensitively, or nil.
+func (this *Media) FindCodec(name string) *Codec {
+	for _, c := range this.GetCodecs() {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// AddCodec appends a codec to the formats of the media with its rtpmap and,
+// when it has format parameters, fmtp attributes.
+func (this *Media) AddCodec(c *Codec) {
+	pt := strconv.Itoa(c.PayloadType)
+	this.Formats = append(this.Formats, pt)
+	rtpMap := &RtpMap{PayloadType: c.PayloadType, EncodingName: c.Name, ClockRate: c.ClockRate}
+	if c.Channels > 1 {
+		rtpMap.EncodingParameters = strconv.Itoa(c.Channels)
+	}
+	this.AddAttribute(ATTRIBUTE_RTPMAP, rtpMap.String())
+	if c.Fmtp != "" {
+		this.AddAttribute(ATTRIBUTE_FMTP, pt+" "+c.Fmtp)
+	}
+}
+
+// RemoveFormat deletes a format of the media and its rtpmap and fmtp
+// attributes.
+func (this *Media) RemoveFormat(format string) {
+	formats := this.Formats[:0]
+	for _, f := range this.Formats {
+		if f != format {
+			formats = append(formats, f)
+		}
+	}
+	this.Formats = formats
+	attributes := this.Attributes[:0]
+	for _, a := range this.Attributes {
+		if (a.Name == ATTRIBUTE_RTPMAP || a.Name == ATTRIBUTE_FMTP) &&
+			(a.Value == format || strings.HasPrefix(a.Value, format+" ")) {
+			continue
+		}
+		attributes = append(attributes, a)
+	}
+	this.Attributes = attributes
+}
@@ -0,0 +1,105 @@
+package sdp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// The ICE candidate types, RFC 8445 §5.1.1.
+const (
+	CANDIDATE_HOST  = "host"
+	CANDIDATE_SRFLX = "srflx"
+	CANDIDATE_PRFLX = "prflx"
+	CANDIDATE_RELAY = "relay"
+)
+
+// Candidate is an ICE candidate attribute, RFC 8839 §5.1.
+type Candidate struct {
+	Foundation     string
+	Component      int
+	Transport      string
+	Priority       uint32
+	Address        string
+	Port           int
+	Type           string
+	RelatedAddress string //raddr, of the reflexive and relayed candidates
+	RelatedPort    int    //rport
+	Extensions     []*Attribute
+}
+
+// ParseCandidate parses the value of a candidate attribute, e.g.
+// "1 1 UDP 2130706431 203.0.113.141 8998 typ host".
+func ParseCandidate(value string) (*Candidate, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 8 || fields[6] != "typ" {
+		return nil, errors.New("malformed candidate: " + value)
+	}
+	c := &Candidate{Foundation: fields[0], Transport: fields[2], Address: fields[4], Type: fields[7]}
+	var err error
+	if c.Component, err = strconv.Atoi(fields[1]); err != nil {
+		return nil, errors.New("malformed candidate component: " + fields[1])
+	}
+	priority, err := strconv.ParseUint(fields[3], 10, 32)
+	if err != nil {
+		return nil, errors.New("malformed candidate priority: " + fields[3])
+	}
+	c.Priority = uint32(priority)
+	if c.Port, err = strconv.Atoi(fields[5]); err != nil {
+		return nil, errors.New("malformed candidate port: " + fields[5])
+	}
+
+	rest := fields[8:]
+	if len(rest)%2 != 0 {
+		return nil, errors.New("malformed candidate extension: " + value)
+	}
+	for i := 0; i < len(rest); i += 2 {
+		switch name, v := rest[i], rest[i+1]; name {
+		case "raddr":
+			c.RelatedAddress = v
+		case "rport":
+			if c.RelatedPort, err = strconv.Atoi(v); err != nil {
+				return nil, errors.New("malformed candidate rport: " + v)
+			}
+		default:
+			c.Extensions = append(c.Extensions, &Attribute{Name: name, Value: v})
+		}
+	}
+	return c, nil
+}
+
+func (this *Candidate) String() string {
+	fields := []string{
+		this.Foundation,
+		strconv.Itoa(this.Component),
+		this.Transport,
+		strconv.FormatUint(uint64(this.Priority), 10),
+		this.Address,
+		strconv.Itoa(this.Port),
+		"typ", this.Type,
+	}
+	if this.RelatedAddress != "" {
+		fields = append(fields, "raddr", this.RelatedAddress, "rport", strconv.Itoa(this.RelatedPort))
+	}
+	for _, e := range this.Extensions {
+		fields = append(fields, e.Name, e.Value)
+	}
+	return strings.Join(fields, " ")
+}
+
+// GetCandidates returns the ICE candidates of the media. The malformed ones
+// are skipped.
+func (this *Media) GetCandidates() []*Candidate {
+	var candidates []*Candidate
+	for _, v := range this.GetAttributes(ATTRIBUTE_CANDIDATE) {
+		if c, err := ParseCandidate(v); err == nil {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// AddCandidate appends an ICE candidate to the media.
+func (this *Media) AddCandidate(c *Candidate) {
+	this.AddAttribute(ATTRIBUTE_CANDIDATE, c.String())
+}
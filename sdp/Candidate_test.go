@@ -0,0 +1,46 @@
+package sdp
+
+import (
+	"testing"
+)
+
+func TestCandidate(t *testing.T) {
+	var tvi = []string{
+		"1 1 UDP 2130706431 203.0.113.141 8998 typ host",
+		"2 1 UDP 1694498815 192.0.2.3 45664 typ srflx raddr 203.0.113.141 rport 8998",
+		"3 1 TCP 2128609279 10.0.1.1 9 typ host tcptype active generation 0",
+	}
+
+	for i := 0; i < len(tvi); i++ {
+		c, err := ParseCandidate(tvi[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.String() != tvi[i] {
+			t.Log("golden = " + tvi[i])
+			t.Log("failed = " + c.String())
+			t.Fail()
+		}
+	}
+
+	c, _ := ParseCandidate(tvi[1])
+	if c.Type != CANDIDATE_SRFLX || c.Priority != 1694498815 || c.RelatedAddress != "203.0.113.141" || c.RelatedPort != 8998 {
+		t.Log(c)
+		t.Fail()
+	}
+
+	for _, value := range []string{"1 1 UDP 2130706431 203.0.113.141 8998", "1 x UDP 1 192.0.2.1 9 typ host", "1 1 UDP 1 192.0.2.1 9 typ host raddr"} {
+		if _, err := ParseCandidate(value); err == nil {
+			t.Log("accepted " + value)
+			t.Fail()
+		}
+	}
+
+	m := &Media{Type: "audio", Port: 9, Protocol: "UDP/TLS/RTP/SAVPF"}
+	m.AddCandidate(c)
+	m.AddAttribute(ATTRIBUTE_CANDIDATE, "malformed")
+	if candidates := m.GetCandidates(); len(candidates) != 1 || candidates[0].String() != tvi[1] {
+		t.Log(candidates)
+		t.Fail()
+	}
+}
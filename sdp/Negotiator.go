@@ -0,0 +1,327 @@
+package sdp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrOfferPending = errors.New("sdp: an offer is already pending")
+var ErrNoOfferPending = errors.New("sdp: no offer is pending")
+
+// Negotiator builds the offers and answers of one side of a session and
+// keeps track of the negotiated session, RFC 3264. Its capabilities are a
+// session description listing, for each media type it supports, the
+// port, protocol, codecs and direction it is willing to use.
+//
+// The offer/answer exchanges of a dialog are sequential: CreateOffer is
+// followed by ProcessAnswer, or by CancelOffer when the offer is rejected,
+// and an offer received is answered by CreateAnswer.
+type Negotiator struct {
+	capabilities *SessionDescription
+
+	origin   Origin
+	lastSent *SessionDescription //the last offer or answer created
+
+	local   *SessionDescription //the negotiated session
+	remote  *SessionDescription
+	pending *SessionDescription //the offer waiting for its answer
+}
+
+// NewNegotiator returns a negotiator for the given capabilities. The
+// origin of the capabilities is used for all the descriptions created, a
+// session id is chosen when it has none.
+func NewNegotiator(capabilities *SessionDescription) *Negotiator {
+	origin := capabilities.Origin
+	if origin.SessionId == 0 {
+		//RFC 8866 §5.2 suggests an NTP format timestamp
+		origin.SessionId = uint64(time.Now().Unix()) + 2208988800
+		origin.SessionVersion = origin.SessionId
+	}
+	return &Negotiator{capabilities: capabilities, origin: origin}
+}
+
+// GetCapabilities returns the capabilities of the negotiator, they may be
+// changed between exchanges, e.g. to put the session on hold.
+func (this *Negotiator) GetCapabilities() *SessionDescription {
+	return this.capabilities
+}
+
+// GetLocal returns the local description of the negotiated session, nil
+// before the first exchange completes.
+func (this *Negotiator) GetLocal() *SessionDescription {
+	return this.local
+}
+
+// GetRemote returns the remote description of the negotiated session, nil
+// before the first exchange completes.
+func (this *Negotiator) GetRemote() *SessionDescription {
+	return this.remote
+}
+
+// IsOfferPending reports whether an offer created is waiting for its
+// answer.
+func (this *Negotiator) IsOfferPending() bool {
+	return this.pending != nil
+}
+
+// CreateOffer builds an offer from the capabilities. A new offer within a
+// session keeps the m-lines of the session in place, RFC 3264 §8: the
+// media types of the capabilities reuse them and the new ones are
+// appended.
+func (this *Negotiator) CreateOffer() (*SessionDescription, error) {
+	if this.pending != nil {
+		return nil, ErrOfferPending
+	}
+	offer := this.base()
+	used := make(map[*Media]bool)
+	if this.local != nil {
+		for _, m := range this.local.Media {
+			if c := this.capability(m.Type, m.Protocol, used); c != nil {
+				offer.Media = append(offer.Media, this.offerMedia(c))
+			} else {
+				offer.Media = append(offer.Media, rejectedMedia(m))
+			}
+		}
+	}
+	for _, c := range this.capabilities.Media {
+		if !used[c] {
+			offer.Media = append(offer.Media, this.offerMedia(c))
+		}
+	}
+	this.sent(offer)
+	this.pending = offer
+	return offer, nil
+}
+
+// CancelOffer forgets the pending offer, after it was rejected. The
+// negotiated session stays in place.
+func (this *Negotiator) CancelOffer() {
+	this.pending = nil
+}
+
+// ProcessAnswer completes the exchange of the pending offer, the answer
+// becomes the remote description of the session.
+func (this *Negotiator) ProcessAnswer(answer *SessionDescription) error {
+	offer := this.pending
+	if offer == nil {
+		return ErrNoOfferPending
+	}
+	this.pending = nil
+	if len(answer.Media) != len(offer.Media) {
+		return errors.New("sdp: the answer has " + strconv.Itoa(len(answer.Media)) +
+			" m-lines, the offer " + strconv.Itoa(len(offer.Media)))
+	}
+	for i, am := range answer.Media {
+		om := offer.Media[i]
+		if am.Type != om.Type {
+			return errors.New("sdp: m-line " + strconv.Itoa(i+1) + " of the answer is " + am.Type + ", not " + om.Type)
+		}
+		if am.Port == 0 {
+			continue
+		}
+		if om.Port == 0 {
+			return errors.New("sdp: m-line " + strconv.Itoa(i+1) + " rejected in the offer is accepted in the answer")
+		}
+		for _, f := range am.Formats {
+			if !hasFormat(om, f) {
+				return errors.New("sdp: format " + f + " of m-line " + strconv.Itoa(i+1) + " was not offered")
+			}
+		}
+	}
+	this.local = offer
+	this.remote = answer
+	return nil
+}
+
+// CreateAnswer answers offer and makes it the remote description of the
+// session, RFC 3264 §6. The m-lines of the answer follow those of the
+// offer: an m-line is rejected, with port 0, when it has no capability of
+// the same media type and protocol or no codec in common, each accepted
+// m-line keeps the offered codecs the capabilities support, with the
+// payload types and format parameters of the offer, and gets the direction
+// both sides allow.
+func (this *Negotiator) CreateAnswer(offer *SessionDescription) (*SessionDescription, error) {
+	if this.pending != nil {
+		return nil, ErrOfferPending
+	}
+	if this.remote != nil && len(offer.Media) < len(this.remote.Media) {
+		//m-lines are never removed from a session, RFC 3264 §8
+		return nil, errors.New("sdp: the offer removes m-lines of the session")
+	}
+	answer := this.base()
+	used := make(map[*Media]bool)
+	for _, om := range offer.Media {
+		answer.Media = append(answer.Media, this.answerMedia(offer, om, used))
+	}
+	this.sent(answer)
+	this.local = answer
+	this.remote = offer
+	return answer, nil
+}
+
+// base returns a description with the session-level lines of the
+// capabilities and no media.
+func (this *Negotiator) base() *SessionDescription {
+	sd := this.capabilities.Clone()
+	sd.Origin = this.origin
+	sd.Media = nil
+	return sd
+}
+
+// sent gives sd the origin version it is sent with: the version of the
+// previous description when nothing changed, the next one otherwise,
+// RFC 3264 §8.
+func (this *Negotiator) sent(sd *SessionDescription) {
+	if this.lastSent != nil {
+		sd.Origin.SessionVersion = this.lastSent.Origin.SessionVersion
+		if sd.String() != this.lastSent.String() {
+			sd.Origin.SessionVersion++
+		}
+	}
+	this.lastSent = sd
+}
+
+// capability returns the first unused media of the capabilities with the
+// given type and protocol, and marks it used.
+func (this *Negotiator) capability(mediaType, protocol string, used map[*Media]bool) *Media {
+	for _, c := range this.capabilities.Media {
+		if !used[c] && c.Type == mediaType && strings.EqualFold(c.Protocol, protocol) && c.Port != 0 {
+			used[c] = true
+			return c
+		}
+	}
+	return nil
+}
+
+func (this *Negotiator) direction(c *Media) Direction {
+	return this.capabilities.GetDirection(c)
+}
+
+func (this *Negotiator) offerMedia(c *Media) *Media {
+	m := c.Clone()
+	m.SetDirection(this.direction(c))
+	return m
+}
+
+func (this *Negotiator) answerMedia(offer *SessionDescription, om *Media, used map[*Media]bool) *Media {
+	if om.Port == 0 {
+		return rejectedMedia(om)
+	}
+	c := this.capability(om.Type, om.Protocol, used)
+	if c == nil {
+		return rejectedMedia(om)
+	}
+
+	m := &Media{Type: om.Type, Port: c.Port, PortCount: c.PortCount, Protocol: om.Protocol}
+	if isRTP(om.Protocol) {
+		for _, oc := range om.GetCodecs() {
+			if findCompatibleCodec(c, oc) != nil {
+				m.AddCodec(oc)
+			}
+		}
+	} else {
+		for _, f := range om.Formats {
+			if hasFormat(c, f) {
+				m.Formats = append(m.Formats, f)
+			}
+		}
+	}
+	if len(m.Formats) == 0 {
+		used[c] = false
+		return rejectedMedia(om)
+	}
+
+	for _, cc := range c.Connections {
+		m.Connections = append(m.Connections, &Connection{cc.NetworkType, cc.AddressType, cc.Address})
+	}
+	m.Bandwidths = append(m.Bandwidths, c.Bandwidths...)
+	for _, a := range c.Attributes {
+		switch a.Name {
+		case ATTRIBUTE_RTPMAP, ATTRIBUTE_FMTP, string(SENDRECV), string(SENDONLY), string(RECVONLY), string(INACTIVE):
+		default:
+			m.AddAttribute(a.Name, a.Value)
+		}
+	}
+	m.SetDirection(answerDirection(offer.GetDirection(om), this.direction(c)))
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// answerDirection returns the direction of an answer to the offered
+// direction, limited to what the answerer allows, RFC 3264 §6.1.
+func answerDirection(offered, local Direction) Direction {
+	sends := offered.Receives() && local.Sends()
+	receives := offered.Sends() && local.Receives()
+	switch {
+	case sends && receives:
+		return SENDRECV
+	case sends:
+		return SENDONLY
+	case receives:
+		return RECVONLY
+	}
+	return INACTIVE
+}
+
+// rejectedMedia returns the m-line rejecting m, RFC 3264 §6.
+func rejectedMedia(m *Media) *Media {
+	return &Media{Type: m.Type, Port: 0, Protocol: m.Protocol, Formats: append([]string(nil), m.Formats...)}
+}
+
+func isRTP(protocol string) bool {
+	return strings.Contains(strings.ToUpper(protocol), "RTP/")
+}
+
+func hasFormat(m *Media, format string) bool {
+	for _, f := range m.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// findCompatibleCodec returns the codec of m that is the same as codec: the
+// same encoding name, clock rate and channels, and format parameters that
+// agree on the parameters both set.
+func findCompatibleCodec(m *Media, codec *Codec) *Codec {
+	for _, c := range m.GetCodecs() {
+		if strings.EqualFold(c.Name, codec.Name) && c.ClockRate == codec.ClockRate &&
+			channels(c) == channels(codec) && compatibleFmtp(c.Fmtp, codec.Fmtp) {
+			return c
+		}
+	}
+	return nil
+}
+
+func channels(c *Codec) int {
+	if c.Channels == 0 {
+		return 1
+	}
+	return c.Channels
+}
+
+func compatibleFmtp(a, b string) bool {
+	pa, pb := fmtpParameters(a), fmtpParameters(b)
+	for k, v := range pa {
+		if w, ok := pb[k]; ok && !strings.EqualFold(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// fmtpParameters splits format parameters of the "name=value;..." form.
+// Other forms, such as the event list of telephone-event, are left out.
+func fmtpParameters(fmtp string) map[string]string {
+	parameters := make(map[string]string)
+	for _, p := range strings.Split(fmtp, ";") {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			parameters[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+		}
+	}
+	return parameters
+}
@@ -0,0 +1,154 @@
+package sdp
+
+import (
+	"testing"
+)
+
+const testOffer = "v=0\r\n" +
+	"o=alice 2890844526 2890844526 IN IP4 atlanta.example.com\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 192.0.2.101\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 49172 RTP/AVP 0 96 18\r\n" +
+	"a=rtpmap:96 opus/48000/2\r\n" +
+	"a=fmtp:96 useinbandfec=1\r\n" +
+	"a=recvonly\r\n" +
+	"m=video 51372 RTP/AVP 31\r\n"
+
+func newTestNegotiator() *Negotiator {
+	capabilities := NewSessionDescription("bob", 2808844564, "192.0.2.201")
+	audio := &Media{Type: "audio", Port: 49174, Protocol: "RTP/AVP"}
+	audio.AddCodec(&Codec{PayloadType: 111, Name: "opus", ClockRate: 48000, Channels: 2})
+	audio.AddCodec(&Codec{PayloadType: 8, Name: "PCMA", ClockRate: 8000})
+	audio.AddCodec(&Codec{PayloadType: 0, Name: "PCMU", ClockRate: 8000})
+	audio.SetPtime(20)
+	capabilities.Media = append(capabilities.Media, audio)
+	return NewNegotiator(capabilities)
+}
+
+func TestNegotiatorAnswer(t *testing.T) {
+	n := newTestNegotiator()
+	offer, _ := Parse([]byte(testOffer))
+	answer, err := n.CreateAnswer(offer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "v=0\r\n" +
+		"o=bob 2808844564 2808844564 IN IP4 192.0.2.201\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 192.0.2.201\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 49174 RTP/AVP 0 96\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n" +
+		"a=rtpmap:96 opus/48000/2\r\n" +
+		"a=fmtp:96 useinbandfec=1\r\n" +
+		"a=ptime:20\r\n" +
+		"a=sendonly\r\n" +
+		"m=video 0 RTP/AVP 31\r\n"
+	if s := answer.String(); s != expected {
+		t.Log(s)
+		t.Fail()
+	}
+	if n.GetLocal() != answer || n.GetRemote() != offer {
+		t.Fail()
+	}
+
+	//the same offer again gets the same answer, and the same version
+	again, _ := n.CreateAnswer(offer)
+	if again.String() != expected {
+		t.Log(again)
+		t.Fail()
+	}
+}
+
+func TestNegotiatorOffer(t *testing.T) {
+	n := newTestNegotiator()
+	video := &Media{Type: "video", Port: 51374, Protocol: "RTP/AVP"}
+	video.AddCodec(&Codec{PayloadType: 31, Name: "H261", ClockRate: 90000})
+	n.GetCapabilities().Media = append(n.GetCapabilities().Media, video)
+
+	offer, err := n.CreateOffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.CreateOffer(); err != ErrOfferPending {
+		t.Log(err)
+		t.Fail()
+	}
+
+	//the answer rejects the video
+	answer, _ := Parse([]byte("v=0\r\n" +
+		"o=alice 1 1 IN IP4 192.0.2.101\r\n" +
+		"s=-\r\n" +
+		"c=IN IP4 192.0.2.101\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 49172 RTP/AVP 111\r\n" +
+		"a=rtpmap:111 opus/48000/2\r\n" +
+		"m=video 0 RTP/AVP 31\r\n"))
+	if err := n.ProcessAnswer(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	//the video is removed from the capabilities and the audio put on hold:
+	//the m-lines stay in place and the version is incremented
+	n.GetCapabilities().Media = n.GetCapabilities().Media[:1]
+	n.GetCapabilities().Media[0].SetDirection(SENDONLY)
+	hold, _ := n.CreateOffer()
+	if len(hold.Media) != 2 || hold.Media[1].Port != 0 || hold.Media[1].Type != "video" ||
+		hold.Origin.SessionVersion != offer.Origin.SessionVersion+1 {
+		t.Log(hold)
+		t.Fail()
+	}
+	if d, _ := hold.Media[0].GetDirection(); d != SENDONLY {
+		t.Log(d)
+		t.Fail()
+	}
+	n.CancelOffer()
+
+	//nothing changed since the last offer, the version stays
+	same, _ := n.CreateOffer()
+	if same.Origin.SessionVersion != hold.Origin.SessionVersion {
+		t.Log(same.Origin)
+		t.Fail()
+	}
+	if n.GetLocal() != offer {
+		t.Log("a cancelled offer replaced the session")
+		t.Fail()
+	}
+}
+
+func TestNegotiatorBadAnswer(t *testing.T) {
+	n := newTestNegotiator()
+	n.CreateOffer()
+	answer, _ := Parse([]byte("v=0\r\n" +
+		"o=alice 1 1 IN IP4 192.0.2.101\r\n" +
+		"s=-\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 49172 RTP/AVP 18\r\n"))
+	if err := n.ProcessAnswer(answer); err == nil {
+		t.Log("an answer with a format not offered was accepted")
+		t.Fail()
+	}
+	if err := n.ProcessAnswer(answer); err != ErrNoOfferPending {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestAnswerDirection(t *testing.T) {
+	for _, test := range []struct {
+		offered, local, answer Direction
+	}{
+		{SENDRECV, SENDRECV, SENDRECV},
+		{SENDONLY, SENDRECV, RECVONLY},
+		{RECVONLY, SENDRECV, SENDONLY},
+		{SENDRECV, SENDONLY, SENDONLY},
+		{SENDONLY, SENDONLY, INACTIVE},
+		{INACTIVE, SENDRECV, INACTIVE},
+	} {
+		if d := answerDirection(test.offered, test.local); d != test.answer {
+			t.Log(test, d)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package sdp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseError reports a malformed line of a session description.
+type ParseError struct {
+	Line   int //1-based, 0 when the error is not tied to a line
+	Text   string
+	Reason string
+}
+
+func (this *ParseError) Error() string {
+	if this.Line == 0 {
+		return "sdp: " + this.Reason
+	}
+	return "sdp: line " + strconv.Itoa(this.Line) + ": " + this.Reason + ": " + strconv.Quote(this.Text)
+}
+
+// ParseErrors lists the errors found while parsing a session description.
+type ParseErrors []*ParseError
+
+func (this ParseErrors) Error() string {
+	messages := make([]string, len(this))
+	for i, e := range this {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Parse decodes a session description. Parsing is lenient: lines may end
+// with LF as well as CRLF, blank lines and lines of unknown types are
+// ignored, and a malformed line is skipped rather than aborting. The
+// description is returned along with the ParseErrors describing the lines
+// skipped and the mandatory lines missing; the error is nil when there is
+// none.
+func Parse(data []byte) (*SessionDescription, error) {
+	p := &sdpParser{sd: &SessionDescription{}}
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(text, "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		p.line = i + 1
+		p.text = text
+		if len(text) < 2 || text[1] != '=' {
+			p.fail("not a <type>=<value> line")
+			continue
+		}
+		p.parseLine(text[0], strings.TrimSpace(text[2:]))
+	}
+	if !p.seen['v'] {
+		p.errors = append(p.errors, &ParseError{Reason: "missing v= line"})
+	}
+	if !p.seen['o'] {
+		p.errors = append(p.errors, &ParseError{Reason: "missing o= line"})
+	}
+	if len(p.errors) > 0 {
+		return p.sd, p.errors
+	}
+	return p.sd, nil
+}
+
+type sdpParser struct {
+	sd     *SessionDescription
+	media  *Media //the media description being parsed, nil at session level
+	seen   [256]bool
+	line   int
+	text   string
+	errors ParseErrors
+}
+
+func (this *sdpParser) fail(reason string) {
+	this.errors = append(this.errors, &ParseError{Line: this.line, Text: this.text, Reason: reason})
+}
+
+func (this *sdpParser) parseLine(typ byte, value string) {
+	sd, m := this.sd, this.media
+	switch typ {
+	case 'v':
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			this.fail("malformed version")
+			return
+		}
+		sd.Version = version
+	case 'o':
+		origin, ok := parseOrigin(value)
+		if !ok {
+			this.fail("malformed origin")
+			return
+		}
+		sd.Origin = origin
+	case 's':
+		sd.SessionName = value
+	case 'i':
+		if m != nil {
+			m.Information = value
+		} else {
+			sd.Information = value
+		}
+	case 'u':
+		sd.URI = value
+	case 'e':
+		sd.Emails = append(sd.Emails, value)
+	case 'p':
+		sd.Phones = append(sd.Phones, value)
+	case 'c':
+		c, ok := parseConnection(value)
+		if !ok {
+			this.fail("malformed connection")
+			return
+		}
+		if m != nil {
+			m.Connections = append(m.Connections, c)
+		} else {
+			sd.Connection = c
+		}
+	case 'b':
+		bw, ok := parseBandwidth(value)
+		if !ok {
+			this.fail("malformed bandwidth")
+			return
+		}
+		if m != nil {
+			m.Bandwidths = append(m.Bandwidths, bw)
+		} else {
+			sd.Bandwidths = append(sd.Bandwidths, bw)
+		}
+	case 't':
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			this.fail("malformed timing")
+			return
+		}
+		start, err1 := strconv.ParseUint(fields[0], 10, 64)
+		stop, err2 := strconv.ParseUint(fields[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			this.fail("malformed timing")
+			return
+		}
+		sd.Timings = append(sd.Timings, Timing{Start: start, Stop: stop})
+	case 'r':
+		if len(sd.Timings) == 0 {
+			this.fail("repeat time without timing")
+			return
+		}
+		t := &sd.Timings[len(sd.Timings)-1]
+		t.Repeats = append(t.Repeats, value)
+	case 'z':
+		sd.TimeZones = value
+	case 'k':
+		if m != nil {
+			m.EncryptionKey = value
+		} else {
+			sd.EncryptionKey = value
+		}
+	case 'a':
+		a := parseAttribute(value)
+		if a.Name == "" {
+			this.fail("malformed attribute")
+			return
+		}
+		if m != nil {
+			m.Attributes = append(m.Attributes, a)
+		} else {
+			sd.Attributes = append(sd.Attributes, a)
+		}
+	case 'm':
+		media, ok := parseMedia(value)
+		if !ok {
+			this.fail("malformed media")
+			//the lines that follow belong to the malformed media, not to
+			//the previous one
+			this.media = &Media{}
+			return
+		}
+		sd.Media = append(sd.Media, media)
+		this.media = media
+	default:
+		//unknown types are ignored, RFC 8866 §5
+		return
+	}
+	this.seen[typ] = true
+}
+
+func parseOrigin(value string) (Origin, bool) {
+	fields := strings.Fields(value)
+	if len(fields) != 6 {
+		return Origin{}, false
+	}
+	id, err1 := strconv.ParseUint(fields[1], 10, 64)
+	version, err2 := strconv.ParseUint(fields[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Origin{}, false
+	}
+	return Origin{
+		Username:       fields[0],
+		SessionId:      id,
+		SessionVersion: version,
+		NetworkType:    fields[3],
+		AddressType:    fields[4],
+		Address:        fields[5],
+	}, true
+}
+
+func parseConnection(value string) (*Connection, bool) {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return nil, false
+	}
+	return &Connection{NetworkType: fields[0], AddressType: fields[1], Address: fields[2]}, true
+}
+
+func parseBandwidth(value string) (Bandwidth, bool) {
+	i := strings.IndexByte(value, ':')
+	if i <= 0 {
+		return Bandwidth{}, false
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(value[i+1:]))
+	if err != nil {
+		return Bandwidth{}, false
+	}
+	return Bandwidth{Type: strings.TrimSpace(value[:i]), Value: v}, true
+}
+
+func parseAttribute(value string) *Attribute {
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		return &Attribute{Name: value[:i], Value: value[i+1:]}
+	}
+	return &Attribute{Name: value}
+}
+
+func parseMedia(value string) (*Media, bool) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil, false
+	}
+	m := &Media{Type: fields[0], Protocol: fields[2], Formats: fields[3:]}
+	port := fields[1]
+	if i := strings.IndexByte(port, '/'); i >= 0 {
+		count, err := strconv.Atoi(port[i+1:])
+		if err != nil {
+			return nil, false
+		}
+		m.PortCount = count
+		port = port[:i]
+	}
+	var err error
+	if m.Port, err = strconv.Atoi(port); err != nil {
+		return nil, false
+	}
+	return m, true
+}
@@ -0,0 +1,457 @@
+// Package sdp implements the Session Description Protocol, RFC 8866 (which
+// obsoletes RFC 4566): a typed model of session descriptions, a lenient
+// parser and a deterministic serialiser.
+package sdp
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	"sip/header"
+)
+
+// CONTENT_TYPE is the media type of the bodies carrying a session
+// description.
+const CONTENT_TYPE = "application/sdp"
+
+// SessionDescription is a session-level description and the media
+// descriptions that follow it, RFC 8866 §5.
+type SessionDescription struct {
+	Version       int          //v=
+	Origin        Origin       //o=
+	SessionName   string       //s=
+	Information   string       //i=
+	URI           string       //u=
+	Emails        []string     //e=
+	Phones        []string     //p=
+	Connection    *Connection  //c=
+	Bandwidths    []Bandwidth  //b=
+	Timings       []Timing     //t= and r=
+	TimeZones     string       //z=
+	EncryptionKey string       //k=
+	Attributes    []*Attribute //a=
+	Media         []*Media     //m= sections
+}
+
+// Origin is the o= line, RFC 8866 §5.2.
+type Origin struct {
+	Username       string
+	SessionId      uint64
+	SessionVersion uint64
+	NetworkType    string
+	AddressType    string
+	Address        string
+}
+
+// Connection is a c= line, RFC 8866 §5.7. A multicast Address keeps its
+// TTL and number of addresses, e.g. "233.252.0.1/127/3".
+type Connection struct {
+	NetworkType string
+	AddressType string
+	Address     string
+}
+
+// Bandwidth is a b= line, RFC 8866 §5.8.
+type Bandwidth struct {
+	Type  string
+	Value int
+}
+
+// Timing is a t= line and the r= lines that follow it, RFC 8866 §5.9 and
+// §5.10.
+type Timing struct {
+	Start   uint64
+	Stop    uint64
+	Repeats []string
+}
+
+// Attribute is an a= line, RFC 8866 §5.13. A property attribute, such as
+// "a=sendrecv", has no value.
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// Media is an m= line and the lines of its media description, RFC 8866
+// §5.14.
+type Media struct {
+	Type          string
+	Port          int
+	PortCount     int //0 when the m= line has no port count
+	Protocol      string
+	Formats       []string
+	Information   string        //i=
+	Connections   []*Connection //c=
+	Bandwidths    []Bandwidth   //b=
+	EncryptionKey string        //k=
+	Attributes    []*Attribute  //a=
+}
+
+// NewSessionDescription returns a description with the mandatory fields
+// set: an origin for address, an empty session name and a permanent
+// session timing.
+func NewSessionDescription(username string, sessionId uint64, address string) *SessionDescription {
+	return &SessionDescription{
+		Origin: Origin{
+			Username:       username,
+			SessionId:      sessionId,
+			SessionVersion: sessionId,
+			NetworkType:    "IN",
+			AddressType:    addressType(address),
+			Address:        address,
+		},
+		SessionName: "-",
+		Connection:  NewConnection(address),
+		Timings:     []Timing{{}},
+	}
+}
+
+// NewConnection returns the c= line of a unicast address.
+func NewConnection(address string) *Connection {
+	return &Connection{NetworkType: "IN", AddressType: addressType(address), Address: address}
+}
+
+// IsSessionDescription reports whether contentType is application/sdp.
+func IsSessionDescription(contentType header.ContentTypeHeader) bool {
+	return contentType != nil &&
+		strings.EqualFold(contentType.GetContentType(), "application") &&
+		strings.EqualFold(contentType.GetContentSubType(), "sdp")
+}
+
+// NewContentType returns the Content-Type header of a session description.
+func NewContentType() *header.ContentType {
+	return header.NewContentTypeFromString("application", "sdp")
+}
+
+// GetAttribute returns the value of the first session-level attribute
+// called name, and whether there is one.
+func (this *SessionDescription) GetAttribute(name string) (string, bool) {
+	return getAttribute(this.Attributes, name)
+}
+
+// SetAttribute replaces the session-level attributes called name.
+func (this *SessionDescription) SetAttribute(name, value string) {
+	this.Attributes = setAttribute(this.Attributes, name, value)
+}
+
+// RemoveAttribute deletes the session-level attributes called name.
+func (this *SessionDescription) RemoveAttribute(name string) {
+	this.Attributes = removeAttribute(this.Attributes, name)
+}
+
+// GetMedia returns the media descriptions of the given type, e.g. "audio".
+func (this *SessionDescription) GetMedia(mediaType string) []*Media {
+	var media []*Media
+	for _, m := range this.Media {
+		if m.Type == mediaType {
+			media = append(media, m)
+		}
+	}
+	return media
+}
+
+// GetConnection returns the connection of m, its own or the session-level
+// one.
+func (this *SessionDescription) GetConnection(m *Media) *Connection {
+	if len(m.Connections) > 0 {
+		return m.Connections[0]
+	}
+	return this.Connection
+}
+
+// GetDirection returns the direction of m, RFC 3264 §5.1: its own
+// direction attribute, else the session-level one, else sendrecv.
+func (this *SessionDescription) GetDirection(m *Media) Direction {
+	if d, ok := direction(m.Attributes); ok {
+		return d
+	}
+	if d, ok := direction(this.Attributes); ok {
+		return d
+	}
+	return SENDRECV
+}
+
+// SetConnectionAddress rewrites the session-level and media-level unicast
+// connection addresses, as well as the origin address, to address. It is
+// used to advertise the public address of a UA behind a NAT, or the
+// address of a media relay.
+func (this *SessionDescription) SetConnectionAddress(address string) {
+	this.Origin.Address = address
+	this.Origin.AddressType = addressType(address)
+	if this.Connection != nil {
+		this.Connection.setAddress(address)
+	}
+	for _, m := range this.Media {
+		for _, c := range m.Connections {
+			c.setAddress(address)
+		}
+	}
+}
+
+// FindCodec returns the first media description of the given type that
+// offers the named codec, and that codec.
+func (this *SessionDescription) FindCodec(mediaType, name string) (*Media, *Codec) {
+	for _, m := range this.GetMedia(mediaType) {
+		if c := m.FindCodec(name); c != nil {
+			return m, c
+		}
+	}
+	return nil, nil
+}
+
+// Clone returns a deep copy of the description.
+func (this *SessionDescription) Clone() *SessionDescription {
+	clone := *this
+	clone.Emails = append([]string(nil), this.Emails...)
+	clone.Phones = append([]string(nil), this.Phones...)
+	if this.Connection != nil {
+		c := *this.Connection
+		clone.Connection = &c
+	}
+	clone.Bandwidths = append([]Bandwidth(nil), this.Bandwidths...)
+	clone.Timings = make([]Timing, len(this.Timings))
+	for i, t := range this.Timings {
+		clone.Timings[i] = Timing{Start: t.Start, Stop: t.Stop, Repeats: append([]string(nil), t.Repeats...)}
+	}
+	clone.Attributes = cloneAttributes(this.Attributes)
+	clone.Media = make([]*Media, len(this.Media))
+	for i, m := range this.Media {
+		clone.Media[i] = m.Clone()
+	}
+	return &clone
+}
+
+// Marshal encodes the description. The lines are always written in the
+// order of RFC 8866 §5 so that equal descriptions encode identically.
+func (this *SessionDescription) Marshal() []byte {
+	var b bytes.Buffer
+	line(&b, 'v', strconv.Itoa(this.Version))
+	line(&b, 'o', this.Origin.String())
+	sessionName := this.SessionName
+	if sessionName == "" {
+		//s= is mandatory, a single space or "-" stands for no name
+		sessionName = "-"
+	}
+	line(&b, 's', sessionName)
+	optionalLine(&b, 'i', this.Information)
+	optionalLine(&b, 'u', this.URI)
+	for _, e := range this.Emails {
+		line(&b, 'e', e)
+	}
+	for _, p := range this.Phones {
+		line(&b, 'p', p)
+	}
+	if this.Connection != nil {
+		line(&b, 'c', this.Connection.String())
+	}
+	for _, bw := range this.Bandwidths {
+		line(&b, 'b', bw.String())
+	}
+	timings := this.Timings
+	if len(timings) == 0 {
+		timings = []Timing{{}}
+	}
+	for _, t := range timings {
+		line(&b, 't', strconv.FormatUint(t.Start, 10)+" "+strconv.FormatUint(t.Stop, 10))
+		for _, r := range t.Repeats {
+			line(&b, 'r', r)
+		}
+	}
+	optionalLine(&b, 'z', this.TimeZones)
+	optionalLine(&b, 'k', this.EncryptionKey)
+	for _, a := range this.Attributes {
+		line(&b, 'a', a.String())
+	}
+	for _, m := range this.Media {
+		m.marshal(&b)
+	}
+	return b.Bytes()
+}
+
+func (this *SessionDescription) String() string {
+	return string(this.Marshal())
+}
+
+func (this Origin) String() string {
+	username := this.Username
+	if username == "" {
+		username = "-"
+	}
+	return username + " " + strconv.FormatUint(this.SessionId, 10) + " " +
+		strconv.FormatUint(this.SessionVersion, 10) + " " +
+		this.NetworkType + " " + this.AddressType + " " + this.Address
+}
+
+func (this *Connection) String() string {
+	return this.NetworkType + " " + this.AddressType + " " + this.Address
+}
+
+// IsMulticast reports whether the connection address is a multicast group.
+func (this *Connection) IsMulticast() bool {
+	ip := net.ParseIP(strings.SplitN(this.Address, "/", 2)[0])
+	return ip != nil && ip.IsMulticast()
+}
+
+// IsHold reports whether the connection is the RFC 2543 hold address
+// 0.0.0.0.
+func (this *Connection) IsHold() bool {
+	return this.Address == "0.0.0.0"
+}
+
+func (this *Connection) setAddress(address string) {
+	if this.IsMulticast() {
+		return
+	}
+	this.Address = address
+	this.AddressType = addressType(address)
+}
+
+func (this Bandwidth) String() string {
+	return this.Type + ":" + strconv.Itoa(this.Value)
+}
+
+func (this *Attribute) String() string {
+	if this.Value == "" {
+		return this.Name
+	}
+	return this.Name + ":" + this.Value
+}
+
+// GetAttribute returns the value of the first attribute of the media
+// called name, and whether there is one.
+func (this *Media) GetAttribute(name string) (string, bool) {
+	return getAttribute(this.Attributes, name)
+}
+
+// GetAttributes returns the values of the attributes of the media called
+// name.
+func (this *Media) GetAttributes(name string) []string {
+	var values []string
+	for _, a := range this.Attributes {
+		if a.Name == name {
+			values = append(values, a.Value)
+		}
+	}
+	return values
+}
+
+// SetAttribute replaces the attributes of the media called name.
+func (this *Media) SetAttribute(name, value string) {
+	this.Attributes = setAttribute(this.Attributes, name, value)
+}
+
+// AddAttribute appends an attribute to the media.
+func (this *Media) AddAttribute(name, value string) {
+	this.Attributes = append(this.Attributes, &Attribute{Name: name, Value: value})
+}
+
+// RemoveAttribute deletes the attributes of the media called name.
+func (this *Media) RemoveAttribute(name string) {
+	this.Attributes = removeAttribute(this.Attributes, name)
+}
+
+// IsRejected reports whether the media stream is disabled, by a zero port,
+// RFC 3264 §5.1.
+func (this *Media) IsRejected() bool {
+	return this.Port == 0
+}
+
+// Clone returns a deep copy of the media description.
+func (this *Media) Clone() *Media {
+	clone := *this
+	clone.Formats = append([]string(nil), this.Formats...)
+	clone.Connections = make([]*Connection, len(this.Connections))
+	for i, c := range this.Connections {
+		cc := *c
+		clone.Connections[i] = &cc
+	}
+	clone.Bandwidths = append([]Bandwidth(nil), this.Bandwidths...)
+	clone.Attributes = cloneAttributes(this.Attributes)
+	return &clone
+}
+
+func (this *Media) marshal(b *bytes.Buffer) {
+	port := strconv.Itoa(this.Port)
+	if this.PortCount > 0 {
+		port += "/" + strconv.Itoa(this.PortCount)
+	}
+	fields := append([]string{this.Type, port, this.Protocol}, this.Formats...)
+	line(b, 'm', strings.Join(fields, " "))
+	optionalLine(b, 'i', this.Information)
+	for _, c := range this.Connections {
+		line(b, 'c', c.String())
+	}
+	for _, bw := range this.Bandwidths {
+		line(b, 'b', bw.String())
+	}
+	optionalLine(b, 'k', this.EncryptionKey)
+	for _, a := range this.Attributes {
+		line(b, 'a', a.String())
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func line(b *bytes.Buffer, typ byte, value string) {
+	b.WriteByte(typ)
+	b.WriteByte('=')
+	b.WriteString(value)
+	b.WriteString("\r\n")
+}
+
+func optionalLine(b *bytes.Buffer, typ byte, value string) {
+	if value != "" {
+		line(b, typ, value)
+	}
+}
+
+// addressType returns the c= and o= address type of address: IP6 for an
+// IPv6 address, IP4 for anything else, host names included.
+func addressType(address string) string {
+	if ip := net.ParseIP(address); ip != nil && ip.To4() == nil {
+		return "IP6"
+	}
+	return "IP4"
+}
+
+func getAttribute(attributes []*Attribute, name string) (string, bool) {
+	for _, a := range attributes {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// setAttribute replaces the first attribute called name, in place, and
+// deletes the others.
+func setAttribute(attributes []*Attribute, name, value string) []*Attribute {
+	for i, a := range attributes {
+		if a.Name == name {
+			attributes[i] = &Attribute{Name: name, Value: value}
+			return append(attributes[:i+1], removeAttribute(attributes[i+1:], name)...)
+		}
+	}
+	return append(attributes, &Attribute{Name: name, Value: value})
+}
+
+func removeAttribute(attributes []*Attribute, name string) []*Attribute {
+	kept := attributes[:0]
+	for _, a := range attributes {
+		if a.Name != name {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+func cloneAttributes(attributes []*Attribute) []*Attribute {
+	clone := make([]*Attribute, len(attributes))
+	for i, a := range attributes {
+		aa := *a
+		clone[i] = &aa
+	}
+	return clone
+}
@@ -0,0 +1,164 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+)
+
+// the example of RFC 8866 §5, with a media-level connection and an rtpmap
+const testSDP = "v=0\r\n" +
+	"o=jdoe 3724394400 3724394405 IN IP4 198.51.100.1\r\n" +
+	"s=Call to John Smith\r\n" +
+	"i=SDP Offer #1\r\n" +
+	"u=http://www.jdoe.example.com/home.html\r\n" +
+	"e=Jane Doe <jane@jdoe.example.com>\r\n" +
+	"p=+1 617 555-6011\r\n" +
+	"c=IN IP4 198.51.100.1\r\n" +
+	"b=AS:128\r\n" +
+	"t=0 0\r\n" +
+	"a=recvonly\r\n" +
+	"m=audio 49170 RTP/AVP 0 96\r\n" +
+	"a=rtpmap:96 opus/48000/2\r\n" +
+	"a=fmtp:96 useinbandfec=1\r\n" +
+	"a=ptime:20\r\n" +
+	"m=video 51372/2 RTP/AVP 99\r\n" +
+	"c=IN IP6 2001:db8::2\r\n" +
+	"a=rtpmap:99 h263-1998/90000\r\n" +
+	"a=sendonly\r\n"
+
+func TestParse(t *testing.T) {
+	sd, err := Parse([]byte(testSDP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Origin.Username != "jdoe" || sd.Origin.SessionVersion != 3724394405 || sd.SessionName != "Call to John Smith" ||
+		sd.Connection.Address != "198.51.100.1" || len(sd.Bandwidths) != 1 || sd.Bandwidths[0].Value != 128 {
+		t.Log(sd.Origin, sd.SessionName, sd.Connection)
+		t.Fail()
+	}
+	if len(sd.Media) != 2 {
+		t.Fatal(len(sd.Media))
+	}
+	audio, video := sd.Media[0], sd.Media[1]
+	if audio.Port != 49170 || len(audio.Formats) != 2 || audio.GetPtime() != 20 ||
+		sd.GetDirection(audio) != RECVONLY || sd.GetConnection(audio) != sd.Connection {
+		t.Log(audio)
+		t.Fail()
+	}
+	if video.Port != 51372 || video.PortCount != 2 || sd.GetDirection(video) != SENDONLY ||
+		sd.GetConnection(video).AddressType != "IP6" {
+		t.Log(video)
+		t.Fail()
+	}
+
+	//serialising gives back the original text
+	if s := sd.String(); s != testSDP {
+		t.Log(s)
+		t.Fail()
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	sd, err := Parse([]byte("v=0\n" +
+		"o=- 1 1 IN IP4 192.0.2.1\n" +
+		"s=\n" +
+		"\n" +
+		"c=IN IP4\n" +
+		"t=0 0\n" +
+		"x=unknown\n" +
+		"m=audio 9 RTP/AVP 0\n" +
+		"garbage\n" +
+		"a=sendrecv\n"))
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 2 || errs[0].Line != 5 || errs[1].Line != 9 {
+		t.Fatal(err)
+	}
+	if sd.Connection != nil || len(sd.Media) != 1 || sd.GetDirection(sd.Media[0]) != SENDRECV {
+		t.Log(sd)
+		t.Fail()
+	}
+	if !strings.Contains(errs[0].Error(), "line 5: malformed connection") {
+		t.Log(errs[0])
+		t.Fail()
+	}
+
+	if _, err := Parse([]byte("s=-\r\nt=0 0\r\n")); err == nil {
+		t.Log("a description without v= and o= was accepted")
+		t.Fail()
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	sd, _ := Parse([]byte(testSDP))
+	codecs := sd.Media[0].GetCodecs()
+	if len(codecs) != 2 || codecs[0].Name != "PCMU" || codecs[0].ClockRate != 8000 ||
+		codecs[1].String() != "opus/48000/2" || codecs[1].Fmtp != "useinbandfec=1" {
+		t.Log(codecs)
+		t.Fail()
+	}
+	if m, c := sd.FindCodec("audio", "OPUS"); m != sd.Media[0] || c == nil || c.PayloadType != 96 {
+		t.Log(m, c)
+		t.Fail()
+	}
+	if m, c := sd.FindCodec("video", "PCMU"); m != nil || c != nil {
+		t.Fail()
+	}
+
+	m := &Media{Type: "audio", Port: 4000, Protocol: "RTP/AVP"}
+	m.AddCodec(&Codec{PayloadType: 101, Name: "telephone-event", ClockRate: 8000, Fmtp: "0-16"})
+	m.AddCodec(&Codec{PayloadType: 8, Name: "PCMA", ClockRate: 8000})
+	m.RemoveFormat("101")
+	if len(m.Formats) != 1 || len(m.Attributes) != 1 || m.Attributes[0].String() != "rtpmap:8 PCMA/8000" {
+		t.Log(m.Formats, m.Attributes)
+		t.Fail()
+	}
+}
+
+func TestSetConnectionAddress(t *testing.T) {
+	sd, _ := Parse([]byte(testSDP))
+	clone := sd.Clone()
+	sd.SetConnectionAddress("203.0.113.7")
+	if sd.Origin.Address != "203.0.113.7" || sd.Connection.Address != "203.0.113.7" ||
+		sd.Media[1].Connections[0].Address != "203.0.113.7" || sd.Media[1].Connections[0].AddressType != "IP4" {
+		t.Log(sd)
+		t.Fail()
+	}
+	if clone.String() != testSDP {
+		t.Log("the clone shares the connections of the description")
+		t.Fail()
+	}
+}
+
+func TestSetDirection(t *testing.T) {
+	sd, _ := Parse([]byte(testSDP))
+	video := sd.Media[1]
+	video.SetDirection(INACTIVE)
+	if d, ok := video.GetDirection(); !ok || d != INACTIVE || len(video.Attributes) != 2 {
+		t.Log(video.Attributes)
+		t.Fail()
+	}
+	if SENDONLY.Reverse() != RECVONLY || INACTIVE.Reverse() != INACTIVE || RECVONLY.Sends() {
+		t.Fail()
+	}
+}
+
+func TestNewSessionDescription(t *testing.T) {
+	sd := NewSessionDescription("alice", 2890844526, "2001:db8::1")
+	m := &Media{Type: "audio", Port: 49172, Protocol: "RTP/AVP"}
+	m.AddCodec(&Codec{PayloadType: 0, Name: "PCMU", ClockRate: 8000})
+	sd.Media = append(sd.Media, m)
+	expected := "v=0\r\n" +
+		"o=alice 2890844526 2890844526 IN IP6 2001:db8::1\r\n" +
+		"s=-\r\n" +
+		"c=IN IP6 2001:db8::1\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 49172 RTP/AVP 0\r\n" +
+		"a=rtpmap:0 PCMU/8000\r\n"
+	if s := sd.String(); s != expected {
+		t.Log(s)
+		t.Fail()
+	}
+	if !IsSessionDescription(NewContentType()) {
+		t.Fail()
+	}
+}